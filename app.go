@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+
+	"message-service/internal/config"
+	"message-service/internal/handler"
+	"message-service/internal/httpx"
+	"message-service/internal/middleware"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/gpostgresql"
+	"message-service/internal/pkg/gredis"
+	"message-service/internal/pkg/instance"
+	"message-service/internal/pkg/lifecycle"
+	"message-service/internal/pkg/localredis"
+	"message-service/internal/service"
+)
+
+// app holds the storage, services and background jobs every command shares.
+type app struct {
+	config *config.App
+	logger inslogger.Interface
+
+	dbPool         *pgxpool.Pool
+	replicaPool    *pgxpool.Pool
+	messageService mpostgres.MessageService
+	schedulerRuns  mpostgres.SchedulerRunService
+	messageAudit   mpostgres.MessageAuditService
+	redisClient    insredis.RedisInterface
+	leaderElector  interface {
+		service.LeaderElector
+		Run(ctx context.Context)
+	}
+	readinessChecks map[string]handler.ReadinessCheck
+	rateLimiter     *middleware.RateLimiter
+	outbound        service.OutboundLimiter
+	breakers        []*service.CircuitBreaker
+	// events publishes to eventBus and to the sinks EVENTS_STREAM and EVENTS_HTTP_URL set.
+	events service.EventPublisher
+	// eventBus streams events to the clients of GET /api/messages/stream.
+	eventBus *service.EventBus
+	// eventTail feeds eventBus from EVENTS_STREAM, so it sees the events of every replica.
+	// It is nil without the stream, eventBus is then fed by this replica alone.
+	eventTail *service.EventStreamTail
+
+	dispatcher            service.DispatchService
+	schedulerService      service.SchedulerService
+	schedulerState        service.SchedulerStateStore
+	templateService       service.TemplateService
+	apiKeyService         service.APIKeyService
+	providerConfigService service.ProviderConfigService
+	quotas                *service.QuotaCounter
+	retentionJob          *service.RetentionJob
+	claimReaper           *service.ClaimReaper
+	scalingAdvisor        *service.ScalingAdvisor
+	tuningAdvisor         *service.TuningAdvisor
+	cacheChecker          *service.CacheConsistencyChecker
+	statsService          *service.StatsService
+	diagnostics           *service.Diagnostics
+	// moderation is nil unless MODERATION_SCOPES is set.
+	moderation *service.ModerationService
+	// queue is nil unless QUEUE_MODE is stream.
+	queue service.MessageQueue
+	// messageListener is nil in local mode.
+	messageListener mpostgres.MessageListener
+
+	// lifecycle stops the pools and, once added, the background jobs on shutdown.
+	lifecycle *lifecycle.Manager
+}
+
+// newApp connects to storage, or sets up in-memory storage in local mode, and builds the
+// services. Failures stop the process.
+func newApp(ctx context.Context, appConfig *config.App, logger inslogger.Interface) *app {
+	a := &app{config: appConfig, logger: logger, lifecycle: lifecycle.New(logger)}
+
+	var (
+		templateRepo       mpostgres.TemplateRepository
+		apiKeyRepo         mpostgres.APIKeyRepository
+		providerConfigRepo mpostgres.ProviderConfigRepository
+	)
+	outboundLimits := model.OutboundLimits{Rate: appConfig.Outbound.Rate, Burst: appConfig.Outbound.Burst}
+
+	if appConfig.Local.Enabled {
+		logger.Warn("LOCAL_MODE is enabled, using in-memory storage instead of PostgreSQL and Redis")
+		seed, err := readSeedMessages(appConfig.Local.SeedFile)
+		if err != nil {
+			logger.Fatal(fmt.Errorf("failed to read local seed messages: %w", err))
+		}
+		memoryMessages := mmemory.NewMessageService(logger, seed...)
+		memoryMessages.SetPriorityAging(appConfig.Scheduler.PriorityAging)
+		memoryMessages.SetClockSkew(appConfig.Scheduler.ClockSkew)
+		memoryMessages.SetTenantBatchLimits(appConfig.Tenants.BatchLimits)
+		a.messageService = memoryMessages
+		a.schedulerRuns = mmemory.NewSchedulerRunService(appConfig.Scheduler.RunRetention)
+		a.messageAudit = mmemory.NewMessageAuditService()
+		templateRepo = mmemory.NewTemplateRepository()
+		apiKeyRepo = mmemory.NewAPIKeyRepository()
+		providerConfigRepo = mmemory.NewProviderConfigRepository()
+		a.readinessChecks = map[string]handler.ReadinessCheck{}
+		a.withoutRedis(outboundLimits)
+	} else {
+		logger.Log("Connecting to the database...")
+		var err error
+		a.dbPool, err = gpostgresql.NewDBConnection(ctx, &appConfig.Database, logger)
+		if err != nil {
+			logger.Fatal(fmt.Errorf("database connection failed: %w", err))
+		}
+		logger.Log("Connected to the database.")
+		if appConfig.Database.ReplicaURL != "" {
+			replicaConfig := appConfig.Database
+			replicaConfig.URL = appConfig.Database.ReplicaURL
+			a.replicaPool, err = gpostgresql.NewDBConnection(ctx, &replicaConfig, logger)
+			if err != nil {
+				logger.Fatal(fmt.Errorf("read replica connection failed: %w", err))
+			}
+			logger.Log("Listings are read from the replica.")
+		}
+		reads := mpostgres.NewReadPool(a.dbPool, a.replicaPool, appConfig.Database.ReplicaRetry, logger)
+
+		a.messageService = mpostgres.NewMessageService(a.dbPool, reads, appConfig.Scheduler.PriorityAging, appConfig.Scheduler.ClockSkew, appConfig.Tenants.BatchLimits, logger)
+		a.schedulerRuns = mpostgres.NewSchedulerRunService(a.dbPool, appConfig.Scheduler.RunRetention, logger)
+		a.messageAudit = mpostgres.NewMessageAuditService(a.dbPool)
+		a.messageListener = mpostgres.NewMessageListener(a.dbPool)
+		templateRepo = mpostgres.NewTemplateRepository(a.dbPool, logger)
+		apiKeyRepo = mpostgres.NewAPIKeyRepository(a.dbPool, logger)
+		providerConfigRepo = mpostgres.NewProviderConfigRepository(a.dbPool, logger)
+
+		a.readinessChecks = map[string]handler.ReadinessCheck{"database": a.dbPool.Ping}
+		if appConfig.Redis.Enabled {
+			a.withRedis(outboundLimits)
+		} else {
+			logger.Warn("REDIS_ENABLED is false, keeping caches, locks and limits in process; run a single replica")
+			a.withoutRedis(outboundLimits)
+		}
+	}
+
+	// Appended first so they are closed last, after everything that writes to them.
+	a.lifecycle.Append(lifecycle.Hook{
+		Name: "PostgreSQL connection pool",
+		Stop: func(ctx context.Context) error {
+			gpostgresql.Close(ctx, a.replicaPool, logger)
+			gpostgresql.Close(ctx, a.dbPool, logger)
+			return nil
+		},
+	})
+	a.lifecycle.Append(lifecycle.Hook{
+		Name: "Redis connection pool",
+		Stop: func(context.Context) error {
+			return a.redisClient.Close()
+		},
+	})
+
+	logger.Log("Initializing services...")
+	audit := service.NewMessageAudit(a.messageAudit, logger)
+	a.messageService = service.NewAuditedMessageService(a.messageService, audit)
+	// The checker compares the cache with the database, so it reads around the cache.
+	a.cacheChecker = service.NewCacheConsistencyChecker(a.messageService, a.redisClient, a.leaderElector,
+		appConfig.Cache.CheckSample, appConfig.Cache.CheckInterval, logger)
+	a.messageService = service.NewCachedMessageService(a.messageService, a.redisClient, appConfig.Cache.MessageTTL, appConfig.Cache.SentMarkerTTL, logger)
+	if appConfig.Queue.Mode == config.QueueModeStream {
+		a.queue = service.NewRedisMessageQueue(a.redisClient, appConfig.Queue.Stream, appConfig.Queue.MaxLen)
+		a.messageService = service.NewQueueingMessageService(a.messageService, a.queue, logger)
+	}
+	smsBreaker := service.NewCircuitBreaker(appConfig.SMS.Driver, appConfig.Provider.BreakerThreshold, appConfig.Provider.BreakerCooldown, logger)
+	httpClient, err := httpx.NewClient(&appConfig.HTTP)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("failed to configure http client: %w", err))
+	}
+	a.events = a.newEventPublisher(httpClient)
+	smsProvider, err := service.NewSMSProvider(smsBreaker, httpClient, appConfig, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	a.breakers = []*service.CircuitBreaker{smsBreaker}
+	smsProvider = service.NewTenantWebhookProvider(smsProvider, providerConfigRepo, appConfig.Tenants.WebhookConfigTTL, func(tenant string) *service.CircuitBreaker {
+		return service.NewCircuitBreaker(appConfig.SMS.Driver+"-"+tenant, appConfig.Provider.BreakerThreshold, appConfig.Provider.BreakerCooldown, logger)
+	}, logger)
+	tenants := service.NewTenantEnvironments(appConfig.Tenants.Environments, appConfig.Tenants.DefaultEnvironment)
+	if tenants.HasSandbox() {
+		var sandboxProvider service.Provider
+		if sandboxConfig, ok := appConfig.Sandbox(); ok {
+			sandboxBreaker := service.NewCircuitBreaker(appConfig.SMS.Driver+"-sandbox", appConfig.Provider.BreakerThreshold, appConfig.Provider.BreakerCooldown, logger)
+			a.breakers = append(a.breakers, sandboxBreaker)
+			if sandboxProvider, err = service.NewSMSProvider(sandboxBreaker, httpClient, sandboxConfig, logger); err != nil {
+				logger.Fatal(err)
+			}
+		} else {
+			logger.Warnf("No sandbox credentials for the %s provider, messages of sandbox tenants will fail", appConfig.SMS.Driver)
+		}
+		smsProvider = service.NewEnvironmentProvider(smsProvider, sandboxProvider, tenants, logger)
+	}
+	providers := service.NewProviderRegistry()
+	providers.Register(model.ChannelSMS, smsProvider)
+	if appConfig.SMTP.Host != "" {
+		providers.Register(model.ChannelEmail, service.NewSMTPProvider(&appConfig.SMTP, logger))
+	} else {
+		logger.Warn("SMTP_HOST is empty, email messages cannot be sent")
+	}
+	a.moderation = a.newModerationService(httpClient)
+	quietHours, err := service.ParseQuietHours(appConfig.QuietHours.Window, appConfig.QuietHours.Timezone, appConfig.QuietHours.CountryTimezones)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid QUIET_HOURS settings: %w", err))
+	}
+	a.dispatcher = service.NewDispatchService(a.messageService, a.redisClient, providers, a.events, a.moderation, a.outbound, quietHours, audit, appConfig, logger)
+	schedules, err := service.ResolveChannelSettings(providers.Channels(),
+		service.ChannelSettings{BatchSize: appConfig.Scheduler.BatchSize, Interval: appConfig.Scheduler.Interval},
+		appConfig.Scheduler.ChannelOverrides, appConfig.Scheduler.ChannelFile)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid scheduler channel settings: %w", err))
+	}
+	reportedRuns := service.NewBatchReportingRunService(a.schedulerRuns, appConfig.Report.URL, appConfig.Report.Timeout, httpClient, logger)
+	a.schedulerService = service.NewSchedulerService(a.dispatcher, a.leaderElector, reportedRuns, schedules, service.WarmupSettings{
+		Batches:        appConfig.Scheduler.WarmupBatches,
+		SkipFirstBatch: appConfig.Scheduler.SkipFirstBatch,
+	}, logger, service.NewLogAlertHook(logger))
+	a.schedulerState = service.NewRedisSchedulerStateStore(a.redisClient)
+	a.templateService = service.NewTemplateService(templateRepo, logger)
+	a.apiKeyService = service.NewAPIKeyService(apiKeyRepo, logger)
+	a.providerConfigService = service.NewProviderConfigService(providerConfigRepo, logger)
+	a.quotas = service.NewQuotaCounter(a.redisClient)
+	a.retentionJob = service.NewRetentionJob(a.messageService, a.leaderElector, appConfig.Retention.MaxAge, appConfig.Retention.Interval, model.PurgeMode(appConfig.Retention.Mode), logger)
+	a.claimReaper = service.NewClaimReaper(a.messageService, a.leaderElector, appConfig.Scheduler.ReapInterval, logger)
+	a.scalingAdvisor = service.NewScalingAdvisor(a.messageService, appConfig.Scaling.WorkerThroughput, appConfig.Scaling.DrainTarget, appConfig.Scaling.MinReplicas, appConfig.Scaling.MaxReplicas)
+	a.statsService = service.NewStatsService(a.messageService, service.NewStatsCounters(a.redisClient))
+	pings := make(map[string]func(ctx context.Context) error, len(a.readinessChecks))
+	for name, check := range a.readinessChecks {
+		pings[name] = check
+	}
+	a.diagnostics = service.NewDiagnostics(pings, providers, a.messageService, a.breakers, a.schedulerService, a.leaderElector, appConfig, instance.ID())
+	a.tuningAdvisor = service.NewTuningAdvisor(a.schedulerRuns, a.messageService, a.schedulerService, a.leaderElector, appConfig.Tuning.Runs,
+		appConfig.Scaling.DrainTarget, appConfig.Scheduler.SendConcurrency, appConfig.Tuning.LogInterval, logger)
+
+	return a
+}
+
+// withRedis connects to Redis and keeps caches, locks, limits and the scheduler leadership
+// there. Failures stop the process.
+func (a *app) withRedis(outboundLimits model.OutboundLimits) {
+	redisClient, err := gredis.NewRedisClient(&a.config.Redis)
+	if err != nil {
+		a.logger.Fatal(err)
+	}
+	if err := redisClient.Ping().Err(); err != nil {
+		a.logger.Fatal(fmt.Errorf("failed to connect to Redis: %w", err))
+	}
+	a.redisClient = redisClient
+	a.logger.Log("Connected to Redis.")
+
+	a.leaderElector = service.NewRedisLeaderElector(redisClient, instance.ID(), a.config.Scheduler.LeaderTTL, a.logger)
+	a.readinessChecks["redis"] = func(ctx context.Context) error {
+		return redisClient.Ping().Err()
+	}
+	a.rateLimiter = middleware.NewRateLimiter(redisClient, a.config.RateLimit.Window, a.config.RateLimit.Classes, a.logger)
+	a.outbound = service.NewRedisOutboundLimiter(redisClient, outboundLimits, a.config.Outbound.MaxWait)
+}
+
+// withoutRedis keeps caches, locks, limits and the scheduler leadership in process, which
+// only holds them for this replica.
+func (a *app) withoutRedis(outboundLimits model.OutboundLimits) {
+	a.redisClient = localredis.NewWithMaxKeys(a.config.Redis.LocalMaxKeys)
+	a.leaderElector = service.NewLocalLeaderElector(instance.ID())
+	a.rateLimiter = middleware.NewLocalRateLimiter(a.config.RateLimit.Window, a.config.RateLimit.Classes, a.logger)
+	a.outbound = service.NewLocalOutboundLimiter(outboundLimits, a.config.Outbound.MaxWait)
+}
+
+// newEventPublisher returns the publisher of the event bus and every configured event
+// sink. Each sink buffers and retries on its own, so a down collector does not hold back
+// the stream.
+func (a *app) newEventPublisher(httpClient *http.Client) service.EventPublisher {
+	eventsConfig := a.config.Events
+	a.eventBus = service.NewEventBus(eventsConfig.SSEBuffer)
+
+	var sinks []service.EventPublisher
+	if eventsConfig.Stream != "" {
+		sink := service.NewRedisStreamPublisher(a.redisClient, eventsConfig.Stream, eventsConfig.MaxLen)
+		if a.config.RedisInProcess() {
+			sink = service.NewLogEventPublisher(a.logger)
+		} else {
+			a.eventTail = service.NewEventStreamTail(a.redisClient, eventsConfig.Stream, a.eventBus, a.logger)
+		}
+		sinks = append(sinks, sink)
+	}
+	if eventsConfig.HTTPURL != "" {
+		sinks = append(sinks, service.NewHTTPEventPublisher(httpClient, eventsConfig.HTTPURL, eventsConfig.HTTPFormat, eventsConfig.HTTPTimeout))
+	}
+
+	publishers := make([]service.EventPublisher, 0, len(sinks)+1)
+	if a.eventTail == nil {
+		// The bus never blocks, it needs no buffer of its own.
+		publishers = append(publishers, a.eventBus)
+	}
+	for _, sink := range sinks {
+		publisher := service.NewAsyncEventPublisher(sink, eventsConfig.BufferSize, eventsConfig.RetryBackoff, a.logger)
+		publishers = append(publishers, publisher)
+		// Stopped after everything that publishes and before the Redis pool it publishes to.
+		a.lifecycle.Append(lifecycle.Hook{
+			Name:  "event publisher",
+			Start: publisher.Start,
+			Stop:  publisher.Stop,
+		})
+	}
+	return service.NewFanoutEventPublisher(publishers...)
+}
+
+// newModerationService builds the moderators MODERATION_SCOPES names, or returns nil when
+// no scope is configured.
+func (a *app) newModerationService(httpClient *http.Client) *service.ModerationService {
+	moderationConfig := a.config.Moderation
+	if len(moderationConfig.Scopes) == 0 {
+		return nil
+	}
+
+	var webhook, rules service.Moderator
+	if moderationConfig.WebhookURL != "" {
+		webhook = service.NewWebhookModerator(moderationConfig.WebhookURL, moderationConfig.WebhookTimeout, httpClient)
+	}
+	if moderationConfig.RulesFile != "" {
+		parsed, err := service.ReadModerationRules(moderationConfig.RulesFile)
+		if err != nil {
+			a.logger.Fatal(fmt.Errorf("failed to read moderation rules: %w", err))
+		}
+		rules = service.NewRuleModerator(parsed)
+	}
+
+	scopes := make(map[string]service.Moderator, len(moderationConfig.Scopes))
+	for scope, moderator := range moderationConfig.Scopes {
+		switch moderator {
+		case config.ModeratorWebhook:
+			scopes[scope] = webhook
+		case config.ModeratorRules:
+			scopes[scope] = rules
+		default:
+			scopes[scope] = nil
+		}
+	}
+	return service.NewModerationService(scopes, a.messageService, model.ModerationDecision(moderationConfig.OnError), a.logger)
+}
+
+// addBackgroundJobs registers leader election, the retention job, the claim reaper, the
+// tuning advisor, the cache consistency check, the queue consumer, the scheduler wakeup and
+// the scheduler with the lifecycle.
+// Whether the scheduler starts follows SCHEDULER_AUTOSTART, with startScheduler deciding
+// when nothing else does; the queue consumer only reads while the scheduler runs.
+func (a *app) addBackgroundJobs(startScheduler bool) {
+	a.lifecycle.Append(lifecycle.Background("leader election", a.leaderElector.Run))
+	a.lifecycle.Append(lifecycle.Background("retention job", a.retentionJob.Run))
+	a.lifecycle.Append(lifecycle.Background("claim reaper", a.claimReaper.Run))
+	a.lifecycle.Append(lifecycle.Background("tuning advisor", a.tuningAdvisor.Run))
+	a.lifecycle.Append(lifecycle.Background("cache consistency check", a.cacheChecker.Run))
+	if a.queue != nil {
+		queueConfig := a.config.Queue
+		consumer := service.NewQueueConsumer(a.redisClient, a.dispatcher, a.schedulerService.IsRunning,
+			queueConfig.Stream, queueConfig.Group, instance.ID(), queueConfig.BatchSize, queueConfig.Block, queueConfig.ReclaimIdle, a.logger)
+		a.lifecycle.Append(lifecycle.Background("queue consumer", consumer.Run))
+	}
+	if a.messageListener != nil && a.config.Scheduler.WakeOnInsert {
+		wakeup := service.NewSchedulerWakeup(a.messageListener, a.schedulerService, time.Second, a.logger)
+		a.lifecycle.Append(lifecycle.Background("scheduler wakeup", wakeup.Run))
+	}
+	a.lifecycle.Append(lifecycle.Hook{
+		Name: "scheduler",
+		Start: func(ctx context.Context) error {
+			if !a.autostartScheduler(startScheduler) {
+				a.logger.Log("Scheduler stays stopped until POST /api/scheduler/start")
+				return nil
+			}
+			return a.schedulerService.Start(ctx)
+		},
+		Stop: func(ctx context.Context) error {
+			return a.schedulerService.Stop(ctx)
+		},
+	})
+}
+
+// autostartScheduler applies SCHEDULER_AUTOSTART. In auto mode the scheduler resumes the
+// state operators last set through the API, and fallback applies when they never did or
+// the state cannot be read.
+func (a *app) autostartScheduler(fallback bool) bool {
+	switch a.config.Scheduler.Autostart {
+	case config.SchedulerAutostartTrue:
+		return true
+	case config.SchedulerAutostartFalse:
+		return false
+	}
+
+	running, ok, err := a.schedulerState.Running()
+	if err != nil {
+		a.logger.Warnf("Failed to restore the scheduler state, using the default: %v", err)
+		return fallback
+	}
+	if !ok {
+		return fallback
+	}
+	return running
+}
+
+// readSeedMessages loads the messages local mode starts with from a JSON array.
+func readSeedMessages(path string) ([]model.Message, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []model.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
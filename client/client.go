@@ -0,0 +1,315 @@
+// Package client is a typed Go client of the message-service API for other internal
+// services. It covers the message, template, scheduler status, stats and quota endpoints
+// and mirrors their swagger documentation in docs/swagger.json; the contract tests of this package run it against the handlers and
+// check every answer against that spec, so a handler change that breaks the contract
+// fails them.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+)
+
+// Types of the API, aliased so callers outside this module can name them.
+type (
+	Message               = model.Message
+	MessageList           = model.MessageList
+	MessageStatus         = model.MessageStatus
+	MessageAuditEntry     = model.MessageAuditEntry
+	MessagePatchRequest   = model.MessagePatchRequest
+	SendMessageRequest    = model.SendMessageRequest
+	MessageActionResponse = model.MessageActionResponse
+	ExternalRef           = model.ExternalRef
+	FieldError            = model.FieldError
+	Template              = model.Template
+	TemplateRequest       = model.TemplateRequest
+	SchedulerStatus       = model.SchedulerStatus
+	MessageStats          = model.MessageStats
+	QuotaStatus           = model.QuotaStatus
+)
+
+// apiKeyHeader carries the API key, see middleware.APIKeyHeader.
+const apiKeyHeader = "X-API-Key"
+
+// Error is returned for answers outside 2xx.
+type Error struct {
+	StatusCode int
+	// Message is the error of the answer, the status text when it has none.
+	Message string
+	// Fields lists the rejected request fields of a 422 answer.
+	Fields []FieldError
+	// RetryAfter is the Retry-After header of 429 and 503 answers, empty without one.
+	RetryAfter string
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("message-service: %d %s", e.StatusCode, e.Message)
+	}
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, field.Field+": "+field.Message)
+	}
+	return fmt.Sprintf("message-service: %d %s (%s)", e.StatusCode, e.Message, strings.Join(fields, ", "))
+}
+
+// Client calls the API at a base URL with an API key.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// New returns a Client of the API at baseURL, e.g. https://messages.internal. apiKey may be
+// empty where the API is not authenticated, httpClient is http.DefaultClient when nil.
+func New(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, httpClient: httpClient}
+}
+
+// SendMessage sends a stored message right away, or schedules or holds it, see
+// POST /api/messages/send. DryRun overrides SEND_MODE, it needs the admin scope.
+func (c *Client) SendMessage(ctx context.Context, req SendMessageRequest, opts ...SendOption) (MessageActionResponse, error) {
+	query := url.Values{}
+	for _, opt := range opts {
+		opt(query)
+	}
+	var out MessageActionResponse
+	err := c.do(ctx, http.MethodPost, "/api/messages/send", query, req, &out)
+	return out, err
+}
+
+// SendOption changes a single send.
+type SendOption func(url.Values)
+
+// DryRun sends the message as a dry run, or live when dryRun is false, whatever SEND_MODE is.
+func DryRun(dryRun bool) SendOption {
+	return func(query url.Values) {
+		query.Set("dry_run", strconv.FormatBool(dryRun))
+	}
+}
+
+// GetMessage returns the message with id.
+func (c *Client) GetMessage(ctx context.Context, id uint) (Message, error) {
+	var out Message
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/messages/%d", id), nil, nil, &out)
+	return out, err
+}
+
+// GetMessageByExternalRef returns the message of the calling tenant with ref.
+func (c *Client) GetMessageByExternalRef(ctx context.Context, ref ExternalRef) (Message, error) {
+	var out Message
+	path := "/api/messages/by-ref/" + url.PathEscape(ref.System) + "/" + url.PathEscape(ref.ID)
+	err := c.do(ctx, http.MethodGet, path, nil, nil, &out)
+	return out, err
+}
+
+// ListOptions filters GET /api/messages, zero values leave a filter out.
+type ListOptions struct {
+	// Status is sent, unsent or a single message status.
+	Status     MessageStatus
+	From, To   time.Time
+	Phone      string
+	Held       *bool
+	Backfilled *bool
+	Page       int
+	PageSize   int
+	// Eventual reads the list from the read replica, which may miss the latest writes.
+	Eventual bool
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Status != "" {
+		query.Set("status", string(o.Status))
+	}
+	if !o.From.IsZero() {
+		query.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		query.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.Phone != "" {
+		query.Set("phone", o.Phone)
+	}
+	if o.Held != nil {
+		query.Set("held", strconv.FormatBool(*o.Held))
+	}
+	if o.Backfilled != nil {
+		query.Set("backfilled", strconv.FormatBool(*o.Backfilled))
+	}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	if o.Eventual {
+		query.Set("consistency", "eventual")
+	}
+	return query
+}
+
+// ListMessages returns one page of messages, newest first. Request the NextPage of the
+// result until it is zero to read them all.
+func (c *Client) ListMessages(ctx context.Context, opts ListOptions) (MessageList, error) {
+	var out MessageList
+	err := c.do(ctx, http.MethodGet, "/api/messages", opts.query(), nil, &out)
+	return out, err
+}
+
+// GetSentMessages returns every sent message.
+//
+// Deprecated: use ListMessages with the sent status, it pages and filters.
+func (c *Client) GetSentMessages(ctx context.Context) ([]Message, error) {
+	var out []Message
+	err := c.do(ctx, http.MethodGet, "/api/messages/sent", nil, nil, &out)
+	return out, err
+}
+
+// PatchMessage changes the message with id, e.g. holds a pending message or releases it.
+func (c *Client) PatchMessage(ctx context.Context, id uint, req MessagePatchRequest) (Message, error) {
+	var out Message
+	err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/messages/%d", id), nil, req, &out)
+	return out, err
+}
+
+// ListMessageEvents returns the audit trail of the message with id, oldest first.
+func (c *Client) ListMessageEvents(ctx context.Context, id uint) ([]MessageAuditEntry, error) {
+	var out []MessageAuditEntry
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/messages/%d/events", id), nil, nil, &out)
+	return out, err
+}
+
+// CancelMessage cancels a pending or failed message.
+func (c *Client) CancelMessage(ctx context.Context, id uint) (MessageActionResponse, error) {
+	var out MessageActionResponse
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/messages/%d/cancel", id), nil, nil, &out)
+	return out, err
+}
+
+// ReleaseMessage releases a held message, it needs the admin scope.
+func (c *Client) ReleaseMessage(ctx context.Context, id uint) (MessageActionResponse, error) {
+	var out MessageActionResponse
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/messages/%d/release", id), nil, nil, &out)
+	return out, err
+}
+
+// CreateTemplate stores a template and returns it with the variables of its body.
+func (c *Client) CreateTemplate(ctx context.Context, req TemplateRequest) (Template, error) {
+	var out Template
+	err := c.do(ctx, http.MethodPost, "/api/templates", nil, req, &out)
+	return out, err
+}
+
+// ListTemplates returns every template.
+func (c *Client) ListTemplates(ctx context.Context) ([]Template, error) {
+	var out []Template
+	err := c.do(ctx, http.MethodGet, "/api/templates", nil, nil, &out)
+	return out, err
+}
+
+// GetTemplate returns the template with id.
+func (c *Client) GetTemplate(ctx context.Context, id uint) (Template, error) {
+	var out Template
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/templates/%d", id), nil, nil, &out)
+	return out, err
+}
+
+// UpdateTemplate replaces the name and body of the template with id.
+func (c *Client) UpdateTemplate(ctx context.Context, id uint, req TemplateRequest) (Template, error) {
+	var out Template
+	err := c.do(ctx, http.MethodPut, fmt.Sprintf("/api/templates/%d", id), nil, req, &out)
+	return out, err
+}
+
+// DeleteTemplate deletes the template with id.
+func (c *Client) DeleteTemplate(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/templates/%d", id), nil, nil, nil)
+}
+
+// GetSchedulerStatus reports whether the scheduler runs and which instance leads it.
+func (c *Client) GetSchedulerStatus(ctx context.Context) (SchedulerStatus, error) {
+	var out SchedulerStatus
+	err := c.do(ctx, http.MethodGet, "/api/scheduler/status", nil, nil, &out)
+	return out, err
+}
+
+// GetStats returns the message counts by status and the sending statistics of the last 24 hours.
+func (c *Client) GetStats(ctx context.Context) (MessageStats, error) {
+	var out MessageStats
+	err := c.do(ctx, http.MethodGet, "/api/stats", nil, nil, &out)
+	return out, err
+}
+
+// GetQuota returns the usage and quotas of the API key of the client.
+func (c *Client) GetQuota(ctx context.Context) (QuotaStatus, error) {
+	var out QuotaStatus
+	err := c.do(ctx, http.MethodGet, "/api/quota", nil, nil, &out)
+	return out, err
+}
+
+// do sends body as JSON and decodes a 2xx answer into out, or returns an *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &Error{StatusCode: resp.StatusCode, RetryAfter: resp.Header.Get("Retry-After")}
+		var answer model.ValidationErrorResponse
+		if json.NewDecoder(resp.Body).Decode(&answer) == nil {
+			apiErr.Message, apiErr.Fields = answer.Error, answer.Fields
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s answer: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/handler"
+	"message-service/internal/middleware"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/pkg/localredis"
+	"message-service/internal/service"
+)
+
+// stubDispatcher accepts every direct send.
+type stubDispatcher struct{}
+
+func (stubDispatcher) SendMessages(ctx context.Context, channel model.Channel, count int) (model.BatchResult, error) {
+	return model.BatchResult{}, nil
+}
+
+func (stubDispatcher) SendMessageIDs(ctx context.Context, ids []uint) (model.BatchResult, error) {
+	return model.BatchResult{}, nil
+}
+
+func (stubDispatcher) SendMessage(ctx context.Context, message model.Message) (string, error) {
+	return "provider-" + strconv.Itoa(int(message.ID)), nil
+}
+
+// spec is the part of docs/swagger.json the contract is checked against.
+type spec struct {
+	Paths map[string]map[string]struct {
+		Responses map[string]struct {
+			Schema map[string]any `json:"schema"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Definitions map[string]map[string]any `json:"definitions"`
+}
+
+// contractTransport checks every answer against the response schema documented for its
+// path, method and status code.
+type contractTransport struct {
+	t    *testing.T
+	spec spec
+}
+
+func (c *contractTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	operation := req.Method + " " + req.URL.Path
+	path, ok := c.spec.match(req.URL.Path)
+	if !ok {
+		c.t.Errorf("%s: path is not documented", operation)
+		return resp, nil
+	}
+	response, ok := c.spec.Paths[path][strings.ToLower(req.Method)].Responses[strconv.Itoa(resp.StatusCode)]
+	if !ok {
+		c.t.Errorf("%s: status %d is not documented", operation, resp.StatusCode)
+		return resp, nil
+	}
+	if len(body) == 0 && response.Schema == nil {
+		return resp, nil
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		c.t.Errorf("%s: answer is not JSON: %v", operation, err)
+		return resp, nil
+	}
+	for _, problem := range c.spec.validate(response.Schema, value, "$") {
+		c.t.Errorf("%s %d: %s", operation, resp.StatusCode, problem)
+	}
+	return resp, nil
+}
+
+// match returns the documented path template path is an instance of, the one with the
+// fewest parameters when several match, like /api/messages/send and /api/messages/{id}.
+func (s spec) match(path string) (string, bool) {
+	match, params := "", -1
+	for template := range s.Paths {
+		pattern := "^" + regexp.MustCompile(`\\\{[^}]+\\\}`).ReplaceAllString(regexp.QuoteMeta(template), `[^/]+`) + "$"
+		if n := strings.Count(template, "{"); regexp.MustCompile(pattern).MatchString(path) && (params < 0 || n < params) {
+			match, params = template, n
+		}
+	}
+	return match, params >= 0
+}
+
+// validate checks value against the subset of JSON schema swag generates.
+func (s spec) validate(schema map[string]any, value any, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return s.validate(s.Definitions[strings.TrimPrefix(ref, "#/definitions/")], value, at)
+	}
+
+	var problems []string
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			problems = append(problems, s.validate(sub.(map[string]any), value, at)...)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !contains(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an object, got %T", at, value))
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, field := range object {
+			property, documented := properties[name].(map[string]any)
+			if !documented {
+				if properties != nil {
+					problems = append(problems, fmt.Sprintf("%s.%s is not documented", at, name))
+				}
+				continue
+			}
+			problems = append(problems, s.validate(property, field, at+"."+name)...)
+		}
+		for _, name := range stringsOf(schema["required"]) {
+			if _, ok := object[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s is required", at, name))
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected an array, got %T", at, value))
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			problems = append(problems, s.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a string, got %T", at, value))
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			problems = append(problems, fmt.Sprintf("%s: expected an integer, got %v", at, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a number, got %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected a boolean, got %T", at, value))
+		}
+	}
+	return problems
+}
+
+func contains(values []any, value any) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func stringsOf(value any) []string {
+	values, _ := value.([]any)
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, v.(string))
+	}
+	return out
+}
+
+// newContractClient serves the handlers the client calls on messages and returns a Client of
+// them whose answers are checked against the swagger spec. Requests are made as the API key
+// "billing" with a daily quota of 10.
+func newContractClient(t *testing.T, messages *mmemory.MessageService) *Client {
+	data, err := os.ReadFile("../docs/swagger.json")
+	require.NoError(t, err)
+	var doc spec
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	logger := inslogger.NewNopLogger()
+	redisClient := localredis.New()
+	scheduler := service.NewSchedulerService(stubDispatcher{}, nil, nil, nil, service.WarmupSettings{}, logger)
+	messageHandler := handler.NewMessageHandler(messages, scheduler, nil, stubDispatcher{}, nil, "90", 0, nil, nil, nil, logger)
+	auditHandler := handler.NewMessageAuditHandler(messages, mmemory.NewMessageAuditService(), logger)
+	templateHandler := handler.NewTemplateHandler(service.NewTemplateService(mmemory.NewTemplateRepository(), logger), logger)
+	statsHandler := handler.NewStatsHandler(service.NewStatsService(messages, service.NewStatsCounters(redisClient)), logger)
+	quotaHandler := handler.NewQuotaHandler(service.NewQuotaCounter(redisClient), logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.APIKeyNameContextKey, "billing")
+		c.Set(middleware.APIKeyQuotaContextKey, model.Quota{Daily: 10})
+	})
+	router.POST("/api/messages/send", messageHandler.SendMessage)
+	router.GET("/api/messages", messageHandler.ListMessages)
+	router.GET("/api/messages/sent", messageHandler.GetSentMessages)
+	router.GET("/api/messages/:id", messageHandler.GetMessage)
+	router.PATCH("/api/messages/:id", messageHandler.PatchMessage)
+	router.GET("/api/messages/by-ref/:system/:id", messageHandler.GetMessageByExternalRef)
+	router.GET("/api/messages/:id/events", auditHandler.ListMessageEvents)
+	router.POST("/api/messages/:id/cancel", messageHandler.CancelMessage)
+	router.POST("/api/messages/:id/release", messageHandler.ReleaseMessage)
+	router.GET("/api/scheduler/status", messageHandler.GetSchedulerStatus)
+	router.POST("/api/templates", templateHandler.CreateTemplate)
+	router.GET("/api/templates", templateHandler.ListTemplates)
+	router.GET("/api/templates/:id", templateHandler.GetTemplate)
+	router.PUT("/api/templates/:id", templateHandler.UpdateTemplate)
+	router.DELETE("/api/templates/:id", templateHandler.DeleteTemplate)
+	router.GET("/api/stats", statsHandler.GetStats)
+	router.GET("/api/quota", quotaHandler.GetQuota)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return New(server.URL, "", &http.Client{Transport: &contractTransport{t: t, spec: doc}})
+}
+
+func TestClientContract(t *testing.T) {
+	ctx := context.Background()
+	scheduledAt := time.Now().Add(time.Hour).UTC()
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Content: "hello", RecipientPhone: "+905551111111"},
+		model.Message{ID: 2, Content: "later", RecipientPhone: "+905551111111"},
+		model.Message{ID: 3, Content: "review", RecipientPhone: "+905551111111"},
+	)
+	client := newContractClient(t, messages)
+
+	sent, err := client.SendMessage(ctx, SendMessageRequest{
+		ID:             1,
+		Content:        "hello",
+		RecipientPhone: "+905551111111",
+		ExternalRef:    &ExternalRef{System: "orders", ID: "42"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, MessageActionResponse{Message: "Accepted", MessageID: 1, ProviderMessageID: "provider-1"}, sent)
+
+	scheduled, err := client.SendMessage(ctx, SendMessageRequest{ID: 2, Content: "later", RecipientPhone: "+905551111111", ScheduledAt: &scheduledAt})
+	require.NoError(t, err)
+	assert.Equal(t, "Scheduled", scheduled.Message)
+
+	held, err := client.SendMessage(ctx, SendMessageRequest{ID: 3, Content: "review", RecipientPhone: "+905551111111", Hold: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Held", held.Message)
+	_, err = client.ReleaseMessage(ctx, 3)
+	require.NoError(t, err)
+
+	message, err := client.GetMessage(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusSent, message.Status)
+	byRef, err := client.GetMessageByExternalRef(ctx, ExternalRef{System: "orders", ID: "42"})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), byRef.ID)
+
+	list, err := client.ListMessages(ctx, ListOptions{Status: model.StatusSent, Eventual: true})
+	require.NoError(t, err)
+	assert.Len(t, list.Messages, 1)
+	sentMessages, err := client.GetSentMessages(ctx)
+	require.NoError(t, err)
+	assert.Len(t, sentMessages, 1)
+
+	_, err = client.ListMessageEvents(ctx, 1)
+	require.NoError(t, err)
+
+	hold := true
+	patched, err := client.PatchMessage(ctx, 2, MessagePatchRequest{Hold: &hold})
+	require.NoError(t, err)
+	assert.True(t, patched.Held)
+
+	cancelled, err := client.CancelMessage(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, MessageActionResponse{Message: "Cancelled", MessageID: 2}, cancelled)
+
+	var apiErr *Error
+	_, err = client.GetMessage(ctx, 99)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	_, err = client.CancelMessage(ctx, 1)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	_, err = client.SendMessage(ctx, SendMessageRequest{ID: 4, RecipientPhone: "+905551111111"})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.NotEmpty(t, apiErr.Fields)
+}
+
+func TestClientContractBeyondMessages(t *testing.T) {
+	ctx := context.Background()
+	client := newContractClient(t, mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Content: "hello", RecipientPhone: "+905551111111", Status: model.StatusSent},
+	))
+
+	created, err := client.CreateTemplate(ctx, TemplateRequest{Name: "otp", Body: "Hi {{name}}, your code is {{code}}{{if .promo}} ({{.promo}}){{end}}"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code", "name"}, created.Variables)
+	assert.Equal(t, []string{"promo"}, created.OptionalVariables)
+	updated, err := client.UpdateTemplate(ctx, created.ID, TemplateRequest{Name: "otp", Body: "Your code is {{code}}"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code"}, updated.Variables)
+	got, err := client.GetTemplate(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Your code is {{code}}", got.Body)
+	templates, err := client.ListTemplates(ctx)
+	require.NoError(t, err)
+	assert.Len(t, templates, 1)
+	require.NoError(t, client.DeleteTemplate(ctx, created.ID))
+	var apiErr *Error
+	_, err = client.GetTemplate(ctx, created.ID)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	status, err := client.GetSchedulerStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Running)
+
+	stats, err := client.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Len(t, stats.SentPerHour, 24)
+
+	quota, err := client.GetQuota(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 10, quota.Daily.Limit)
+}
@@ -24,9 +24,58 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/messages/send": {
+        "/api/admin/apikeys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Secrets are never returned, keys are recognized by their prefix.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Send a message to a recipient",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Issue a key for a client. The secret is only returned in this response, store it right away. Tenant defaults to the key name.",
                 "consumes": [
                     "application/json"
                 ],
@@ -34,41 +83,74 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "api-keys"
                 ],
-                "summary": "Send a message",
+                "summary": "Create an API key",
                 "parameters": [
                     {
-                        "description": "Message payload",
-                        "name": "message",
+                        "description": "API key",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.SendMessageRequest"
+                            "$ref": "#/definitions/model.APIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.APIKeySecret"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/messages/sent": {
-            "get": {
-                "description": "Retrieve a list of all sent messages",
+        "/api/admin/apikeys/{id}/quota": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Cap the messages the key may send through POST /api/messages/send per UTC day and month, 0 leaves a period uncapped. Usage counted so far is kept.",
                 "consumes": [
                     "application/json"
                 ],
@@ -76,49 +158,265 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "api-keys"
+                ],
+                "summary": "Set the quota of an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quota",
+                        "name": "quota",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Quota"
+                        }
+                    }
                 ],
-                "summary": "Get all sent messages",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Message"
-                            }
+                            "$ref": "#/definitions/model.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/scheduler/start": {
+        "/api/admin/apikeys/{id}/revoke": {
             "post": {
-                "description": "Start the automatic message sending process",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/apikeys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Issue a new secret for the key, keeping its name, tenant and scopes. The previous secret stops working immediately.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "scheduler"
+                    "api-keys"
+                ],
+                "summary": "Rotate an API key",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Start the message scheduler",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.APIKeySecret"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/api/scheduler/stop": {
+        "/api/admin/diagnostics": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Run every live check at once and report them with the worst status: database and Redis latency, provider reachability, the backlog, messages stuck sending, circuit breakers, the scheduler and settings unusual for production. Round trips slower than DIAGNOSTICS_SLOW_THRESHOLD, a due message waiting longer than DIAGNOSTICS_BACKLOG_AGE and messages sending for longer than DIAGNOSTICS_STUCK_AFTER are warnings. format=text returns an aligned plain text table for terminals",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Run live diagnostics",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "text"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DiagnosticsReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/messages/backfill": {
             "post": {
-                "description": "Stop the automatic message sending process",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Store messages that were sent before the migration to this service, with their original sent_at, provider message ID and delivery status. They are stored as sent and backfilled, are never handed to a provider and can be told apart from live traffic with GET /api/messages?backfilled=true|false. Either every message of the request is imported or none: invalid messages are refused (422), as is an external reference used by another message of the tenant (409)",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,66 +424,3985 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scheduler"
+                    "admin"
+                ],
+                "summary": "Import sent messages from a legacy system",
+                "parameters": [
+                    {
+                        "description": "Messages to import",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BackfillRequest"
+                        }
+                    }
                 ],
-                "summary": "Stop the message scheduler",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.BackfillResult"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.Message": {
-            "description": "Message entity",
-            "type": "object",
-            "properties": {
-                "content": {
-                    "type": "string"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "recipient_phone": {
-                    "type": "string"
-                },
-                "sent": {
-                    "type": "boolean"
-                },
-                "sent_at": {
-                    "type": "string"
+        },
+        "/api/admin/outbound-limits": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get how many messages per second all replicas hand to the providers, over the scheduler and the API. A rate of 0 means no limit",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the outbound rate limit",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OutboundLimits"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the outbound rate limit of every replica, e.g. after the provider changed its throughput limit. The change replaces OUTBOUND_RATE and OUTBOUND_BURST until it is reset",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update the outbound rate limit",
+                "parameters": [
+                    {
+                        "description": "Outbound limits",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OutboundLimits"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OutboundLimits"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Drop the limit set through PUT /api/admin/outbound-limits and return to OUTBOUND_RATE and OUTBOUND_BURST",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset the outbound rate limit",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.OutboundLimits"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/provider-configs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider-configs"
+                ],
+                "summary": "List provider configs",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ProviderConfig"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send the SMS of a tenant to its own webhook URL with its own auth key instead of WEBHOOK_URL and AUTH_KEY. Only applies to the webhook driver and to production tenants, sends pick it up within TENANT_WEBHOOK_CONFIG_TTL. The auth key is never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider-configs"
+                ],
+                "summary": "Create a provider config",
+                "parameters": [
+                    {
+                        "description": "Provider config",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/provider-configs/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider-configs"
+                ],
+                "summary": "Get a provider config",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Provider config ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "provider-configs"
+                ],
+                "summary": "Update a provider config",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Provider config ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Provider config",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ProviderConfig"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "provider-configs"
+                ],
+                "summary": "Delete a provider config",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Provider config ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/tuning": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Analyze the latest scheduler runs (TUNING_RUNS) and the backlog, and suggest larger batches, shorter intervals, more send concurrency or more workers so the backlog drains within SCALING_DRAIN_TARGET. Nothing is changed, batch sizes and intervals can be applied with PUT /api/scheduler/channels/{channel}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get scheduler tuning suggestions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TuningReport"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/internal/scaling": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the number of due pending messages, how long the oldest has waited, and the worker replica count that drains them within SCALING_DRAIN_TARGET. Meant for a KEDA metrics-api or HPA external scaler",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "internal"
+                ],
+                "summary": "Get the worker scaling signal",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ScalingSignal"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List messages newest first, one page at a time. status is sent, unsent (pending, sending or failed) or any single status; from and to bound the creation time.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "List messages",
+                "parameters": [
+                    {
+                        "enum": [
+                            "sent",
+                            "unsent",
+                            "pending",
+                            "sending",
+                            "failed",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Message status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-01-01T00:00:00Z",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2025-02-01T00:00:00Z",
+                        "description": "Created before, RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient phone number",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only held (true) or not held (false) messages",
+                        "name": "held",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only messages imported from a legacy system (true) or live ones (false)",
+                        "name": "backfilled",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Messages per page (max 500)",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "strong",
+                            "eventual"
+                        ],
+                        "type": "string",
+                        "default": "strong",
+                        "description": "eventual allows a read replica that may lag behind the latest writes",
+                        "name": "consistency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/by-ref/{system}/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the message of the caller's tenant that was sent with the external_ref system and id, so upstream systems do not need to store message IDs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a message by external reference",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "orders",
+                        "description": "Upstream system",
+                        "name": "system",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "SO-10045",
+                        "description": "ID of the message in the upstream system",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Message"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/purge": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Anonymize (blank the content, hash the phone, cancel unsent messages and hide them) or delete every message sent to the phone number. Deleting also removes messages anonymized before.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Purge the messages of a recipient",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Recipient phone number",
+                        "name": "phone",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "anonymize",
+                            "delete"
+                        ],
+                        "type": "string",
+                        "description": "anonymize (default) or delete",
+                        "name": "mode",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/send": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a message to a recipient. When scheduled_at is in the future the message is stored and sent by the scheduler once due. With template_id the content is rendered from the template and variables instead. With external_ref the ID of the message in the calling system is recorded, see GET /api/messages/by-ref/{system}/{id}; a reference used by another message of the tenant is refused (409). With hold the message is stored for review and only sent after POST /api/messages/{id}/release; held messages cannot be sent (409). Where moderation is configured, messages sent right away are moderated first: held ones are answered with 202 and a reason, rejected ones with 422. Sends over the outbound rate limit are answered with 503 and Retry-After. A provider rejecting the service credentials is answered with 502 and pauses the scheduler, as in a batch. Every accepted message counts against the daily and monthly quota of the API key, see GET /api/quota; sends over it are answered with 429, X-Quota-Reset and Retry-After. A message the scheduler is sending at the same time is not sent twice (409). With SEND_MODE=dry-run no provider is called: messages are marked sent and dry_run with a simulated provider message ID; keys with the admin scope can override the mode of a message sent right away with dry_run=true|false. Messages sent right away are dispatched by the request itself and never wait for a scheduler batch; priority only orders the messages batches claim, highest first and oldest first within a priority.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Send a message",
+                "parameters": [
+                    {
+                        "description": "Message payload",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SendMessageRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Override SEND_MODE for a message sent right away, admin scope only",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageActionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/sent": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve a list of all sent messages, with timestamps in DISPLAY_TIMEZONE (default UTC). Use GET /api/messages?status=sent instead, it pages and filters.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get all sent messages",
+                "deprecated": true,
+                "parameters": [
+                    {
+                        "enum": [
+                            "strong",
+                            "eventual"
+                        ],
+                        "type": "string",
+                        "default": "strong",
+                        "description": "eventual allows a read replica that may lag behind the latest writes",
+                        "name": "consistency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Message"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/stream": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stream message.sent and message.failed events as Server-Sent Events while the connection is open, so dashboards need not poll /api/messages/sent. Every event is sent as \"id: \u003cevent ID\u003e\", \"event: \u003ctype\u003e\" and \"data: \u003cmodel.MessageEvent as JSON\u003e\"; an idle stream sends a comment every EVENTS_SSE_HEARTBEAT. Keys without the admin scope only receive the events of their tenant. With EVENTS_STREAM set every replica streams the events of all of them, otherwise only its own. A client that falls more than EVENTS_SSE_BUFFER events behind misses some, events are not replayed on reconnect",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Stream message events",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a message with its status, timestamps, provider message ID and delivery status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get a message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Hold a pending or failed message for review: the scheduler and external workers skip it and it cannot be sent until it is released with POST /api/messages/{id}/release. hold can only be set to true here.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Update a message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Changes to apply",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MessagePatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mark a pending or failed message as cancelled so the scheduler skips it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Cancel a message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageActionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get every state change and send attempt of a message, oldest first, with who made it (the API key, \"api\", \"scheduler\", \"queue consumer\" or \"system\"), and for send attempts the provider, its HTTP response code and the error. Requeued failures, delivery status callbacks and retention purges are not recorded",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get the audit trail of a message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.MessageAuditEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/messages/{id}/release": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Clear the hold of a message after review. A pending message is picked up by the next scheduler batch once due, or can be sent with POST /api/messages/send",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Release a held message",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageActionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/quota": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report how many messages the calling key sent through POST /api/messages/send in the current UTC day and month, its quotas and what is left of them. Uncapped periods have a limit of 0 and no remaining count; their usage is still counted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "quota"
+                ],
+                "summary": "Get the quota of the API key",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.QuotaStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/channels": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report the batch size and interval of every channel the scheduler runs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "List scheduler channel settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.ChannelSchedule"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/channels/{channel}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the batch size and interval of a channel. The change applies to the instance serving the request until it restarts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Update scheduler channel settings",
+                "parameters": [
+                    {
+                        "enum": [
+                            "sms",
+                            "email",
+                            "push"
+                        ],
+                        "type": "string",
+                        "description": "Channel",
+                        "name": "channel",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Channel settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ChannelScheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ChannelSchedule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/run-now": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send one batch of the channel, or of every scheduled channel when channel is omitted, with its configured batch size and wait for it, e.g. after a provider outage was fixed. Works whether or not the scheduler is running and does not start it. The runs are recorded like scheduled ones, see GET /api/scheduler/runs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Run a scheduler batch now",
+                "parameters": [
+                    {
+                        "enum": [
+                            "sms",
+                            "email",
+                            "push"
+                        ],
+                        "type": "string",
+                        "description": "Channel",
+                        "name": "channel",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.SchedulerRun"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/runs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the latest scheduler runs, newest first, with per-batch counts and an error summary. Timestamps are in DISPLAY_TIMEZONE (default UTC)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "List scheduler runs",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of runs to return (max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.SchedulerRun"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/start": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Start the automatic message sending process. Replicas started with SCHEDULER_AUTOSTART=auto resume it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Start the message scheduler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/status": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Report whether the scheduler is running, which instance is the leader and why it paused, if it did",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Get the scheduler status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SchedulerStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/scheduler/stop": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Stop the automatic message sending process. Replicas started with SCHEDULER_AUTOSTART=auto keep it stopped",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scheduler"
+                ],
+                "summary": "Stop the message scheduler",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stats": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Count sent, unsent (pending, sending or failed) and failed messages, the messages sent in each of the last 24 hours (UTC, oldest first, backfilled messages excluded), and, over the same hours and every replica, the average provider call latency and the share of message lookups answered from the cache. The averages are omitted while there is nothing to average",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Get message sending statistics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MessageStats"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/templates": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "List templates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Template"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Store a message template. Variables are written as {{name}} and listed in the response, the ones only tested by if or passed to default as optional_variables.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Create a template",
+                "parameters": [
+                    {
+                        "description": "Template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/templates/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Get a template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Update a template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Template"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Delete a template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/delivery-status": {
+            "post": {
+                "description": "Called by the SMS provider when a sent message is delivered, failed or undelivered. The message is looked up by the ID the provider returned for the send. Requests must be signed with one of CALLBACK_SIGNING_SECRETS.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Report a delivery status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "hex HMAC-SHA256 of \\",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time the signature was made (hmac scheme)",
+                        "name": "X-Signature-Timestamp",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Delivery outcome",
+                        "name": "callback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.DeliveryStatusCallback"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/worker/claim": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lease a batch of pending messages to an external delivery worker. Messages not completed before the lease expires become claimable again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "worker"
+                ],
+                "summary": "Claim pending messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Worker identifier",
+                        "name": "worker_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Number of messages to claim (max 1000)",
+                        "name": "batch",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WorkerClaimResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/worker/complete": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Mark claimed messages as sent or release them for another attempt. Results for expired leases are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "worker"
+                ],
+                "summary": "Complete claimed messages",
+                "parameters": [
+                    {
+                        "description": "Delivery results",
+                        "name": "results",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.WorkerCompleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WorkerCompleteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/model.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Report that the service is alive and the state of each circuit breaker",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Check dependencies and report conditions that prevent messages from being sent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "monthly_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 20000
+                },
+                "name": {
+                    "type": "string",
+                    "example": "billing-service"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "msk_3Fq9xA1b"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "description": "RotatedAt is when the key was last replaced, RevokedAt when it stopped working.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                },
+                "tenant": {
+                    "type": "string",
+                    "example": "acme"
+                }
+            }
+        },
+        "model.APIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "daily_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "monthly_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 20000
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "billing-service"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                },
+                "tenant": {
+                    "description": "Tenant defaults to Name.",
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "acme"
+                }
+            }
+        },
+        "model.APIKeySecret": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "key": {
+                    "type": "string",
+                    "example": "msk_3Fq9xA1bR2c8uV0kLmN4pQ7sT6wY5zH1jD3eG9fK2iO"
+                },
+                "monthly_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 20000
+                },
+                "name": {
+                    "type": "string",
+                    "example": "billing-service"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "msk_3Fq9xA1b"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "rotated_at": {
+                    "description": "RotatedAt is when the key was last replaced, RevokedAt when it stopped working.",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read",
+                        "write"
+                    ]
+                },
+                "tenant": {
+                    "type": "string",
+                    "example": "acme"
+                }
+            }
+        },
+        "model.BackfillMessage": {
+            "type": "object",
+            "required": [
+                "content",
+                "sent_at"
+            ],
+            "properties": {
+                "channel": {
+                    "enum": [
+                        "sms",
+                        "email",
+                        "push"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "sms"
+                },
+                "content": {
+                    "type": "string",
+                    "example": "Your order has shipped"
+                },
+                "created_at": {
+                    "description": "CreatedAt defaults to SentAt.",
+                    "type": "string",
+                    "example": "2023-05-01T09:59:58Z"
+                },
+                "delivered_at": {
+                    "type": "string",
+                    "example": "2023-05-01T10:00:04Z"
+                },
+                "delivery_status": {
+                    "enum": [
+                        "delivered",
+                        "failed",
+                        "undelivered"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeliveryStatus"
+                        }
+                    ],
+                    "example": "delivered"
+                },
+                "external_ref": {
+                    "$ref": "#/definitions/model.ExternalRef"
+                },
+                "provider_message_id": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "SM0a1b2c3d"
+                },
+                "recipient_email": {
+                    "type": "string",
+                    "example": "ada@example.com"
+                },
+                "recipient_phone": {
+                    "description": "RecipientPhone is normalized like the phone of a new message.",
+                    "type": "string",
+                    "example": "+905551111111"
+                },
+                "sent_at": {
+                    "type": "string",
+                    "example": "2023-05-01T10:00:00Z"
+                },
+                "tenant": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "acme"
+                }
+            }
+        },
+        "model.BackfillRequest": {
+            "type": "object",
+            "required": [
+                "messages"
+            ],
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/model.BackfillMessage"
+                    }
+                }
+            }
+        },
+        "model.BackfillResult": {
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        101,
+                        102
+                    ]
+                },
+                "imported": {
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "model.Channel": {
+            "type": "string",
+            "enum": [
+                "sms",
+                "email",
+                "push"
+            ],
+            "x-enum-varnames": [
+                "ChannelSMS",
+                "ChannelEmail",
+                "ChannelPush"
+            ]
+        },
+        "model.ChannelSchedule": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "example": 500
+                },
+                "channel": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "email"
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "5m0s"
+                }
+            }
+        },
+        "model.ChannelScheduleRequest": {
+            "type": "object",
+            "required": [
+                "batch_size",
+                "interval"
+            ],
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "maximum": 1000,
+                    "minimum": 1,
+                    "example": 500
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "5m"
+                }
+            }
+        },
+        "model.ChannelThroughput": {
+            "type": "object",
+            "properties": {
+                "avg_batch_seconds": {
+                    "description": "AvgBatchSeconds is how long a batch took on average, AvgSendMillis how long one send\ntook, estimated from the batch duration and the send concurrency.",
+                    "type": "number",
+                    "example": 12.4
+                },
+                "avg_send_millis": {
+                    "type": "number",
+                    "example": 480
+                },
+                "batch_size": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "channel": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "sms"
+                },
+                "full_batches": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "1m0s"
+                },
+                "runs": {
+                    "description": "Runs is how many recent runs were analyzed, FullBatches how many claimed a full batch.",
+                    "type": "integer",
+                    "example": 10
+                },
+                "sent_per_minute": {
+                    "type": "number",
+                    "example": 85.5
+                }
+            }
+        },
+        "model.DeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "delivered",
+                "failed",
+                "undelivered"
+            ],
+            "x-enum-varnames": [
+                "DeliveryDelivered",
+                "DeliveryFailed",
+                "DeliveryUndelivered"
+            ]
+        },
+        "model.DeliveryStatusCallback": {
+            "type": "object",
+            "required": [
+                "message_id",
+                "status"
+            ],
+            "properties": {
+                "message_id": {
+                    "type": "string",
+                    "example": "67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"
+                },
+                "status": {
+                    "enum": [
+                        "delivered",
+                        "failed",
+                        "undelivered"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeliveryStatus"
+                        }
+                    ],
+                    "example": "delivered"
+                },
+                "timestamp": {
+                    "description": "Timestamp is when the outcome happened, the time the callback is received when omitted.",
+                    "type": "string",
+                    "example": "2025-01-01T18:00:05Z"
+                }
+            }
+        },
+        "model.DiagnosticCheck": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "12 pending, oldest due 3m ago"
+                },
+                "latency_ms": {
+                    "description": "LatencyMs is the round trip of checks that reach a dependency.",
+                    "type": "number",
+                    "example": 1.8
+                },
+                "name": {
+                    "type": "string",
+                    "example": "database"
+                },
+                "status": {
+                    "enum": [
+                        "ok",
+                        "warn",
+                        "fail"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DiagnosticStatus"
+                        }
+                    ],
+                    "example": "ok"
+                }
+            }
+        },
+        "model.DiagnosticStatus": {
+            "type": "string",
+            "enum": [
+                "ok",
+                "warn",
+                "fail"
+            ],
+            "x-enum-varnames": [
+                "DiagnosticOK",
+                "DiagnosticWarn",
+                "DiagnosticFail"
+            ]
+        },
+        "model.DiagnosticsReport": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DiagnosticCheck"
+                    }
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "instance_id": {
+                    "type": "string",
+                    "example": "message-service-7f9c-1"
+                },
+                "status": {
+                    "enum": [
+                        "ok",
+                        "warn",
+                        "fail"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DiagnosticStatus"
+                        }
+                    ],
+                    "example": "warn"
+                }
+            }
+        },
+        "model.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid API key"
+                }
+            }
+        },
+        "model.ExternalRef": {
+            "type": "object",
+            "required": [
+                "id",
+                "system"
+            ],
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "SO-10045"
+                },
+                "system": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "orders"
+                }
+            }
+        },
+        "model.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "recipient_phone"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "must be an E.164 phone number"
+                }
+            }
+        },
+        "model.HourlyCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 50
+                },
+                "hour": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Message": {
+            "description": "Message entity",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "Attempts counts the sends of the message that failed. The scheduler stops retrying it\nafter SCHEDULER_MAX_ATTEMPTS.",
+                    "type": "integer"
+                },
+                "backfilled": {
+                    "description": "Backfilled marks a message imported from a legacy system, it was sent before and\nnever went through the providers of this service.",
+                    "type": "boolean"
+                },
+                "channel": {
+                    "enum": [
+                        "sms",
+                        "email",
+                        "push"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ]
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "delivery_status": {
+                    "description": "DeliveryStatus and DeliveredAt are set by the provider's delivery status callback.",
+                    "enum": [
+                        "delivered",
+                        "failed",
+                        "undelivered"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.DeliveryStatus"
+                        }
+                    ]
+                },
+                "dry_run": {
+                    "description": "DryRun marks a message sent in dry-run mode, no provider was called and\nProviderMessageID is simulated.",
+                    "type": "boolean"
+                },
+                "external_ref": {
+                    "description": "ExternalRef is the ID of the message in the upstream system that requested it.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ExternalRef"
+                        }
+                    ]
+                },
+                "held": {
+                    "description": "Held keeps a pending or failed message from being sent until it is released.",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "moderation_decision": {
+                    "description": "ModerationDecision and ModerationReason record the pre-send moderation step, they are\nempty until the message is moderated.",
+                    "enum": [
+                        "approve",
+                        "hold",
+                        "reject"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ModerationDecision"
+                        }
+                    ]
+                },
+                "moderation_reason": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer",
+                    "maximum": 9,
+                    "minimum": 0
+                },
+                "provider_message_id": {
+                    "type": "string"
+                },
+                "recipient_email": {
+                    "type": "string"
+                },
+                "recipient_phone": {
+                    "type": "string"
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "sending",
+                        "sent",
+                        "failed",
+                        "cancelled"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageStatus"
+                        }
+                    ]
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MessageActionResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Accepted"
+                },
+                "messageId": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "providerMessageId": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Reason is why moderation held the message.",
+                    "type": "string"
+                },
+                "scheduledAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.MessageAuditEntry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is the API key that made the change, or the component, e.g. \"scheduler\".",
+                    "type": "string",
+                    "example": "scheduler"
+                },
+                "detail": {
+                    "description": "Detail describes the change, e.g. \"held\" or \"scheduled for 2025-01-01T18:00:00Z\".",
+                    "type": "string",
+                    "example": "claimed"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "unexpected status code: 500"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "kind": {
+                    "enum": [
+                        "status_change",
+                        "send_attempt"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageAuditKind"
+                        }
+                    ],
+                    "example": "send_attempt"
+                },
+                "message_id": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string",
+                    "example": "webhook"
+                },
+                "response_code": {
+                    "description": "ResponseCode is the HTTP status code the provider answered a send attempt with.",
+                    "type": "integer",
+                    "example": 202
+                },
+                "status": {
+                    "description": "Status is the status the message changed to, empty when the change kept it.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.MessageStatus"
+                        }
+                    ],
+                    "example": "sent"
+                }
+            }
+        },
+        "model.MessageAuditKind": {
+            "type": "string",
+            "enum": [
+                "status_change",
+                "send_attempt"
+            ],
+            "x-enum-varnames": [
+                "AuditStatusChange",
+                "AuditSendAttempt"
+            ]
+        },
+        "model.MessageEvent": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "$ref": "#/definitions/model.Channel"
+                },
+                "error": {
+                    "description": "Error is why the send failed, on message.failed events only.",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "provider_message_id": {
+                    "type": "string"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.MessageEventType"
+                }
+            }
+        },
+        "model.MessageEventType": {
+            "type": "string",
+            "enum": [
+                "message.created",
+                "message.sent",
+                "message.failed"
+            ],
+            "x-enum-varnames": [
+                "EventMessageCreated",
+                "EventMessageSent",
+                "EventMessageFailed"
+            ]
+        },
+        "model.MessageList": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "next_page": {
+                    "description": "NextPage is the page to request next, omitted on the last page.",
+                    "type": "integer",
+                    "example": 2
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "page_size": {
+                    "type": "integer",
+                    "example": 50
+                }
+            }
+        },
+        "model.MessagePatchRequest": {
+            "type": "object",
+            "required": [
+                "hold"
+            ],
+            "properties": {
+                "hold": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "model.MessageStats": {
+            "type": "object",
+            "properties": {
+                "average_provider_latency_ms": {
+                    "description": "AverageProviderLatencyMs is the average duration of a provider call over the last\n24 hours, omitted when there was none.",
+                    "type": "number",
+                    "example": 182.5
+                },
+                "cache_hit_rate": {
+                    "description": "CacheHitRate is the share of message lookups of the last 24 hours answered from the\ncache, omitted when there was none.",
+                    "type": "number",
+                    "example": 0.82
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "sent": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "sent_per_hour": {
+                    "description": "SentPerHour has one entry per hour of the last 24 hours, oldest first. Backfilled\nmessages are not counted.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.HourlyCount"
+                    }
+                },
+                "unsent": {
+                    "description": "Unsent counts pending, sending and failed messages, Failed only the failed ones.",
+                    "type": "integer",
+                    "example": 40
+                }
+            }
+        },
+        "model.MessageStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "sending",
+                "sent",
+                "failed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "StatusPending",
+                "StatusSending",
+                "StatusSent",
+                "StatusFailed",
+                "StatusCancelled"
+            ]
+        },
+        "model.ModerationDecision": {
+            "type": "string",
+            "enum": [
+                "approve",
+                "hold",
+                "reject"
+            ],
+            "x-enum-varnames": [
+                "ModerationApprove",
+                "ModerationHold",
+                "ModerationReject"
+            ]
+        },
+        "model.OutboundLimits": {
+            "type": "object",
+            "required": [
+                "burst"
+            ],
+            "properties": {
+                "burst": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 100
+                },
+                "rate": {
+                    "type": "number",
+                    "minimum": 0,
+                    "example": 50
+                }
+            }
+        },
+        "model.ProviderConfig": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "tenant": {
+                    "type": "string",
+                    "example": "acme"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://sms.acme.example/send"
+                }
+            }
+        },
+        "model.ProviderConfigRequest": {
+            "type": "object",
+            "required": [
+                "auth_key",
+                "tenant",
+                "webhook_url"
+            ],
+            "properties": {
+                "auth_key": {
+                    "description": "AuthKey is sent as x-ins-auth-key with every request to WebhookURL.",
+                    "type": "string",
+                    "example": "acme-secret"
+                },
+                "tenant": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "acme"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://sms.acme.example/send"
+                }
+            }
+        },
+        "model.PurgeMode": {
+            "type": "string",
+            "enum": [
+                "anonymize",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "PurgeAnonymize",
+                "PurgeDelete"
+            ]
+        },
+        "model.PurgeResult": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "enum": [
+                        "anonymize",
+                        "delete"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.PurgeMode"
+                        }
+                    ],
+                    "example": "anonymize"
+                },
+                "purged": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "model.Quota": {
+            "type": "object",
+            "properties": {
+                "daily_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 1000
+                },
+                "monthly_quota": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 20000
+                }
+            }
+        },
+        "model.QuotaPeriod": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "remaining": {
+                    "type": "integer",
+                    "example": 880
+                },
+                "resets_at": {
+                    "type": "string"
+                },
+                "used": {
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "model.QuotaStatus": {
+            "type": "object",
+            "properties": {
+                "daily": {
+                    "$ref": "#/definitions/model.QuotaPeriod"
+                },
+                "monthly": {
+                    "$ref": "#/definitions/model.QuotaPeriod"
+                }
+            }
+        },
+        "model.ScalingSignal": {
+            "type": "object",
+            "properties": {
+                "backlog_depth": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "oldest_pending_age_seconds": {
+                    "type": "integer",
+                    "example": 95
+                },
+                "recommended_replicas": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "model.SchedulerRun": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "channel": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "sms"
+                },
+                "claimed": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "errors": {
+                    "description": "Errors summarizes the distinct errors of the run, one per line.",
+                    "type": "string",
+                    "example": "unexpected status code: 500"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "instance_id": {
+                    "type": "string",
+                    "example": "message-service-7f9c-1"
+                },
+                "sent": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "skipped": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.SchedulerStatus": {
+            "type": "object",
+            "properties": {
+                "is_leader": {
+                    "type": "boolean"
+                },
+                "leader": {
+                    "description": "Leader is the instance currently allowed to run batches.",
+                    "type": "string"
+                },
+                "pause_reason": {
+                    "type": "string"
+                },
+                "running": {
+                    "type": "boolean"
                 }
             }
         },
         "model.SendMessageRequest": {
             "type": "object",
+            "required": [
+                "id"
+            ],
             "properties": {
+                "channel": {
+                    "description": "Channel defaults to sms. Email messages need RecipientEmail instead of RecipientPhone.",
+                    "enum": [
+                        "sms",
+                        "email",
+                        "push"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "sms"
+                },
                 "content": {
+                    "description": "Content is required unless TemplateID is set.",
                     "type": "string",
+                    "maxLength": 160,
                     "example": "message-service - Project"
                 },
+                "external_ref": {
+                    "description": "ExternalRef records the ID of the message in the calling system, so it can be looked\nup without storing ours.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.ExternalRef"
+                        }
+                    ]
+                },
+                "hold": {
+                    "description": "Hold stores the message for review instead of sending it, see POST /api/messages/{id}/release.",
+                    "type": "boolean",
+                    "example": false
+                },
                 "id": {
                     "type": "integer",
                     "example": 5
                 },
+                "priority": {
+                    "description": "Priority changes the priority the scheduler claims the message with, 0 to MaxPriority.\nOmit it to keep the stored priority.",
+                    "type": "integer",
+                    "maximum": 9,
+                    "minimum": 0,
+                    "example": 9
+                },
+                "recipient_email": {
+                    "type": "string",
+                    "example": "ada@example.com"
+                },
                 "recipient_phone": {
                     "type": "string",
                     "example": "+905551111111"
+                },
+                "scheduled_at": {
+                    "description": "ScheduledAt delays delivery until the given time. Omit it to send immediately.",
+                    "type": "string",
+                    "example": "2025-01-01T18:00:00Z"
+                },
+                "template_id": {
+                    "description": "TemplateID renders the content from a stored template with Variables instead.",
+                    "type": "integer",
+                    "example": 3
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.Template": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "Hi {{name}}, your code is {{code}}"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "name": {
+                    "type": "string",
+                    "example": "otp"
+                },
+                "optional_variables": {
+                    "description": "OptionalVariables lists the variables Body only tests with if or passes to default,\nthey render as empty when a send leaves them out.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "promo"
+                    ]
+                },
+                "tenant": {
+                    "description": "Tenant is the tenant of the key that created the template, only its keys and admin\nkeys see it.",
+                    "type": "string",
+                    "example": "acme"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "variables": {
+                    "description": "Variables lists the variables Body requires, in alphabetical order.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "code",
+                        "name"
+                    ]
+                }
+            }
+        },
+        "model.TemplateRequest": {
+            "type": "object",
+            "required": [
+                "body",
+                "name"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "Hi {{name}}, your code is {{code}}"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "example": "otp"
+                }
+            }
+        },
+        "model.TuningReport": {
+            "type": "object",
+            "properties": {
+                "backlog_depth": {
+                    "type": "integer",
+                    "example": 1200
+                },
+                "backlog_growth_per_minute": {
+                    "description": "BacklogGrowthPerMinute is how fast the backlog grew over the samples the advisor\nkept, negative while it shrinks.",
+                    "type": "number",
+                    "example": 14.2
+                },
+                "channels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ChannelThroughput"
+                    }
+                },
+                "generated_at": {
+                    "type": "string"
+                },
+                "suggestions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TuningSuggestion"
+                    }
+                }
+            }
+        },
+        "model.TuningSuggestion": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "Channel is the channel the suggestion applies to, empty when it applies to all.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Channel"
+                        }
+                    ],
+                    "example": "sms"
+                },
+                "current": {
+                    "type": "string",
+                    "example": "100"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "increase_batch",
+                        "shorten_interval",
+                        "increase_concurrency",
+                        "add_worker"
+                    ],
+                    "example": "increase_batch"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "8 of the last 10 batches were full and 1200 messages are pending"
+                },
+                "setting": {
+                    "description": "Setting names what to change, a field of PUT /api/scheduler/channels/{channel} or an\nenvironment variable. Current and Suggested are its values. They are empty for add_worker.",
+                    "type": "string",
+                    "example": "batch_size"
+                },
+                "suggested": {
+                    "type": "string",
+                    "example": "250"
+                }
+            }
+        },
+        "model.ValidationErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.FieldError"
+                    }
+                }
+            }
+        },
+        "model.WorkerClaimResponse": {
+            "type": "object",
+            "properties": {
+                "lease_expires_at": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Message"
+                    }
+                },
+                "worker_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WorkerCompleteRequest": {
+            "type": "object",
+            "required": [
+                "results",
+                "worker_id"
+            ],
+            "properties": {
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.WorkerResult"
+                    }
+                },
+                "worker_id": {
+                    "type": "string",
+                    "example": "billing-worker-1"
+                }
+            }
+        },
+        "model.WorkerCompleteResponse": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "rejected": {
+                    "description": "Rejected lists IDs whose lease had expired or belonged to another worker.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "model.WorkerResult": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "provider_message_id": {
+                    "type": "string",
+                    "example": "67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": true
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
     }
 }`
 
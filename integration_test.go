@@ -0,0 +1,178 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/gpostgresql"
+	"message-service/internal/pkg/gpostgresql/pgtest"
+	"message-service/internal/pkg/mockwebhook"
+	"message-service/migrations"
+)
+
+// integration is the API of a full app against real PostgreSQL and Redis, sending through a
+// mock webhook gateway.
+type integration struct {
+	app      *app
+	api      *httptest.Server
+	provider *mockwebhook.Server
+}
+
+// startIntegration migrates a new database on the server of INTEGRATION_DATABASE_URL,
+// flushes the Redis at INTEGRATION_REDIS_ADDR and serves the router of an app using both.
+// The database is dropped when the test ends. Point both at throwaway instances, e.g. the
+// ones of docker-compose.
+func startIntegration(t *testing.T) *integration {
+	adminURL, redisAddr := os.Getenv("INTEGRATION_DATABASE_URL"), os.Getenv("INTEGRATION_REDIS_ADDR")
+	if adminURL == "" || redisAddr == "" {
+		t.Skip("INTEGRATION_DATABASE_URL and INTEGRATION_REDIS_ADDR are not set")
+	}
+	ctx := context.Background()
+	logger := inslogger.NewNopLogger()
+
+	databaseURL := pgtest.CreateDatabase(t, adminURL)
+	redisHost, redisPort, err := net.SplitHostPort(redisAddr)
+	require.NoError(t, err)
+	rdb := redis.NewClient(&redis.Options{Addr: redisAddr})
+	defer rdb.Close()
+	// IDs start over in every database, cached messages of earlier runs would shadow them.
+	require.NoError(t, rdb.FlushDB().Err())
+
+	provider := mockwebhook.NewServer("integration", logger)
+	providerServer := httptest.NewServer(provider)
+	t.Cleanup(providerServer.Close)
+
+	for key, value := range map[string]string{
+		"LOCAL_MODE":           "false",
+		"DATABASE_URL":         databaseURL,
+		"DATABASE_REPLICA_URL": "",
+		"REDIS_ENABLED":        "true",
+		"REDIS_MODE":           "standalone",
+		"REDIS_HOST":           redisHost,
+		"REDIS_PORT":           redisPort,
+		"PROVIDER":             config.ProviderWebhook,
+		"WEBHOOK_URL":          providerServer.URL + "/send",
+		"AUTH_KEY":             "integration",
+		"API_KEYS":             "",
+		"AUTH_DISABLED":        "true",
+		"QUEUE_MODE":           config.QueueModePoll,
+		"SEND_MODE":            config.SendModeLive,
+		"SCHEDULER_AUTOSTART":  config.SchedulerAutostartFalse,
+		"SCHEDULER_BATCH_SIZE": "10",
+	} {
+		t.Setenv(key, value)
+	}
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+
+	pool, err := gpostgresql.NewDBConnection(ctx, &appConfig.Database, logger)
+	require.NoError(t, err)
+	_, err = gpostgresql.Migrate(ctx, pool, migrations.Files, 0, logger)
+	gpostgresql.Close(ctx, pool, logger)
+	require.NoError(t, err)
+
+	a := newApp(ctx, appConfig, logger)
+	require.NoError(t, a.lifecycle.Start(ctx))
+	t.Cleanup(func() { _ = a.lifecycle.Stop(context.Background()) })
+
+	router, err := newRouter(a, appConfig, logger)
+	require.NoError(t, err)
+	api := httptest.NewServer(router)
+	t.Cleanup(api.Close)
+
+	return &integration{app: a, api: api, provider: provider}
+}
+
+func (it *integration) createMessage(t *testing.T) model.Message {
+	message, err := it.app.messageService.CreateMessage(context.Background(), model.Message{
+		Content:        "integration",
+		RecipientPhone: "+905551111111",
+	})
+	require.NoError(t, err)
+	return message
+}
+
+func (it *integration) call(t *testing.T, method, path string, body any, out any) int {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, it.api.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func (it *integration) message(t *testing.T, id uint) model.Message {
+	var message model.Message
+	require.Equal(t, http.StatusOK, it.call(t, http.MethodGet, fmt.Sprintf("/api/messages/%d", id), nil, &message))
+	return message
+}
+
+func TestIntegration(t *testing.T) {
+	it := startIntegration(t)
+
+	t.Run("direct send", func(t *testing.T) {
+		message := it.createMessage(t)
+
+		code := it.call(t, http.MethodPost, "/api/messages/send", model.SendMessageRequest{
+			ID:             message.ID,
+			Content:        message.Content,
+			RecipientPhone: message.RecipientPhone,
+		}, nil)
+		require.Equal(t, http.StatusAccepted, code)
+
+		sent := it.message(t, message.ID)
+		assert.Equal(t, model.StatusSent, sent.Status)
+		assert.True(t, strings.HasPrefix(sent.ProviderMessageID, mockwebhook.MessageIDPrefix))
+	})
+
+	t.Run("scheduler batch", func(t *testing.T) {
+		first, second := it.createMessage(t), it.createMessage(t)
+
+		var runs []model.SchedulerRun
+		require.Equal(t, http.StatusOK, it.call(t, http.MethodPost, "/api/scheduler/run-now?channel=sms", nil, &runs))
+
+		for _, id := range []uint{first.ID, second.ID} {
+			sent := it.message(t, id)
+			assert.Equal(t, model.StatusSent, sent.Status, "message %d", id)
+			assert.True(t, strings.HasPrefix(sent.ProviderMessageID, mockwebhook.MessageIDPrefix))
+		}
+	})
+
+	t.Run("cache invalidation", func(t *testing.T) {
+		message := it.createMessage(t)
+		// The first read caches the pending message.
+		assert.Equal(t, model.StatusPending, it.message(t, message.ID).Status)
+
+		require.Equal(t, http.StatusOK, it.call(t, http.MethodPost, fmt.Sprintf("/api/messages/%d/cancel", message.ID), nil, nil))
+
+		assert.Equal(t, model.StatusCancelled, it.message(t, message.ID).Status)
+	})
+}
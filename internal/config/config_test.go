@@ -0,0 +1,38 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sethvargo/go-envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appFromEnv reads an App from env on top of the defaults, running in local mode so no
+// database or Redis settings are needed.
+func appFromEnv(t *testing.T, env map[string]string) *App {
+	t.Helper()
+	vars := map[string]string{"SERVER_PORT": "8080", "LOCAL_MODE": "true"}
+	for name, value := range env {
+		vars[name] = value
+	}
+	var app App
+	require.NoError(t, envconfig.ProcessWith(context.Background(), &envconfig.Config{
+		Target:   &app,
+		Lookuper: envconfig.MapLookuper(vars),
+	}))
+	return &app
+}
+
+func TestValidateRetryBucket(t *testing.T) {
+	assert.NoError(t, appFromEnv(t, nil).validate())
+
+	for _, env := range []map[string]string{
+		{"RETRY_RATE": "0"},
+		{"RETRY_RATE": "-1"},
+		{"RETRY_BURST": "0"},
+	} {
+		assert.EqualError(t, appFromEnv(t, env).validate(), "RETRY_RATE and RETRY_BURST must be positive", env)
+	}
+}
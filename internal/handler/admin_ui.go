@@ -0,0 +1,22 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminUIPage is the operator dashboard. It is static, every value on it is fetched from
+// the JSON API with the API key entered on the page.
+//
+//go:embed admin_ui.html
+var adminUIPage []byte
+
+// AdminUI serves the operator dashboard: scheduler state with start and stop buttons,
+// queue depth, sent counts and the latest failed messages.
+func AdminUI(c *gin.Context) {
+	noStore(c)
+	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", adminUIPage)
+}
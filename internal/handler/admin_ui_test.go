@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminUI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", AdminUI)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+	// The page reads the endpoints it depends on, renaming one must update it.
+	for _, path := range []string{"/api/scheduler/status", "/api/scheduler/start", "/api/scheduler/stop", "/api/internal/scaling", "/api/stats", "/api/messages?status=failed"} {
+		assert.Contains(t, resp.Body.String(), path)
+	}
+}
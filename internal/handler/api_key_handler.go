@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type APIKeyHandler struct {
+	keys   service.APIKeyService
+	logger inslogger.Interface
+}
+
+func NewAPIKeyHandler(keys service.APIKeyService, logger inslogger.Interface) *APIKeyHandler {
+	return &APIKeyHandler{
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// CreateAPIKey issues a new API key.
+// @Summary Create an API key
+// @Description Issue a key for a client. The secret is only returned in this response, store it right away. Tenant defaults to the key name.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body model.APIKeyRequest true "API key"
+// @Success 201 {object} model.APIKeySecret
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/apikeys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req model.APIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	key, err := h.keys.Create(c.Request.Context(), req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys returns every issued API key, revoked ones included.
+// @Summary List API keys
+// @Description Secrets are never returned, keys are recognized by their prefix.
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} model.APIKey
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/apikeys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.keys.List(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey disables an API key for good.
+// @Summary Revoke an API key
+// @Tags api-keys
+// @Param id path int true "API key ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/apikeys/{id}/revoke [post]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.keys.Revoke(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateAPIKey replaces the secret of an API key.
+// @Summary Rotate an API key
+// @Description Issue a new secret for the key, keeping its name, tenant and scopes. The previous secret stops working immediately.
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} model.APIKeySecret
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/apikeys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.keys.Rotate(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to rotate API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// SetAPIKeyQuota replaces the message quota of an API key.
+// @Summary Set the quota of an API key
+// @Description Cap the messages the key may send through POST /api/messages/send per UTC day and month, 0 leaves a period uncapped. Usage counted so far is kept.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path int true "API key ID"
+// @Param quota body model.Quota true "Quota"
+// @Success 200 {object} model.APIKey
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/apikeys/{id}/quota [put]
+func (h *APIKeyHandler) SetAPIKeyQuota(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+
+	var quota model.Quota
+	if !bindJSON(c, &quota) {
+		return
+	}
+
+	key, err := h.keys.SetQuota(c.Request.Context(), id, quota)
+	if err != nil {
+		h.writeError(c, err, "Failed to set API key quota")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func apiKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid API key ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *APIKeyHandler) writeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, mpostgres.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "API key not found"})
+	case errors.Is(err, mpostgres.ErrAPIKeyNameTaken):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: "API key name already exists"})
+	default:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: message})
+	}
+}
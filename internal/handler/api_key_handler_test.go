@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/middleware"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func newAPIKeyRouter() *gin.Engine {
+	logger := inslogger.NewLogger(inslogger.Debug)
+	keys := service.NewAPIKeyService(mmemory.NewAPIKeyRepository(), logger)
+	handler := NewAPIKeyHandler(keys, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	api := router.Group("/api", middleware.APIKeyAuth(map[string]string{"ops": "bootstrap"}, keys, logger))
+	api.POST("/admin/apikeys", middleware.RequireScope(model.ScopeAdmin), handler.CreateAPIKey)
+	api.GET("/admin/apikeys", middleware.RequireScope(model.ScopeAdmin), handler.ListAPIKeys)
+	api.POST("/admin/apikeys/:id/revoke", middleware.RequireScope(model.ScopeAdmin), handler.RevokeAPIKey)
+	api.POST("/admin/apikeys/:id/rotate", middleware.RequireScope(model.ScopeAdmin), handler.RotateAPIKey)
+	api.GET("/ping", middleware.RequireScope(model.ScopeRead), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(middleware.APIKeyNameContextKey))
+	})
+	return router
+}
+
+func serveAPIKey(router *gin.Engine, method, path, key string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.APIKeyHeader, key)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func serveWithKey(router *gin.Engine, method, path, key string, payload any) int {
+	return serveAPIKey(router, method, path, key, payload).Code
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	router := newAPIKeyRouter()
+
+	resp := serveAPIKey(router, http.MethodPost, "/api/admin/apikeys", "bootstrap", model.APIKeyRequest{Name: "dashboard", Scopes: []string{model.ScopeRead}})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var created model.APIKeySecret
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.Equal(t, "dashboard", created.Tenant)
+	assert.Equal(t, created.Key[:len(created.Prefix)], created.Prefix)
+
+	assert.Equal(t, http.StatusOK, serveWithKey(router, http.MethodGet, "/api/ping", created.Key, nil))
+	assert.Equal(t, http.StatusForbidden, serveWithKey(router, http.MethodGet, "/api/admin/apikeys", created.Key, nil))
+
+	resp = serveAPIKey(router, http.MethodPost, "/api/admin/apikeys/1/rotate", "bootstrap", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var rotated model.APIKeySecret
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &rotated))
+	assert.NotEqual(t, created.Key, rotated.Key)
+	assert.NotNil(t, rotated.RotatedAt)
+	assert.Equal(t, http.StatusForbidden, serveWithKey(router, http.MethodGet, "/api/ping", created.Key, nil))
+	assert.Equal(t, http.StatusOK, serveWithKey(router, http.MethodGet, "/api/ping", rotated.Key, nil))
+
+	assert.Equal(t, http.StatusNoContent, serveWithKey(router, http.MethodPost, "/api/admin/apikeys/1/revoke", "bootstrap", nil))
+	assert.Equal(t, http.StatusForbidden, serveWithKey(router, http.MethodGet, "/api/ping", rotated.Key, nil))
+	assert.Equal(t, http.StatusNotFound, serveWithKey(router, http.MethodPost, "/api/admin/apikeys/1/revoke", "bootstrap", nil))
+	assert.Equal(t, http.StatusNotFound, serveWithKey(router, http.MethodPost, "/api/admin/apikeys/1/rotate", "bootstrap", nil))
+
+	resp = serveAPIKey(router, http.MethodGet, "/api/admin/apikeys", "bootstrap", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotContains(t, resp.Body.String(), rotated.Key)
+	assert.Contains(t, resp.Body.String(), `"revoked_at"`)
+}
+
+func TestCreateAPIKeyErrors(t *testing.T) {
+	router := newAPIKeyRouter()
+	serveWithKey(router, http.MethodPost, "/api/admin/apikeys", "bootstrap", model.APIKeyRequest{Name: "dashboard", Scopes: []string{model.ScopeRead}})
+
+	assert.Equal(t, http.StatusConflict, serveWithKey(router, http.MethodPost, "/api/admin/apikeys", "bootstrap",
+		model.APIKeyRequest{Name: "dashboard", Scopes: []string{model.ScopeRead}}))
+	assert.Equal(t, http.StatusUnprocessableEntity, serveWithKey(router, http.MethodPost, "/api/admin/apikeys", "bootstrap",
+		model.APIKeyRequest{Name: "partner", Scopes: []string{"superuser"}}))
+	assert.Equal(t, http.StatusUnprocessableEntity, serveWithKey(router, http.MethodPost, "/api/admin/apikeys", "bootstrap",
+		model.APIKeyRequest{Name: "partner"}))
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/phone"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type BackfillHandler struct {
+	messageService     mpostgres.MessageService
+	defaultCountryCode string
+	logger             inslogger.Interface
+}
+
+func NewBackfillHandler(messageService mpostgres.MessageService, defaultCountryCode string, logger inslogger.Interface) *BackfillHandler {
+	return &BackfillHandler{
+		messageService:     messageService,
+		defaultCountryCode: defaultCountryCode,
+		logger:             logger,
+	}
+}
+
+// BackfillMessages imports messages a legacy system already sent.
+// @Summary Import sent messages from a legacy system
+// @Description Store messages that were sent before the migration to this service, with their original sent_at, provider message ID and delivery status. They are stored as sent and backfilled, are never handed to a provider and can be told apart from live traffic with GET /api/messages?backfilled=true|false. Either every message of the request is imported or none: invalid messages are refused (422), as is an external reference used by another message of the tenant (409)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body model.BackfillRequest true "Messages to import"
+// @Success 201 {object} model.BackfillResult
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/messages/backfill [post]
+func (h *BackfillHandler) BackfillMessages(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	var req model.BackfillRequest
+	if !bindJSON(c, &req) {
+		logger.Log("Invalid backfill request")
+		return
+	}
+
+	now := time.Now()
+	messages := make([]model.Message, 0, len(req.Messages))
+	var fields []model.FieldError
+	for i, item := range req.Messages {
+		message := model.Message{
+			Content:           item.Content,
+			RecipientPhone:    item.RecipientPhone,
+			RecipientEmail:    item.RecipientEmail,
+			Channel:           item.Channel,
+			Tenant:            item.Tenant,
+			SentAt:            item.SentAt,
+			ProviderMessageID: item.ProviderMessageID,
+			DeliveryStatus:    item.DeliveryStatus,
+			DeliveredAt:       item.DeliveredAt,
+			ExternalRef:       item.ExternalRef,
+		}
+		if item.CreatedAt != nil {
+			message.CreatedAt = *item.CreatedAt
+		}
+
+		if message.DeliveryChannel() != model.ChannelEmail {
+			normalized, err := phone.Normalize(item.RecipientPhone, h.defaultCountryCode)
+			if err != nil {
+				fields = append(fields, model.FieldError{Field: fmt.Sprintf("messages[%d].recipient_phone", i), Message: "must be a valid phone number"})
+			}
+			message.RecipientPhone = normalized
+		}
+		if item.SentAt.After(now) {
+			fields = append(fields, model.FieldError{Field: fmt.Sprintf("messages[%d].sent_at", i), Message: "must not be in the future"})
+		}
+		if item.CreatedAt != nil && item.CreatedAt.After(item.SentAt) {
+			fields = append(fields, model.FieldError{Field: fmt.Sprintf("messages[%d].created_at", i), Message: "must not be after sent_at"})
+		}
+		messages = append(messages, message)
+	}
+	if len(fields) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{Error: "Validation failed", Fields: fields})
+		return
+	}
+
+	imported, err := h.messageService.ImportMessages(c.Request.Context(), messages)
+	if errors.Is(err, mpostgres.ErrExternalRefConflict) {
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to import %d backfilled messages: %v", len(messages), err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to import the messages"})
+		return
+	}
+
+	result := model.BackfillResult{Imported: len(imported), IDs: make([]uint, 0, len(imported))}
+	for _, message := range imported {
+		result.IDs = append(result.IDs, message.ID)
+	}
+	logger.Logf("Imported %d backfilled messages", result.Imported)
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestBackfillMessages(t *testing.T) {
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1, Content: "live"})
+	handler := NewBackfillHandler(messages, "90", inslogger.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/admin/messages/backfill", handler.BackfillMessages)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/api/admin/messages/backfill", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post(`{"messages": [
+		{"content": "shipped", "recipient_phone": "5551111111", "tenant": "acme", "sent_at": "2023-05-01T10:00:00Z",
+		 "provider_message_id": "SM1", "delivery_status": "delivered", "delivered_at": "2023-05-01T10:00:04Z",
+		 "external_ref": {"system": "orders", "id": "SO-1"}},
+		{"content": "welcome", "channel": "email", "recipient_email": "ada@example.com", "sent_at": "2023-05-02T10:00:00Z"}
+	]}`)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var result model.BackfillResult
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+	assert.Equal(t, model.BackfillResult{Imported: 2, IDs: []uint{2, 3}}, result)
+
+	imported, err := messages.Message(2)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatusSent, imported.Status)
+	assert.True(t, imported.Backfilled)
+	assert.Equal(t, "+905551111111", imported.RecipientPhone)
+	assert.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), imported.SentAt)
+	assert.Equal(t, imported.SentAt, imported.CreatedAt, "created_at defaults to sent_at")
+	assert.Equal(t, model.DeliveryDelivered, imported.DeliveryStatus)
+
+	backfilled := false
+	live, err := messages.ListMessages(context.Background(), model.MessageFilter{Backfilled: &backfilled, Page: 1, PageSize: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, live, 1) {
+		assert.Equal(t, uint(1), live[0].ID)
+	}
+
+	resp = post(`{"messages": [
+		{"content": "again", "recipient_phone": "5552222222", "tenant": "acme", "sent_at": "2023-05-03T10:00:00Z"},
+		{"content": "duplicate", "recipient_phone": "5552222222", "tenant": "acme", "sent_at": "2023-05-03T10:00:00Z",
+		 "external_ref": {"system": "orders", "id": "SO-1"}}
+	]}`)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	_, err = messages.Message(4)
+	assert.Error(t, err, "nothing of a refused request is imported")
+
+	resp = post(`{"messages": [{"content": "later", "recipient_phone": "5553333333", "sent_at": "` +
+		time.Now().Add(time.Hour).Format(time.RFC3339) + `"}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "messages[0].sent_at")
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// CallbackHandler receives notifications pushed by providers. Its routes are authenticated
+// by middleware.VerifyCallbackSignature instead of API keys.
+type CallbackHandler struct {
+	messageService mpostgres.MessageService
+	logger         inslogger.Interface
+}
+
+func NewCallbackHandler(
+	messageService mpostgres.MessageService,
+	logger inslogger.Interface,
+) *CallbackHandler {
+
+	return &CallbackHandler{
+		messageService: messageService,
+		logger:         logger,
+	}
+}
+
+// DeliveryStatus records the delivery outcome of a sent message.
+// @Summary Report a delivery status
+// @Description Called by the SMS provider when a sent message is delivered, failed or undelivered. The message is looked up by the ID the provider returned for the send. Requests must be signed with one of CALLBACK_SIGNING_SECRETS.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Signature header string false "hex HMAC-SHA256 of \"<timestamp>.<body>\" (hmac scheme)"
+// @Param X-Signature-Timestamp header string false "Unix time the signature was made (hmac scheme)"
+// @Param callback body model.DeliveryStatusCallback true "Delivery outcome"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Router /api/webhooks/delivery-status [post]
+func (h *CallbackHandler) DeliveryStatus(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	var req model.DeliveryStatusCallback
+	if !bindJSON(c, &req) {
+		logger.Log("Invalid delivery status callback")
+		return
+	}
+
+	at := time.Now().UTC()
+	if req.Timestamp != nil {
+		at = req.Timestamp.UTC()
+	}
+
+	err := h.messageService.UpdateDeliveryStatus(c.Request.Context(), req.MessageID, req.Status, at)
+	if errors.Is(err, mpostgres.ErrMessageNotFound) {
+		logger.Warnf("Delivery status callback for unknown provider message %s", req.MessageID)
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Message not found"})
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to update delivery status: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update delivery status"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
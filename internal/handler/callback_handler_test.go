@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestDeliveryStatus(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Status: model.StatusSent, ProviderMessageID: "provider-1"},
+		model.Message{ID: 2, Status: model.StatusSent, ProviderMessageID: "provider-2"},
+	)
+	handler := NewCallbackHandler(messageService, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/webhooks/delivery-status", handler.DeliveryStatus)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"delivered", `{"message_id": "provider-1", "status": "delivered", "timestamp": "2025-01-01T18:00:05Z"}`, http.StatusNoContent},
+		{"undelivered", `{"message_id": "provider-2", "status": "undelivered"}`, http.StatusNoContent},
+		{"unknown message", `{"message_id": "provider-3", "status": "failed"}`, http.StatusNotFound},
+		{"unknown status", `{"message_id": "provider-1", "status": "read"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/api/webhooks/delivery-status", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+
+	delivered, _ := messageService.Message(1)
+	assert.Equal(t, model.DeliveryDelivered, delivered.DeliveryStatus)
+	assert.True(t, time.Date(2025, 1, 1, 18, 0, 5, 0, time.UTC).Equal(*delivered.DeliveredAt))
+	undelivered, _ := messageService.Message(2)
+	assert.Equal(t, model.DeliveryUndelivered, undelivered.DeliveryStatus)
+	assert.Nil(t, undelivered.DeliveredAt)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type DiagnosticsHandler struct {
+	diagnostics *service.Diagnostics
+	logger      inslogger.Interface
+}
+
+func NewDiagnosticsHandler(diagnostics *service.Diagnostics, logger inslogger.Interface) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		diagnostics: diagnostics,
+		logger:      logger,
+	}
+}
+
+// GetDiagnostics runs the live checks for incident triage.
+// @Summary Run live diagnostics
+// @Description Run every live check at once and report them with the worst status: database and Redis latency, provider reachability, the backlog, messages stuck sending, circuit breakers, the scheduler and settings unusual for production. Round trips slower than DIAGNOSTICS_SLOW_THRESHOLD, a due message waiting longer than DIAGNOSTICS_BACKLOG_AGE and messages sending for longer than DIAGNOSTICS_STUCK_AFTER are warnings. format=text returns an aligned plain text table for terminals
+// @Tags admin
+// @Produce json
+// @Produce plain
+// @Param format query string false "Response format" Enums(json, text) default(json)
+// @Success 200 {object} model.DiagnosticsReport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/diagnostics [get]
+func (h *DiagnosticsHandler) GetDiagnostics(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "format must be json or text"})
+		return
+	}
+
+	report := h.diagnostics.Run(c.Request.Context())
+	if format == "text" {
+		c.String(http.StatusOK, formatDiagnostics(report))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// formatDiagnostics renders report as a table of one check per line.
+func formatDiagnostics(report model.DiagnosticsReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on %s at %s\n\n", strings.ToUpper(string(report.Status)), report.InstanceID, report.GeneratedAt.Format(time.RFC3339))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, check := range report.Checks {
+		latency := ""
+		if check.LatencyMs != nil {
+			latency = fmt.Sprintf("%.1fms", *check.LatencyMs)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Status, check.Name, latency, check.Detail)
+	}
+	_ = w.Flush()
+	return b.String()
+}
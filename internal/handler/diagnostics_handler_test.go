@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/mmemory"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type leaderElector bool
+
+func (e leaderElector) IsLeader() bool { return bool(e) }
+func (e leaderElector) Leader() string { return "" }
+
+func TestGetDiagnostics(t *testing.T) {
+	cfg := &config.App{}
+	cfg.Diagnostics = config.DiagnosticsConfig{Timeout: time.Second, SlowThreshold: time.Second, BacklogAge: time.Minute, StuckAfter: time.Minute}
+	scheduler := new(MockSchedulerService)
+	scheduler.On("PauseReason").Return(nil)
+	scheduler.On("IsRunning").Return(true)
+	pings := map[string]func(ctx context.Context) error{"database": func(context.Context) error { return nil }}
+	diagnostics := service.NewDiagnostics(pings, service.NewProviderRegistry(), mmemory.NewMessageService(inslogger.NewNopLogger()),
+		nil, scheduler, leaderElector(true), cfg, "replica-1")
+
+	handler := NewDiagnosticsHandler(diagnostics, inslogger.NewNopLogger())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/admin/diagnostics", handler.GetDiagnostics)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin/diagnostics?format=text", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "WARN on replica-1")
+	assert.Regexp(t, `(?m)^ok\s+database\s+\d+\.\dms\s*$`, resp.Body.String())
+	assert.Regexp(t, `(?m)^ok\s+scheduler\s+running \(leader\)$`, resp.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/admin/diagnostics?format=yaml", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
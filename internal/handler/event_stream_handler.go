@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type EventStreamHandler struct {
+	bus *service.EventBus
+	// heartbeat is how often an idle stream sends a comment, so proxies keep it open.
+	heartbeat time.Duration
+	logger    inslogger.Interface
+}
+
+func NewEventStreamHandler(bus *service.EventBus, heartbeat time.Duration, logger inslogger.Interface) *EventStreamHandler {
+	return &EventStreamHandler{
+		bus:       bus,
+		heartbeat: heartbeat,
+		logger:    logger,
+	}
+}
+
+// StreamMessageEvents streams message events as they happen.
+// @Summary Stream message events
+// @Description Stream message.sent and message.failed events as Server-Sent Events while the connection is open, so dashboards need not poll /api/messages/sent. Every event is sent as "id: <event ID>", "event: <type>" and "data: <model.MessageEvent as JSON>"; an idle stream sends a comment every EVENTS_SSE_HEARTBEAT. Keys without the admin scope only receive the events of their tenant. With EVENTS_STREAM set every replica streams the events of all of them, otherwise only its own. A client that falls more than EVENTS_SSE_BUFFER events behind misses some, events are not replayed on reconnect
+// @Tags messages
+// @Produce text/event-stream
+// @Success 200 {object} model.MessageEvent
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/stream [get]
+func (h *EventStreamHandler) StreamMessageEvents(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	events, unsubscribe := h.bus.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-store")
+	// Keeps nginx from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type != model.EventMessageSent && event.Type != model.EventMessageFailed {
+				continue
+			}
+			if scope := logctx.TenantScope(c.Request.Context()); scope != "" && event.Tenant != scope {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Errorf("Failed to encode %s event of message ID %d: %v", event.Type, event.MessageID, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/model"
+	"message-service/internal/service"
+)
+
+func TestStreamMessageEvents(t *testing.T) {
+	bus := service.NewEventBus(10)
+	h := NewEventStreamHandler(bus, time.Hour, inslogger.NewNopLogger())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/messages/stream", h.StreamMessageEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/messages/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The handler subscribed before the headers were flushed.
+	ctx := context.Background()
+	_ = bus.Publish(ctx, model.MessageEvent{ID: "1", Type: model.EventMessageCreated, MessageID: 1})
+	_ = bus.Publish(ctx, model.MessageEvent{ID: "2", Type: model.EventMessageSent, MessageID: 1})
+	_ = bus.Publish(ctx, model.MessageEvent{ID: "3", Type: model.EventMessageFailed, MessageID: 2})
+	bus.Close()
+
+	var frames []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "id: ") || strings.HasPrefix(line, "event: ") {
+			frames = append(frames, line)
+		}
+	}
+	assert.Equal(t, []string{"id: 2", "event: message.sent", "id: 3", "event: message.failed"}, frames)
+}
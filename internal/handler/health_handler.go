@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// ReadinessCheck reports whether a dependency is able to serve traffic.
+type ReadinessCheck func(ctx context.Context) error
+
+type HealthHandler struct {
+	checks    map[string]ReadinessCheck
+	scheduler service.SchedulerService
+	breakers  []*service.CircuitBreaker
+	logger    inslogger.Interface
+}
+
+func NewHealthHandler(
+	checks map[string]ReadinessCheck,
+	scheduler service.SchedulerService,
+	breakers []*service.CircuitBreaker,
+	logger inslogger.Interface,
+) *HealthHandler {
+
+	return &HealthHandler{
+		checks:    checks,
+		scheduler: scheduler,
+		breakers:  breakers,
+		logger:    logger,
+	}
+}
+
+// Healthz reports that the process is alive along with circuit breaker states.
+// An open breaker does not fail the probe, restarting would not bring the provider back.
+// @Summary Liveness probe
+// @Description Report that the service is alive and the state of each circuit breaker
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	breakers := gin.H{}
+	for _, breaker := range h.breakers {
+		breakers[breaker.Name()] = breaker.State().String()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":           "ok",
+		"circuit_breakers": breakers,
+	})
+}
+
+// Readyz reports whether the service is ready to send messages.
+// @Summary Readiness probe
+// @Description Check dependencies and report conditions that prevent messages from being sent
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ready := true
+	checks := gin.H{}
+
+	for name, check := range h.checks {
+		if err := check(c.Request.Context()); err != nil {
+			logctx.Logger(c.Request.Context(), h.logger).Errorf("readiness check %s failed: %v", name, err)
+			checks[name] = err.Error()
+			ready = false
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	if h.scheduler != nil {
+		if reason := h.scheduler.PauseReason(); reason != nil {
+			checks["scheduler"] = reason.Error()
+			ready = false
+		} else {
+			checks["scheduler"] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"ready":  ready,
+		"checks": checks,
+	})
+}
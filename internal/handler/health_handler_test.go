@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestReadyz(t *testing.T) {
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("PauseReason").Return(nil)
+
+	handler := NewHealthHandler(map[string]ReadinessCheck{
+		"database": func(ctx context.Context) error { return nil },
+	}, mockScheduler, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/readyz", handler.Readyz)
+
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestReadyzSchedulerPaused(t *testing.T) {
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("PauseReason").Return(errors.New("webhook rejected authentication"))
+
+	handler := NewHealthHandler(nil, mockScheduler, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/readyz", handler.Readyz)
+
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Contains(t, resp.Body.String(), "webhook rejected authentication")
+}
+
+func TestHealthzReportsCircuitBreakers(t *testing.T) {
+	logger := inslogger.NewLogger(inslogger.Debug)
+	breaker := service.NewCircuitBreaker("webhook", 1, time.Minute, logger)
+	assert.NoError(t, breaker.Allow())
+	breaker.Record(false)
+
+	handler := NewHealthHandler(nil, nil, []*service.CircuitBreaker{breaker}, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/healthz", handler.Healthz)
+
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status":"ok","circuit_breakers":{"webhook":"open"}}`, resp.Body.String())
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type MessageAuditHandler struct {
+	messageService mpostgres.MessageService
+	audit          mpostgres.MessageAuditService
+	logger         inslogger.Interface
+}
+
+func NewMessageAuditHandler(messageService mpostgres.MessageService, audit mpostgres.MessageAuditService, logger inslogger.Interface) *MessageAuditHandler {
+	return &MessageAuditHandler{
+		messageService: messageService,
+		audit:          audit,
+		logger:         logger,
+	}
+}
+
+// ListMessageEvents returns the audit trail of a message.
+// @Summary Get the audit trail of a message
+// @Description Get every state change and send attempt of a message, oldest first, with who made it (the API key, "api", "scheduler", "queue consumer" or "system"), and for send attempts the provider, its HTTP response code and the error. Requeued failures, delivery status callbacks and retention purges are not recorded
+// @Tags messages
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {array} model.MessageAuditEntry
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/{id}/events [get]
+func (h *MessageAuditHandler) ListMessageEvents(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid message ID"})
+		return
+	}
+
+	_, err = h.messageService.GetMessage(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Message not found"})
+		return
+	case err != nil:
+		logger.Errorf("Failed to get message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to get message"})
+		return
+	}
+
+	entries, err := h.audit.ListMessageEvents(c.Request.Context(), uint(id))
+	if err != nil {
+		logger.Errorf("Failed to list the audit trail of message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to list message events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
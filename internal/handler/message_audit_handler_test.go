@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestListMessageEvents(t *testing.T) {
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger())
+	created, _ := messages.CreateMessage(context.Background(), model.Message{Content: "hi", RecipientPhone: "+905551111111"})
+	audit := mmemory.NewMessageAuditService()
+	_ = audit.RecordMessageEvent(context.Background(), model.MessageAuditEntry{MessageID: created.ID, Kind: model.AuditStatusChange, Status: model.StatusPending})
+	_ = audit.RecordMessageEvent(context.Background(), model.MessageAuditEntry{MessageID: created.ID + 1, Kind: model.AuditSendAttempt})
+
+	handler := NewMessageAuditHandler(messages, audit, inslogger.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/messages/:id/events", handler.ListMessageEvents)
+
+	for path, code := range map[string]int{"/api/messages/1/events": http.StatusOK, "/api/messages/99/events": http.StatusNotFound, "/api/messages/abc/events": http.StatusBadRequest} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, code, resp.Code, path)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/messages/1/events", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	var body []model.MessageAuditEntry
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	if assert.Len(t, body, 1, "only the events of the message") {
+		assert.Equal(t, model.StatusPending, body[0].Status)
+	}
+}
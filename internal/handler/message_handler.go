@@ -1,150 +1,1076 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	"message-service/internal/middleware"
 	"message-service/internal/model"
 	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/msgtemplate"
+	"message-service/internal/pkg/phone"
 	"message-service/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/useinsider/go-pkg/inslogger"
 )
 
+const (
+	defaultMessagesPageSize = 50
+	maxMessagesPageSize     = 500
+)
+
+// maxScheduleAhead is the furthest in the future a message can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
 type MessageHandler struct {
-	messageService mpostgres.MessageService
-	scheduler      service.SchedulerService
-	logger         inslogger.Interface
-	messageSender  service.MessageSender
+	messageService     mpostgres.MessageService
+	scheduler          service.SchedulerService
+	logger             inslogger.Interface
+	dispatcher         service.DispatchService
+	templates          service.TemplateService
+	defaultCountryCode string
+	// clockSkew is how far in the future scheduled_at may be and still be sent right away.
+	clockSkew time.Duration
+	// displayLocation is the zone GET /api/messages/sent renders timestamps in, UTC when nil.
+	displayLocation *time.Location
+	// events receives message.created for every accepted send, nil disables events.
+	events service.EventPublisher
+	// moderation checks direct sends before they are handed to the provider, nil disables it.
+	moderation *service.ModerationService
+	// schedulerState remembers operator starts and stops for the next boot, nil disables it.
+	schedulerState service.SchedulerStateStore
 }
 
 func NewMessageHandler(
 	messageService mpostgres.MessageService,
 	scheduler service.SchedulerService,
-	messageSender service.MessageSender,
+	schedulerState service.SchedulerStateStore,
+	dispatcher service.DispatchService,
+	templates service.TemplateService,
+	defaultCountryCode string,
+	clockSkew time.Duration,
+	displayLocation *time.Location,
+	events service.EventPublisher,
+	moderation *service.ModerationService,
 	logger inslogger.Interface,
 ) *MessageHandler {
 
 	return &MessageHandler{
-		messageService: messageService,
-		scheduler:      scheduler,
-		messageSender:  messageSender,
-		logger:         logger,
+		messageService:     messageService,
+		scheduler:          scheduler,
+		schedulerState:     schedulerState,
+		dispatcher:         dispatcher,
+		templates:          templates,
+		defaultCountryCode: defaultCountryCode,
+		clockSkew:          clockSkew,
+		displayLocation:    displayLocation,
+		events:             events,
+		moderation:         moderation,
+		logger:             logger,
 	}
 }
 
-// StartScheduler starts the message scheduler.
+// StartScheduler starts the message scheduler and remembers it for the next boot.
 // @Summary Start the message scheduler
-// @Description Start the automatic message sending process
+// @Description Start the automatic message sending process. Replicas started with SCHEDULER_AUTOSTART=auto resume it
 // @Tags scheduler
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /api/scheduler/start [post]
 func (h *MessageHandler) StartScheduler(c *gin.Context) {
-	if err := h.scheduler.Start(); err != nil {
-		h.logger.Error(err)
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	if err := h.scheduler.Start(c.Request.Context()); err != nil {
+		logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to start scheduler",
 		})
 		return
 	}
 
+	h.saveSchedulerState(c, true)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Scheduler started successfully",
 		"status":  "running",
 	})
 }
 
-// StopScheduler stops the message scheduler.
+// StopScheduler stops the message scheduler and remembers it for the next boot.
 // @Summary Stop the message scheduler
-// @Description Stop the automatic message sending process
+// @Description Stop the automatic message sending process. Replicas started with SCHEDULER_AUTOSTART=auto keep it stopped
 // @Tags scheduler
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /api/scheduler/stop [post]
 func (h *MessageHandler) StopScheduler(c *gin.Context) {
-	if err := h.scheduler.Stop(); err != nil {
-		h.logger.Error(err)
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	if err := h.scheduler.Stop(c.Request.Context()); err != nil {
+		logger.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to stop scheduler",
 		})
 		return
 	}
 
+	h.saveSchedulerState(c, false)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Scheduler stopped successfully",
 		"status":  "stopped",
 	})
 }
 
+// saveSchedulerState stores running for the next boot. A failure is only logged, the
+// scheduler itself already changed.
+func (h *MessageHandler) saveSchedulerState(c *gin.Context, running bool) {
+	if h.schedulerState == nil {
+		return
+	}
+	if err := h.schedulerState.SaveRunning(running); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Warnf("Scheduler state will not survive a restart: %v", err)
+	}
+}
+
+// GetSchedulerStatus reports the scheduler state.
+// @Summary Get the scheduler status
+// @Description Report whether the scheduler is running, which instance is the leader and why it paused, if it did
+// @Tags scheduler
+// @Produce json
+// @Success 200 {object} model.SchedulerStatus
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/scheduler/status [get]
+func (h *MessageHandler) GetSchedulerStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scheduler.Status())
+}
+
+// GetSchedulerChannels lists the batch settings of every scheduled channel.
+// @Summary List scheduler channel settings
+// @Description Report the batch size and interval of every channel the scheduler runs
+// @Tags scheduler
+// @Produce json
+// @Success 200 {array} model.ChannelSchedule
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/scheduler/channels [get]
+func (h *MessageHandler) GetSchedulerChannels(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scheduler.Schedules())
+}
+
+// UpdateSchedulerChannel changes the batch settings of one channel.
+// @Summary Update scheduler channel settings
+// @Description Change the batch size and interval of a channel. The change applies to the instance serving the request until it restarts.
+// @Tags scheduler
+// @Accept json
+// @Produce json
+// @Param channel path string true "Channel" Enums(sms, email, push)
+// @Param settings body model.ChannelScheduleRequest true "Channel settings"
+// @Success 200 {object} model.ChannelSchedule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/scheduler/channels/{channel} [put]
+func (h *MessageHandler) UpdateSchedulerChannel(c *gin.Context) {
+	channel := model.Channel(c.Param("channel"))
+
+	var req model.ChannelScheduleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	interval, err := time.ParseDuration(req.Interval)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: []model.FieldError{{Field: "interval", Message: "must be a duration such as 30s or 5m"}},
+		})
+		return
+	}
+
+	settings := service.ChannelSettings{BatchSize: req.BatchSize, Interval: interval}
+	err = h.scheduler.SetSchedule(channel, settings)
+	switch {
+	case errors.Is(err, service.ErrUnscheduledChannel):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Channel is not scheduled"})
+		return
+	case errors.Is(err, service.ErrInvalidChannelSettings):
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: []model.FieldError{{Field: "interval", Message: fmt.Sprintf("must be at least %s", service.MinScheduleInterval)}},
+		})
+		return
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to update %s schedule: %v", channel, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update channel settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.ChannelSchedule{Channel: channel, BatchSize: settings.BatchSize, Interval: settings.Interval.String()})
+}
+
+// RunSchedulerNow sends one batch per channel right away.
+// @Summary Run a scheduler batch now
+// @Description Send one batch of the channel, or of every scheduled channel when channel is omitted, with its configured batch size and wait for it, e.g. after a provider outage was fixed. Works whether or not the scheduler is running and does not start it. The runs are recorded like scheduled ones, see GET /api/scheduler/runs
+// @Tags scheduler
+// @Produce json
+// @Param channel query string false "Channel" Enums(sms, email, push)
+// @Success 200 {array} model.SchedulerRun
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/scheduler/run-now [post]
+func (h *MessageHandler) RunSchedulerNow(c *gin.Context) {
+	channel := model.Channel(c.Query("channel"))
+
+	runs, err := h.scheduler.RunNow(c.Request.Context(), channel)
+	switch {
+	case errors.Is(err, service.ErrUnscheduledChannel):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Channel is not scheduled"})
+		return
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to run a batch on demand: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to run the batch"})
+		return
+	}
+
+	if h.displayLocation != nil {
+		for i := range runs {
+			runs[i] = runs[i].In(h.displayLocation)
+		}
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// ListMessages lists messages filtered by status, creation time and recipient.
+// @Summary List messages
+// @Description List messages newest first, one page at a time. status is sent, unsent (pending, sending or failed) or any single status; from and to bound the creation time.
+// @Tags messages
+// @Produce json
+// @Param status query string false "Message status" Enums(sent, unsent, pending, sending, failed, cancelled)
+// @Param from query string false "Created at or after, RFC 3339" example(2025-01-01T00:00:00Z)
+// @Param to query string false "Created before, RFC 3339" example(2025-02-01T00:00:00Z)
+// @Param phone query string false "Recipient phone number"
+// @Param held query bool false "Only held (true) or not held (false) messages"
+// @Param backfilled query bool false "Only messages imported from a legacy system (true) or live ones (false)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Messages per page (max 500)" default(50)
+// @Param consistency query string false "eventual allows a read replica that may lag behind the latest writes" Enums(strong, eventual) default(strong)
+// @Success 200 {object} model.MessageList
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages [get]
+func (h *MessageHandler) ListMessages(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+	noStore(c)
+
+	ctx, ok := listContext(c)
+	if !ok {
+		return
+	}
+
+	filter := model.MessageFilter{Page: 1, PageSize: defaultMessagesPageSize}
+	switch status := model.MessageStatus(c.Query("status")); status {
+	case "":
+	case "unsent":
+		filter.Statuses = model.UnsentStatuses
+	case model.StatusPending, model.StatusSending, model.StatusSent, model.StatusFailed, model.StatusCancelled:
+		filter.Statuses = []model.MessageStatus{status}
+	default:
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "status must be sent, unsent, pending, sending, failed or cancelled"})
+		return
+	}
+
+	bounds := []struct {
+		name  string
+		bound **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, b := range bounds {
+		raw := c.Query(b.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: b.name + " must be an RFC 3339 time"})
+			return
+		}
+		parsed = parsed.UTC()
+		*b.bound = &parsed
+	}
+
+	if raw := c.Query("phone"); raw != "" {
+		normalized, err := phone.Normalize(raw, h.defaultCountryCode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "phone must be a valid phone number"})
+			return
+		}
+		filter.RecipientPhone = normalized
+	}
+
+	if raw := c.Query("held"); raw != "" {
+		held, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "held must be true or false"})
+			return
+		}
+		filter.Held = &held
+	}
+
+	if raw := c.Query("backfilled"); raw != "" {
+		backfilled, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "backfilled must be true or false"})
+			return
+		}
+		filter.Backfilled = &backfilled
+	}
+
+	if raw := c.Query("page"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "page must be a positive number"})
+			return
+		}
+		filter.Page = parsed
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxMessagesPageSize {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "page_size must be between 1 and 500"})
+			return
+		}
+		filter.PageSize = parsed
+	}
+
+	messages, err := h.messageService.ListMessages(ctx, filter)
+	if err != nil {
+		logger.Errorf("Failed to list messages: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to list messages"})
+		return
+	}
+
+	list := model.MessageList{Messages: messages, Page: filter.Page, PageSize: filter.PageSize}
+	if len(messages) > filter.PageSize {
+		list.Messages = messages[:filter.PageSize]
+		list.NextPage = filter.Page + 1
+	}
+	if list.Messages == nil {
+		list.Messages = []model.Message{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
 // GetSentMessages retrieves all sent messages.
 // @Summary Get all sent messages
-// @Description Retrieve a list of all sent messages
+// @Description Retrieve a list of all sent messages, with timestamps in DISPLAY_TIMEZONE (default UTC). Use GET /api/messages?status=sent instead, it pages and filters.
 // @Tags messages
+// @Deprecated
 // @Accept json
 // @Produce json
+// @Param consistency query string false "eventual allows a read replica that may lag behind the latest writes" Enums(strong, eventual) default(strong)
 // @Success 200 {array} model.Message
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /api/messages/sent [get]
 func (h *MessageHandler) GetSentMessages(c *gin.Context) {
-	messages, err := h.messageService.GetSentMessages(c.Request.Context())
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+	noStore(c)
+	ctx, ok := listContext(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.messageService.GetSentMessages(ctx)
 	if err != nil {
-		h.logger.Errorf("error retrieving sent messages: %v", err)
+		logger.Errorf("error retrieving sent messages: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sent messages", "details": err.Error()})
 		return
 	}
 
 	// Return an empty array if no messages are found
 	if len(messages) == 0 {
-		h.logger.Log("No sent messages found")
+		logger.Log("No sent messages found")
 		c.JSON(http.StatusOK, []model.Message{})
 		return
 	}
-	h.logger.Logf("Retrieved %d sent messages", len(messages))
+	logger.Logf("Retrieved %d sent messages", len(messages))
+	if h.displayLocation != nil {
+		for i := range messages {
+			messages[i] = messages[i].In(h.displayLocation)
+		}
+	}
 	c.JSON(http.StatusOK, messages)
 }
 
+// listContext returns the context to list messages with, reading from the replica when the
+// request asks for consistency=eventual. It answers 400 and returns false for other values.
+func listContext(c *gin.Context) (context.Context, bool) {
+	switch c.Query("consistency") {
+	case "", "strong":
+		return c.Request.Context(), true
+	case "eventual":
+		return mpostgres.WithEventualReads(c.Request.Context()), true
+	default:
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "consistency must be strong or eventual"})
+		return nil, false
+	}
+}
+
+// noStore keeps clients and proxies from caching a message list. Lists are read from the
+// primary database, never from the message cache, so a message sent or changed before the
+// request is in the response; only consistency=eventual trades that for the read replica.
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+}
+
 // SendMessage handles sending a message.
 // @Summary Send a message
-// @Description Send a message to a recipient
+// @Description Send a message to a recipient. When scheduled_at is in the future the message is stored and sent by the scheduler once due. With template_id the content is rendered from the template and variables instead. With external_ref the ID of the message in the calling system is recorded, see GET /api/messages/by-ref/{system}/{id}; a reference used by another message of the tenant is refused (409). With hold the message is stored for review and only sent after POST /api/messages/{id}/release; held messages cannot be sent (409). Where moderation is configured, messages sent right away are moderated first: held ones are answered with 202 and a reason, rejected ones with 422. Sends over the outbound rate limit are answered with 503 and Retry-After. A provider rejecting the service credentials is answered with 502 and pauses the scheduler, as in a batch. Every accepted message counts against the daily and monthly quota of the API key, see GET /api/quota; sends over it are answered with 429, X-Quota-Reset and Retry-After. A message the scheduler is sending at the same time is not sent twice (409). With SEND_MODE=dry-run no provider is called: messages are marked sent and dry_run with a simulated provider message ID; keys with the admin scope can override the mode of a message sent right away with dry_run=true|false. Messages sent right away are dispatched by the request itself and never wait for a scheduler batch; priority only orders the messages batches claim, highest first and oldest first within a priority.
 // @Tags messages
 // @Accept json
 // @Produce json
 // @Param message body model.SendMessageRequest true "Message payload"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
+// @Param dry_run query bool false "Override SEND_MODE for a message sent right away, admin scope only"
+// @Success 202 {object} model.MessageActionResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 502 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Security ApiKeyAuth
 // @Router /api/messages/send [post]
 func (h *MessageHandler) SendMessage(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	if raw := c.Query("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "dry_run must be true or false"})
+			return
+		}
+		if !middleware.HasScope(c, model.ScopeAdmin) {
+			c.JSON(http.StatusForbidden, model.ErrorResponse{Error: "API key lacks the " + model.ScopeAdmin + " scope"})
+			return
+		}
+		c.Request = c.Request.WithContext(service.WithDryRun(c.Request.Context(), dryRun))
+	}
+
 	var req model.SendMessageRequest
+	if !bindJSON(c, &req) {
+		logger.Log("Invalid send message request")
+		return
+	}
+
+	channel := req.Channel
+	if channel == "" {
+		channel = model.ChannelSMS
+	}
+
+	recipientPhone := req.RecipientPhone
+	if channel != model.ChannelEmail {
+		var err error
+		recipientPhone, err = phone.Normalize(req.RecipientPhone, h.defaultCountryCode)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+				Error:  "Validation failed",
+				Fields: []model.FieldError{{Field: "recipient_phone", Message: "must be a valid phone number"}},
+			})
+			return
+		}
+	}
+
+	content := req.Content
+	if req.TemplateID != nil {
+		var ok bool
+		if content, ok = h.renderTemplate(c, *req.TemplateID, req.Variables); !ok {
+			return
+		}
+	}
+
 	message := model.Message{
 		ID:             req.ID,
-		Content:        req.Content,
-		RecipientPhone: req.RecipientPhone,
+		Content:        content,
+		RecipientPhone: recipientPhone,
+		RecipientEmail: req.RecipientEmail,
+		Channel:        channel,
+		ScheduledAt:    req.ScheduledAt,
+		Tenant:         logctx.Tenant(c.Request.Context()),
+		ExternalRef:    req.ExternalRef,
+	}
+
+	if message.ExternalRef != nil && !h.setExternalRef(c, message.ID, *message.ExternalRef) {
+		return
+	}
+	if req.Priority != nil {
+		if !h.setPriority(c, message.ID, *req.Priority) {
+			return
+		}
+		message.Priority = *req.Priority
+	}
+	if req.Hold {
+		h.holdMessage(c, message)
+		return
+	}
+	if message.ScheduledAt != nil && message.ScheduledAt.After(time.Now().Add(h.clockSkew)) {
+		h.scheduleMessage(c, message)
+		return
+	}
+	if !h.moderate(c, message) {
+		return
 	}
 
-	// Bind the JSON payload to the message struct
-	if err := c.ShouldBindJSON(&message); err != nil {
-		h.logger.Errorf("Invalid request payload: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+	if err := h.messageService.MarkMessageSending(c.Request.Context(), message.ID); err != nil {
+		if errors.Is(err, mpostgres.ErrMessageHeld) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message is held for review"})
+			return
+		}
+		if errors.Is(err, mpostgres.ErrInvalidStatusTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message is not pending"})
+			return
+		}
+		logger.Errorf("Failed to mark message as sending: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message status"})
 		return
 	}
+	h.publishCreated(c, message)
 
-	err := h.messageSender.SendMessage(message)
+	providerMessageID, err := h.dispatcher.SendMessage(c.Request.Context(), message)
+	if errors.Is(err, service.ErrSendInProgress) {
+		// The scheduler is sending the message and records its outcome.
+		c.JSON(http.StatusConflict, gin.H{"error": "Message is already being sent"})
+		return
+	}
+	if errors.Is(err, service.ErrCircuitOpen) {
+		// The provider was never called, leave the message pending for the retry.
+		h.requeue(c, message.ID)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Provider is unavailable, try again later"})
+		return
+	}
+	if errors.Is(err, service.ErrOutboundRateLimited) {
+		// The provider was never called, leave the message pending for the retry.
+		h.requeue(c, message.ID)
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Outbound rate limit reached, try again later"})
+		return
+	}
 	if err != nil {
-		h.logger.Errorf("Failed to send message: %v", err)
+		logger.Errorf("Failed to send message: %v", err)
+		if err := h.messageService.MarkMessageFailed(c.Request.Context(), message.ID); err != nil {
+			logger.Errorf("Failed to mark message as failed: %v", err)
+		}
+		if errors.Is(err, service.ErrProviderUnauthorized) {
+			// Every other send would fail the same way, pause the scheduler like a batch does.
+			if h.scheduler != nil {
+				h.scheduler.Pause(err)
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Provider rejected the configured credentials"})
+			return
+		}
+		if errors.Is(err, service.ErrNoProvider) {
+			c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+				Error:  "Validation failed",
+				Fields: []model.FieldError{{Field: "channel", Message: "no provider is configured for the channel"}},
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
 		return
 	}
 
-	if err := h.messageService.UpdateMessageSent(c.Request.Context(), message.ID); err != nil {
-		h.logger.Logf("Failed to update message status: %v", err)
+	if err := h.messageService.UpdateMessageSentWithProviderID(c.Request.Context(), message.ID, providerMessageID); err != nil {
+		logger.Logf("Failed to update message status: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message status"})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":   "Accepted",
-		"messageId": message.ID,
+	c.JSON(http.StatusAccepted, model.MessageActionResponse{
+		Message:           "Accepted",
+		MessageID:         message.ID,
+		ProviderMessageID: providerMessageID,
+	})
+}
+
+// requeue returns a message whose send was put off to pending, so sending it again works.
+func (h *MessageHandler) requeue(c *gin.Context, id uint) {
+	if err := h.messageService.RequeueMessage(c.Request.Context(), id); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to requeue message: %v", err)
+	}
+}
+
+// renderTemplate renders the content of a templated send. Unknown templates, missing
+// variables and content over MaxContentLength are answered with 422.
+func (h *MessageHandler) renderTemplate(c *gin.Context, templateID uint, vars map[string]string) (string, bool) {
+	content, err := h.templates.Render(c.Request.Context(), templateID, vars)
+	var field model.FieldError
+	switch {
+	case errors.Is(err, mpostgres.ErrTemplateNotFound):
+		field = model.FieldError{Field: "template_id", Message: "template does not exist"}
+	case errors.Is(err, msgtemplate.ErrMissingVariables):
+		field = model.FieldError{Field: "variables", Message: err.Error()}
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to render template %d: %v", templateID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render template"})
+		return "", false
+	case utf8.RuneCountInString(content) > model.MaxContentLength:
+		field = model.FieldError{Field: "content", Message: fmt.Sprintf("rendered template must be at most %d characters", model.MaxContentLength)}
+	default:
+		return content, true
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+		Error:  "Validation failed",
+		Fields: []model.FieldError{field},
+	})
+	return "", false
+}
+
+// scheduleMessage stores a future send time instead of sending right away.
+func (h *MessageHandler) scheduleMessage(c *gin.Context, message model.Message) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	if message.ScheduledAt.After(time.Now().Add(maxScheduleAhead)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_at must be within one year"})
+		return
+	}
+
+	scheduledAt := message.ScheduledAt.UTC()
+	if err := h.messageService.ScheduleMessage(c.Request.Context(), message.ID, scheduledAt); err != nil {
+		if errors.Is(err, mpostgres.ErrInvalidStatusTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message is not pending"})
+			return
+		}
+		logger.Errorf("Failed to schedule message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule message"})
+		return
+	}
+	h.publishCreated(c, message)
+
+	c.JSON(http.StatusAccepted, model.MessageActionResponse{
+		Message:     "Scheduled",
+		MessageID:   message.ID,
+		ScheduledAt: &scheduledAt,
+	})
+}
+
+// holdMessage stores a message for review instead of sending it. A future scheduled_at is
+// kept, so once released the message is sent when it is due.
+func (h *MessageHandler) holdMessage(c *gin.Context, message model.Message) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	scheduled := message.ScheduledAt != nil && message.ScheduledAt.After(time.Now())
+	if scheduled && message.ScheduledAt.After(time.Now().Add(maxScheduleAhead)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_at must be within one year"})
+		return
+	}
+
+	// Held and scheduled together, so a failed schedule does not leave the message held
+	// without its send time.
+	ctx := c.Request.Context()
+	var scheduleErr error
+	err := h.messageService.WithTx(ctx, func(tx mpostgres.MessageService) error {
+		if err := tx.SetMessageHold(ctx, message.ID, true); err != nil {
+			return err
+		}
+		if scheduled {
+			scheduleErr = tx.ScheduleMessage(ctx, message.ID, message.ScheduledAt.UTC())
+		}
+		return scheduleErr
+	})
+	if scheduleErr != nil {
+		logger.Errorf("Failed to schedule held message: %v", scheduleErr)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule message"})
+		return
+	}
+	if !h.answerHoldError(c, message.ID, err) {
+		return
+	}
+	h.publishCreated(c, message)
+
+	c.JSON(http.StatusAccepted, model.MessageActionResponse{
+		Message:   "Held",
+		MessageID: message.ID,
 	})
 }
+
+// moderate runs the moderation step of a direct send and reports whether the message was
+// approved. Held and rejected messages are answered here.
+func (h *MessageHandler) moderate(c *gin.Context, message model.Message) bool {
+	if h.moderation == nil {
+		return true
+	}
+
+	result, err := h.moderation.Review(c.Request.Context(), message)
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound), errors.Is(err, mpostgres.ErrInvalidStatusTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Message is not pending"})
+		return false
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to moderate message %d: %v", message.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate message"})
+		return false
+	}
+
+	switch result.Decision {
+	case model.ModerationHold:
+		h.publishCreated(c, message)
+		c.JSON(http.StatusAccepted, model.MessageActionResponse{
+			Message:   "Held",
+			MessageID: message.ID,
+			Reason:    result.Reason,
+		})
+		return false
+	case model.ModerationReject:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Message was rejected by moderation",
+			"reason": result.Reason,
+		})
+		return false
+	}
+	return true
+}
+
+// publishCreated publishes message.created once a send request is accepted, whether the
+// message is sent right away, scheduled or held.
+func (h *MessageHandler) publishCreated(c *gin.Context, message model.Message) {
+	if h.events == nil {
+		return
+	}
+
+	event := service.NewMessageEvent(model.EventMessageCreated, message)
+	if err := h.events.Publish(c.Request.Context(), event); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Warnf("Failed to publish %s event of message ID %d: %v", event.Type, message.ID, err)
+	}
+}
+
+// setHold holds or releases a message and answers the request itself when that fails.
+func (h *MessageHandler) setHold(c *gin.Context, id uint, held bool) bool {
+	return h.answerHoldError(c, id, h.messageService.SetMessageHold(c.Request.Context(), id, held))
+}
+
+// answerHoldError answers the request when holding or releasing message id failed with
+// err and reports whether it succeeded.
+func (h *MessageHandler) answerHoldError(c *gin.Context, id uint, err error) bool {
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return false
+	case errors.Is(err, mpostgres.ErrInvalidStatusTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending or failed messages can be held or released"})
+		return false
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to update the hold of message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+		return false
+	}
+	return true
+}
+
+// setExternalRef records the external reference of a message and answers the request
+// itself when that fails.
+func (h *MessageHandler) setExternalRef(c *gin.Context, id uint, ref model.ExternalRef) bool {
+	err := h.messageService.SetMessageExternalRef(c.Request.Context(), id, ref)
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return false
+	case errors.Is(err, mpostgres.ErrExternalRefConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "External reference is used by another message"})
+		return false
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to set the external reference of message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+		return false
+	}
+	return true
+}
+
+// setPriority sets the priority of a pending message and answers the request itself when
+// that fails.
+func (h *MessageHandler) setPriority(c *gin.Context, id uint, priority int) bool {
+	err := h.messageService.SetMessagePriority(c.Request.Context(), id, priority)
+	switch {
+	case errors.Is(err, mpostgres.ErrInvalidStatusTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Message is not pending"})
+		return false
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to set the priority of message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update message"})
+		return false
+	}
+	return true
+}
+
+// PatchMessage changes a stored message.
+// @Summary Update a message
+// @Description Hold a pending or failed message for review: the scheduler and external workers skip it and it cannot be sent until it is released with POST /api/messages/{id}/release. hold can only be set to true here.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path int true "Message ID"
+// @Param request body model.MessagePatchRequest true "Changes to apply"
+// @Success 200 {object} model.Message
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/{id} [patch]
+func (h *MessageHandler) PatchMessage(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req model.MessagePatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !*req.Hold {
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: []model.FieldError{{Field: "hold", Message: "release held messages with POST /api/messages/{id}/release"}},
+		})
+		return
+	}
+
+	if !h.setHold(c, uint(id), true) {
+		return
+	}
+
+	message, err := h.messageService.GetMessage(c.Request.Context(), uint(id))
+	if err != nil {
+		logger.Errorf("Failed to get message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// ReleaseMessage lets a held message be sent again.
+// @Summary Release a held message
+// @Description Clear the hold of a message after review. A pending message is picked up by the next scheduler batch once due, or can be sent with POST /api/messages/send
+// @Tags messages
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {object} model.MessageActionResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/{id}/release [post]
+func (h *MessageHandler) ReleaseMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	if !h.setHold(c, uint(id), false) {
+		return
+	}
+
+	c.JSON(http.StatusOK, model.MessageActionResponse{
+		Message:   "Released",
+		MessageID: uint(id),
+	})
+}
+
+// GetMessage returns a single message.
+// @Summary Get a message
+// @Description Get a message with its status, timestamps, provider message ID and delivery status
+// @Tags messages
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {object} model.Message
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/{id} [get]
+func (h *MessageHandler) GetMessage(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	message, err := h.messageService.GetMessage(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	case err != nil:
+		logger.Errorf("Failed to get message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// GetMessageByExternalRef returns the message an upstream system knows by its own ID.
+// @Summary Get a message by external reference
+// @Description Get the message of the caller's tenant that was sent with the external_ref system and id, so upstream systems do not need to store message IDs
+// @Tags messages
+// @Produce json
+// @Param system path string true "Upstream system" example(orders)
+// @Param id path string true "ID of the message in the upstream system" example(SO-10045)
+// @Success 200 {object} model.Message
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/by-ref/{system}/{id} [get]
+func (h *MessageHandler) GetMessageByExternalRef(c *gin.Context) {
+	ref := model.ExternalRef{System: c.Param("system"), ID: c.Param("id")}
+
+	message, err := h.messageService.GetMessageByExternalRef(c.Request.Context(), logctx.Tenant(c.Request.Context()), ref)
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	case err != nil:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to get message %s/%s: %v", ref.System, ref.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// CancelMessage cancels a message that has not been sent yet.
+// @Summary Cancel a message
+// @Description Mark a pending or failed message as cancelled so the scheduler skips it
+// @Tags messages
+// @Produce json
+// @Param id path int true "Message ID"
+// @Success 200 {object} model.MessageActionResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/{id}/cancel [post]
+func (h *MessageHandler) CancelMessage(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	err = h.messageService.CancelMessage(c.Request.Context(), uint(id))
+	switch {
+	case errors.Is(err, mpostgres.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	case errors.Is(err, mpostgres.ErrInvalidStatusTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Message can no longer be cancelled"})
+		return
+	case err != nil:
+		logger.Errorf("Failed to cancel message %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.MessageActionResponse{
+		Message:   "Cancelled",
+		MessageID: uint(id),
+	})
+}
+
+// PurgeMessages removes every message sent to a phone number, e.g. for a GDPR erasure request.
+// @Summary Purge the messages of a recipient
+// @Description Anonymize (blank the content, hash the phone, cancel unsent messages and hide them) or delete every message sent to the phone number. Deleting also removes messages anonymized before.
+// @Tags messages
+// @Produce json
+// @Param phone query string true "Recipient phone number"
+// @Param mode query string false "anonymize (default) or delete" Enums(anonymize, delete)
+// @Success 200 {object} model.PurgeResult
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/messages/purge [delete]
+func (h *MessageHandler) PurgeMessages(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	recipient, err := phone.Normalize(c.Query("phone"), h.defaultCountryCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "phone must be a valid phone number"})
+		return
+	}
+
+	mode := model.PurgeMode(c.DefaultQuery("mode", string(model.PurgeAnonymize)))
+	if !mode.Valid() {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "mode must be anonymize or delete"})
+		return
+	}
+
+	ids, err := h.messageService.PurgeRecipient(c.Request.Context(), recipient, mode)
+	if err != nil {
+		logger.Errorf("Failed to purge messages: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to purge messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.PurgeResult{Mode: mode, Purged: len(ids)})
+}
@@ -4,11 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"message-service/internal/mmemory"
 	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/localredis"
+	"message-service/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -17,15 +25,11 @@ import (
 )
 
 // Mock dependencies
-type MockMessageService struct {
-	mock.Mock
-}
-
-func (m *MockSchedulerService) Start() error {
+func (m *MockSchedulerService) Start(ctx context.Context) error {
 	return m.Called().Error(0)
 }
 
-func (m *MockSchedulerService) Stop() error {
+func (m *MockSchedulerService) Stop(ctx context.Context) error {
 	return m.Called().Error(0)
 }
 
@@ -33,37 +37,58 @@ func (m *MockSchedulerService) IsRunning() bool {
 	args := m.Called()
 	return args.Bool(0)
 }
-func (m *MockMessageService) GetSentMessages(ctx context.Context) ([]model.Message, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]model.Message), args.Error(1)
+
+func (m *MockSchedulerService) PauseReason() error {
+	return m.Called().Error(0)
+}
+
+func (m *MockSchedulerService) Pause(reason error) {
+	m.Called(reason)
+}
+
+func (m *MockSchedulerService) Status() model.SchedulerStatus {
+	return m.Called().Get(0).(model.SchedulerStatus)
+}
+
+func (m *MockSchedulerService) Schedules() []model.ChannelSchedule {
+	return m.Called().Get(0).([]model.ChannelSchedule)
+}
+
+func (m *MockSchedulerService) SetSchedule(channel model.Channel, settings service.ChannelSettings) error {
+	return m.Called(channel, settings).Error(0)
 }
 
-func (m *MockMessageService) UpdateMessageSent(ctx context.Context, id uint) error {
-	args := m.Called(ctx, id)
-	return args.Error(0)
+func (m *MockSchedulerService) RunNow(ctx context.Context, channel model.Channel) ([]model.SchedulerRun, error) {
+	args := m.Called(channel)
+	runs, _ := args.Get(0).([]model.SchedulerRun)
+	return runs, args.Error(1)
 }
 
-func (m *MockMessageService) GetUnsentMessages(ctx context.Context, limit int) ([]model.Message, error) {
-	args := m.Called(ctx, limit)
-	return args.Get(0).([]model.Message), args.Error(1)
+func (m *MockSchedulerService) Wake(channel model.Channel) {
+	m.Called(channel)
 }
 
 type MockSchedulerService struct {
 	mock.Mock
 }
 
-type MockMessageSender struct {
+type MockDispatchService struct {
 	mock.Mock
 }
 
-func (m *MockMessageSender) SendMessage(message model.Message) error {
-	args := m.Called(message)
-	return args.Error(0)
+func (m *MockDispatchService) SendMessage(ctx context.Context, message model.Message) (string, error) {
+	args := m.Called(ctx, message)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockMessageSender) SendMessages(limit int) error {
+func (m *MockDispatchService) SendMessages(ctx context.Context, channel model.Channel, limit int) (model.BatchResult, error) {
 	args := m.Called(limit)
-	return args.Error(0)
+	return args.Get(0).(model.BatchResult), args.Error(1)
+}
+
+func (m *MockDispatchService) SendMessageIDs(ctx context.Context, ids []uint) (model.BatchResult, error) {
+	args := m.Called(ids)
+	return args.Get(0).(model.BatchResult), args.Error(1)
 }
 func TestStartScheduler(t *testing.T) {
 	mockScheduler := new(MockSchedulerService)
@@ -109,14 +134,48 @@ func TestStopScheduler(t *testing.T) {
 	mockScheduler.AssertCalled(t, "Stop")
 }
 
+func TestSchedulerStateIsRemembered(t *testing.T) {
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("Start").Return(nil)
+	mockScheduler.On("Stop").Return(nil)
+	state := service.NewRedisSchedulerStateStore(localredis.New())
+
+	handler := &MessageHandler{
+		scheduler:      mockScheduler,
+		schedulerState: state,
+		logger:         inslogger.NewNopLogger(),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/scheduler/start", handler.StartScheduler)
+	router.POST("/api/scheduler/stop", handler.StopScheduler)
+
+	_, ok, err := state.Running()
+	assert.NoError(t, err)
+	assert.False(t, ok, "nothing is stored before the first start or stop")
+
+	for _, action := range []string{"start", "stop"} {
+		req, _ := http.NewRequest(http.MethodPost, "/api/scheduler/"+action, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		running, ok, err := state.Running()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, action == "start", running)
+	}
+}
+
 func TestGetSentMessages(t *testing.T) {
-	mockService := new(MockMessageService)
-	mockService.On("GetSentMessages", mock.Anything).Return([]model.Message{
-		{ID: 1, Content: "Test Message", RecipientPhone: "+123456789", Sent: true},
-	}, nil)
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Content: "Test Message", RecipientPhone: "+123456789", Status: model.StatusSent},
+		model.Message{ID: 2, Content: "Pending Message", RecipientPhone: "+123456789"},
+	)
 
 	handler := &MessageHandler{
-		messageService: mockService,
+		messageService: messageService,
 		logger:         inslogger.NewLogger(inslogger.Debug),
 	}
 
@@ -130,19 +189,155 @@ func TestGetSentMessages(t *testing.T) {
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
-	mockService.AssertCalled(t, "GetSentMessages", mock.Anything)
+	var out []model.Message
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	assert.Len(t, out, 1)
+	assert.Equal(t, uint(1), out[0].ID)
+}
+
+func TestGetSentMessagesInDisplayTimezone(t *testing.T) {
+	sentAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Content: "Test Message", RecipientPhone: "+123456789", Status: model.StatusSent, SentAt: sentAt, CreatedAt: sentAt},
+	)
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		name     string
+		location *time.Location
+		want     string
+	}{
+		{"UTC by default", nil, `"sent_at":"2025-03-01T09:00:00Z"`},
+		{"display timezone", time.FixedZone("UTC+3", 3*60*60), `"sent_at":"2025-03-01T12:00:00+03:00"`},
+	} {
+		handler := &MessageHandler{
+			messageService:  messageService,
+			displayLocation: tt.location,
+			logger:          inslogger.NewLogger(inslogger.Debug),
+		}
+		router := gin.Default()
+		router.GET("/api/messages/sent", handler.GetSentMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/messages/sent", nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code, tt.name)
+		assert.Contains(t, resp.Body.String(), tt.want, tt.name)
+	}
+}
+
+func TestListMessages(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, RecipientPhone: "+905551111111", Status: model.StatusSent, CreatedAt: created},
+		model.Message{ID: 2, RecipientPhone: "+905552222222", Status: model.StatusFailed, CreatedAt: created.Add(time.Hour)},
+		model.Message{ID: 3, RecipientPhone: "+905551111111", Status: model.StatusPending, CreatedAt: created.Add(2 * time.Hour), Held: true},
+		model.Message{ID: 4, RecipientPhone: "+905551111111", Status: model.StatusCancelled, CreatedAt: created.Add(3 * time.Hour)},
+	)
+
+	handler := &MessageHandler{
+		messageService:     messageService,
+		defaultCountryCode: "90",
+		logger:             inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/messages", handler.ListMessages)
+
+	tests := []struct {
+		query    string
+		code     int
+		ids      []uint
+		nextPage int
+	}{
+		{"", http.StatusOK, []uint{4, 3, 2, 1}, 0},
+		{"?status=sent", http.StatusOK, []uint{1}, 0},
+		{"?status=unsent", http.StatusOK, []uint{3, 2}, 0},
+		{"?phone=05551111111&to=2025-01-01T14:00:00Z", http.StatusOK, []uint{1}, 0},
+		{"?from=2025-01-01T13:00:00Z&page_size=2", http.StatusOK, []uint{4, 3}, 2},
+		{"?from=2025-01-01T13:00:00Z&page_size=2&page=2", http.StatusOK, []uint{2}, 0},
+		{"?held=true", http.StatusOK, []uint{3}, 0},
+		{"?status=sent&consistency=eventual", http.StatusOK, []uint{1}, 0},
+		{"?consistency=latest", http.StatusBadRequest, nil, 0},
+		{"?status=delivered", http.StatusBadRequest, nil, 0},
+		{"?held=maybe", http.StatusBadRequest, nil, 0},
+		{"?from=yesterday", http.StatusBadRequest, nil, 0},
+		{"?page_size=1000", http.StatusBadRequest, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/messages"+tt.query, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.code, resp.Code)
+			if tt.code != http.StatusOK {
+				return
+			}
+			var out model.MessageList
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			ids := make([]uint, 0, len(out.Messages))
+			for _, msg := range out.Messages {
+				ids = append(ids, msg.ID)
+			}
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, tt.nextPage, out.NextPage)
+		})
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Content: "Test Message", Status: model.StatusSent, ProviderMessageID: "provider-1"},
+	)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/messages/sent", handler.GetSentMessages)
+	router.GET("/api/messages/:id", handler.GetMessage)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/messages/1", http.StatusOK},
+		{"/api/messages/2", http.StatusNotFound},
+		{"/api/messages/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, tt.code, resp.Code, tt.path)
+		if tt.code == http.StatusOK {
+			var out model.Message
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			assert.Equal(t, model.StatusSent, out.Status)
+			assert.Equal(t, "provider-1", out.ProviderMessageID)
+		}
+	}
 }
 
 func TestSendMessage(t *testing.T) {
-	mockService := new(MockMessageService)
-	mockSender := new(MockMessageSender)
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
 
-	mockSender.On("SendMessage", mock.Anything).Return(nil)
-	mockService.On("UpdateMessageSent", mock.Anything, mock.Anything).Return(nil)
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("provider-123", nil)
 
 	handler := &MessageHandler{
-		messageService: mockService,
-		messageSender:  mockSender,
+		messageService: messageService,
+		dispatcher:     mockSender,
 		logger:         inslogger.NewLogger(inslogger.Debug),
 	}
 
@@ -164,6 +359,818 @@ func TestSendMessage(t *testing.T) {
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusAccepted, resp.Code)
-	mockSender.AssertCalled(t, "SendMessage", mock.Anything)
-	mockService.AssertCalled(t, "UpdateMessageSent", mock.Anything, uint(1))
+	mockSender.AssertCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusSent, stored.Status)
+	assert.Equal(t, "provider-123", stored.ProviderMessageID)
+}
+
+func TestSendMessageThenListSent(t *testing.T) {
+	memory := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1})
+	messageService := service.NewCachedMessageService(memory, localredis.New(), time.Minute, time.Hour, inslogger.NewNopLogger())
+	mockSender := new(MockDispatchService)
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("provider-123", nil)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewNopLogger(),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/messages/send", handler.SendMessage)
+	router.GET("/api/messages/sent", handler.GetSentMessages)
+	router.GET("/api/messages", handler.ListMessages)
+
+	// Cache the message and the lists as they are before the send.
+	_, _ = messageService.GetMessage(context.Background(), 1)
+	for _, path := range []string{"/api/messages/sent", "/api/messages?status=sent"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+
+	for _, path := range []string{"/api/messages/sent", "/api/messages?status=sent"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"), path)
+		assert.Contains(t, resp.Body.String(), `"provider_message_id":"provider-123"`, "%s lists the message sent just before", path)
+	}
+}
+
+func TestGetSchedulerStatus(t *testing.T) {
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("Status").Return(model.SchedulerStatus{Running: true, Leader: "host-1", IsLeader: true})
+
+	handler := &MessageHandler{
+		scheduler: mockScheduler,
+		logger:    inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/scheduler/status", handler.GetSchedulerStatus)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/scheduler/status", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"leader":"host-1"`)
+}
+
+func TestUpdateSchedulerChannel(t *testing.T) {
+	schedules := map[model.Channel]service.ChannelSettings{model.ChannelSMS: {BatchSize: 2, Interval: 2 * time.Minute}}
+	handler := &MessageHandler{
+		scheduler: service.NewSchedulerService(new(MockDispatchService), nil, nil, schedules, service.WarmupSettings{}, inslogger.NewNopLogger()),
+		logger:    inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/scheduler/channels", handler.GetSchedulerChannels)
+	router.PUT("/api/scheduler/channels/:channel", handler.UpdateSchedulerChannel)
+
+	tests := []struct {
+		name    string
+		channel string
+		body    string
+		code    int
+	}{
+		{"updated", "sms", `{"batch_size":100,"interval":"30s"}`, http.StatusOK},
+		{"unscheduled channel", "email", `{"batch_size":100,"interval":"30s"}`, http.StatusNotFound},
+		{"batch size too large", "sms", `{"batch_size":5000,"interval":"30s"}`, http.StatusUnprocessableEntity},
+		{"invalid interval", "sms", `{"batch_size":100,"interval":"soon"}`, http.StatusUnprocessableEntity},
+		{"interval too short", "sms", `{"batch_size":100,"interval":"10ms"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPut, "/api/scheduler/channels/"+tt.channel, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.code, resp.Code, resp.Body.String())
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/scheduler/channels", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `[{"channel":"sms","batch_size":100,"interval":"30s"}]`, resp.Body.String())
+}
+
+func TestRunSchedulerNow(t *testing.T) {
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("RunNow", model.ChannelSMS).Return([]model.SchedulerRun{{Channel: model.ChannelSMS, Claimed: 2, Sent: 2}}, nil)
+	mockScheduler.On("RunNow", model.ChannelPush).Return(nil, service.ErrUnscheduledChannel)
+	handler := &MessageHandler{scheduler: mockScheduler, logger: inslogger.NewNopLogger()}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/scheduler/run-now", handler.RunSchedulerNow)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/scheduler/run-now?channel=sms", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var runs []model.SchedulerRun
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &runs))
+	assert.Equal(t, []model.SchedulerRun{{Channel: model.ChannelSMS, Claimed: 2, Sent: 2}}, runs)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/scheduler/run-now?channel=push", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	mockScheduler.AssertExpectations(t)
+}
+
+func TestSendMessageNotPending(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1, Status: model.StatusSent})
+	mockSender := new(MockDispatchService)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	mockSender.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendMessageProviderUnauthorized(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	unauthorized := fmt.Errorf("%w: status code 401", service.ErrProviderUnauthorized)
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("", unauthorized)
+	mockScheduler := new(MockSchedulerService)
+	mockScheduler.On("Pause", unauthorized).Return()
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		scheduler:      mockScheduler,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadGateway, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Provider rejected the configured credentials")
+	mockScheduler.AssertCalled(t, "Pause", unauthorized)
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusFailed, stored.Status)
+}
+
+func TestSendMessageRateLimitedCanBeRetried(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("", service.ErrOutboundRateLimited).Once()
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("provider-123", nil).Once()
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+	send := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, "1", resp.Header().Get("Retry-After"))
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusPending, stored.Status, "a rate-limited send never reached the provider")
+	assert.Zero(t, stored.Attempts)
+
+	resp = send()
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	stored, _ = messageService.Message(1)
+	assert.Equal(t, model.StatusSent, stored.Status)
+}
+
+func TestSendMessageWhileCircuitOpenCanBeRetried(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("", fmt.Errorf("%w: webhook", service.ErrCircuitOpen)).Once()
+	mockSender.On("SendMessage", mock.Anything, mock.Anything).Return("provider-123", nil).Once()
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+	send := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusPending, stored.Status, "the provider was not called while the circuit was open")
+	assert.Zero(t, stored.Attempts)
+
+	resp = send()
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	stored, _ = messageService.Message(1)
+	assert.Equal(t, model.StatusSent, stored.Status)
+}
+
+func TestCancelMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   []model.Message
+		status int
+	}{
+		{name: "cancelled", seed: []model.Message{{ID: 7}}, status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound},
+		{name: "already sent", seed: []model.Message{{ID: 7, Status: model.StatusSent}}, status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &MessageHandler{
+				messageService: mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), tt.seed...),
+				logger:         inslogger.NewLogger(inslogger.Debug),
+			}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			router.POST("/api/messages/:id/cancel", handler.CancelMessage)
+
+			req, _ := http.NewRequest(http.MethodPost, "/api/messages/7/cancel", nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func TestHoldAndReleaseMessage(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2, Status: model.StatusSent})
+	mockSender := new(MockDispatchService)
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+	router.PATCH("/api/messages/:id", handler.PatchMessage)
+	router.POST("/api/messages/:id/release", handler.ReleaseMessage)
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+	send := `{"id": 1, "content": "Flagged", "recipient_phone": "+905551111111"}`
+
+	resp := serve(http.MethodPatch, "/api/messages/1", `{"hold": true}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"held":true`)
+
+	resp = serve(http.MethodPost, "/api/messages/send", send)
+	assert.Equal(t, http.StatusConflict, resp.Code, "held messages cannot be sent")
+	mockSender.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+
+	claimed, err := messageService.ClaimMessages(context.Background(), "worker-1", 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed, "held messages are not claimed")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(http.MethodPatch, "/api/messages/1", `{"hold": false}`).Code)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPatch, "/api/messages/2", `{"hold": true}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/api/messages/3/release", "").Code)
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/api/messages/1/release", "").Code)
+	stored, _ := messageService.Message(1)
+	assert.False(t, stored.Held)
+	assert.Equal(t, model.StatusPending, stored.Status)
+}
+
+func TestSendMessageHeld(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	scheduledAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	body, _ := json.Marshal(model.SendMessageRequest{
+		ID:             1,
+		Content:        "Flagged",
+		RecipientPhone: "+905551111111",
+		ScheduledAt:    &scheduledAt,
+		Hold:           true,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Held")
+	stored, _ := messageService.Message(1)
+	assert.True(t, stored.Held)
+	assert.True(t, scheduledAt.Equal(*stored.ScheduledAt), "the schedule is kept for after the release")
+	mockSender.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendMessageWithExternalRef(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2})
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     new(MockDispatchService),
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+	router.GET("/api/messages/by-ref/:system/:id", handler.GetMessageByExternalRef)
+
+	ref := &model.ExternalRef{System: "orders", ID: "SO-10045"}
+	// Message 1 takes the reference first, so the order matters.
+	for _, send := range []struct {
+		id   uint
+		code int
+	}{{1, http.StatusAccepted}, {2, http.StatusConflict}} {
+		body, _ := json.Marshal(model.SendMessageRequest{
+			ID:             send.id,
+			Content:        "Your order shipped",
+			RecipientPhone: "+905551111111",
+			Hold:           true,
+			ExternalRef:    ref,
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, send.code, resp.Code, "message %d", send.id)
+	}
+
+	for path, code := range map[string]int{
+		"/api/messages/by-ref/orders/SO-10045": http.StatusOK,
+		"/api/messages/by-ref/crm/SO-10045":    http.StatusNotFound,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, code, resp.Code, path)
+		if code == http.StatusOK {
+			var out model.Message
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			assert.Equal(t, uint(1), out.ID)
+			assert.Equal(t, ref, out.ExternalRef)
+		}
+	}
+}
+
+func TestSendMessageScheduled(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+
+	scheduledAt := time.Now().Add(6 * time.Hour).UTC().Truncate(time.Second)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	body, _ := json.Marshal(model.SendMessageRequest{
+		ID:             1,
+		Content:        "Test Message",
+		RecipientPhone: "+123456789",
+		ScheduledAt:    &scheduledAt,
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Contains(t, resp.Body.String(), scheduledAt.Format(time.RFC3339))
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusPending, stored.Status)
+	assert.True(t, scheduledAt.Equal(*stored.ScheduledAt))
+	mockSender.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendMessageWithPriority(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 2, Status: model.StatusSent})
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     new(MockDispatchService),
+		logger:         inslogger.NewNopLogger(),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	scheduledAt := time.Now().Add(time.Hour)
+	send := func(id uint, priority int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.SendMessageRequest{
+			ID:             id,
+			Content:        "Test Message",
+			RecipientPhone: "+123456789",
+			ScheduledAt:    &scheduledAt,
+			Priority:       &priority,
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusAccepted, send(1, model.MaxPriority).Code)
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.MaxPriority, stored.Priority)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, send(1, model.MaxPriority+1).Code)
+	assert.Equal(t, http.StatusConflict, send(2, model.MaxPriority).Code, "only pending messages take a priority")
+}
+
+type recordingEvents struct {
+	events []model.MessageEvent
+}
+
+func (p *recordingEvents) Publish(ctx context.Context, event model.MessageEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestSendMessagePublishesCreated(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2, Status: model.StatusSent})
+	events := &recordingEvents{}
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     new(MockDispatchService),
+		events:         events,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	scheduledAt := time.Now().Add(time.Hour).UTC()
+	for _, id := range []uint{1, 2} {
+		body, _ := json.Marshal(model.SendMessageRequest{ID: id, Content: "Hi", RecipientPhone: "+905551111111", ScheduledAt: &scheduledAt})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if assert.Len(t, events.events, 1, "rejected sends publish nothing") {
+		assert.Equal(t, model.EventMessageCreated, events.events[0].Type)
+		assert.Equal(t, uint(1), events.events[0].MessageID)
+		assert.Equal(t, model.ChannelSMS, events.events[0].Channel)
+		assert.NotEmpty(t, events.events[0].ID)
+	}
+}
+
+func TestSendMessageModeration(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2})
+	mockSender := new(MockDispatchService)
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		moderation: service.NewModerationService(map[string]service.Moderator{
+			"*/*": service.NewRuleModerator([]service.ModerationRule{
+				{Pattern: regexp.MustCompile(`(?i)casino`), Decision: model.ModerationReject, Reason: "gambling"},
+				{Pattern: regexp.MustCompile(`(?i)loan`), Decision: model.ModerationHold, Reason: "financial offer"},
+			}),
+		}, messageService, model.ModerationHold, inslogger.NewNopLogger()),
+		logger: inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	send := func(id uint, content string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.SendMessageRequest{ID: id, Content: content, RecipientPhone: "+905551111111"})
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send(1, "Casino night")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "gambling")
+	rejected, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusCancelled, rejected.Status)
+
+	resp = send(2, "Cheap loan")
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Held")
+	held, _ := messageService.Message(2)
+	assert.True(t, held.Held)
+
+	mockSender.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestSendMessageValidation(t *testing.T) {
+	handler := &MessageHandler{
+		messageService: mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug)),
+		dispatcher:     new(MockDispatchService),
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	tests := []struct {
+		name     string
+		request  model.SendMessageRequest
+		expected []model.FieldError
+	}{
+		{
+			name:    "Content too long",
+			request: model.SendMessageRequest{ID: 1, Content: strings.Repeat("a", model.MaxContentLength+1), RecipientPhone: "+905551111111"},
+			expected: []model.FieldError{
+				{Field: "content", Message: "must be at most 160 characters"},
+			},
+		},
+		{
+			name:    "Missing fields",
+			request: model.SendMessageRequest{Content: "Test Message"},
+			expected: []model.FieldError{
+				{Field: "id", Message: "is required"},
+				{Field: "recipient_phone", Message: "is required"},
+			},
+		},
+		{
+			name:    "Invalid phone",
+			request: model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "555-abc"},
+			expected: []model.FieldError{
+				{Field: "recipient_phone", Message: "must be a valid phone number"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+			var out model.ValidationErrorResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+			assert.ElementsMatch(t, tt.expected, out.Fields)
+		})
+	}
+}
+
+func TestSendMessageNormalizesPhone(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	handler := &MessageHandler{
+		messageService:     messageService,
+		dispatcher:         mockSender,
+		defaultCountryCode: "90",
+		logger:             inslogger.NewLogger(inslogger.Debug),
+	}
+
+	mockSender.On("SendMessage", mock.Anything, mock.MatchedBy(func(m model.Message) bool {
+		return m.RecipientPhone == "+905551111111"
+	})).Return("provider-1", nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	body, _ := json.Marshal(model.SendMessageRequest{ID: 1, Content: "Test Message", RecipientPhone: "0555 111 11 11"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	mockSender.AssertExpectations(t)
+}
+
+func TestSendMessageWithTemplate(t *testing.T) {
+	ctx := context.Background()
+	templates := service.NewTemplateService(mmemory.NewTemplateRepository(), inslogger.NewLogger(inslogger.Debug))
+	template, err := templates.Create(ctx, model.TemplateRequest{Name: "otp", Body: "Hi {{name}}, your code is {{code}}"})
+	assert.NoError(t, err)
+
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2})
+	mockSender := new(MockDispatchService)
+	mockSender.On("SendMessage", mock.Anything, mock.MatchedBy(func(msg model.Message) bool {
+		return msg.Content == "Hi Ada, your code is 1234"
+	})).Return("provider-123", nil)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		templates:      templates,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	send := func(req model.SendMessageRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		httpReq, _ := http.NewRequest(http.MethodPost, "/api/messages/send", bytes.NewBuffer(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httpReq)
+		return resp
+	}
+
+	resp := send(model.SendMessageRequest{ID: 1, RecipientPhone: "+905551111111", TemplateID: &template.ID,
+		Variables: map[string]string{"name": "Ada", "code": "1234"}})
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	mockSender.AssertNumberOfCalls(t, "SendMessage", 1)
+
+	resp = send(model.SendMessageRequest{ID: 2, RecipientPhone: "+905551111111", TemplateID: &template.ID,
+		Variables: map[string]string{"name": "Ada"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"field":"variables"`)
+
+	unknown := template.ID + 1
+	resp = send(model.SendMessageRequest{ID: 2, RecipientPhone: "+905551111111", TemplateID: &unknown})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"field":"template_id"`)
+
+	resp = send(model.SendMessageRequest{ID: 2, RecipientPhone: "+905551111111"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, "content is required without a template")
+	mockSender.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestSendEmailMessage(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1})
+	mockSender := new(MockDispatchService)
+	mockSender.On("SendMessage", mock.Anything, mock.MatchedBy(func(msg model.Message) bool {
+		return msg.Channel == model.ChannelEmail && msg.RecipientEmail == "ada@example.com"
+	})).Return("<id@example.com>", nil)
+
+	handler := &MessageHandler{
+		messageService: messageService,
+		dispatcher:     mockSender,
+		logger:         inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/messages/send", handler.SendMessage)
+
+	send := func(payload string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/api/messages/send", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send(`{"id":1,"content":"Hi","channel":"email"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"field":"recipient_email"`)
+
+	resp = send(`{"id":1,"content":"Hi","channel":"fax","recipient_phone":"+905551111111"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"field":"channel"`)
+
+	resp = send(`{"id":1,"content":"Hi","channel":"email","recipient_email":"ada@example.com"}`)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	mockSender.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestPurgeMessages(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Content: "Hello", RecipientPhone: "+905551111111", Status: model.StatusSent},
+		model.Message{ID: 2, Content: "Hello", RecipientPhone: "+905551111111", Status: model.StatusPending},
+		model.Message{ID: 3, Content: "Hello", RecipientPhone: "+905552222222", Status: model.StatusSent},
+	)
+
+	handler := &MessageHandler{
+		messageService:     messageService,
+		defaultCountryCode: "90",
+		logger:             inslogger.NewLogger(inslogger.Debug),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.DELETE("/api/messages/purge", handler.PurgeMessages)
+	router.GET("/api/messages/:id", handler.GetMessage)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(http.MethodDelete, "/api/messages/purge?phone=05551111111")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"mode":"anonymize","purged":2}`, resp.Body.String())
+
+	anonymized, err := messageService.Message(2)
+	assert.NoError(t, err)
+	assert.Empty(t, anonymized.Content)
+	assert.Equal(t, model.StatusCancelled, anonymized.Status)
+	assert.NotContains(t, anonymized.RecipientPhone, "5551111111")
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/messages/1").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/messages/3").Code)
+
+	resp = serve(http.MethodDelete, "/api/messages/purge?phone=%2B905551111111&mode=delete")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"mode":"delete","purged":2}`, resp.Body.String())
+	_, err = messageService.Message(1)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, "/api/messages/purge").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, "/api/messages/purge?phone=05551111111&mode=shred").Code)
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"net/http"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type OutboundLimitHandler struct {
+	limiter service.OutboundLimiter
+	logger  inslogger.Interface
+}
+
+func NewOutboundLimitHandler(limiter service.OutboundLimiter, logger inslogger.Interface) *OutboundLimitHandler {
+	return &OutboundLimitHandler{
+		limiter: limiter,
+		logger:  logger,
+	}
+}
+
+// GetLimits returns the outbound rate limit in effect.
+// @Summary Get the outbound rate limit
+// @Description Get how many messages per second all replicas hand to the providers, over the scheduler and the API. A rate of 0 means no limit
+// @Tags admin
+// @Produce json
+// @Success 200 {object} model.OutboundLimits
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/outbound-limits [get]
+func (h *OutboundLimitHandler) GetLimits(c *gin.Context) {
+	limits, err := h.limiter.Limits()
+	if err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to read the outbound limits: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to read the outbound limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// UpdateLimits changes the outbound rate limit at runtime.
+// @Summary Update the outbound rate limit
+// @Description Change the outbound rate limit of every replica, e.g. after the provider changed its throughput limit. The change replaces OUTBOUND_RATE and OUTBOUND_BURST until it is reset
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param limits body model.OutboundLimits true "Outbound limits"
+// @Success 200 {object} model.OutboundLimits
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/outbound-limits [put]
+func (h *OutboundLimitHandler) UpdateLimits(c *gin.Context) {
+	var req model.OutboundLimits
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// The binding rules match the limiter's, so SetLimits only fails on storage errors.
+	if err := h.limiter.SetLimits(req); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to update the outbound limits: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to update the outbound limits"})
+		return
+	}
+
+	logctx.Logger(c.Request.Context(), h.logger).Logf("Outbound limits changed to %.2f/s, burst %d", req.Rate, req.Burst)
+	c.JSON(http.StatusOK, req)
+}
+
+// ResetLimits returns to the configured outbound rate limit.
+// @Summary Reset the outbound rate limit
+// @Description Drop the limit set through PUT /api/admin/outbound-limits and return to OUTBOUND_RATE and OUTBOUND_BURST
+// @Tags admin
+// @Produce json
+// @Success 200 {object} model.OutboundLimits
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/outbound-limits [delete]
+func (h *OutboundLimitHandler) ResetLimits(c *gin.Context) {
+	if err := h.limiter.ResetLimits(); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to reset the outbound limits: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to reset the outbound limits"})
+		return
+	}
+
+	h.GetLimits(c)
+}
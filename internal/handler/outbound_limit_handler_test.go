@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestOutboundLimits(t *testing.T) {
+	configured := model.OutboundLimits{Rate: 10, Burst: 20}
+	handler := NewOutboundLimitHandler(service.NewLocalOutboundLimiter(configured, time.Second), inslogger.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/admin/outbound-limits", handler.GetLimits)
+	router.PUT("/api/admin/outbound-limits", handler.UpdateLimits)
+	router.DELETE("/api/admin/outbound-limits", handler.ResetLimits)
+
+	call := func(method, body string) (int, model.OutboundLimits) {
+		req, _ := http.NewRequest(method, "/api/admin/outbound-limits", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		var limits model.OutboundLimits
+		_ = json.Unmarshal(resp.Body.Bytes(), &limits)
+		return resp.Code, limits
+	}
+
+	code, limits := call(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, configured, limits)
+
+	code, _ = call(http.MethodPut, `{"rate": -1, "burst": 5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, limits = call(http.MethodPut, `{"rate": 2.5, "burst": 5}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.OutboundLimits{Rate: 2.5, Burst: 5}, limits)
+	_, limits = call(http.MethodGet, "")
+	assert.Equal(t, model.OutboundLimits{Rate: 2.5, Burst: 5}, limits)
+
+	code, limits = call(http.MethodDelete, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, configured, limits)
+}
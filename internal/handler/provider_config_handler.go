@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type ProviderConfigHandler struct {
+	configs service.ProviderConfigService
+	logger  inslogger.Interface
+}
+
+func NewProviderConfigHandler(configs service.ProviderConfigService, logger inslogger.Interface) *ProviderConfigHandler {
+	return &ProviderConfigHandler{
+		configs: configs,
+		logger:  logger,
+	}
+}
+
+// CreateProviderConfig routes the webhook sends of a tenant to its own gateway.
+// @Summary Create a provider config
+// @Description Send the SMS of a tenant to its own webhook URL with its own auth key instead of WEBHOOK_URL and AUTH_KEY. Only applies to the webhook driver and to production tenants, sends pick it up within TENANT_WEBHOOK_CONFIG_TTL. The auth key is never returned.
+// @Tags provider-configs
+// @Accept json
+// @Produce json
+// @Param config body model.ProviderConfigRequest true "Provider config"
+// @Success 201 {object} model.ProviderConfig
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/provider-configs [post]
+func (h *ProviderConfigHandler) CreateProviderConfig(c *gin.Context) {
+	var req model.ProviderConfigRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	config, err := h.configs.Create(c.Request.Context(), req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create provider config")
+		return
+	}
+
+	c.JSON(http.StatusCreated, config)
+}
+
+// ListProviderConfigs returns the provider config of every tenant that has one.
+// @Summary List provider configs
+// @Tags provider-configs
+// @Produce json
+// @Success 200 {array} model.ProviderConfig
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/provider-configs [get]
+func (h *ProviderConfigHandler) ListProviderConfigs(c *gin.Context) {
+	configs, err := h.configs.List(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list provider configs")
+		return
+	}
+
+	c.JSON(http.StatusOK, configs)
+}
+
+// GetProviderConfig returns one provider config.
+// @Summary Get a provider config
+// @Tags provider-configs
+// @Produce json
+// @Param id path int true "Provider config ID"
+// @Success 200 {object} model.ProviderConfig
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/provider-configs/{id} [get]
+func (h *ProviderConfigHandler) GetProviderConfig(c *gin.Context) {
+	id, ok := providerConfigID(c)
+	if !ok {
+		return
+	}
+
+	config, err := h.configs.Get(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get provider config")
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// UpdateProviderConfig replaces the tenant, webhook URL and auth key of a provider config.
+// @Summary Update a provider config
+// @Tags provider-configs
+// @Accept json
+// @Produce json
+// @Param id path int true "Provider config ID"
+// @Param config body model.ProviderConfigRequest true "Provider config"
+// @Success 200 {object} model.ProviderConfig
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/provider-configs/{id} [put]
+func (h *ProviderConfigHandler) UpdateProviderConfig(c *gin.Context) {
+	id, ok := providerConfigID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ProviderConfigRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	config, err := h.configs.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update provider config")
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// DeleteProviderConfig removes a provider config, the tenant is sent through WEBHOOK_URL again.
+// @Summary Delete a provider config
+// @Tags provider-configs
+// @Param id path int true "Provider config ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/provider-configs/{id} [delete]
+func (h *ProviderConfigHandler) DeleteProviderConfig(c *gin.Context) {
+	id, ok := providerConfigID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.configs.Delete(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete provider config")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func providerConfigID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid provider config ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *ProviderConfigHandler) writeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, mpostgres.ErrProviderConfigNotFound):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Provider config not found"})
+	case errors.Is(err, mpostgres.ErrProviderConfigTenantTaken):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Tenant already has a provider config"})
+	default:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: message})
+	}
+}
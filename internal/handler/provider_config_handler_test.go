@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func newProviderConfigRouter() *gin.Engine {
+	logger := inslogger.NewNopLogger()
+	handler := NewProviderConfigHandler(service.NewProviderConfigService(mmemory.NewProviderConfigRepository(), logger), logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/admin/provider-configs", handler.CreateProviderConfig)
+	router.GET("/api/admin/provider-configs", handler.ListProviderConfigs)
+	router.GET("/api/admin/provider-configs/:id", handler.GetProviderConfig)
+	router.PUT("/api/admin/provider-configs/:id", handler.UpdateProviderConfig)
+	router.DELETE("/api/admin/provider-configs/:id", handler.DeleteProviderConfig)
+	return router
+}
+
+func TestProviderConfigCRUD(t *testing.T) {
+	router := newProviderConfigRouter()
+	acme := model.ProviderConfigRequest{Tenant: "acme", WebhookURL: "https://sms.acme.example/send", AuthKey: "acme-secret"}
+
+	resp := serveTemplate(router, http.MethodPost, "/api/admin/provider-configs", acme)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "acme-secret", "auth keys are never returned")
+
+	resp = serveTemplate(router, http.MethodPost, "/api/admin/provider-configs", acme)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = serveTemplate(router, http.MethodPost, "/api/admin/provider-configs", model.ProviderConfigRequest{Tenant: "globex", WebhookURL: "not a url", AuthKey: "key"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	acme.WebhookURL = "https://sms2.acme.example/send"
+	resp = serveTemplate(router, http.MethodPut, "/api/admin/provider-configs/1", acme)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/admin/provider-configs/1", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"webhook_url":"https://sms2.acme.example/send"`)
+
+	resp = serveTemplate(router, http.MethodDelete, "/api/admin/provider-configs/1", nil)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/admin/provider-configs/1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"message-service/internal/middleware"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type QuotaHandler struct {
+	quotas *service.QuotaCounter
+	logger inslogger.Interface
+}
+
+func NewQuotaHandler(quotas *service.QuotaCounter, logger inslogger.Interface) *QuotaHandler {
+	return &QuotaHandler{
+		quotas: quotas,
+		logger: logger,
+	}
+}
+
+// GetQuota reports the message allowance left to the calling API key.
+// @Summary Get the quota of the API key
+// @Description Report how many messages the calling key sent through POST /api/messages/send in the current UTC day and month, its quotas and what is left of them. Uncapped periods have a limit of 0 and no remaining count; their usage is still counted.
+// @Tags quota
+// @Produce json
+// @Success 200 {object} model.QuotaStatus
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/quota [get]
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	apiKey := c.GetString(middleware.APIKeyNameContextKey)
+	if apiKey == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Quotas are kept per API key, authentication is disabled"})
+		return
+	}
+	value, _ := c.Get(middleware.APIKeyQuotaContextKey)
+	quota, _ := value.(model.Quota)
+
+	status, err := h.quotas.Status(c.Request.Context(), apiKey, quota)
+	if err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to read the quota of API key %q: %v", apiKey, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to read quota"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
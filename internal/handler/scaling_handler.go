@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type ScalingHandler struct {
+	advisor *service.ScalingAdvisor
+	logger  inslogger.Interface
+}
+
+func NewScalingHandler(advisor *service.ScalingAdvisor, logger inslogger.Interface) *ScalingHandler {
+	return &ScalingHandler{
+		advisor: advisor,
+		logger:  logger,
+	}
+}
+
+// GetScaling returns the signal the worker autoscaler polls.
+// @Summary Get the worker scaling signal
+// @Description Get the number of due pending messages, how long the oldest has waited, and the worker replica count that drains them within SCALING_DRAIN_TARGET. Meant for a KEDA metrics-api or HPA external scaler
+// @Tags internal
+// @Produce json
+// @Success 200 {object} model.ScalingSignal
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/internal/scaling [get]
+func (h *ScalingHandler) GetScaling(c *gin.Context) {
+	signal, err := h.advisor.Signal(c.Request.Context())
+	if err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to compute the scaling signal: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to compute the scaling signal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, signal)
+}
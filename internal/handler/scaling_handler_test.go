@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestGetScaling(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+	var seed []model.Message
+	for i := 1; i <= 25; i++ {
+		seed = append(seed, model.Message{ID: uint(i), CreatedAt: time.Now().Add(-time.Minute)})
+	}
+	seed = append(seed, model.Message{ID: 26, ScheduledAt: &later}, model.Message{ID: 27, Status: model.StatusSent})
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), seed...)
+
+	// Each worker drains 10 messages within the target, 25 due messages need 3.
+	handler := NewScalingHandler(service.NewScalingAdvisor(messages, 2, 5*time.Minute, 1, 10), inslogger.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/internal/scaling", handler.GetScaling)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/internal/scaling", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body model.ScalingSignal
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, int64(25), body.BacklogDepth, "scheduled and sent messages are not backlog")
+	assert.InDelta(t, 60, body.OldestPendingAgeSeconds, 5)
+	assert.Equal(t, 3, body.RecommendedReplicas)
+}
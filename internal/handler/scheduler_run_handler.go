@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+const (
+	defaultRunsLimit = 50
+	maxRunsLimit     = 500
+)
+
+type SchedulerRunHandler struct {
+	runs mpostgres.SchedulerRunService
+	// displayLocation is the zone run timestamps are rendered in, UTC when nil.
+	displayLocation *time.Location
+	logger          inslogger.Interface
+}
+
+func NewSchedulerRunHandler(runs mpostgres.SchedulerRunService, displayLocation *time.Location, logger inslogger.Interface) *SchedulerRunHandler {
+	return &SchedulerRunHandler{
+		runs:            runs,
+		displayLocation: displayLocation,
+		logger:          logger,
+	}
+}
+
+// ListRuns returns the history of scheduler runs.
+// @Summary List scheduler runs
+// @Description Get the latest scheduler runs, newest first, with per-batch counts and an error summary. Timestamps are in DISPLAY_TIMEZONE (default UTC)
+// @Tags scheduler
+// @Produce json
+// @Param limit query int false "Number of runs to return (max 500)" default(50)
+// @Success 200 {array} model.SchedulerRun
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/scheduler/runs [get]
+func (h *SchedulerRunHandler) ListRuns(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	limit := defaultRunsLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxRunsLimit {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "limit must be between 1 and 500"})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.runs.ListRuns(c.Request.Context(), limit)
+	if err != nil {
+		logger.Errorf("Failed to list scheduler runs: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to list scheduler runs"})
+		return
+	}
+
+	if h.displayLocation != nil {
+		for i := range runs {
+			runs[i] = runs[i].In(h.displayLocation)
+		}
+	}
+	c.JSON(http.StatusOK, runs)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestListRuns(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_ = runs.RecordRun(context.Background(), model.SchedulerRun{StartedAt: start.Add(time.Duration(i) * time.Minute), BatchSize: 2, Sent: i})
+	}
+
+	handler := NewSchedulerRunHandler(runs, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/scheduler/runs", handler.ListRuns)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/scheduler/runs?limit=2", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body []model.SchedulerRun
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Len(t, body, 2)
+	assert.Equal(t, 2, body[0].Sent, "newest run first")
+}
+
+func TestListRunsRejectsInvalidLimit(t *testing.T) {
+	handler := NewSchedulerRunHandler(mmemory.NewSchedulerRunService(0), nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/scheduler/runs", handler.ListRuns)
+
+	for _, limit := range []string{"0", "501", "abc"} {
+		req, _ := http.NewRequest(http.MethodGet, "/api/scheduler/runs?limit="+limit, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code, "limit=%s", limit)
+	}
+}
+
+func TestListRunsInDisplayTimezone(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	startedAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	_ = runs.RecordRun(context.Background(), model.SchedulerRun{StartedAt: startedAt, FinishedAt: startedAt.Add(time.Second)})
+
+	istanbul, err := time.LoadLocation("Europe/Istanbul")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewSchedulerRunHandler(runs, istanbul, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/scheduler/runs", handler.ListRuns)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/scheduler/runs", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"started_at":"2025-03-01T12:00:00+03:00"`)
+}
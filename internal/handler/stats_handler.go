@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type StatsHandler struct {
+	stats  *service.StatsService
+	logger inslogger.Interface
+}
+
+func NewStatsHandler(stats *service.StatsService, logger inslogger.Interface) *StatsHandler {
+	return &StatsHandler{
+		stats:  stats,
+		logger: logger,
+	}
+}
+
+// GetStats returns message sending statistics.
+// @Summary Get message sending statistics
+// @Description Count sent, unsent (pending, sending or failed) and failed messages, the messages sent in each of the last 24 hours (UTC, oldest first, backfilled messages excluded), and, over the same hours and every replica, the average provider call latency and the share of message lookups answered from the cache. The averages are omitted while there is nothing to average
+// @Tags messages
+// @Produce json
+// @Success 200 {object} model.MessageStats
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/stats [get]
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	stats, err := h.stats.Stats(c.Request.Context())
+	if err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to compute the message statistics: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to compute the message statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
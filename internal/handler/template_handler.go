@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/msgtemplate"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type TemplateHandler struct {
+	templates service.TemplateService
+	logger    inslogger.Interface
+}
+
+func NewTemplateHandler(templates service.TemplateService, logger inslogger.Interface) *TemplateHandler {
+	return &TemplateHandler{
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+// CreateTemplate stores a new message template.
+// @Summary Create a template
+// @Description Store a message template. Variables are written as {{name}} and listed in the response, the ones only tested by if or passed to default as optional_variables.
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param template body model.TemplateRequest true "Template"
+// @Success 201 {object} model.Template
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/templates [post]
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req model.TemplateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	template, err := h.templates.Create(c.Request.Context(), req)
+	if err != nil {
+		h.writeError(c, err, "Failed to create template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListTemplates returns every template.
+// @Summary List templates
+// @Tags templates
+// @Produce json
+// @Success 200 {array} model.Template
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.templates.List(c.Request.Context())
+	if err != nil {
+		h.writeError(c, err, "Failed to list templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate returns one template.
+// @Summary Get a template
+// @Tags templates
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} model.Template
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/templates/{id} [get]
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templates.Get(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err, "Failed to get template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateTemplate replaces the name and body of a template.
+// @Summary Update a template
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param template body model.TemplateRequest true "Template"
+// @Success 200 {object} model.Template
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/templates/{id} [put]
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	var req model.TemplateRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	template, err := h.templates.Update(c.Request.Context(), id, req)
+	if err != nil {
+		h.writeError(c, err, "Failed to update template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate removes a template.
+// @Summary Delete a template
+// @Tags templates
+// @Param id path int true "Template ID"
+// @Success 204
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/templates/{id} [delete]
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.templates.Delete(c.Request.Context(), id); err != nil {
+		h.writeError(c, err, "Failed to delete template")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func templateID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid template ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TemplateHandler) writeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, mpostgres.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: "Template not found"})
+	case errors.Is(err, mpostgres.ErrTemplateNameTaken):
+		c.JSON(http.StatusConflict, model.ErrorResponse{Error: "Template name already exists"})
+	case errors.Is(err, msgtemplate.ErrInvalidTemplate):
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: []model.FieldError{{Field: "body", Message: err.Error()}},
+		})
+	default:
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: message})
+	}
+}
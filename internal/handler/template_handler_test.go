@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/middleware"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func newTemplateRouter() *gin.Engine {
+	logger := inslogger.NewLogger(inslogger.Debug)
+	handler := NewTemplateHandler(service.NewTemplateService(mmemory.NewTemplateRepository(), logger), logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/templates", handler.CreateTemplate)
+	router.GET("/api/templates", handler.ListTemplates)
+	router.GET("/api/templates/:id", handler.GetTemplate)
+	router.PUT("/api/templates/:id", handler.UpdateTemplate)
+	router.DELETE("/api/templates/:id", handler.DeleteTemplate)
+	return router
+}
+
+func serveTemplate(router *gin.Engine, method, path string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestTemplateCRUD(t *testing.T) {
+	router := newTemplateRouter()
+
+	resp := serveTemplate(router, http.MethodPost, "/api/templates", model.TemplateRequest{Name: "otp", Body: "Your code is {{code}}"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	var created model.Template
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	assert.Equal(t, []string{"code"}, created.Variables)
+
+	resp = serveTemplate(router, http.MethodPut, "/api/templates/1", model.TemplateRequest{Name: "otp", Body: "Hi {{name}}, your code is {{code}}"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"variables":["code","name"]`)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/templates/1", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `Hi {{name}}`)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/templates", nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var listed []model.Template
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+
+	resp = serveTemplate(router, http.MethodDelete, "/api/templates/1", nil)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/templates/1", nil)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestCreateTemplateErrors(t *testing.T) {
+	router := newTemplateRouter()
+	serveTemplate(router, http.MethodPost, "/api/templates", model.TemplateRequest{Name: "otp", Body: "Your code is {{code}}"})
+
+	resp := serveTemplate(router, http.MethodPost, "/api/templates", model.TemplateRequest{Name: "otp", Body: "Other"})
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = serveTemplate(router, http.MethodPost, "/api/templates", model.TemplateRequest{Name: "bad", Body: "{{exec .cmd}}"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"field":"body"`)
+
+	resp = serveTemplate(router, http.MethodPost, "/api/templates", model.TemplateRequest{Name: "empty"})
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	resp = serveTemplate(router, http.MethodGet, "/api/templates/abc", nil)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestTemplatesAreTenantScoped(t *testing.T) {
+	logger := inslogger.NewLogger(inslogger.Debug)
+	keys := service.NewAPIKeyService(mmemory.NewAPIKeyRepository(), logger)
+	handler := NewTemplateHandler(service.NewTemplateService(mmemory.NewTemplateRepository(), logger), logger)
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	api := router.Group("/api", middleware.APIKeyAuth(map[string]string{"ops": "bootstrap"}, keys, logger))
+	api.POST("/templates", handler.CreateTemplate)
+	api.GET("/templates", handler.ListTemplates)
+	api.GET("/templates/:id", handler.GetTemplate)
+	api.PUT("/templates/:id", handler.UpdateTemplate)
+	api.DELETE("/templates/:id", handler.DeleteTemplate)
+
+	issue := func(tenant string) string {
+		secret, err := keys.Create(context.Background(), model.APIKeyRequest{Name: tenant + "-service", Tenant: tenant, Scopes: []string{model.ScopeWrite, model.ScopeRead}})
+		assert.NoError(t, err)
+		return secret.Key
+	}
+	acme, globex := issue("acme"), issue("globex")
+	serve := func(method, path, key string, payload any) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		if payload != nil {
+			_ = json.NewEncoder(&body).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.APIKeyHeader, key)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := serve(http.MethodPost, "/api/templates", acme, model.TemplateRequest{Name: "otp", Body: "Your code is {{code}}"})
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"tenant":"acme"`)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/templates/1", globex, nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/api/templates/1", globex, model.TemplateRequest{Name: "otp", Body: "Hijacked"}).Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/api/templates/1", globex, nil).Code)
+	assert.JSONEq(t, `[]`, serve(http.MethodGet, "/api/templates", globex, nil).Body.String())
+	assert.Equal(t, http.StatusCreated, serve(http.MethodPost, "/api/templates", globex, model.TemplateRequest{Name: "otp", Body: "Code {{code}}"}).Code, "names are unique per tenant")
+
+	resp = serve(http.MethodGet, "/api/templates/1", acme, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Your code is {{code}}")
+
+	var listed []model.Template
+	assert.NoError(t, json.Unmarshal(serve(http.MethodGet, "/api/templates", "bootstrap", nil).Body.Bytes(), &listed))
+	assert.Len(t, listed, 2, "admin keys see every tenant")
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type TuningHandler struct {
+	advisor *service.TuningAdvisor
+	logger  inslogger.Interface
+}
+
+func NewTuningHandler(advisor *service.TuningAdvisor, logger inslogger.Interface) *TuningHandler {
+	return &TuningHandler{
+		advisor: advisor,
+		logger:  logger,
+	}
+}
+
+// GetTuning returns the scheduler tuning suggestions.
+// @Summary Get scheduler tuning suggestions
+// @Description Analyze the latest scheduler runs (TUNING_RUNS) and the backlog, and suggest larger batches, shorter intervals, more send concurrency or more workers so the backlog drains within SCALING_DRAIN_TARGET. Nothing is changed, batch sizes and intervals can be applied with PUT /api/scheduler/channels/{channel}
+// @Tags admin
+// @Produce json
+// @Success 200 {object} model.TuningReport
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/admin/tuning [get]
+func (h *TuningHandler) GetTuning(c *gin.Context) {
+	report, err := h.advisor.Report(c.Request.Context())
+	if err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Errorf("Failed to compute the tuning suggestions: %v", err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to compute the tuning suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestGetTuning(t *testing.T) {
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1})
+	schedules := map[model.Channel]service.ChannelSettings{model.ChannelSMS: {BatchSize: 10, Interval: time.Minute}}
+	scheduler := service.NewSchedulerService(new(MockDispatchService), nil, nil, schedules, service.WarmupSettings{}, inslogger.NewNopLogger())
+	advisor := service.NewTuningAdvisor(mmemory.NewSchedulerRunService(0), messages, scheduler, nil, 50, 5*time.Minute, 4, 0, inslogger.NewNopLogger())
+	handler := NewTuningHandler(advisor, inslogger.NewNopLogger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/api/admin/tuning", handler.GetTuning)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/admin/tuning", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body model.TuningReport
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, int64(1), body.BacklogDepth)
+	if assert.Len(t, body.Channels, 1) {
+		assert.Equal(t, model.ChannelSMS, body.Channels[0].Channel)
+		assert.Equal(t, "1m0s", body.Channels[0].Interval)
+	}
+	assert.Empty(t, body.Suggestions, "channels without runs are not judged")
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report JSON field names instead of Go struct field names in validation errors.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" || name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds the request body into obj. Malformed JSON is answered with 400 and
+// failed validation rules with 422 listing every invalid field. It reports whether
+// binding succeeded.
+func bindJSON(c *gin.Context, obj any) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]model.FieldError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields = append(fields, model.FieldError{
+				Field:   fieldErr.Field(),
+				Message: validationMessage(fieldErr),
+			})
+		}
+		c.JSON(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Error:  "Validation failed",
+			Fields: fields,
+		})
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+	return false
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required", "required_without", "required_if", "required_unless":
+		return "is required"
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fieldErr.Param(), lengthUnit(fieldErr))
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fieldErr.Param(), lengthUnit(fieldErr))
+	case "e164":
+		return "must be an E.164 phone number"
+	case "email":
+		return "must be an email address"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed %s validation", fieldErr.Tag())
+	}
+}
+
+// lengthUnit is the unit of a min/max bound: string bounds are lengths, numeric ones values.
+func lengthUnit(fieldErr validator.FieldError) string {
+	if fieldErr.Kind() == reflect.String {
+		return " characters"
+	}
+	return ""
+}
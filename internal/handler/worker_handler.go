@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+const (
+	defaultClaimBatch = 100
+	maxClaimBatch     = 1000
+)
+
+type WorkerHandler struct {
+	messageService mpostgres.MessageService
+	lease          time.Duration
+	// events receives the outcome of completed messages, nil disables events.
+	events service.EventPublisher
+	logger inslogger.Interface
+}
+
+func NewWorkerHandler(
+	messageService mpostgres.MessageService,
+	lease time.Duration,
+	events service.EventPublisher,
+	logger inslogger.Interface,
+) *WorkerHandler {
+
+	return &WorkerHandler{
+		messageService: messageService,
+		lease:          lease,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// Claim leases a batch of pending messages to an external worker.
+// @Summary Claim pending messages
+// @Description Lease a batch of pending messages to an external delivery worker. Messages not completed before the lease expires become claimable again.
+// @Tags worker
+// @Produce json
+// @Param worker_id query string true "Worker identifier"
+// @Param batch query int false "Number of messages to claim (max 1000)" default(100)
+// @Success 200 {object} model.WorkerClaimResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/worker/claim [post]
+func (h *WorkerHandler) Claim(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	workerID := c.Query("worker_id")
+	if workerID == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "worker_id is required"})
+		return
+	}
+
+	batch := defaultClaimBatch
+	if raw := c.Query("batch"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxClaimBatch {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "batch must be between 1 and 1000"})
+			return
+		}
+		batch = parsed
+	}
+
+	leaseExpiresAt := time.Now().UTC().Add(h.lease)
+	messages, err := h.messageService.ClaimMessages(c.Request.Context(), workerID, batch, h.lease)
+	if err != nil {
+		logger.Errorf("Failed to claim messages for worker %s: %v", workerID, err)
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to claim messages"})
+		return
+	}
+	if messages == nil {
+		messages = []model.Message{}
+	}
+
+	c.JSON(http.StatusOK, model.WorkerClaimResponse{
+		WorkerID:       workerID,
+		LeaseExpiresAt: leaseExpiresAt,
+		Messages:       messages,
+	})
+}
+
+// Complete reports the outcome of claimed messages.
+// @Summary Complete claimed messages
+// @Description Mark claimed messages as sent or release them for another attempt. Results for expired leases are rejected.
+// @Tags worker
+// @Accept json
+// @Produce json
+// @Param results body model.WorkerCompleteRequest true "Delivery results"
+// @Success 200 {object} model.WorkerCompleteResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 422 {object} model.ValidationErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Security ApiKeyAuth
+// @Router /api/worker/complete [post]
+func (h *WorkerHandler) Complete(c *gin.Context) {
+	logger := logctx.Logger(c.Request.Context(), h.logger)
+
+	var req model.WorkerCompleteRequest
+	if !bindJSON(c, &req) {
+		logger.Log("Invalid worker complete request")
+		return
+	}
+
+	resp := model.WorkerCompleteResponse{Completed: []uint{}, Rejected: []uint{}}
+	for _, result := range req.Results {
+		ok, err := h.messageService.CompleteClaimedMessage(c.Request.Context(), req.WorkerID, result)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to complete messages"})
+			return
+		}
+
+		if ok {
+			resp.Completed = append(resp.Completed, result.ID)
+			h.publishResult(c, result)
+		} else {
+			resp.Rejected = append(resp.Rejected, result.ID)
+		}
+	}
+
+	logger.Logf("Worker %s completed %d messages, %d rejected", req.WorkerID, len(resp.Completed), len(resp.Rejected))
+	c.JSON(http.StatusOK, resp)
+}
+
+// publishResult publishes the outcome a worker reported. Workers only report IDs, so the
+// event carries no channel or tenant.
+func (h *WorkerHandler) publishResult(c *gin.Context, result model.WorkerResult) {
+	if h.events == nil {
+		return
+	}
+
+	event := model.MessageEvent{
+		ID:                logctx.NewID(),
+		Type:              model.EventMessageSent,
+		MessageID:         result.ID,
+		ProviderMessageID: result.ProviderMessageID,
+		OccurredAt:        time.Now().UTC(),
+	}
+	if !result.Success {
+		event.Type = model.EventMessageFailed
+	}
+	if err := h.events.Publish(c.Request.Context(), event); err != nil {
+		logctx.Logger(c.Request.Context(), h.logger).Warnf("Failed to publish %s event of message ID %d: %v", event.Type, result.ID, err)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestClaim(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug),
+		model.Message{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"},
+	)
+
+	handler := NewWorkerHandler(messageService, 5*time.Minute, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/worker/claim", handler.Claim)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/worker/claim?worker_id=worker-1&batch=50", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"id":1`)
+	stored, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusSending, stored.Status)
+}
+
+func TestClaimRequiresWorkerID(t *testing.T) {
+	handler := NewWorkerHandler(mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug)), 5*time.Minute, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/worker/claim", handler.Claim)
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/worker/claim", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestComplete(t *testing.T) {
+	messageService := mmemory.NewMessageService(inslogger.NewLogger(inslogger.Debug), model.Message{ID: 1}, model.Message{ID: 2})
+	// Only message 1 is leased to the worker, so the result for message 2 is rejected.
+	_, _ = messageService.ClaimMessages(context.Background(), "worker-1", 1, 5*time.Minute)
+	delivered := model.WorkerResult{ID: 1, Success: true, ProviderMessageID: "provider-1"}
+	expired := model.WorkerResult{ID: 2, Success: true, ProviderMessageID: "provider-2"}
+
+	handler := NewWorkerHandler(messageService, 5*time.Minute, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/worker/complete", handler.Complete)
+
+	body, _ := json.Marshal(model.WorkerCompleteRequest{
+		WorkerID: "worker-1",
+		Results:  []model.WorkerResult{delivered, expired},
+	})
+	req, _ := http.NewRequest(http.MethodPost, "/api/worker/complete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var out model.WorkerCompleteResponse
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	assert.Equal(t, []uint{1}, out.Completed)
+	assert.Equal(t, []uint{2}, out.Rejected)
+}
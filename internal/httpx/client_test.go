@@ -0,0 +1,56 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient(&config.HTTPClientConfig{
+		Timeout:             5 * time.Second,
+		MaxIdleConnsPerHost: 20,
+		TLSMinVersion:       "1.3",
+		ProxyURL:            "http://proxy.internal:3128",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, 20, transport.MaxIdleConnsPerHost)
+
+	proxy, err := transport.Proxy(httptest.NewRequest(http.MethodPost, "https://webhook.site/", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "proxy.internal:3128", proxy.Host)
+}
+
+func TestNewClientRejectsInvalidSettings(t *testing.T) {
+	_, err := NewClient(&config.HTTPClientConfig{TLSMinVersion: "1.0"})
+	assert.Error(t, err)
+
+	_, err = NewClient(&config.HTTPClientConfig{TLSMinVersion: "1.2", ProxyURL: "::not a url"})
+	assert.Error(t, err)
+
+	_, err = NewClient(&config.HTTPClientConfig{TLSMinVersion: "1.2", TLSCAFile: "testdata/missing.pem"})
+	assert.Error(t, err)
+}
+
+func TestNewClientTimesOutHungServer(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(&config.HTTPClientConfig{Timeout: 50 * time.Millisecond, TLSMinVersion: "1.2"})
+	assert.NoError(t, err)
+
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+}
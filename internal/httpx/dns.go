@@ -0,0 +1,121 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"message-service/internal/pkg/metrics"
+)
+
+var dnsLookups = metrics.NewCounterVec(
+	"http_client_dns_lookups",
+	"Outbound DNS lookups by host and result: hit, miss or failure.",
+	"host", "result",
+)
+
+// ErrDNSResolution wraps failures to resolve an outbound host, so DNS flaps can be told
+// apart from connection and HTTP errors. The underlying *net.DNSError stays in the chain.
+// Resolution failures are transport errors and DefaultShouldRetry retries them.
+var ErrDNSResolution = errors.New("dns resolution failed")
+
+// HostResolver is the part of *net.Resolver used by DNSCache.
+type HostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DNSCache keeps resolved addresses for TTL so every new connection does not hit DNS.
+// Go's resolver does not expose record TTLs, so TTL should not exceed the TTL of the
+// provider records. Failures are never cached. A zero TTL disables caching but failures
+// are still wrapped in ErrDNSResolution.
+type DNSCache struct {
+	resolver HostResolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+
+	// Now is used to expire entries. Tests replace it to control time.
+	Now func() time.Time
+}
+
+type dnsEntry struct {
+	addrs     []string
+	expiresAt time.Time
+}
+
+// NewDNSCache resolves through resolver, or net.DefaultResolver when it is nil.
+func NewDNSCache(resolver HostResolver, ttl time.Duration) *DNSCache {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return &DNSCache{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[string]dnsEntry),
+		Now:      time.Now,
+	}
+}
+
+// LookupHost returns the addresses of host, from the cache while they are fresh.
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	now := c.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		dnsLookups.Inc(host, "hit")
+		return entry.addrs, nil
+	}
+
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		dnsLookups.Inc(host, "failure")
+		return nil, fmt.Errorf("%w: %s: %w", ErrDNSResolution, host, err)
+	}
+	dnsLookups.Inc(host, "miss")
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[host] = dnsEntry{addrs: addrs, expiresAt: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+// DialContext wraps dial so host names are resolved through the cache. Addresses are
+// tried in order and the error of the last one is returned when none connects.
+func (c *DNSCache) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
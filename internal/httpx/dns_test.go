@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResolver struct {
+	addrs   []string
+	err     error
+	lookups int
+}
+
+func (r *stubResolver) LookupHost(context.Context, string) ([]string, error) {
+	r.lookups++
+	return r.addrs, r.err
+}
+
+func TestDNSCacheReusesAnswersUntilTTL(t *testing.T) {
+	resolver := &stubResolver{addrs: []string{"10.0.0.1"}}
+	cache := NewDNSCache(resolver, time.Minute)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache.Now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		addrs, err := cache.LookupHost(context.Background(), "webhook.site")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	}
+	assert.Equal(t, 1, resolver.lookups)
+
+	now = now.Add(time.Minute)
+	_, _ = cache.LookupHost(context.Background(), "webhook.site")
+	assert.Equal(t, 2, resolver.lookups, "expired answers are resolved again")
+}
+
+func TestDNSCacheDoesNotCacheFailures(t *testing.T) {
+	resolver := &stubResolver{err: &net.DNSError{Err: "server misbehaving", Name: "webhook.site", IsTemporary: true}}
+	cache := NewDNSCache(resolver, time.Minute)
+
+	_, err := cache.LookupHost(context.Background(), "webhook.site")
+	assert.ErrorIs(t, err, ErrDNSResolution)
+	var dnsErr *net.DNSError
+	assert.True(t, errors.As(err, &dnsErr))
+
+	resolver.err = nil
+	resolver.addrs = []string{"10.0.0.1"}
+	addrs, err := cache.LookupHost(context.Background(), "webhook.site")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.Equal(t, 2, resolver.lookups)
+}
+
+func TestDNSCacheDialContext(t *testing.T) {
+	resolver := &stubResolver{addrs: []string{"10.0.0.1", "10.0.0.2"}}
+	cache := NewDNSCache(resolver, time.Minute)
+
+	var dialed []string
+	dial := cache.DialContext(func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr == "10.0.0.2:443" {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	conn, err := dial(context.Background(), "tcp", "webhook.site:443")
+	assert.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, []string{"10.0.0.1:443", "10.0.0.2:443"}, dialed)
+
+	dialed = nil
+	_, _ = dial(context.Background(), "tcp", "127.0.0.1:80")
+	assert.Equal(t, []string{"127.0.0.1:80"}, dialed, "IP addresses are dialed without a lookup")
+	assert.Equal(t, 1, resolver.lookups)
+
+	resolver.err = &net.DNSError{Err: "no such host", Name: "down.example", IsNotFound: true}
+	_, err = dial(context.Background(), "tcp", "down.example:443")
+	assert.ErrorIs(t, err, ErrDNSResolution)
+	assert.True(t, DefaultShouldRetry(nil, err), "resolution failures are retryable")
+}
+
+func TestNewClientClassifiesResolutionFailures(t *testing.T) {
+	client, err := NewClient(&config.HTTPClientConfig{TLSMinVersion: "1.2", DNSCacheTTL: time.Minute})
+	assert.NoError(t, err)
+
+	// The .invalid TLD never resolves (RFC 6761).
+	_, err = client.Get("http://does-not-exist.invalid/")
+	assert.ErrorIs(t, err, ErrDNSResolution)
+}
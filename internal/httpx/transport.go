@@ -0,0 +1,167 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// Policy controls how many times a request is attempted and how long to wait in between.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// ShouldRetry decides whether an attempt is retried. Nil uses DefaultShouldRetry.
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+// DefaultShouldRetry retries transport errors, 429 and 5xx responses. Cancelled or
+// expired contexts are never retried.
+func DefaultShouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+type attemptKey struct{}
+
+// Attempt returns the 1-based attempt number carried by a request context made by
+// Transport, or 0 outside of it.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// Attempts reports how many attempts produced resp, or 0 when it did not come from Transport.
+func Attempts(resp *http.Response) int {
+	if resp == nil || resp.Request == nil {
+		return 0
+	}
+	return Attempt(resp.Request.Context())
+}
+
+// Transport retries requests through next according to its Policy and logs every attempt.
+// Requests whose body cannot be replayed (no GetBody) are attempted once.
+type Transport struct {
+	next   http.RoundTripper
+	policy Policy
+	logger inslogger.Interface
+
+	// Sleep waits between attempts. Tests replace it to run without real delays.
+	Sleep func(ctx context.Context, d time.Duration) error
+	// Now is used to time attempts.
+	Now func() time.Time
+}
+
+func NewTransport(next http.RoundTripper, policy Policy, logger inslogger.Interface) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.ShouldRetry == nil {
+		policy.ShouldRetry = DefaultShouldRetry
+	}
+
+	return &Transport{
+		next:   next,
+		policy: policy,
+		logger: logger,
+		Sleep:  sleep,
+		Now:    time.Now,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	logger := logctx.Logger(req.Context(), t.logger)
+
+	maxAttempts := t.policy.MaxAttempts
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq, err := t.attemptRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		start := t.Now()
+		resp, err := t.next.RoundTrip(attemptReq)
+		duration := t.Now().Sub(start)
+
+		retry := attempt < maxAttempts && t.policy.ShouldRetry(resp, err)
+		if err != nil {
+			logger.Warnf("http attempt=%d/%d method=%s host=%s duration=%s retry=%t error=%v",
+				attempt, maxAttempts, req.Method, req.URL.Host, duration, retry, err)
+		} else {
+			logger.Logf("http attempt=%d/%d method=%s host=%s status=%d duration=%s retry=%t",
+				attempt, maxAttempts, req.Method, req.URL.Host, resp.StatusCode, duration, retry)
+		}
+		if !retry {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, resp)
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.Sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	attemptReq := req.Clone(context.WithValue(req.Context(), attemptKey{}, attempt))
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+	return attemptReq, nil
+}
+
+// backoff doubles BaseDelay per attempt up to MaxDelay. A Retry-After header in seconds
+// takes precedence but is still capped by MaxDelay.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	delay := t.policy.BaseDelay << (attempt - 1)
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	if t.policy.MaxDelay > 0 && (delay > t.policy.MaxDelay || delay < 0) {
+		delay = t.policy.MaxDelay
+	}
+	return delay
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// APIKeyHeader carries the client API key.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyNameContextKey is the gin context key holding the name of the authenticated key.
+const APIKeyNameContextKey = "api_key_name"
+
+// APIKeyScopesContextKey is the gin context key holding the scopes of the authenticated key.
+const APIKeyScopesContextKey = "api_key_scopes"
+
+// APIKeyQuotaContextKey is the gin context key holding the model.Quota of the authenticated key.
+const APIKeyQuotaContextKey = "api_key_quota"
+
+// APIKeyStore looks up keys issued through the admin API.
+type APIKeyStore interface {
+	Authenticate(ctx context.Context, presented string) (model.APIKey, error)
+}
+
+// APIKeyAuth rejects requests that do not present one of the configured keys or an active
+// key of store. keys maps a human readable key name to the secret, the name is what ends
+// up in logs. Configured keys are bootstrap keys: they carry every scope and are their own
+// tenant. Keys without the admin scope only see the messages of their tenant, see
+// logctx.WithTenantScope. store may be nil when keys are only configured.
+func APIKeyAuth(keys map[string]string, store APIKeyStore, logger inslogger.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := c.GetHeader(APIKeyHeader)
+		if presented == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Missing API key"})
+			return
+		}
+
+		key, ok := lookupAPIKey(keys, presented)
+		if !ok && store != nil {
+			var err error
+			key, err = store.Authenticate(c.Request.Context(), presented)
+			switch {
+			case err == nil:
+				ok = true
+			case !errors.Is(err, mpostgres.ErrAPIKeyNotFound):
+				logctx.Logger(c.Request.Context(), logger).Errorf("Failed to look up API key: %v", err)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, model.ErrorResponse{Error: "Failed to verify API key"})
+				return
+			}
+		}
+		if !ok {
+			logctx.Logger(c.Request.Context(), logger).Warnf("Rejected request with unknown API key to %s", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{Error: "Invalid API key"})
+			return
+		}
+
+		c.Set(APIKeyNameContextKey, key.Name)
+		c.Set(APIKeyScopesContextKey, key.Scopes)
+		c.Set(APIKeyQuotaContextKey, key.Quota)
+		ctx := logctx.WithTenant(logctx.WithAPIKeyName(c.Request.Context(), key.Name), key.Tenant)
+		// Only admin keys see the messages of every tenant.
+		if !slices.Contains(key.Scopes, model.ScopeAdmin) {
+			ctx = logctx.WithTenantScope(ctx, key.Tenant)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// DisabledAuth lets every request through with every scope, for deployments that set
+// AUTH_DISABLED. Requests are not tenant scoped.
+func DisabledAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIKeyScopesContextKey, []string{model.ScopeAdmin})
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose key lacks scope. The admin scope grants every other
+// one.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{Error: "API key lacks the " + scope + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasScope reports whether the key of the request grants scope, as RequireScope checks it.
+// It is false for requests that passed neither APIKeyAuth nor DisabledAuth.
+func HasScope(c *gin.Context, scope string) bool {
+	scopes := c.GetStringSlice(APIKeyScopesContextKey)
+	return slices.Contains(scopes, scope) || slices.Contains(scopes, model.ScopeAdmin)
+}
+
+func lookupAPIKey(keys map[string]string, presented string) (model.APIKey, bool) {
+	for name, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+			return model.APIKey{Name: name, Tenant: name, Scopes: []string{model.ScopeAdmin}}, true
+		}
+	}
+	return model.APIKey{}, false
+}
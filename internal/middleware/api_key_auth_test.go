@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type stubAPIKeyStore map[string]model.APIKey
+
+func (s stubAPIKeyStore) Authenticate(ctx context.Context, presented string) (model.APIKey, error) {
+	key, ok := s[presented]
+	if !ok {
+		return model.APIKey{}, mpostgres.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"ops": "secret"}, nil, inslogger.NewLogger(inslogger.Debug)))
+	router.GET("/api/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(APIKeyNameContextKey))
+	})
+
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{name: "missing key", key: "", status: http.StatusUnauthorized},
+		{name: "unknown key", key: "wrong", status: http.StatusForbidden},
+		{name: "valid key", key: "secret", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/api/ping", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, "ops", resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := stubAPIKeyStore{
+		"msk_reader": {Name: "dashboard", Tenant: "acme", Scopes: []string{model.ScopeRead}},
+	}
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"ops": "secret"}, store, inslogger.NewLogger(inslogger.Debug)))
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, logctx.Tenant(c.Request.Context()))
+	}
+	router.GET("/api/messages", RequireScope(model.ScopeRead), handler)
+	router.POST("/api/messages/send", RequireScope(model.ScopeWrite), handler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+		tenant string
+	}{
+		{name: "managed key in scope", method: http.MethodGet, path: "/api/messages", key: "msk_reader", status: http.StatusOK, tenant: "acme"},
+		{name: "managed key out of scope", method: http.MethodPost, path: "/api/messages/send", key: "msk_reader", status: http.StatusForbidden},
+		{name: "bootstrap key has every scope", method: http.MethodPost, path: "/api/messages/send", key: "secret", status: http.StatusOK, tenant: "ops"},
+		{name: "revoked or unknown key", method: http.MethodGet, path: "/api/messages", key: "msk_revoked", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(APIKeyHeader, tt.key)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.tenant, resp.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthTenantScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := stubAPIKeyStore{
+		"msk_reader": {Name: "dashboard", Tenant: "acme", Scopes: []string{model.ScopeRead}},
+		"msk_admin":  {Name: "operator", Tenant: "acme", Scopes: []string{model.ScopeAdmin}},
+	}
+	router := gin.New()
+	router.Use(APIKeyAuth(map[string]string{"ops": "secret"}, store, inslogger.NewLogger(inslogger.Debug)))
+	router.GET("/api/messages", func(c *gin.Context) {
+		c.String(http.StatusOK, logctx.TenantScope(c.Request.Context()))
+	})
+
+	for key, scope := range map[string]string{"msk_reader": "acme", "msk_admin": "", "secret": ""} {
+		req, _ := http.NewRequest(http.MethodGet, "/api/messages", nil)
+		req.Header.Set(APIKeyHeader, key)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, scope, resp.Body.String(), key)
+	}
+}
+
+func TestAPIKeyAuthWithoutBootstrapKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := stubAPIKeyStore{
+		"msk_admin": {Name: "operator", Tenant: "acme", Scopes: []string{model.ScopeAdmin}},
+	}
+	router := gin.New()
+	router.Use(APIKeyAuth(nil, store, inslogger.NewLogger(inslogger.Debug)))
+	router.GET("/api/admin/apikeys", RequireScope(model.ScopeAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for key, status := range map[string]int{"": http.StatusUnauthorized, "secret": http.StatusForbidden, "msk_admin": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, "/api/admin/apikeys", nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, status, resp.Code, "key %q", key)
+	}
+}
+
+func TestRequireScopeWithoutAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := gin.New()
+	router.GET("/unauthenticated", RequireScope(model.ScopeRead), handler)
+	router.GET("/disabled", DisabledAuth(), RequireScope(model.ScopeAdmin), handler)
+
+	for path, status := range map[string]int{"/unauthenticated": http.StatusForbidden, "/disabled": http.StatusOK} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, status, resp.Code, path)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StaticCacheHeaders sets Cache-Control on generated docs and static assets. Assets that
+// only change with a release (scripts, styles, images) are cached for maxAge; the HTML
+// entry point and the API spec are revalidated on every load so a deploy shows up at once.
+// A maxAge of zero makes every response revalidate.
+func StaticCacheHeaders(maxAge time.Duration) gin.HandlerFunc {
+	cached := fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		switch path.Ext(c.Request.URL.Path) {
+		case ".js", ".css", ".png", ".svg", ".ico", ".map":
+			if maxAge > 0 {
+				c.Header("Cache-Control", cached)
+				break
+			}
+			fallthrough
+		default:
+			c.Header("Cache-Control", "no-cache")
+		}
+		c.Next()
+	}
+}
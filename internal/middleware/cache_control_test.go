@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticCacheHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/swagger/*any", StaticCacheHeaders(time.Hour), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/swagger/swagger-ui-bundle.js", "public, max-age=3600"},
+		{"/swagger/swagger-ui.css", "public, max-age=3600"},
+		{"/swagger/index.html", "no-cache"},
+		{"/swagger/doc.json", "no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.cacheControl, resp.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func sign(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyCallbackSignatureHMAC(t *testing.T) {
+	verifier, err := NewSignatureVerifier("hmac", []string{"new-secret", "old-secret"})
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/callbacks", VerifyCallbackSignature("webhook", verifier, inslogger.NewLogger(inslogger.Debug)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	body := `{"messageId":"abc","status":"delivered"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		status    int
+	}{
+		{name: "current secret", timestamp: now, signature: sign("new-secret", now, body), status: http.StatusNoContent},
+		{name: "rotated secret", timestamp: now, signature: sign("old-secret", now, body), status: http.StatusNoContent},
+		{name: "unknown secret", timestamp: now, signature: sign("other", now, body), status: http.StatusUnauthorized},
+		{name: "stale timestamp", timestamp: stale, signature: sign("new-secret", stale, body), status: http.StatusUnauthorized},
+		{name: "missing signature", timestamp: now, signature: "", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/callbacks", bytes.NewBufferString(body))
+			req.Header.Set(SignatureTimestampHeader, tt.timestamp)
+			if tt.signature != "" {
+				req.Header.Set(SignatureHeader, "sha256="+tt.signature)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
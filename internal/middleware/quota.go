@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// QuotaCounter counts the messages of API keys, see service.QuotaCounter.
+type QuotaCounter interface {
+	// Reserve counts one message of apiKey and returns the usage including it. release
+	// takes the message back.
+	Reserve(ctx context.Context, apiKey string, quota model.Quota) (status model.QuotaStatus, release func(), err error)
+}
+
+// WithQuota counts every message next accepts against the quota of the API key and rejects
+// it with 429 once the key went over its daily or monthly quota. Answers outside 2xx are
+// not counted. Responses of capped periods carry X-Quota-Daily-Limit and
+// X-Quota-Daily-Remaining, or their Monthly twins. Unauthenticated requests are not counted.
+func WithQuota(counter QuotaCounter, next gin.HandlerFunc, logger inslogger.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetString(APIKeyNameContextKey)
+		if apiKey == "" {
+			next(c)
+			return
+		}
+		value, _ := c.Get(APIKeyQuotaContextKey)
+		quota, _ := value.(model.Quota)
+
+		status, release, err := counter.Reserve(c.Request.Context(), apiKey, quota)
+		if err != nil {
+			// Fail open like the rate limits, an unavailable Redis should not stop sends.
+			logctx.Logger(c.Request.Context(), logger).Warnf("Quota check failed for API key %q: %v", apiKey, err)
+			next(c)
+			return
+		}
+
+		setQuotaHeaders(c, "Daily", status.Daily)
+		setQuotaHeaders(c, "Monthly", status.Monthly)
+		if status.Exceeded() {
+			release()
+			period, message := status.Daily, "Daily quota exceeded"
+			if status.Monthly.Exceeded() {
+				period, message = status.Monthly, "Monthly quota exceeded"
+			}
+			c.Header("X-Quota-Reset", strconv.FormatInt(period.ResetsAt.Unix(), 10))
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(period.ResetsAt).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, model.ErrorResponse{Error: message})
+			return
+		}
+
+		next(c)
+		if status := c.Writer.Status(); status < 200 || status > 299 {
+			release()
+		}
+	}
+}
+
+func setQuotaHeaders(c *gin.Context, name string, period model.QuotaPeriod) {
+	if period.Remaining == nil {
+		return
+	}
+	c.Header("X-Quota-"+name+"-Limit", strconv.Itoa(period.Limit))
+	c.Header("X-Quota-"+name+"-Remaining", strconv.Itoa(*period.Remaining))
+}
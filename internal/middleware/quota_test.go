@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// countingQuota counts messages per key in memory, for a single period.
+type countingQuota struct {
+	used map[string]int
+}
+
+func (q *countingQuota) Reserve(ctx context.Context, apiKey string, quota model.Quota) (model.QuotaStatus, func(), error) {
+	q.used[apiKey]++
+	resetsAt := time.Now().Add(time.Hour)
+	return model.QuotaStatus{
+		Daily:   model.NewQuotaPeriod(quota.Daily, q.used[apiKey], resetsAt),
+		Monthly: model.NewQuotaPeriod(quota.Monthly, q.used[apiKey], resetsAt),
+	}, func() { q.used[apiKey]-- }, nil
+}
+
+func TestWithQuota(t *testing.T) {
+	counter := &countingQuota{used: make(map[string]int)}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/send", func(c *gin.Context) {
+		c.Set(APIKeyNameContextKey, "billing")
+		c.Set(APIKeyQuotaContextKey, model.Quota{Daily: 2})
+	}, WithQuota(counter, func(c *gin.Context) {
+		if c.Query("invalid") != "" {
+			c.Status(http.StatusUnprocessableEntity)
+			return
+		}
+		c.Status(http.StatusAccepted)
+	}, inslogger.NewNopLogger()))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, path, nil))
+		return resp
+	}
+
+	resp := send("/send")
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("X-Quota-Daily-Limit"))
+	assert.Equal(t, "1", resp.Header().Get("X-Quota-Daily-Remaining"))
+	assert.Empty(t, resp.Header().Get("X-Quota-Monthly-Limit"), "uncapped periods have no headers")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, send("/send?invalid=1").Code)
+	assert.Equal(t, http.StatusAccepted, send("/send").Code, "rejected sends are not counted")
+
+	resp = send("/send")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Daily quota exceeded")
+	assert.NotEmpty(t, resp.Header().Get("X-Quota-Reset"))
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+	assert.Equal(t, 2, counter.used["billing"], "sends over quota are not counted")
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// ErrUnknownRateLimitClass is returned when a route references a class missing from config.
+var ErrUnknownRateLimitClass = errors.New("unknown rate limit class")
+
+// RateLimiter counts requests per client in a sliding window stored in Redis,
+// so the limit holds across every replica.
+type RateLimiter struct {
+	store   windowStore
+	window  time.Duration
+	classes map[string]int
+	logger  inslogger.Interface
+}
+
+// windowStore records a request at now and returns how many requests for key fall inside window.
+type windowStore interface {
+	hit(key string, now time.Time, window time.Duration) (int64, error)
+}
+
+// NewRateLimiter builds a limiter whose classes map a class name (e.g. write, read, admin)
+// to the number of requests allowed per window.
+func NewRateLimiter(redisClient insredis.RedisInterface, window time.Duration, classes map[string]int, logger inslogger.Interface) *RateLimiter {
+	return &RateLimiter{
+		store:   &redisWindow{redisClient: redisClient},
+		window:  window,
+		classes: classes,
+		logger:  logger,
+	}
+}
+
+// NewLocalRateLimiter keeps the sliding windows in process memory. Limits only hold for
+// this process, which is enough for local mode.
+func NewLocalRateLimiter(window time.Duration, classes map[string]int, logger inslogger.Interface) *RateLimiter {
+	return &RateLimiter{
+		store:   &memoryWindow{hits: make(map[string][]time.Time)},
+		window:  window,
+		classes: classes,
+		logger:  logger,
+	}
+}
+
+// Class returns middleware enforcing the named class. Every route attached to the same
+// class shares one budget per client.
+func (l *RateLimiter) Class(name string) (gin.HandlerFunc, error) {
+	limit, ok := l.classes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRateLimitClass, name)
+	}
+	return l.Limit(name, limit), nil
+}
+
+// Limit returns middleware allowing limit requests per window for each client of group.
+// Clients are identified by API key name when authenticated and by IP otherwise.
+// A non-positive limit disables the check.
+func (l *RateLimiter) Limit(group string, limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.enforce(c, fmt.Sprintf("ratelimit:%s:%s", group, rateLimitClient(c)), limit)
+	}
+}
+
+// Tenants returns middleware allowing limits[tenant] requests per window for all keys of a
+// tenant together. Requests of tenants without a limit, and unauthenticated ones, pass.
+func (l *RateLimiter) Tenants(limits map[string]int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := logctx.Tenant(c.Request.Context())
+		if tenant == "" {
+			c.Next()
+			return
+		}
+		l.enforce(c, "ratelimit:tenant:"+tenant, limits[tenant])
+	}
+}
+
+// enforce counts the request against the window of key and aborts it once more than limit
+// requests fall into the window.
+func (l *RateLimiter) enforce(c *gin.Context, key string, limit int) {
+	if limit <= 0 {
+		c.Next()
+		return
+	}
+
+	now := time.Now()
+	count, err := l.store.hit(key, now, l.window)
+	if err != nil {
+		// Fail open, an unavailable Redis should not take the API down with it.
+		logctx.Logger(c.Request.Context(), l.logger).Warnf("Rate limit check failed for %s: %v", key, err)
+		c.Next()
+		return
+	}
+
+	remaining := limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := now.Add(l.window)
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if int(count) > limit {
+		c.Header("Retry-After", strconv.Itoa(int(l.window.Seconds())))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, model.ErrorResponse{Error: "Rate limit exceeded"})
+		return
+	}
+
+	c.Next()
+}
+
+// redisWindow stores each window as a sorted set of request timestamps.
+type redisWindow struct {
+	redisClient insredis.RedisInterface
+}
+
+func (w *redisWindow) hit(key string, now time.Time, window time.Duration) (int64, error) {
+	windowStart := now.Add(-window).UnixNano()
+
+	var count *redis.IntCmd
+	_, err := w.redisClient.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(key, "-inf", strconv.FormatInt(windowStart, 10))
+		pipe.ZAdd(key, redis.Z{Score: float64(now.UnixNano()), Member: fmt.Sprintf("%d-%s", now.UnixNano(), logctx.NewID()[:8])})
+		count = pipe.ZCard(key)
+		pipe.PExpire(key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+type memoryWindow struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func (w *memoryWindow) hit(key string, now time.Time, window time.Duration) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	windowStart := now.Add(-window)
+	hits := w.hits[key]
+	kept := hits[:0]
+	for _, ts := range hits {
+		if ts.After(windowStart) {
+			kept = append(kept, ts)
+		}
+	}
+	w.hits[key] = append(kept, now)
+
+	return int64(len(w.hits[key])), nil
+}
+
+func rateLimitClient(c *gin.Context) string {
+	if name := c.GetString(APIKeyNameContextKey); name != "" {
+		return "key:" + name
+	}
+	return "ip:" + c.ClientIP()
+}
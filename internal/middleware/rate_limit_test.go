@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestRateLimiterClass(t *testing.T) {
+	limiter := NewRateLimiter(nil, time.Minute, map[string]int{"read": 0}, inslogger.NewLogger(inslogger.Debug))
+
+	_, err := limiter.Class("write")
+	assert.True(t, errors.Is(err, ErrUnknownRateLimitClass))
+
+	// A zero limit disables the class without touching Redis.
+	limit, err := limiter.Class("read")
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestLocalRateLimiter(t *testing.T) {
+	limiter := NewLocalRateLimiter(time.Minute, map[string]int{"write": 2}, inslogger.NewLogger(inslogger.Debug))
+	limit, err := limiter.Class("write")
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/", nil))
+		codes = append(codes, resp.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestTenantRateLimit(t *testing.T) {
+	limiter := NewLocalRateLimiter(time.Minute, nil, inslogger.NewLogger(inslogger.Debug))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logctx.WithTenant(c.Request.Context(), c.GetHeader("Tenant")))
+	}, limiter.Tenants(map[string]int{"acme": 1}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var codes []int
+	for _, tenant := range []string{"acme", "globex", "acme", "globex"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Tenant", tenant)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		codes = append(codes, resp.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusOK}, codes)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"time"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// RequestLogger propagates or generates an X-Request-ID, stores it on the request
+// context for downstream loggers and writes one access log entry per request.
+func RequestLogger(logger inslogger.Interface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(logctx.RequestIDHeader)
+		if requestID == "" {
+			requestID = logctx.NewID()
+		}
+
+		// Requests without an API key act as "api", see logctx.Actor.
+		ctx := logctx.WithActor(logctx.WithRequestID(c.Request.Context(), requestID), "api")
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(logctx.RequestIDHeader, requestID)
+
+		c.Next()
+
+		logctx.Logger(c.Request.Context(), logger).Logf(
+			"method=%s path=%s status=%d latency=%s client_ip=%s",
+			c.Request.Method,
+			c.Request.URL.Path,
+			c.Writer.Status(),
+			time.Since(start),
+			c.ClientIP(),
+		)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestRequestLoggerPropagatesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger(inslogger.NewLogger(inslogger.Debug)))
+
+	var seen string
+	router.GET("/ping", func(c *gin.Context) {
+		seen = logctx.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(logctx.RequestIDHeader, "abc-123")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", resp.Header().Get(logctx.RequestIDHeader))
+}
+
+func TestRequestLoggerGeneratesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger(inslogger.NewLogger(inslogger.Debug)))
+	router.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.NotEmpty(t, resp.Header().Get(logctx.RequestIDHeader))
+}
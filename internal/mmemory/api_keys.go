@@ -0,0 +1,121 @@
+package mmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+)
+
+// APIKeyRepository keeps API keys in process memory.
+type APIKeyRepository struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	nextID uint
+	keys   map[uint]model.APIKey
+	hashes map[uint]string
+}
+
+var _ mpostgres.APIKeyRepository = (*APIKeyRepository)(nil)
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{
+		now:    utcNow,
+		keys:   make(map[uint]model.APIKey),
+		hashes: make(map[uint]string),
+	}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key model.APIKey, hash string) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.Name == key.Name {
+			return model.APIKey{}, mpostgres.ErrAPIKeyNameTaken
+		}
+	}
+
+	r.nextID++
+	key.ID = r.nextID
+	key.CreatedAt = r.now()
+	key.RotatedAt = nil
+	key.RevokedAt = nil
+	r.keys[key.ID] = key
+	r.hashes[key.ID] = hash
+	return key, nil
+}
+
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]model.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, stored := range r.hashes {
+		if stored == hash && r.keys[id].RevokedAt == nil {
+			return r.keys[id], nil
+		}
+	}
+	return model.APIKey{}, mpostgres.ErrAPIKeyNotFound
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return mpostgres.ErrAPIKeyNotFound
+	}
+
+	now := r.now()
+	key.RevokedAt = &now
+	r.keys[id] = key
+	return nil
+}
+
+func (r *APIKeyRepository) RotateAPIKey(ctx context.Context, id uint, hash, prefix string) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return model.APIKey{}, mpostgres.ErrAPIKeyNotFound
+	}
+
+	now := r.now()
+	key.Prefix = prefix
+	key.RotatedAt = &now
+	r.keys[id] = key
+	r.hashes[id] = hash
+	return key, nil
+}
+
+func (r *APIKeyRepository) SetAPIKeyQuota(ctx context.Context, id uint, quota model.Quota) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return model.APIKey{}, mpostgres.ErrAPIKeyNotFound
+	}
+
+	key.Quota = quota
+	r.keys[id] = key
+	return key, nil
+}
@@ -0,0 +1,46 @@
+package mmemory
+
+import (
+	"context"
+	"sync"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+)
+
+// MessageAuditService keeps the audit trail of messages in process memory.
+type MessageAuditService struct {
+	mu      sync.Mutex
+	nextID  uint
+	entries []model.MessageAuditEntry
+}
+
+var _ mpostgres.MessageAuditService = (*MessageAuditService)(nil)
+
+func NewMessageAuditService() *MessageAuditService {
+	return &MessageAuditService{}
+}
+
+func (r *MessageAuditService) RecordMessageEvent(ctx context.Context, entry model.MessageAuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	entry.ID = r.nextID
+	entry.OccurredAt = entry.OccurredAt.UTC()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *MessageAuditService) ListMessageEvents(ctx context.Context, id uint) ([]model.MessageAuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []model.MessageAuditEntry{}
+	for _, entry := range r.entries {
+		if entry.MessageID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
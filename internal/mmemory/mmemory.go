@@ -0,0 +1,881 @@
+package mmemory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/instance"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// defaultClaimLease matches the lease mpostgres uses for GetUnsentMessages.
+const defaultClaimLease = 5 * time.Minute
+
+// utcNow is the default clock of the in-memory repositories. PostgreSQL returns every
+// timestamp in UTC, so these do too.
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// record is a stored message plus the lease and deletion columns that are not part of
+// model.Message.
+type record struct {
+	message        model.Message
+	claimedBy      string
+	leaseExpiresAt time.Time
+	// deleted is set once the message is anonymized, it is then hidden from reads and claims.
+	deleted bool
+}
+
+// MessageService keeps messages in process memory. It is safe for concurrent use and
+// follows the same status transitions, leases and ordering as the PostgreSQL repository,
+// so local mode and tests exercise the real semantics instead of scripted mocks.
+type MessageService struct {
+	logger     inslogger.Interface
+	instanceID string
+	claimLease time.Duration
+	now        func() time.Time
+	// priorityAging is how long a pending message waits to gain one priority level.
+	priorityAging time.Duration
+	// clockSkew is how early a scheduled message becomes claimable.
+	clockSkew time.Duration
+	// tenantBatchLimits caps the messages of a tenant one claim picks.
+	tenantBatchLimits map[string]int
+
+	mu      sync.Mutex
+	records map[uint]*record
+}
+
+// NewMessageService returns an in-memory MessageService holding seed. Seed messages
+// without a status start as pending, without a channel as SMS, and their timestamps are
+// converted to UTC.
+func NewMessageService(logger inslogger.Interface, seed ...model.Message) *MessageService {
+	r := &MessageService{
+		logger:     logger,
+		instanceID: instance.ID(),
+		claimLease: defaultClaimLease,
+		now:        utcNow,
+		records:    make(map[uint]*record, len(seed)),
+	}
+
+	now := r.now()
+	for _, msg := range seed {
+		msg = msg.In(time.UTC)
+		if msg.Status == "" {
+			msg.Status = model.StatusPending
+		}
+		if msg.Channel == "" {
+			msg.Channel = model.ChannelSMS
+		}
+		if msg.CreatedAt.IsZero() {
+			msg.CreatedAt = now
+		}
+		if msg.UpdatedAt.IsZero() {
+			msg.UpdatedAt = msg.CreatedAt
+		}
+		r.records[msg.ID] = &record{message: msg}
+	}
+
+	return r
+}
+
+var _ mpostgres.MessageService = (*MessageService)(nil)
+
+// WithTx runs fn on r itself. Changes are visible to other callers before fn returns, but
+// when fn fails every message is restored to its state before fn, like a rolled back
+// transaction.
+func (r *MessageService) WithTx(ctx context.Context, fn func(tx mpostgres.MessageService) error) error {
+	r.mu.Lock()
+	snapshot := make(map[uint]*record, len(r.records))
+	for id, rec := range r.records {
+		copied := *rec
+		snapshot[id] = &copied
+	}
+	r.mu.Unlock()
+
+	err := fn(r)
+	if err != nil {
+		r.mu.Lock()
+		r.records = snapshot
+		r.mu.Unlock()
+	}
+	return err
+}
+
+// Message returns a copy of the stored message, or ErrMessageNotFound.
+func (r *MessageService) Message(id uint) (model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.records[id]
+	if !ok {
+		return model.Message{}, mpostgres.ErrMessageNotFound
+	}
+	return messagesOf([]*record{rec})[0], nil
+}
+
+// SetPriorityAging makes claims raise the priority of waiting messages by one level per
+// interval, like the PostgreSQL repository. Zero, the default, disables aging.
+func (r *MessageService) SetPriorityAging(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.priorityAging = interval
+}
+
+// SetClockSkew makes scheduled messages claimable up to skew before their scheduled_at,
+// like the PostgreSQL repository. Zero, the default, claims them exactly on time.
+func (r *MessageService) SetClockSkew(skew time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clockSkew = skew
+}
+
+// SetTenantBatchLimits makes a claim pick at most limits[tenant] messages of a tenant, like
+// the PostgreSQL repository. Tenants without a limit, all of them by default, are not capped.
+func (r *MessageService) SetTenantBatchLimits(limits map[string]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenantBatchLimits = limits
+}
+
+func (r *MessageService) GetUnsentMessages(ctx context.Context, channel model.Channel, limit int) ([]model.Message, error) {
+	return r.claim(ctx, r.instanceID, channel, limit, r.claimLease)
+}
+
+func (r *MessageService) ClaimMessagesByID(ctx context.Context, ids []uint) ([]model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	now := r.now()
+	claimable := r.filter(ctx, func(rec *record) bool {
+		return wanted[rec.message.ID] && !rec.deleted && rec.message.Status == model.StatusPending && !rec.message.Held &&
+			(rec.message.ScheduledAt == nil || !rec.message.ScheduledAt.After(now.Add(r.clockSkew)))
+	})
+	for _, rec := range claimable {
+		rec.message.Status = model.StatusSending
+		rec.message.UpdatedAt = now
+		rec.claimedBy = r.instanceID
+		rec.leaseExpiresAt = now.Add(r.claimLease)
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Claimed %d of %d queued messages", len(claimable), len(ids))
+	return messagesOf(claimable), nil
+}
+
+func (r *MessageService) UpdateMessageSent(ctx context.Context, id uint) error {
+	return r.UpdateMessageSentWithProviderID(ctx, id, "")
+}
+
+func (r *MessageService) UpdateMessageSentWithProviderID(ctx context.Context, id uint, providerMessageID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.message.Status != model.StatusSending {
+		return fmt.Errorf("%w: message %d is not %s", mpostgres.ErrInvalidStatusTransition, id, model.StatusSending)
+	}
+
+	now := r.now()
+	rec.message.Status = model.StatusSent
+	rec.message.SentAt = now
+	rec.message.UpdatedAt = now
+	rec.message.ProviderMessageID = providerMessageID
+	rec.message.DryRun = model.IsDryRunProviderID(providerMessageID)
+	rec.release()
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d updated successfully", id)
+	return nil
+}
+
+func (r *MessageService) MarkMessageSending(ctx context.Context, id uint) error {
+	if err := r.transition(ctx, id, model.StatusPending, model.StatusSending); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.records[id]
+	rec.claimedBy = r.instanceID
+	rec.leaseExpiresAt = rec.message.UpdatedAt.Add(r.claimLease)
+	return nil
+}
+
+func (r *MessageService) ReapExpiredClaims(ctx context.Context) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	expired := r.filter(ctx, func(rec *record) bool {
+		if rec.deleted || rec.message.Status != model.StatusSending {
+			return false
+		}
+		if rec.leaseExpiresAt.IsZero() {
+			return rec.message.UpdatedAt.Before(now.Add(-r.claimLease))
+		}
+		return rec.leaseExpiresAt.Before(now)
+	})
+
+	ids := make([]uint, 0, len(expired))
+	for _, rec := range expired {
+		rec.message.Status = model.StatusPending
+		rec.message.UpdatedAt = now
+		rec.release()
+		ids = append(ids, rec.message.ID)
+	}
+	return ids, nil
+}
+
+func (r *MessageService) MarkMessageFailed(ctx context.Context, id uint) error {
+	return r.transition(ctx, id, model.StatusSending, model.StatusFailed)
+}
+
+func (r *MessageService) RequeueMessage(ctx context.Context, id uint) error {
+	return r.transition(ctx, id, model.StatusSending, model.StatusPending)
+}
+
+func (r *MessageService) DeferMessage(ctx context.Context, id uint, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.message.Status != model.StatusSending {
+		return fmt.Errorf("%w: message %d is not %s", mpostgres.ErrInvalidStatusTransition, id, model.StatusSending)
+	}
+
+	until = until.UTC()
+	rec.message.Status = model.StatusPending
+	rec.message.ScheduledAt = &until
+	rec.message.UpdatedAt = r.now()
+	rec.release()
+	return nil
+}
+
+func (r *MessageService) RetryFailedMessages(ctx context.Context, channel model.Channel, limit, maxAttempts int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	failed := r.filter(ctx, func(rec *record) bool {
+		return rec.message.Status == model.StatusFailed && rec.message.Attempts < maxAttempts &&
+			(channel == "" || rec.message.DeliveryChannel() == channel)
+	})
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].message.UpdatedAt.Before(failed[j].message.UpdatedAt)
+	})
+	if len(failed) > limit {
+		failed = failed[:limit]
+	}
+
+	now := r.now()
+	for _, rec := range failed {
+		rec.message.Status = model.StatusPending
+		rec.message.UpdatedAt = now
+	}
+
+	return int64(len(failed)), nil
+}
+
+func (r *MessageService) CancelMessage(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok {
+		return mpostgres.ErrMessageNotFound
+	}
+	if rec.message.Status != model.StatusPending && rec.message.Status != model.StatusFailed {
+		return fmt.Errorf("%w: message %d is %s", mpostgres.ErrInvalidStatusTransition, id, rec.message.Status)
+	}
+
+	rec.message.Status = model.StatusCancelled
+	rec.message.UpdatedAt = r.now()
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d cancelled", id)
+	return nil
+}
+
+func (r *MessageService) SetMessageHold(ctx context.Context, id uint, held bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.deleted {
+		return mpostgres.ErrMessageNotFound
+	}
+	if rec.message.Status != model.StatusPending && rec.message.Status != model.StatusFailed {
+		return fmt.Errorf("%w: message %d is %s", mpostgres.ErrInvalidStatusTransition, id, rec.message.Status)
+	}
+
+	rec.message.Held = held
+	rec.message.UpdatedAt = r.now()
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d held: %t", id, held)
+	return nil
+}
+
+func (r *MessageService) RecordModeration(ctx context.Context, id uint, result model.ModerationResult) error {
+	if !result.Decision.Valid() {
+		return fmt.Errorf("unknown moderation decision %q", result.Decision)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.deleted {
+		return mpostgres.ErrMessageNotFound
+	}
+	if rec.message.Status != model.StatusPending && rec.message.Status != model.StatusSending {
+		return fmt.Errorf("%w: message %d is %s", mpostgres.ErrInvalidStatusTransition, id, rec.message.Status)
+	}
+
+	rec.message.ModerationDecision = result.Decision
+	rec.message.ModerationReason = result.Reason
+	switch result.Decision {
+	case model.ModerationHold:
+		rec.message.Status = model.StatusPending
+		rec.message.Held = true
+		rec.release()
+	case model.ModerationReject:
+		rec.message.Status = model.StatusCancelled
+		rec.release()
+	}
+	rec.message.UpdatedAt = r.now()
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d moderated: %s", id, result.Decision)
+	return nil
+}
+
+func (r *MessageService) ScheduleMessage(ctx context.Context, id uint, scheduledAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.message.Status != model.StatusPending {
+		return fmt.Errorf("%w: message %d is not %s", mpostgres.ErrInvalidStatusTransition, id, model.StatusPending)
+	}
+
+	scheduledAt = scheduledAt.UTC()
+	rec.message.ScheduledAt = &scheduledAt
+	rec.message.UpdatedAt = r.now()
+	return nil
+}
+
+func (r *MessageService) SetMessagePriority(ctx context.Context, id uint, priority int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.deleted || rec.message.Status != model.StatusPending {
+		return fmt.Errorf("%w: message %d is not %s", mpostgres.ErrInvalidStatusTransition, id, model.StatusPending)
+	}
+
+	rec.message.Priority = priority
+	rec.message.UpdatedAt = r.now()
+	return nil
+}
+
+func (r *MessageService) GetMessage(ctx context.Context, id uint) (model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.deleted {
+		return model.Message{}, mpostgres.ErrMessageNotFound
+	}
+	return messagesOf([]*record{rec})[0], nil
+}
+
+func (r *MessageService) CreateMessage(ctx context.Context, message model.Message) (model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var id uint
+	for existing := range r.records {
+		id = max(id, existing)
+	}
+
+	if message.ExternalRef != nil && r.byExternalRef(ctx, message.Tenant, *message.ExternalRef) != nil {
+		return model.Message{}, fmt.Errorf("%w: %s/%s is used by another message", mpostgres.ErrExternalRefConflict, message.ExternalRef.System, message.ExternalRef.ID)
+	}
+
+	message = message.In(time.UTC)
+	message.ID = id + 1
+	message.Status = model.StatusPending
+	if message.Channel == "" {
+		message.Channel = model.ChannelSMS
+	}
+	message.CreatedAt = r.now()
+	message.UpdatedAt = message.CreatedAt
+	rec := &record{message: message}
+	r.records[message.ID] = rec
+	return messagesOf([]*record{rec})[0], nil
+}
+
+func (r *MessageService) ImportMessages(ctx context.Context, messages []model.Message) ([]model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var id uint
+	for existing := range r.records {
+		id = max(id, existing)
+	}
+
+	// Check every reference first so a conflict stores nothing.
+	refs := make(map[string]bool, len(messages))
+	for _, message := range messages {
+		if message.ExternalRef == nil {
+			continue
+		}
+		key := message.Tenant + "/" + message.ExternalRef.System + "/" + message.ExternalRef.ID
+		if refs[key] || r.byExternalRef(ctx, message.Tenant, *message.ExternalRef) != nil {
+			return nil, fmt.Errorf("%w: %s/%s is used by another message", mpostgres.ErrExternalRefConflict, message.ExternalRef.System, message.ExternalRef.ID)
+		}
+		refs[key] = true
+	}
+
+	now := r.now()
+	imported := make([]*record, 0, len(messages))
+	for _, message := range messages {
+		message = message.In(time.UTC)
+		id++
+		message.ID = id
+		message.Status = model.StatusSent
+		message.Backfilled = true
+		if message.Channel == "" {
+			message.Channel = model.ChannelSMS
+		}
+		if message.CreatedAt.IsZero() {
+			message.CreatedAt = message.SentAt
+		}
+		message.UpdatedAt = now
+		rec := &record{message: message}
+		r.records[message.ID] = rec
+		imported = append(imported, rec)
+	}
+	return messagesOf(imported), nil
+}
+
+func (r *MessageService) GetMessageByExternalRef(ctx context.Context, tenant string, ref model.ExternalRef) (model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec := r.byExternalRef(ctx, tenant, ref)
+	if rec == nil {
+		return model.Message{}, mpostgres.ErrMessageNotFound
+	}
+	return messagesOf([]*record{rec})[0], nil
+}
+
+func (r *MessageService) SetMessageExternalRef(ctx context.Context, id uint, ref model.ExternalRef) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.deleted {
+		return mpostgres.ErrMessageNotFound
+	}
+	if current := rec.message.ExternalRef; current != nil {
+		if *current == ref {
+			return nil
+		}
+		return fmt.Errorf("%w: message %d has another external reference", mpostgres.ErrExternalRefConflict, id)
+	}
+	if r.byExternalRef(ctx, rec.message.Tenant, ref) != nil {
+		return fmt.Errorf("%w: %s/%s is used by another message", mpostgres.ErrExternalRefConflict, ref.System, ref.ID)
+	}
+
+	rec.message.ExternalRef = &ref
+	rec.message.UpdatedAt = r.now()
+	return nil
+}
+
+// byExternalRef returns the record of tenant with ref, nil when there is none. The caller
+// holds r.mu.
+func (r *MessageService) byExternalRef(ctx context.Context, tenant string, ref model.ExternalRef) *record {
+	matched := r.filter(ctx, func(rec *record) bool {
+		return !rec.deleted && rec.message.Tenant == tenant && rec.message.ExternalRef != nil && *rec.message.ExternalRef == ref
+	})
+	if len(matched) == 0 {
+		return nil
+	}
+	return matched[0]
+}
+
+func (r *MessageService) GetSentMessages(ctx context.Context) ([]model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return messagesOf(r.filter(ctx, func(rec *record) bool { return !rec.deleted && rec.message.Status == model.StatusSent })), nil
+}
+
+func (r *MessageService) ListMessages(ctx context.Context, filter model.MessageFilter) ([]model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := r.filter(ctx, func(rec *record) bool {
+		msg := rec.message
+		if rec.deleted {
+			return false
+		}
+		if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, msg.Status) {
+			return false
+		}
+		if filter.From != nil && msg.CreatedAt.Before(*filter.From) {
+			return false
+		}
+		if filter.To != nil && !msg.CreatedAt.Before(*filter.To) {
+			return false
+		}
+		if filter.Held != nil && msg.Held != *filter.Held {
+			return false
+		}
+		if filter.Backfilled != nil && msg.Backfilled != *filter.Backfilled {
+			return false
+		}
+		return filter.RecipientPhone == "" || msg.RecipientPhone == filter.RecipientPhone
+	})
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].message.CreatedAt.Equal(matched[j].message.CreatedAt) {
+			return matched[i].message.CreatedAt.After(matched[j].message.CreatedAt)
+		}
+		return matched[i].message.ID > matched[j].message.ID
+	})
+
+	offset := (filter.Page - 1) * filter.PageSize
+	if offset >= len(matched) {
+		return []model.Message{}, nil
+	}
+	matched = matched[offset:]
+	if len(matched) > filter.PageSize+1 {
+		matched = matched[:filter.PageSize+1]
+	}
+	return messagesOf(matched), nil
+}
+
+func (r *MessageService) ClaimMessages(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Message, error) {
+	return r.claim(ctx, workerID, "", limit, lease)
+}
+
+func (r *MessageService) claim(ctx context.Context, workerID string, channel model.Channel, limit int, lease time.Duration) ([]model.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	claimable := r.filter(ctx, func(rec *record) bool {
+		if rec.deleted || channel != "" && rec.message.DeliveryChannel() != channel {
+			return false
+		}
+		switch rec.message.Status {
+		case model.StatusPending:
+			return !rec.message.Held && (rec.message.ScheduledAt == nil || !rec.message.ScheduledAt.After(now.Add(r.clockSkew)))
+		case model.StatusSending:
+			return rec.leaseExpiresAt.Before(now)
+		}
+		return false
+	})
+	// filter returns records by ID, a stable sort keeps that order among messages created
+	// at the same time.
+	sort.SliceStable(claimable, func(i, j int) bool {
+		pi := model.EffectivePriority(claimable[i].message, now, r.priorityAging)
+		pj := model.EffectivePriority(claimable[j].message, now, r.priorityAging)
+		if pi != pj {
+			return pi > pj
+		}
+		return claimable[i].message.CreatedAt.Before(claimable[j].message.CreatedAt)
+	})
+	if len(r.tenantBatchLimits) > 0 {
+		picked := claimable[:0]
+		perTenant := make(map[string]int)
+		for _, rec := range claimable {
+			tenant := rec.message.Tenant
+			if batchLimit, ok := r.tenantBatchLimits[tenant]; ok && perTenant[tenant] >= batchLimit {
+				continue
+			}
+			perTenant[tenant]++
+			picked = append(picked, rec)
+		}
+		claimable = picked
+	}
+	if len(claimable) > limit {
+		claimable = claimable[:limit]
+	}
+
+	for _, rec := range claimable {
+		rec.message.Status = model.StatusSending
+		rec.message.UpdatedAt = now
+		rec.claimedBy = workerID
+		rec.leaseExpiresAt = now.Add(lease)
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Worker %s claimed %d messages", workerID, len(claimable))
+	return messagesOf(claimable), nil
+}
+
+func (r *MessageService) CompleteClaimedMessage(ctx context.Context, workerID string, result model.WorkerResult) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	rec, ok := r.lookup(ctx, result.ID)
+	if !ok || rec.message.Status != model.StatusSending || rec.claimedBy != workerID || rec.leaseExpiresAt.Before(now) {
+		return false, nil
+	}
+
+	if result.Success {
+		rec.message.Status = model.StatusSent
+		rec.message.SentAt = now
+		rec.message.ProviderMessageID = result.ProviderMessageID
+	} else {
+		rec.message.Status = model.StatusFailed
+		rec.message.Attempts++
+	}
+	rec.message.UpdatedAt = now
+	rec.release()
+
+	return true, nil
+}
+
+func (r *MessageService) UpdateDeliveryStatus(ctx context.Context, providerMessageID string, status model.DeliveryStatus, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := r.filter(ctx, func(rec *record) bool {
+		return providerMessageID != "" && rec.message.ProviderMessageID == providerMessageID
+	})
+	if len(matched) == 0 {
+		return mpostgres.ErrMessageNotFound
+	}
+
+	for _, rec := range matched {
+		rec.message.DeliveryStatus = status
+		if status == model.DeliveryDelivered {
+			deliveredAt := at.UTC()
+			rec.message.DeliveredAt = &deliveredAt
+		}
+		rec.message.UpdatedAt = r.now()
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Provider message %s is %s", providerMessageID, status)
+	return nil
+}
+
+func (r *MessageService) PurgeRecipient(ctx context.Context, phone string, mode model.PurgeMode) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hashed := anonymizedRecipient(phone)
+	ids, err := r.purge(ctx, func(rec *record) bool {
+		return phone != "" && (rec.message.RecipientPhone == phone || rec.message.RecipientPhone == hashed)
+	}, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Purged %d messages of a recipient (%s)", len(ids), mode)
+	return ids, nil
+}
+
+func (r *MessageService) PurgeMessagesBefore(ctx context.Context, cutoff time.Time, mode model.PurgeMode) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids, err := r.purge(ctx, func(rec *record) bool {
+		switch rec.message.Status {
+		case model.StatusSent, model.StatusFailed, model.StatusCancelled:
+			return rec.message.CreatedAt.Before(cutoff)
+		}
+		return false
+	}, mode)
+	return int64(len(ids)), err
+}
+
+func (r *MessageService) GetBacklog(ctx context.Context) (model.Backlog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var backlog model.Backlog
+	for _, rec := range r.filter(ctx, func(rec *record) bool {
+		return !rec.deleted && rec.message.Status == model.StatusPending && !rec.message.Held &&
+			(rec.message.ScheduledAt == nil || !rec.message.ScheduledAt.After(now))
+	}) {
+		dueAt := rec.message.CreatedAt
+		if rec.message.ScheduledAt != nil {
+			dueAt = *rec.message.ScheduledAt
+		}
+		if backlog.OldestDueAt == nil || dueAt.Before(*backlog.OldestDueAt) {
+			backlog.OldestDueAt = &dueAt
+		}
+		backlog.Pending++
+	}
+
+	return backlog, nil
+}
+
+func (r *MessageService) CountStuckMessages(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stuck := r.filter(ctx, func(rec *record) bool {
+		return !rec.deleted && rec.message.Status == model.StatusSending && rec.message.UpdatedAt.Before(before)
+	})
+	return int64(len(stuck)), nil
+}
+
+func (r *MessageService) GetMessageStats(ctx context.Context, since time.Time) (model.MessageStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats model.MessageStats
+	perHour := map[time.Time]int64{}
+	for _, rec := range r.filter(ctx, func(rec *record) bool { return !rec.deleted }) {
+		switch rec.message.Status {
+		case model.StatusSent:
+			stats.Sent++
+			if !rec.message.Backfilled && !rec.message.SentAt.Before(since) {
+				perHour[rec.message.SentAt.UTC().Truncate(time.Hour)]++
+			}
+		case model.StatusFailed:
+			stats.Failed++
+			stats.Unsent++
+		case model.StatusPending, model.StatusSending:
+			stats.Unsent++
+		}
+	}
+	for hour, count := range perHour {
+		stats.SentPerHour = append(stats.SentPerHour, model.HourlyCount{Hour: hour, Count: count})
+	}
+	sort.Slice(stats.SentPerHour, func(i, j int) bool { return stats.SentPerHour[i].Hour.Before(stats.SentPerHour[j].Hour) })
+
+	return stats, nil
+}
+
+// purge deletes or anonymizes the matching records like the PostgreSQL repository and
+// returns their IDs. Callers must hold mu.
+func (r *MessageService) purge(ctx context.Context, match func(*record) bool, mode model.PurgeMode) ([]uint, error) {
+	if !mode.Valid() {
+		return nil, fmt.Errorf("unknown purge mode %q", mode)
+	}
+
+	var ids []uint
+	for _, rec := range r.filter(ctx, match) {
+		if mode == model.PurgeDelete {
+			delete(r.records, rec.message.ID)
+			ids = append(ids, rec.message.ID)
+			continue
+		}
+		if rec.deleted {
+			continue
+		}
+
+		rec.message.Content = ""
+		rec.message.RecipientPhone = anonymizedRecipient(rec.message.RecipientPhone)
+		rec.message.RecipientEmail = anonymizedRecipient(rec.message.RecipientEmail)
+		if rec.message.Status == model.StatusPending || rec.message.Status == model.StatusFailed {
+			rec.message.Status = model.StatusCancelled
+		}
+		rec.message.UpdatedAt = r.now()
+		rec.deleted = true
+		ids = append(ids, rec.message.ID)
+	}
+	return ids, nil
+}
+
+// anonymizedRecipient matches the hash the PostgreSQL repository stores, empty stays empty.
+func anonymizedRecipient(recipient string) string {
+	if recipient == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(recipient))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (r *MessageService) transition(ctx context.Context, id uint, from, to model.MessageStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.lookup(ctx, id)
+	if !ok || rec.message.Status != from {
+		return fmt.Errorf("%w: message %d is not %s", mpostgres.ErrInvalidStatusTransition, id, from)
+	}
+	if rec.message.Held {
+		return fmt.Errorf("%w: message %d", mpostgres.ErrMessageHeld, id)
+	}
+
+	rec.message.Status = to
+	rec.message.UpdatedAt = r.now()
+	if to == model.StatusFailed {
+		rec.message.Attempts++
+	}
+	rec.release()
+	return nil
+}
+
+// filter returns the matching records ctx may see, see logctx.TenantScope, ordered by ID.
+// Callers must hold mu.
+func (r *MessageService) filter(ctx context.Context, match func(*record) bool) []*record {
+	scope := logctx.TenantScope(ctx)
+	var matched []*record
+	for _, rec := range r.records {
+		if (scope == "" || rec.message.Tenant == scope) && match(rec) {
+			matched = append(matched, rec)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].message.ID < matched[j].message.ID })
+	return matched
+}
+
+// lookup returns the record of id if ctx may see it, see logctx.TenantScope. Callers must
+// hold mu.
+func (r *MessageService) lookup(ctx context.Context, id uint) (*record, bool) {
+	rec, ok := r.records[id]
+	if !ok {
+		return nil, false
+	}
+	if scope := logctx.TenantScope(ctx); scope != "" && rec.message.Tenant != scope {
+		return nil, false
+	}
+	return rec, true
+}
+
+func (rec *record) release() {
+	rec.claimedBy = ""
+	rec.leaseExpiresAt = time.Time{}
+}
+
+// messagesOf copies records out so callers never share state with the store.
+func messagesOf(records []*record) []model.Message {
+	messages := make([]model.Message, 0, len(records))
+	for _, rec := range records {
+		msg := rec.message
+		if msg.ScheduledAt != nil {
+			scheduledAt := *msg.ScheduledAt
+			msg.ScheduledAt = &scheduledAt
+		}
+		if msg.DeliveredAt != nil {
+			deliveredAt := *msg.DeliveredAt
+			msg.DeliveredAt = &deliveredAt
+		}
+		if msg.ExternalRef != nil {
+			ref := *msg.ExternalRef
+			msg.ExternalRef = &ref
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
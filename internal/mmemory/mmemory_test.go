@@ -0,0 +1,370 @@
+package mmemory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func newTestService(seed ...model.Message) (*MessageService, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	service := NewMessageService(inslogger.NewLogger(inslogger.Debug), seed...)
+	service.now = func() time.Time { return now }
+	return service, &now
+}
+
+func TestStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(model.Message{ID: 1, Content: "Test Message", RecipientPhone: "+123456789"})
+
+	assert.ErrorIs(t, service.MarkMessageFailed(ctx, 1), mpostgres.ErrInvalidStatusTransition)
+	assert.NoError(t, service.MarkMessageSending(ctx, 1))
+	assert.ErrorIs(t, service.MarkMessageSending(ctx, 1), mpostgres.ErrInvalidStatusTransition)
+	assert.NoError(t, service.UpdateMessageSentWithProviderID(ctx, 1, "provider-1"))
+	assert.ErrorIs(t, service.UpdateMessageSentWithProviderID(ctx, 1, "provider-2"), mpostgres.ErrInvalidStatusTransition)
+
+	msg, err := service.Message(1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatusSent, msg.Status)
+	assert.Equal(t, "provider-1", msg.ProviderMessageID)
+
+	sent, err := service.GetSentMessages(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, sent, 1)
+}
+
+func TestCreateMessage(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService(model.Message{ID: 7, Status: model.StatusSent})
+
+	created, err := service.CreateMessage(ctx, model.Message{ID: 1, Content: "smoke", RecipientPhone: "+905550000000", Tenant: "smoke", Status: model.StatusSent})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(8), created.ID, "IDs are assigned, not taken from the message")
+	assert.Equal(t, model.StatusPending, created.Status)
+	assert.Equal(t, model.ChannelSMS, created.Channel)
+	assert.Equal(t, *now, created.CreatedAt)
+
+	claimed, err := service.GetUnsentMessages(ctx, "", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, "smoke", claimed[0].Tenant)
+	}
+}
+
+func TestExternalRefIsUniquePerTenant(t *testing.T) {
+	ctx := context.Background()
+	ref := model.ExternalRef{System: "orders", ID: "SO-1"}
+	service, _ := newTestService(
+		model.Message{ID: 1, Tenant: "acme"},
+		model.Message{ID: 2, Tenant: "acme"},
+		model.Message{ID: 3, Tenant: "globex"},
+	)
+
+	assert.NoError(t, service.SetMessageExternalRef(ctx, 1, ref))
+	assert.NoError(t, service.SetMessageExternalRef(ctx, 1, ref), "setting the same reference again is a no-op")
+	assert.ErrorIs(t, service.SetMessageExternalRef(ctx, 1, model.ExternalRef{System: "orders", ID: "SO-2"}), mpostgres.ErrExternalRefConflict)
+	assert.ErrorIs(t, service.SetMessageExternalRef(ctx, 2, ref), mpostgres.ErrExternalRefConflict)
+	assert.NoError(t, service.SetMessageExternalRef(ctx, 3, ref), "other tenants may use the same reference")
+	_, err := service.CreateMessage(ctx, model.Message{Tenant: "acme", ExternalRef: &ref})
+	assert.ErrorIs(t, err, mpostgres.ErrExternalRefConflict)
+
+	found, err := service.GetMessageByExternalRef(ctx, "globex", ref)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), found.ID)
+	_, err = service.GetMessageByExternalRef(ctx, "initech", ref)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+}
+
+func TestTimestampsAreUTC(t *testing.T) {
+	ctx := context.Background()
+	istanbul := time.FixedZone("UTC+3", 3*60*60)
+	service, _ := newTestService(model.Message{ID: 1, CreatedAt: time.Date(2025, 1, 1, 9, 0, 0, 0, istanbul)})
+
+	assert.NoError(t, service.ScheduleMessage(ctx, 1, time.Date(2025, 1, 2, 15, 0, 0, 0, istanbul)))
+
+	msg, err := service.Message(1)
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, msg.CreatedAt.Location())
+	assert.Equal(t, time.Date(2025, 1, 1, 6, 0, 0, 0, time.UTC), msg.CreatedAt)
+	assert.Equal(t, time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC), *msg.ScheduledAt)
+}
+
+func TestCancelMessage(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(
+		model.Message{ID: 1, Status: model.StatusPending},
+		model.Message{ID: 2, Status: model.StatusFailed},
+		model.Message{ID: 3, Status: model.StatusSending},
+		model.Message{ID: 4, Status: model.StatusSent},
+	)
+
+	assert.NoError(t, service.CancelMessage(ctx, 1))
+	assert.NoError(t, service.CancelMessage(ctx, 2))
+	assert.ErrorIs(t, service.CancelMessage(ctx, 3), mpostgres.ErrInvalidStatusTransition)
+	assert.ErrorIs(t, service.CancelMessage(ctx, 4), mpostgres.ErrInvalidStatusTransition)
+	assert.ErrorIs(t, service.CancelMessage(ctx, 5), mpostgres.ErrMessageNotFound)
+
+	msg, _ := service.Message(1)
+	assert.Equal(t, model.StatusCancelled, msg.Status)
+}
+
+func TestClaimMessages(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+	later := now.Add(time.Hour)
+	service.records[1] = &record{message: model.Message{ID: 1, Status: model.StatusPending}}
+	service.records[2] = &record{message: model.Message{ID: 2, Status: model.StatusPending, ScheduledAt: &later}}
+	service.records[3] = &record{message: model.Message{ID: 3, Status: model.StatusPending}}
+	service.records[4] = &record{message: model.Message{ID: 4, Status: model.StatusCancelled}}
+
+	claimed, err := service.ClaimMessages(ctx, "worker-1", 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 3}, ids(claimed))
+
+	// Leased messages are not claimable until the lease expires, scheduled ones once due.
+	claimed, _ = service.ClaimMessages(ctx, "worker-2", 10, time.Minute)
+	assert.Empty(t, claimed)
+
+	*now = now.Add(2 * time.Hour)
+	claimed, _ = service.ClaimMessages(ctx, "worker-2", 2, time.Minute)
+	assert.Equal(t, []uint{1, 2}, ids(claimed))
+}
+
+func TestClaimMessagesRespectsTenantBatchLimits(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(
+		model.Message{ID: 1, Tenant: "acme"},
+		model.Message{ID: 2, Tenant: "acme"},
+		model.Message{ID: 3, Tenant: "acme"},
+		model.Message{ID: 4, Tenant: "globex"},
+	)
+	service.SetTenantBatchLimits(map[string]int{"acme": 2})
+
+	claimed, err := service.GetUnsentMessages(ctx, "", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 4}, ids(claimed))
+}
+
+func TestTenantScope(t *testing.T) {
+	ctx := logctx.WithTenantScope(context.Background(), "acme")
+	service, _ := newTestService(
+		model.Message{ID: 1, Tenant: "acme"},
+		model.Message{ID: 2, Tenant: "globex"},
+	)
+
+	_, err := service.GetMessage(ctx, 2)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+	assert.ErrorIs(t, service.CancelMessage(ctx, 2), mpostgres.ErrMessageNotFound)
+	listed, err := service.ListMessages(ctx, model.MessageFilter{Page: 1, PageSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1}, ids(listed))
+	claimed, err := service.ClaimMessages(ctx, "worker-1", 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1}, ids(claimed))
+
+	// Without a scope every tenant is visible.
+	_, err = service.GetMessage(context.Background(), 2)
+	assert.NoError(t, err)
+}
+
+func TestClaimMessagesToleratesClockSkew(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+	soon := now.Add(3 * time.Second)
+	later := now.Add(time.Minute)
+	service.records[1] = &record{message: model.Message{ID: 1, Status: model.StatusPending, ScheduledAt: &soon}}
+	service.records[2] = &record{message: model.Message{ID: 2, Status: model.StatusPending, ScheduledAt: &later}}
+
+	claimed, _ := service.ClaimMessages(ctx, "worker-1", 10, time.Minute)
+	assert.Empty(t, claimed)
+
+	service.SetClockSkew(5 * time.Second)
+	claimed, _ = service.ClaimMessages(ctx, "worker-1", 10, time.Minute)
+	assert.Equal(t, []uint{1}, ids(claimed), "messages due within the skew are claimed")
+}
+
+func TestGetUnsentMessagesByChannel(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(
+		model.Message{ID: 1},
+		model.Message{ID: 2, Channel: model.ChannelEmail},
+		model.Message{ID: 3, Channel: model.ChannelSMS},
+	)
+
+	claimed, err := service.GetUnsentMessages(ctx, model.ChannelEmail, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, ids(claimed))
+
+	claimed, _ = service.GetUnsentMessages(ctx, model.ChannelSMS, 10)
+	assert.Equal(t, []uint{1, 3}, ids(claimed), "messages without a channel are SMS")
+}
+
+func TestCompleteClaimedMessage(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService(
+		model.Message{ID: 1, Status: model.StatusPending},
+		model.Message{ID: 2, Status: model.StatusPending},
+	)
+	_, _ = service.ClaimMessages(ctx, "worker-1", 2, time.Minute)
+
+	ok, err := service.CompleteClaimedMessage(ctx, "worker-2", model.WorkerResult{ID: 1, Success: true})
+	assert.NoError(t, err)
+	assert.False(t, ok, "another worker's lease")
+
+	ok, _ = service.CompleteClaimedMessage(ctx, "worker-1", model.WorkerResult{ID: 1, Success: true, ProviderMessageID: "provider-1"})
+	assert.True(t, ok)
+
+	*now = now.Add(2 * time.Minute)
+	ok, _ = service.CompleteClaimedMessage(ctx, "worker-1", model.WorkerResult{ID: 2, Success: false})
+	assert.False(t, ok, "expired lease")
+
+	msg, _ := service.Message(1)
+	assert.Equal(t, model.StatusSent, msg.Status)
+	assert.Equal(t, "provider-1", msg.ProviderMessageID)
+}
+
+func TestRetryFailedMessages(t *testing.T) {
+	ctx := context.Background()
+	service, now := newTestService()
+	service.records[1] = &record{message: model.Message{ID: 1, Status: model.StatusFailed, UpdatedAt: now.Add(-time.Minute)}}
+	service.records[2] = &record{message: model.Message{ID: 2, Status: model.StatusFailed, UpdatedAt: now.Add(-time.Hour)}}
+	service.records[3] = &record{message: model.Message{ID: 3, Status: model.StatusSent}}
+	service.records[4] = &record{message: model.Message{ID: 4, Status: model.StatusFailed, Attempts: 3, UpdatedAt: now.Add(-2 * time.Hour)}}
+	service.records[5] = &record{message: model.Message{ID: 5, Channel: model.ChannelEmail, Status: model.StatusFailed, UpdatedAt: now.Add(-2 * time.Hour)}}
+
+	retried, err := service.RetryFailedMessages(ctx, model.ChannelSMS, 1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), retried)
+
+	// The oldest failure with attempts left is retried first.
+	msg, _ := service.Message(2)
+	assert.Equal(t, model.StatusPending, msg.Status)
+	msg, _ = service.Message(1)
+	assert.Equal(t, model.StatusFailed, msg.Status)
+	msg, _ = service.Message(4)
+	assert.Equal(t, model.StatusFailed, msg.Status, "no attempts left")
+	msg, _ = service.Message(5)
+	assert.Equal(t, model.StatusFailed, msg.Status, "another channel")
+}
+
+func TestConcurrentClaimsNeverOverlap(t *testing.T) {
+	ctx := context.Background()
+	var seed []model.Message
+	for id := uint(1); id <= 200; id++ {
+		seed = append(seed, model.Message{ID: id})
+	}
+	service := NewMessageService(inslogger.NewLogger(inslogger.Debug), seed...)
+
+	var mu sync.Mutex
+	seen := make(map[uint]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := service.ClaimMessages(ctx, "worker", 7, time.Minute)
+				if err != nil || len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, msg := range claimed {
+					seen[msg.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, 200)
+	for id, count := range seen {
+		assert.Equal(t, 1, count, "message %d claimed more than once", id)
+	}
+}
+
+func ids(messages []model.Message) []uint {
+	out := make([]uint, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, msg.ID)
+	}
+	return out
+}
+
+func TestSchedulerRunRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	runs := NewSchedulerRunService(time.Hour)
+	runs.now = func() time.Time { return now }
+
+	assert.NoError(t, runs.RecordRun(ctx, model.SchedulerRun{StartedAt: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, runs.RecordRun(ctx, model.SchedulerRun{StartedAt: now.Add(-time.Minute)}))
+
+	listed, err := runs.ListRuns(ctx, 50)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1, "runs older than the retention are pruned")
+	assert.Equal(t, uint(2), listed[0].ID)
+}
+
+func TestClaimMessagesByPriority(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newTestService(
+		model.Message{ID: 1, Priority: 0},
+		model.Message{ID: 2, Priority: 5},
+		model.Message{ID: 3, Priority: 5},
+		model.Message{ID: 4, Priority: 9},
+	)
+
+	claimed, err := service.ClaimMessages(ctx, "worker-1", 3, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4, 2, 3}, ids(claimed), "highest priority first, then by ID")
+}
+
+// starvationRun feeds one new high priority message per tick while claiming a single
+// message per tick, and reports the tick the low priority message 1 was claimed at, or -1.
+func starvationRun(aging time.Duration, ticks int) int {
+	ctx := context.Background()
+	service, now := newTestService(model.Message{ID: 1, Priority: 0, CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)})
+	service.SetPriorityAging(aging)
+
+	for tick := 0; tick < ticks; tick++ {
+		id := uint(tick + 2)
+		service.records[id] = &record{message: model.Message{ID: id, Status: model.StatusPending, Priority: 8, CreatedAt: *now}}
+
+		claimed, _ := service.ClaimMessages(ctx, "worker-1", 1, time.Hour)
+		if len(claimed) == 1 && claimed[0].ID == 1 {
+			return tick
+		}
+		*now = now.Add(time.Minute)
+	}
+	return -1
+}
+
+func TestLowPriorityIsStarvedWithoutAging(t *testing.T) {
+	assert.Equal(t, -1, starvationRun(0, 120))
+}
+
+func TestAgingPreventsStarvation(t *testing.T) {
+	// After nine 10 minute intervals the message reaches MaxPriority and outranks the
+	// fresh priority 8 traffic.
+	assert.Equal(t, 90, starvationRun(10*time.Minute, 120))
+}
+
+func TestEffectivePriority(t *testing.T) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	scheduled := created.Add(time.Hour)
+
+	assert.Equal(t, 2, model.EffectivePriority(model.Message{Priority: 2, CreatedAt: created}, created.Add(time.Hour), 0), "aging disabled")
+	assert.Equal(t, 5, model.EffectivePriority(model.Message{Priority: 2, CreatedAt: created}, created.Add(35*time.Minute), 10*time.Minute))
+	assert.Equal(t, model.MaxPriority, model.EffectivePriority(model.Message{Priority: 2, CreatedAt: created}, created.Add(24*time.Hour), 10*time.Minute))
+	assert.Equal(t, 2, model.EffectivePriority(model.Message{Priority: 2, CreatedAt: created, ScheduledAt: &scheduled}, scheduled, 10*time.Minute),
+		"scheduled messages age from their due time")
+}
@@ -0,0 +1,118 @@
+package mmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+)
+
+// ProviderConfigRepository keeps provider configs in process memory.
+type ProviderConfigRepository struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	nextID  uint
+	configs map[uint]model.ProviderConfig
+}
+
+var _ mpostgres.ProviderConfigRepository = (*ProviderConfigRepository)(nil)
+
+func NewProviderConfigRepository() *ProviderConfigRepository {
+	return &ProviderConfigRepository{
+		now:     utcNow,
+		configs: make(map[uint]model.ProviderConfig),
+	}
+}
+
+func (r *ProviderConfigRepository) CreateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.byTenant(config.Tenant, 0); ok {
+		return model.ProviderConfig{}, mpostgres.ErrProviderConfigTenantTaken
+	}
+
+	r.nextID++
+	config.ID = r.nextID
+	config.CreatedAt = r.now()
+	config.UpdatedAt = config.CreatedAt
+	r.configs[config.ID] = config
+	return config, nil
+}
+
+func (r *ProviderConfigRepository) GetProviderConfig(ctx context.Context, id uint) (model.ProviderConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, ok := r.configs[id]
+	if !ok {
+		return model.ProviderConfig{}, mpostgres.ErrProviderConfigNotFound
+	}
+	return config, nil
+}
+
+func (r *ProviderConfigRepository) GetTenantProviderConfig(ctx context.Context, tenant string) (model.ProviderConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, ok := r.byTenant(tenant, 0)
+	if !ok {
+		return model.ProviderConfig{}, mpostgres.ErrProviderConfigNotFound
+	}
+	return config, nil
+}
+
+func (r *ProviderConfigRepository) ListProviderConfigs(ctx context.Context) ([]model.ProviderConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configs := make([]model.ProviderConfig, 0, len(r.configs))
+	for _, config := range r.configs {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].ID < configs[j].ID })
+	return configs, nil
+}
+
+func (r *ProviderConfigRepository) UpdateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.configs[config.ID]
+	if !ok {
+		return model.ProviderConfig{}, mpostgres.ErrProviderConfigNotFound
+	}
+	if _, ok := r.byTenant(config.Tenant, config.ID); ok {
+		return model.ProviderConfig{}, mpostgres.ErrProviderConfigTenantTaken
+	}
+
+	config.CreatedAt = existing.CreatedAt
+	config.UpdatedAt = r.now()
+	r.configs[config.ID] = config
+	return config, nil
+}
+
+func (r *ProviderConfigRepository) DeleteProviderConfig(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.configs[id]; !ok {
+		return mpostgres.ErrProviderConfigNotFound
+	}
+	delete(r.configs, id)
+	return nil
+}
+
+// byTenant returns the config of tenant other than id. Callers must hold mu.
+func (r *ProviderConfigRepository) byTenant(tenant string, id uint) (model.ProviderConfig, bool) {
+	for _, config := range r.configs {
+		if config.Tenant == tenant && config.ID != id {
+			return config, true
+		}
+	}
+	return model.ProviderConfig{}, false
+}
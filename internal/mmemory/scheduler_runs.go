@@ -0,0 +1,63 @@
+package mmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+)
+
+// SchedulerRunService keeps scheduler run history in process memory.
+type SchedulerRunService struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	nextID uint
+	runs   []model.SchedulerRun
+}
+
+var _ mpostgres.SchedulerRunService = (*SchedulerRunService)(nil)
+
+func NewSchedulerRunService(retention time.Duration) *SchedulerRunService {
+	return &SchedulerRunService{
+		retention: retention,
+		now:       utcNow,
+	}
+}
+
+func (r *SchedulerRunService) RecordRun(ctx context.Context, run model.SchedulerRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	run.ID = r.nextID
+	run.StartedAt, run.FinishedAt = run.StartedAt.UTC(), run.FinishedAt.UTC()
+	r.runs = append(r.runs, run)
+
+	if r.retention > 0 {
+		cutoff := r.now().Add(-r.retention)
+		kept := r.runs[:0]
+		for _, existing := range r.runs {
+			if !existing.StartedAt.Before(cutoff) {
+				kept = append(kept, existing)
+			}
+		}
+		r.runs = kept
+	}
+
+	return nil
+}
+
+func (r *SchedulerRunService) ListRuns(ctx context.Context, limit int) ([]model.SchedulerRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runs := make([]model.SchedulerRun, 0, limit)
+	for i := len(r.runs) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, r.runs[i])
+	}
+	return runs, nil
+}
@@ -0,0 +1,126 @@
+package mmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+)
+
+// TemplateRepository keeps templates in process memory.
+type TemplateRepository struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	nextID    uint
+	templates map[uint]model.Template
+}
+
+var _ mpostgres.TemplateRepository = (*TemplateRepository)(nil)
+
+func NewTemplateRepository() *TemplateRepository {
+	return &TemplateRepository{
+		now:       utcNow,
+		templates: make(map[uint]model.Template),
+	}
+}
+
+func (r *TemplateRepository) CreateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(template.Tenant, template.Name, 0) {
+		return model.Template{}, mpostgres.ErrTemplateNameTaken
+	}
+
+	r.nextID++
+	template.ID = r.nextID
+	template.CreatedAt = r.now()
+	template.UpdatedAt = template.CreatedAt
+	r.templates[template.ID] = template
+	return template, nil
+}
+
+func (r *TemplateRepository) GetTemplate(ctx context.Context, id uint) (model.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	template, ok := r.lookup(ctx, id)
+	if !ok {
+		return model.Template{}, mpostgres.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+func (r *TemplateRepository) ListTemplates(ctx context.Context) ([]model.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	scope := logctx.TenantScope(ctx)
+	templates := make([]model.Template, 0, len(r.templates))
+	for _, template := range r.templates {
+		if scope == "" || template.Tenant == scope {
+			templates = append(templates, template)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+func (r *TemplateRepository) UpdateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.lookup(ctx, template.ID)
+	if !ok {
+		return model.Template{}, mpostgres.ErrTemplateNotFound
+	}
+	if r.nameTaken(existing.Tenant, template.Name, template.ID) {
+		return model.Template{}, mpostgres.ErrTemplateNameTaken
+	}
+
+	template.Tenant = existing.Tenant
+	template.CreatedAt = existing.CreatedAt
+	template.UpdatedAt = r.now()
+	r.templates[template.ID] = template
+	return template, nil
+}
+
+func (r *TemplateRepository) DeleteTemplate(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.lookup(ctx, id); !ok {
+		return mpostgres.ErrTemplateNotFound
+	}
+	delete(r.templates, id)
+	return nil
+}
+
+// lookup returns the template with id if ctx may see it, see logctx.TenantScope. Callers
+// must hold mu.
+func (r *TemplateRepository) lookup(ctx context.Context, id uint) (model.Template, bool) {
+	template, ok := r.templates[id]
+	if !ok {
+		return model.Template{}, false
+	}
+	if scope := logctx.TenantScope(ctx); scope != "" && template.Tenant != scope {
+		return model.Template{}, false
+	}
+	return template, true
+}
+
+// nameTaken reports whether a template of tenant other than id uses name. Callers must
+// hold mu.
+func (r *TemplateRepository) nameTaken(tenant, name string, id uint) bool {
+	for _, template := range r.templates {
+		if template.Tenant == tenant && template.Name == name && template.ID != id {
+			return true
+		}
+	}
+	return false
+}
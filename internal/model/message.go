@@ -1,23 +1,737 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
+// MessageStatus is the lifecycle state of a message.
+type MessageStatus string
+
+// Allowed transitions: pending→sending, sending→sent, sending→failed, failed→pending.
+// Pending and failed messages can be cancelled. Moderation may cancel pending or sending
+// messages, or return them to pending held. Quiet hours return sending messages to pending.
+const (
+	StatusPending   MessageStatus = "pending"
+	StatusSending   MessageStatus = "sending"
+	StatusSent      MessageStatus = "sent"
+	StatusFailed    MessageStatus = "failed"
+	StatusCancelled MessageStatus = "cancelled"
+)
+
+// DeliveryStatus is the final outcome of a sent message as reported by the provider.
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered   DeliveryStatus = "delivered"
+	DeliveryFailed      DeliveryStatus = "failed"
+	DeliveryUndelivered DeliveryStatus = "undelivered"
+)
+
+// UnsentStatuses are the statuses of messages that were not sent and may still be.
+var UnsentStatuses = []MessageStatus{StatusPending, StatusSending, StatusFailed}
+
+// PurgeMode is how purged messages are removed.
+type PurgeMode string
+
+const (
+	// PurgeAnonymize blanks the content, replaces the recipient by its SHA-256 hash, cancels
+	// the message if it was not sent yet and hides it from the API.
+	PurgeAnonymize PurgeMode = "anonymize"
+	// PurgeDelete removes the messages.
+	PurgeDelete PurgeMode = "delete"
+)
+
+// Valid reports whether m is a known purge mode.
+func (m PurgeMode) Valid() bool {
+	return m == PurgeAnonymize || m == PurgeDelete
+}
+
+// PurgeResult reports how many messages a purge removed.
+type PurgeResult struct {
+	Mode   PurgeMode `json:"mode" enums:"anonymize,delete" example:"anonymize"`
+	Purged int       `json:"purged" example:"12"`
+}
+
+// MessageFilter selects messages for a listing. Zero fields do not filter. From and To
+// bound created_at, From inclusive and To exclusive. Page starts at 1.
+type MessageFilter struct {
+	Statuses       []MessageStatus
+	From           *time.Time
+	To             *time.Time
+	RecipientPhone string
+	// Held selects held (true) or not held (false) messages, both when nil.
+	Held *bool
+	// Backfilled selects imported (true) or live (false) messages, both when nil.
+	Backfilled *bool
+	Page       int
+	PageSize   int
+}
+
+// MessageList is one page of a message listing, newest first.
+type MessageList struct {
+	Messages []Message `json:"messages"`
+	Page     int       `json:"page" example:"1"`
+	PageSize int       `json:"page_size" example:"50"`
+	// NextPage is the page to request next, omitted on the last page.
+	NextPage int `json:"next_page,omitempty" example:"2"`
+}
+
+// Channel is the medium a message is delivered through.
+type Channel string
+
+const (
+	ChannelSMS   Channel = "sms"
+	ChannelEmail Channel = "email"
+	ChannelPush  Channel = "push"
+)
+
+// Valid reports whether c is a known channel.
+func (c Channel) Valid() bool {
+	switch c {
+	case ChannelSMS, ChannelEmail, ChannelPush:
+		return true
+	}
+	return false
+}
+
 // Message represents a message entity.
 // @Description Message entity
 type Message struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	Content        string    `gorm:"type:text;not null" json:"content"`
-	RecipientPhone string    `gorm:"type:varchar(20);not null" json:"recipient_phone"`
-	Sent           bool      `gorm:"default:false" json:"sent"`
-	SentAt         time.Time `json:"sent_at"`
-	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	Content        string        `gorm:"type:text;not null" json:"content"`
+	RecipientPhone string        `gorm:"type:varchar(80);not null" json:"recipient_phone"`
+	RecipientEmail string        `gorm:"type:varchar(255)" json:"recipient_email,omitempty"`
+	Channel        Channel       `gorm:"type:varchar(16);default:sms" json:"channel" enums:"sms,email,push"`
+	Status         MessageStatus `gorm:"type:varchar(16);default:pending" json:"status" enums:"pending,sending,sent,failed,cancelled"`
+	Priority       int           `gorm:"type:smallint;default:0" json:"priority" minimum:"0" maximum:"9"`
+	Tenant         string        `gorm:"type:varchar(255)" json:"tenant,omitempty"`
+	// Held keeps a pending or failed message from being sent until it is released.
+	Held bool `gorm:"default:false" json:"held"`
+	// ModerationDecision and ModerationReason record the pre-send moderation step, they are
+	// empty until the message is moderated.
+	ModerationDecision ModerationDecision `gorm:"type:varchar(16)" json:"moderation_decision,omitempty" enums:"approve,hold,reject"`
+	ModerationReason   string             `gorm:"type:text" json:"moderation_reason,omitempty"`
+	SentAt             time.Time          `json:"sent_at"`
+	ScheduledAt        *time.Time         `json:"scheduled_at,omitempty"`
+	ProviderMessageID  string             `gorm:"type:varchar(255)" json:"provider_message_id"`
+	// DeliveryStatus and DeliveredAt are set by the provider's delivery status callback.
+	DeliveryStatus DeliveryStatus `gorm:"type:varchar(16)" json:"delivery_status,omitempty" enums:"delivered,failed,undelivered"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	// ExternalRef is the ID of the message in the upstream system that requested it.
+	ExternalRef *ExternalRef `json:"external_ref,omitempty"`
+	// Backfilled marks a message imported from a legacy system, it was sent before and
+	// never went through the providers of this service.
+	Backfilled bool `gorm:"default:false" json:"backfilled,omitempty"`
+	// DryRun marks a message sent in dry-run mode, no provider was called and
+	// ProviderMessageID is simulated.
+	DryRun bool `gorm:"default:false" json:"dry_run,omitempty"`
+	// Attempts counts the sends of the message that failed. The scheduler stops retrying it
+	// after SCHEDULER_MAX_ATTEMPTS.
+	Attempts  int       `gorm:"default:0" json:"attempts"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// ExternalRef identifies a message in an upstream system, e.g. the order that triggered it.
+// A reference is unique per tenant, see GET /api/messages/by-ref/{system}/{id}.
+type ExternalRef struct {
+	System string `json:"system" binding:"required,max=64,excludesall=/" maxLength:"64" example:"orders"`
+	ID     string `json:"id" binding:"required,max=255,excludesall=/" maxLength:"255" example:"SO-10045"`
+}
+
+// ModerationDecision is the verdict of the pre-send moderation step.
+type ModerationDecision string
+
+const (
+	ModerationApprove ModerationDecision = "approve"
+	// ModerationHold holds the message for review, see POST /api/messages/{id}/release.
+	ModerationHold ModerationDecision = "hold"
+	// ModerationReject cancels the message.
+	ModerationReject ModerationDecision = "reject"
+)
+
+// Valid reports whether d is a known decision.
+func (d ModerationDecision) Valid() bool {
+	return d == ModerationApprove || d == ModerationHold || d == ModerationReject
+}
+
+// ModerationResult is a moderation decision and why it was taken. It is also the response
+// expected from the moderation webhook.
+type ModerationResult struct {
+	Decision ModerationDecision `json:"decision"`
+	Reason   string             `json:"reason,omitempty"`
+}
+
+// ModerationRequest is posted to the moderation webhook.
+type ModerationRequest struct {
+	MessageID uint    `json:"message_id"`
+	Tenant    string  `json:"tenant,omitempty"`
+	Channel   Channel `json:"channel"`
+	Content   string  `json:"content"`
+}
+
+// DeliveryChannel returns the message channel, SMS when it is not set.
+func (m Message) DeliveryChannel() Channel {
+	if m.Channel == "" {
+		return ChannelSMS
+	}
+	return m.Channel
+}
+
+// In returns the message with its timestamps in loc, for report endpoints. An unset
+// SentAt stays the zero time.
+func (m Message) In(loc *time.Location) Message {
+	if !m.SentAt.IsZero() {
+		m.SentAt = m.SentAt.In(loc)
+	}
+	m.CreatedAt = m.CreatedAt.In(loc)
+	m.UpdatedAt = m.UpdatedAt.In(loc)
+	for _, at := range []**time.Time{&m.ScheduledAt, &m.DeliveredAt} {
+		if *at != nil {
+			local := (*at).In(loc)
+			*at = &local
+		}
+	}
+	return m
+}
+
+// MaxPriority is the highest message priority. Aged messages never exceed it.
+const MaxPriority = 9
+
+// EffectivePriority is the priority m is selected with at now: one level is added for
+// every agingInterval m has been waiting since it became due, so low priority messages
+// are not starved by a constant stream of high priority ones. A zero agingInterval
+// disables aging. The PostgreSQL selector implements the same rule.
+func EffectivePriority(m Message, now time.Time, agingInterval time.Duration) int {
+	if agingInterval <= 0 {
+		return m.Priority
+	}
+
+	since := m.CreatedAt
+	if m.ScheduledAt != nil {
+		since = *m.ScheduledAt
+	}
+	waited := now.Sub(since)
+	if waited <= 0 {
+		return m.Priority
+	}
+
+	priority := m.Priority + int(waited/agingInterval)
+	if priority > MaxPriority || priority < m.Priority {
+		return MaxPriority
+	}
+	return priority
+}
+
+// DryRunProviderIDPrefix starts the simulated provider message ID of every dry-run send.
+// A message marked sent with such an ID is stored as dry run.
+const DryRunProviderIDPrefix = "dry-run-"
+
+// IsDryRunProviderID reports whether providerMessageID was simulated by a dry-run send.
+func IsDryRunProviderID(providerMessageID string) bool {
+	return strings.HasPrefix(providerMessageID, DryRunProviderIDPrefix)
 }
 
+// MaxContentLength is the longest message content accepted, in characters.
+const MaxContentLength = 160
+
 type SendMessageRequest struct {
-	ID             uint   `json:"id" example:"5"`
-	Content        string `json:"content" example:"message-service - Project"`
-	RecipientPhone string `json:"recipient_phone" example:"+905551111111"`
+	ID uint `json:"id" binding:"required" example:"5"`
+	// Content is required unless TemplateID is set.
+	Content string `json:"content" binding:"required_without=TemplateID,max=160" maxLength:"160" example:"message-service - Project"`
+	// Channel defaults to sms. Email messages need RecipientEmail instead of RecipientPhone.
+	Channel        Channel `json:"channel,omitempty" binding:"omitempty,oneof=sms email push" enums:"sms,email,push" example:"sms"`
+	RecipientPhone string  `json:"recipient_phone" binding:"required_unless=Channel email" example:"+905551111111"`
+	RecipientEmail string  `json:"recipient_email,omitempty" binding:"required_if=Channel email,omitempty,email" example:"ada@example.com"`
+	// ScheduledAt delays delivery until the given time. Omit it to send immediately.
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty" example:"2025-01-01T18:00:00Z"`
+	// TemplateID renders the content from a stored template with Variables instead.
+	TemplateID *uint             `json:"template_id,omitempty" example:"3"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	// Hold stores the message for review instead of sending it, see POST /api/messages/{id}/release.
+	Hold bool `json:"hold,omitempty" example:"false"`
+	// ExternalRef records the ID of the message in the calling system, so it can be looked
+	// up without storing ours.
+	ExternalRef *ExternalRef `json:"external_ref,omitempty"`
+	// Priority changes the priority the scheduler claims the message with, 0 to MaxPriority.
+	// Omit it to keep the stored priority.
+	Priority *int `json:"priority,omitempty" binding:"omitempty,min=0,max=9" minimum:"0" maximum:"9" example:"9"`
+}
+
+// MessagePatchRequest changes a stored message. Hold can only be set, releasing a message
+// goes through POST /api/messages/{id}/release.
+type MessagePatchRequest struct {
+	Hold *bool `json:"hold" binding:"required" example:"true"`
+}
+
+// BackfillMessage is a message a legacy system already sent, imported with its original
+// timestamps and provider outcome.
+type BackfillMessage struct {
+	Content string  `json:"content" binding:"required" example:"Your order has shipped"`
+	Channel Channel `json:"channel,omitempty" binding:"omitempty,oneof=sms email push" enums:"sms,email,push" example:"sms"`
+	// RecipientPhone is normalized like the phone of a new message.
+	RecipientPhone string `json:"recipient_phone" binding:"required_unless=Channel email" example:"+905551111111"`
+	RecipientEmail string `json:"recipient_email,omitempty" binding:"required_if=Channel email,omitempty,email" example:"ada@example.com"`
+	Tenant         string `json:"tenant,omitempty" binding:"max=255" maxLength:"255" example:"acme"`
+	// CreatedAt defaults to SentAt.
+	CreatedAt         *time.Time     `json:"created_at,omitempty" example:"2023-05-01T09:59:58Z"`
+	SentAt            time.Time      `json:"sent_at" binding:"required" example:"2023-05-01T10:00:00Z"`
+	ProviderMessageID string         `json:"provider_message_id,omitempty" binding:"max=255" maxLength:"255" example:"SM0a1b2c3d"`
+	DeliveryStatus    DeliveryStatus `json:"delivery_status,omitempty" binding:"omitempty,oneof=delivered failed undelivered" enums:"delivered,failed,undelivered" example:"delivered"`
+	DeliveredAt       *time.Time     `json:"delivered_at,omitempty" example:"2023-05-01T10:00:04Z"`
+	ExternalRef       *ExternalRef   `json:"external_ref,omitempty"`
+}
+
+// BackfillRequest imports up to 1000 messages at once, all or none of them.
+type BackfillRequest struct {
+	Messages []BackfillMessage `json:"messages" binding:"required,min=1,max=1000,dive"`
+}
+
+// BackfillResult lists the IDs of the imported messages in request order.
+type BackfillResult struct {
+	Imported int    `json:"imported" example:"2"`
+	IDs      []uint `json:"ids" example:"101,102"`
+}
+
+// Template is a stored message body with {{name}} style variables.
+type Template struct {
+	ID uint `json:"id" example:"3"`
+	// Tenant is the tenant of the key that created the template, only its keys and admin
+	// keys see it.
+	Tenant string `json:"tenant,omitempty" example:"acme"`
+	Name   string `json:"name" example:"otp"`
+	Body   string `json:"body" example:"Hi {{name}}, your code is {{code}}"`
+	// Variables lists the variables Body requires, in alphabetical order.
+	Variables []string `json:"variables" example:"code,name"`
+	// OptionalVariables lists the variables Body only tests with if or passes to default,
+	// they render as empty when a send leaves them out.
+	OptionalVariables []string  `json:"optional_variables" example:"promo"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type TemplateRequest struct {
+	Name string `json:"name" binding:"required,max=255" maxLength:"255" example:"otp"`
+	Body string `json:"body" binding:"required" example:"Hi {{name}}, your code is {{code}}"`
+}
+
+// API key scopes. A key may call the routes of the rate limit classes named by its
+// scopes; admin allows every route.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// APIKey is a key issued through the admin API. Only a hash of the key is stored, Prefix
+// is kept to recognize it.
+type APIKey struct {
+	ID     uint     `json:"id" example:"7"`
+	Name   string   `json:"name" example:"billing-service"`
+	Tenant string   `json:"tenant,omitempty" example:"acme"`
+	Scopes []string `json:"scopes" example:"read,write"`
+	Prefix string   `json:"prefix" example:"msk_3Fq9xA1b"`
+	Quota
+	// RotatedAt is when the key was last replaced, RevokedAt when it stopped working.
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=255" maxLength:"255" example:"billing-service"`
+	// Tenant defaults to Name.
+	Tenant string   `json:"tenant,omitempty" binding:"max=255" maxLength:"255" example:"acme"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write admin" example:"read,write"`
+	Quota
+}
+
+// APIKeySecret is an API key together with its secret, returned once when the key is
+// created or rotated.
+type APIKeySecret struct {
+	APIKey
+	Key string `json:"key" example:"msk_3Fq9xA1bR2c8uV0kLmN4pQ7sT6wY5zH1jD3eG9fK2iO"`
+}
+
+// Quota caps the messages an API key may send through POST /api/messages/send per UTC day
+// and month, 0 leaves the period uncapped.
+type Quota struct {
+	Daily   int `json:"daily_quota" binding:"min=0" example:"1000"`
+	Monthly int `json:"monthly_quota" binding:"min=0" example:"20000"`
+}
+
+// QuotaStatus is the usage of an API key in the current day and month.
+type QuotaStatus struct {
+	Daily   QuotaPeriod `json:"daily"`
+	Monthly QuotaPeriod `json:"monthly"`
+}
+
+// Exceeded reports whether the usage went over the quota of either period.
+func (s QuotaStatus) Exceeded() bool {
+	return s.Daily.Exceeded() || s.Monthly.Exceeded()
+}
+
+// QuotaPeriod is the usage of one quota period. Limit is 0 for an uncapped period, which
+// has no Remaining.
+type QuotaPeriod struct {
+	Limit     int       `json:"limit" example:"1000"`
+	Used      int       `json:"used" example:"120"`
+	Remaining *int      `json:"remaining,omitempty" example:"880"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// NewQuotaPeriod returns the usage of used messages against limit until resetsAt.
+func NewQuotaPeriod(limit, used int, resetsAt time.Time) QuotaPeriod {
+	period := QuotaPeriod{Limit: limit, Used: used, ResetsAt: resetsAt}
+	if limit > 0 {
+		remaining := max(limit-used, 0)
+		period.Remaining = &remaining
+	}
+	return period
+}
+
+// Exceeded reports whether the usage went over a capped limit.
+func (p QuotaPeriod) Exceeded() bool {
+	return p.Limit > 0 && p.Used > p.Limit
+}
+
+// ProviderConfig routes the webhook sends of a tenant to its own gateway. The auth key is
+// never returned.
+type ProviderConfig struct {
+	ID         uint      `json:"id" example:"4"`
+	Tenant     string    `json:"tenant" example:"acme"`
+	WebhookURL string    `json:"webhook_url" example:"https://sms.acme.example/send"`
+	AuthKey    string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ProviderConfigRequest struct {
+	Tenant     string `json:"tenant" binding:"required,max=255" maxLength:"255" example:"acme"`
+	WebhookURL string `json:"webhook_url" binding:"required,url" example:"https://sms.acme.example/send"`
+	// AuthKey is sent as x-ins-auth-key with every request to WebhookURL.
+	AuthKey string `json:"auth_key" binding:"required" example:"acme-secret"`
+}
+
+// WorkerResult reports the outcome of a message claimed by an external worker.
+type WorkerResult struct {
+	ID                uint   `json:"id" example:"5"`
+	Success           bool   `json:"success" example:"true"`
+	ProviderMessageID string `json:"provider_message_id" example:"67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"`
+}
+
+type WorkerCompleteRequest struct {
+	WorkerID string         `json:"worker_id" binding:"required" example:"billing-worker-1"`
+	Results  []WorkerResult `json:"results" binding:"required"`
+}
+
+type WorkerClaimResponse struct {
+	WorkerID       string    `json:"worker_id"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	Messages       []Message `json:"messages"`
+}
+
+type WorkerCompleteResponse struct {
+	Completed []uint `json:"completed"`
+	// Rejected lists IDs whose lease had expired or belonged to another worker.
+	Rejected []uint `json:"rejected"`
+}
+
+// DeliveryStatusCallback is the body the SMS provider posts when it learns the outcome
+// of a message. MessageID is the ID the provider returned for the send.
+type DeliveryStatusCallback struct {
+	MessageID string         `json:"message_id" binding:"required" example:"67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"`
+	Status    DeliveryStatus `json:"status" binding:"required,oneof=delivered failed undelivered" enums:"delivered,failed,undelivered" example:"delivered"`
+	// Timestamp is when the outcome happened, the time the callback is received when omitted.
+	Timestamp *time.Time `json:"timestamp,omitempty" example:"2025-01-01T18:00:05Z"`
+}
+
+// SchedulerStatus describes the scheduler on the instance serving the request.
+type SchedulerStatus struct {
+	Running bool `json:"running"`
+	// Leader is the instance currently allowed to run batches.
+	Leader      string `json:"leader,omitempty"`
+	IsLeader    bool   `json:"is_leader"`
+	PauseReason string `json:"pause_reason,omitempty"`
+}
+
+// ChannelSchedule is how often the scheduler runs batches of one channel and how many
+// messages each claims.
+type ChannelSchedule struct {
+	Channel   Channel `json:"channel" example:"email"`
+	BatchSize int     `json:"batch_size" example:"500"`
+	Interval  string  `json:"interval" example:"5m0s"`
+}
+
+type ChannelScheduleRequest struct {
+	BatchSize int    `json:"batch_size" binding:"required,min=1,max=1000" example:"500"`
+	Interval  string `json:"interval" binding:"required" example:"5m"`
+}
+
+// OutboundLimits caps how many messages per second are handed to the providers, with
+// bursts of up to Burst messages. A zero Rate disables the cap.
+type OutboundLimits struct {
+	Rate  float64 `json:"rate" binding:"min=0" example:"50"`
+	Burst int     `json:"burst" binding:"required,min=1" example:"100"`
+}
+
+// BatchResult counts what happened to the messages claimed by one scheduled batch.
+// Skipped messages were deferred to a later batch without calling the provider.
+type BatchResult struct {
+	Claimed int
+	Sent    int
+	Failed  int
+	Skipped int
+	Errors  []string
+}
+
+// MaxBatchErrors bounds how many distinct errors a BatchResult keeps.
+const MaxBatchErrors = 5
+
+// AddError records err unless an identical message or MaxBatchErrors errors were already recorded.
+func (r *BatchResult) AddError(err error) {
+	if err == nil || len(r.Errors) >= MaxBatchErrors {
+		return
+	}
+	msg := err.Error()
+	for _, existing := range r.Errors {
+		if existing == msg {
+			return
+		}
+	}
+	r.Errors = append(r.Errors, msg)
+}
+
+// SchedulerRun is the persisted history entry of one scheduler tick.
+type SchedulerRun struct {
+	ID         uint      `json:"id" example:"42"`
+	InstanceID string    `json:"instance_id" example:"message-service-7f9c-1"`
+	Channel    Channel   `json:"channel" example:"sms"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	BatchSize  int       `json:"batch_size" example:"2"`
+	Claimed    int       `json:"claimed" example:"2"`
+	Sent       int       `json:"sent" example:"1"`
+	Failed     int       `json:"failed" example:"1"`
+	Skipped    int       `json:"skipped" example:"0"`
+	// Errors summarizes the distinct errors of the run, one per line.
+	Errors string `json:"errors,omitempty" example:"unexpected status code: 500"`
+}
+
+// In returns the run with its timestamps in loc, for report endpoints.
+func (r SchedulerRun) In(loc *time.Location) SchedulerRun {
+	r.StartedAt = r.StartedAt.In(loc)
+	r.FinishedAt = r.FinishedAt.In(loc)
+	return r
+}
+
+// BatchReport is the summary of one scheduler batch posted to BATCH_REPORT_URL.
+type BatchReport struct {
+	InstanceID string    `json:"instance_id"`
+	Channel    Channel   `json:"channel"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	BatchSize  int       `json:"batch_size"`
+	Claimed    int       `json:"claimed"`
+	Sent       int       `json:"sent"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	// Errors lists the distinct errors of the batch, at most MaxBatchErrors.
+	Errors []string `json:"errors"`
+}
+
+// Backlog counts the pending messages that are due. OldestDueAt is when the longest
+// waiting of them became due, nil when there is none.
+type Backlog struct {
+	Pending     int64
+	OldestDueAt *time.Time
+}
+
+// MessageStats summarizes messages and the sends of the last day for GET /api/stats.
+type MessageStats struct {
+	Sent int64 `json:"sent" example:"1200"`
+	// Unsent counts pending, sending and failed messages, Failed only the failed ones.
+	Unsent int64 `json:"unsent" example:"40"`
+	Failed int64 `json:"failed" example:"3"`
+	// SentPerHour has one entry per hour of the last 24 hours, oldest first. Backfilled
+	// messages are not counted.
+	SentPerHour []HourlyCount `json:"sent_per_hour"`
+	// AverageProviderLatencyMs is the average duration of a provider call over the last
+	// 24 hours, omitted when there was none.
+	AverageProviderLatencyMs *float64 `json:"average_provider_latency_ms,omitempty" example:"182.5"`
+	// CacheHitRate is the share of message lookups of the last 24 hours answered from the
+	// cache, omitted when there was none.
+	CacheHitRate *float64 `json:"cache_hit_rate,omitempty" example:"0.82"`
+}
+
+// HourlyCount is the number of messages of the hour starting at Hour.
+type HourlyCount struct {
+	Hour  time.Time `json:"hour"`
+	Count int64     `json:"count" example:"50"`
+}
+
+// ScalingSignal is polled by the external scaler of the worker deployment.
+type ScalingSignal struct {
+	BacklogDepth            int64 `json:"backlog_depth" example:"1200"`
+	OldestPendingAgeSeconds int64 `json:"oldest_pending_age_seconds" example:"95"`
+	RecommendedReplicas     int   `json:"recommended_replicas" example:"4"`
+}
+
+// Kinds of TuningSuggestion.
+const (
+	TuneIncreaseBatch       = "increase_batch"
+	TuneShortenInterval     = "shorten_interval"
+	TuneIncreaseConcurrency = "increase_concurrency"
+	TuneAddWorker           = "add_worker"
+)
+
+// TuningSuggestion is one change to the scheduler settings the tuning advisor recommends.
+type TuningSuggestion struct {
+	// Channel is the channel the suggestion applies to, empty when it applies to all.
+	Channel Channel `json:"channel,omitempty" example:"sms"`
+	Kind    string  `json:"kind" enums:"increase_batch,shorten_interval,increase_concurrency,add_worker" example:"increase_batch"`
+	// Setting names what to change, a field of PUT /api/scheduler/channels/{channel} or an
+	// environment variable. Current and Suggested are its values. They are empty for add_worker.
+	Setting   string `json:"setting,omitempty" example:"batch_size"`
+	Current   string `json:"current,omitempty" example:"100"`
+	Suggested string `json:"suggested,omitempty" example:"250"`
+	Reason    string `json:"reason" example:"8 of the last 10 batches were full and 1200 messages are pending"`
+}
+
+// ChannelThroughput summarizes the recent scheduler runs of one channel.
+type ChannelThroughput struct {
+	Channel   Channel `json:"channel" example:"sms"`
+	BatchSize int     `json:"batch_size" example:"100"`
+	Interval  string  `json:"interval" example:"1m0s"`
+	// Runs is how many recent runs were analyzed, FullBatches how many claimed a full batch.
+	Runs          int     `json:"runs" example:"10"`
+	FullBatches   int     `json:"full_batches" example:"8"`
+	SentPerMinute float64 `json:"sent_per_minute" example:"85.5"`
+	// AvgBatchSeconds is how long a batch took on average, AvgSendMillis how long one send
+	// took, estimated from the batch duration and the send concurrency.
+	AvgBatchSeconds float64 `json:"avg_batch_seconds" example:"12.4"`
+	AvgSendMillis   float64 `json:"avg_send_millis" example:"480"`
+}
+
+// TuningReport is the analysis behind the tuning suggestions.
+type TuningReport struct {
+	GeneratedAt  time.Time `json:"generated_at"`
+	BacklogDepth int64     `json:"backlog_depth" example:"1200"`
+	// BacklogGrowthPerMinute is how fast the backlog grew over the samples the advisor
+	// kept, negative while it shrinks.
+	BacklogGrowthPerMinute float64             `json:"backlog_growth_per_minute" example:"14.2"`
+	Channels               []ChannelThroughput `json:"channels"`
+	Suggestions            []TuningSuggestion  `json:"suggestions"`
+}
+
+// MessageEventType names a message lifecycle event.
+type MessageEventType string
+
+const (
+	EventMessageCreated MessageEventType = "message.created"
+	EventMessageSent    MessageEventType = "message.sent"
+	EventMessageFailed  MessageEventType = "message.failed"
+)
+
+// MessageEvent is published to EVENTS_STREAM when a message is accepted or a send attempt
+// ends. Delivery is at least once, consumers deduplicate on ID.
+type MessageEvent struct {
+	ID                string           `json:"id"`
+	Type              MessageEventType `json:"type"`
+	MessageID         uint             `json:"message_id"`
+	Channel           Channel          `json:"channel,omitempty"`
+	Tenant            string           `json:"tenant,omitempty"`
+	ProviderMessageID string           `json:"provider_message_id,omitempty"`
+	// Error is why the send failed, on message.failed events only.
+	Error      string    `json:"error,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// MessageAuditKind tells status changes and send attempts apart in the audit trail.
+type MessageAuditKind string
+
+const (
+	AuditStatusChange MessageAuditKind = "status_change"
+	AuditSendAttempt  MessageAuditKind = "send_attempt"
+)
+
+// MessageAuditEntry is one step of the audit trail of a message, a change of its state or
+// an attempt to hand it to a provider.
+type MessageAuditEntry struct {
+	ID        uint             `json:"id" example:"7"`
+	MessageID uint             `json:"message_id" example:"5"`
+	Kind      MessageAuditKind `json:"kind" enums:"status_change,send_attempt" example:"send_attempt"`
+	// Status is the status the message changed to, empty when the change kept it.
+	Status MessageStatus `json:"status,omitempty" example:"sent"`
+	// Detail describes the change, e.g. "held" or "scheduled for 2025-01-01T18:00:00Z".
+	Detail string `json:"detail,omitempty" example:"claimed"`
+	// Actor is the API key that made the change, or the component, e.g. "scheduler".
+	Actor    string `json:"actor" example:"scheduler"`
+	Provider string `json:"provider,omitempty" example:"webhook"`
+	// ResponseCode is the HTTP status code the provider answered a send attempt with.
+	ResponseCode *int      `json:"response_code,omitempty" example:"202"`
+	Error        string    `json:"error,omitempty" example:"unexpected status code: 500"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// DiagnosticStatus is the outcome of a diagnostic check, ordered from ok to fail.
+type DiagnosticStatus string
+
+const (
+	DiagnosticOK   DiagnosticStatus = "ok"
+	DiagnosticWarn DiagnosticStatus = "warn"
+	DiagnosticFail DiagnosticStatus = "fail"
+)
+
+// Worse reports whether s is more severe than other.
+func (s DiagnosticStatus) Worse(other DiagnosticStatus) bool {
+	severity := map[DiagnosticStatus]int{DiagnosticOK: 0, DiagnosticWarn: 1, DiagnosticFail: 2}
+	return severity[s] > severity[other]
+}
+
+// DiagnosticCheck is the result of one live check of GET /api/admin/diagnostics.
+type DiagnosticCheck struct {
+	Name   string           `json:"name" example:"database"`
+	Status DiagnosticStatus `json:"status" enums:"ok,warn,fail" example:"ok"`
+	// LatencyMs is the round trip of checks that reach a dependency.
+	LatencyMs *float64 `json:"latency_ms,omitempty" example:"1.8"`
+	Detail    string   `json:"detail,omitempty" example:"12 pending, oldest due 3m ago"`
+}
+
+// DiagnosticsReport is the result of every diagnostic check, its Status the worst of them.
+type DiagnosticsReport struct {
+	Status      DiagnosticStatus  `json:"status" enums:"ok,warn,fail" example:"warn"`
+	InstanceID  string            `json:"instance_id" example:"message-service-7f9c-1"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Checks      []DiagnosticCheck `json:"checks"`
+}
+
+// MessageActionResponse is returned when a message was sent, scheduled, held, released or
+// cancelled. Message names the outcome, the other fields are set where they apply.
+type MessageActionResponse struct {
+	Message           string     `json:"message" example:"Accepted"`
+	MessageID         uint       `json:"messageId" example:"5"`
+	ProviderMessageID string     `json:"providerMessageId,omitempty"`
+	ScheduledAt       *time.Time `json:"scheduledAt,omitempty"`
+	// Reason is why moderation held the message.
+	Reason string `json:"reason,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field" example:"recipient_phone"`
+	Message string `json:"message" example:"must be an E.164 phone number"`
+}
+
+// ValidationErrorResponse is returned with 422 when a request body fails validation.
+type ValidationErrorResponse struct {
+	Error  string       `json:"error" example:"Validation failed"`
+	Fields []FieldError `json:"fields"`
+}
+
+// ErrorResponse is the JSON body returned for failed requests.
+type ErrorResponse struct {
+	Error string `json:"error" example:"Invalid API key"`
 }
@@ -0,0 +1,168 @@
+package mpostgres
+
+import (
+	"context"
+	"errors"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// apiKeyColumns is the column list scanned by scanAPIKey.
+const apiKeyColumns = `id, name, tenant, scopes, prefix, daily_quota, monthly_quota, rotated_at, revoked_at, created_at`
+
+// ErrAPIKeyNotFound is returned when no active API key matches.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrAPIKeyNameTaken is returned when another API key already uses the name.
+var ErrAPIKeyNameTaken = errors.New("api key name already exists")
+
+// APIKeyRepository stores issued API keys by the SHA-256 hash of their secret.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key model.APIKey, hash string) (model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	// GetAPIKeyByHash returns the active key with hash. Revoked keys are not found.
+	GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uint) error
+	// RotateAPIKey replaces the hash and prefix of an active key, the old secret stops working.
+	RotateAPIKey(ctx context.Context, id uint, hash, prefix string) (model.APIKey, error)
+	// SetAPIKeyQuota replaces the quota of an active key.
+	SetAPIKeyQuota(ctx context.Context, id uint, quota model.Quota) (model.APIKey, error)
+}
+
+type apiKeyRepository struct {
+	pool   *pgxpool.Pool
+	logger inslogger.Interface
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool, logger inslogger.Interface) APIKeyRepository {
+	return &apiKeyRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key model.APIKey, hash string) (model.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (name, tenant, scopes, key_hash, prefix, daily_quota, monthly_quota) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
+		RETURNING ` + apiKeyColumns + `
+	`
+	created, err := scanAPIKey(r.pool.QueryRow(ctx, query, key.Name, key.Tenant, key.Scopes, hash, key.Prefix, key.Daily, key.Monthly))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.APIKey{}, ErrAPIKeyNameTaken
+		}
+		logctx.Logger(ctx, r.logger).Errorf("Failed to create API key %q: %v", key.Name, err)
+		return model.APIKey{}, err
+	}
+
+	return created, nil
+}
+
+func (r *apiKeyRepository) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, query, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, id uint) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to revoke API key with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("API key with ID %d revoked", id)
+	return nil
+}
+
+func (r *apiKeyRepository) RotateAPIKey(ctx context.Context, id uint, hash, prefix string) (model.APIKey, error) {
+	query := `
+		UPDATE api_keys 
+		SET key_hash = $1, prefix = $2, rotated_at = NOW() 
+		WHERE id = $3 AND revoked_at IS NULL 
+		RETURNING ` + apiKeyColumns + `
+	`
+	rotated, err := scanAPIKey(r.pool.QueryRow(ctx, query, hash, prefix, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to rotate API key with ID %d: %v", id, err)
+		return model.APIKey{}, err
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("API key with ID %d rotated", id)
+	return rotated, nil
+}
+
+func (r *apiKeyRepository) SetAPIKeyQuota(ctx context.Context, id uint, quota model.Quota) (model.APIKey, error) {
+	query := `
+		UPDATE api_keys 
+		SET daily_quota = $1, monthly_quota = $2 
+		WHERE id = $3 AND revoked_at IS NULL 
+		RETURNING ` + apiKeyColumns + `
+	`
+	updated, err := scanAPIKey(r.pool.QueryRow(ctx, query, quota.Daily, quota.Monthly, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to set the quota of API key with ID %d: %v", id, err)
+		return model.APIKey{}, err
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("API key with ID %d quota set to %d a day and %d a month", id, quota.Daily, quota.Monthly)
+	return updated, nil
+}
+
+func scanAPIKey(row pgx.Row) (model.APIKey, error) {
+	var key model.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Tenant,
+		&key.Scopes,
+		&key.Prefix,
+		&key.Daily,
+		&key.Monthly,
+		&key.RotatedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	return key, err
+}
@@ -0,0 +1,42 @@
+//go:build integration
+
+package mpostgres
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"message-service/internal/pkg/gpostgresql"
+	"message-service/internal/pkg/gpostgresql/pgtest"
+	"message-service/migrations"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// TestMessageRowMatchesMigrations applies every migration and checks that messageRow maps
+// exactly the columns of the messages table, so a migration without its field, or a field
+// without its migration, fails here rather than on the first query.
+func TestMessageRowMatchesMigrations(t *testing.T) {
+	ctx := context.Background()
+	databaseURL := pgtest.CreateDatabase(t, pgtest.AdminURL(t))
+
+	config, err := pgxpool.ParseConfig(databaseURL)
+	require.NoError(t, err)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	require.NoError(t, err)
+	defer pool.Close()
+	_, err = gpostgresql.Migrate(ctx, pool, migrations.Files, 0, inslogger.NewNopLogger())
+	require.NoError(t, err)
+
+	rows, err := pool.Query(ctx, `SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'messages'`)
+	require.NoError(t, err)
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, columns, append(columnsOf(reflect.TypeFor[messageRow]()), colClaimedBy, colLeaseExpiresAt, colDeletedAt))
+}
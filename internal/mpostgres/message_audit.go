@@ -0,0 +1,77 @@
+package mpostgres
+
+import (
+	"context"
+
+	"message-service/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MessageAuditService interface {
+	// RecordMessageEvent appends entry to the audit trail of its message.
+	RecordMessageEvent(ctx context.Context, entry model.MessageAuditEntry) error
+	// ListMessageEvents returns the audit trail of message id, oldest first.
+	ListMessageEvents(ctx context.Context, id uint) ([]model.MessageAuditEntry, error)
+}
+
+type messageAudit struct {
+	pool *pgxpool.Pool
+}
+
+// NewMessageAuditService keeps the audit trail in the message_events table. Entries are
+// deleted together with their message.
+func NewMessageAuditService(pool *pgxpool.Pool) MessageAuditService {
+	return &messageAudit{pool: pool}
+}
+
+func (r *messageAudit) RecordMessageEvent(ctx context.Context, entry model.MessageAuditEntry) error {
+	query := `
+		INSERT INTO message_events (message_id, kind, status, detail, actor, provider, response_code, error, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.pool.Exec(ctx, query, entry.MessageID, entry.Kind, entry.Status, entry.Detail, entry.Actor,
+		entry.Provider, entry.ResponseCode, entry.Error, entry.OccurredAt.UTC())
+	return err
+}
+
+func (r *messageAudit) ListMessageEvents(ctx context.Context, id uint) ([]model.MessageAuditEntry, error) {
+	query := `
+		SELECT id, message_id, kind, status, detail, actor, provider, response_code, error, occurred_at
+		FROM message_events
+		WHERE message_id = $1
+		ORDER BY occurred_at, id
+	`
+	rows, err := r.pool.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.MessageAuditEntry{}
+	for rows.Next() {
+		var entry model.MessageAuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.MessageID,
+			&entry.Kind,
+			&entry.Status,
+			&entry.Detail,
+			&entry.Actor,
+			&entry.Provider,
+			&entry.ResponseCode,
+			&entry.Error,
+			&entry.OccurredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,51 @@
+package mpostgres
+
+import (
+	"context"
+
+	"message-service/internal/model"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MessageDueChannel is the NOTIFY channel on which the messages_notify_due trigger
+// announces the channel of every inserted message that is due.
+const MessageDueChannel = "messages_due"
+
+type MessageListener interface {
+	// Listen calls notify with the channel of every due message inserted from now on,
+	// until ctx is done or the connection fails. Notifications made while nobody listens
+	// are lost.
+	Listen(ctx context.Context, notify func(channel model.Channel)) error
+}
+
+type messageListener struct {
+	pool *pgxpool.Pool
+}
+
+// NewMessageListener listens on a connection of pool that it takes out of the pool for
+// as long as it listens.
+func NewMessageListener(pool *pgxpool.Pool) MessageListener {
+	return &messageListener{pool: pool}
+}
+
+func (l *messageListener) Listen(ctx context.Context, notify func(channel model.Channel)) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Closed instead of released, a listening connection must not serve other queries.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+MessageDueChannel); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		notify(model.Channel(notification.Payload))
+	}
+}
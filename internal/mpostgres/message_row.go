@@ -0,0 +1,145 @@
+package mpostgres
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// The columns of the messages table. Queries refer to columns only through these, so a
+// misspelt column fails to compile, and TestMessageRowColumns checks that messageRow maps
+// exactly these.
+const (
+	colID                 = "id"
+	colContent            = "content"
+	colRecipientPhone     = "recipient_phone"
+	colStatus             = "status"
+	colSentAt             = "sent_at"
+	colCreatedAt          = "created_at"
+	colUpdatedAt          = "updated_at"
+	colProviderMessageID  = "provider_message_id"
+	colScheduledAt        = "scheduled_at"
+	colPriority           = "priority"
+	colChannel            = "channel"
+	colRecipientEmail     = "recipient_email"
+	colDeliveryStatus     = "delivery_status"
+	colDeliveredAt        = "delivered_at"
+	colTenant             = "tenant"
+	colHeld               = "held"
+	colModerationDecision = "moderation_decision"
+	colModerationReason   = "moderation_reason"
+	colExternalSystem     = "external_system"
+	colExternalID         = "external_id"
+	colBackfilled         = "backfilled"
+	colDryRun             = "dry_run"
+	colAttempts           = "attempts"
+	colClaimedBy          = "claimed_by"
+	colLeaseExpiresAt     = "lease_expires_at"
+	colDeletedAt          = "deleted_at"
+)
+
+// messageRow is a row of the messages table. Its db tags are the only list of the message
+// columns read back: messageColumns is built from them and pgx maps the selected columns
+// back by name, so adding a column is one field here and a mismatch fails every query
+// instead of shifting values into the wrong fields. The claim bookkeeping and deleted_at
+// are written but never read.
+type messageRow struct {
+	ID                 uint                `db:"id"`
+	Content            string              `db:"content"`
+	RecipientPhone     string              `db:"recipient_phone"`
+	Status             model.MessageStatus `db:"status"`
+	SentAt             *time.Time          `db:"sent_at"`
+	CreatedAt          *time.Time          `db:"created_at"`
+	UpdatedAt          *time.Time          `db:"updated_at"`
+	ProviderMessageID  *string             `db:"provider_message_id"`
+	ScheduledAt        *time.Time          `db:"scheduled_at"`
+	Priority           int                 `db:"priority"`
+	Channel            model.Channel       `db:"channel"`
+	RecipientEmail     string              `db:"recipient_email"`
+	DeliveryStatus     *string             `db:"delivery_status"`
+	DeliveredAt        *time.Time          `db:"delivered_at"`
+	Tenant             string              `db:"tenant"`
+	Held               bool                `db:"held"`
+	ModerationDecision *string             `db:"moderation_decision"`
+	ModerationReason   *string             `db:"moderation_reason"`
+	ExternalSystem     *string             `db:"external_system"`
+	ExternalID         *string             `db:"external_id"`
+	Backfilled         bool                `db:"backfilled"`
+	DryRun             bool                `db:"dry_run"`
+	Attempts           int                 `db:"attempts"`
+}
+
+// messageColumns is the column list scanned by scanMessages.
+var messageColumns = columnsOf(reflect.TypeFor[messageRow]())
+
+// returningMessage is the RETURNING clause of statements whose rows go to scanMessages.
+var returningMessage = "RETURNING " + strings.Join(messageColumns, ", ")
+
+func columnsOf(row reflect.Type) []string {
+	columns := make([]string, 0, row.NumField())
+	for i := 0; i < row.NumField(); i++ {
+		columns = append(columns, row.Field(i).Tag.Get("db"))
+	}
+	return columns
+}
+
+func (row messageRow) message() model.Message {
+	msg := model.Message{
+		ID:             row.ID,
+		Content:        row.Content,
+		RecipientPhone: row.RecipientPhone,
+		Status:         row.Status,
+		ScheduledAt:    row.ScheduledAt,
+		Priority:       row.Priority,
+		Channel:        row.Channel,
+		RecipientEmail: row.RecipientEmail,
+		DeliveredAt:    row.DeliveredAt,
+		Tenant:         row.Tenant,
+		Held:           row.Held,
+		Backfilled:     row.Backfilled,
+		DryRun:         row.DryRun,
+		Attempts:       row.Attempts,
+	}
+	if row.SentAt != nil {
+		msg.SentAt = *row.SentAt
+	}
+	if row.CreatedAt != nil {
+		msg.CreatedAt = *row.CreatedAt
+	}
+	if row.UpdatedAt != nil {
+		msg.UpdatedAt = *row.UpdatedAt
+	}
+	if row.ProviderMessageID != nil {
+		msg.ProviderMessageID = *row.ProviderMessageID
+	}
+	if row.DeliveryStatus != nil {
+		msg.DeliveryStatus = model.DeliveryStatus(*row.DeliveryStatus)
+	}
+	if row.ModerationDecision != nil {
+		msg.ModerationDecision = model.ModerationDecision(*row.ModerationDecision)
+	}
+	if row.ModerationReason != nil {
+		msg.ModerationReason = *row.ModerationReason
+	}
+	if row.ExternalSystem != nil && row.ExternalID != nil {
+		msg.ExternalRef = &model.ExternalRef{System: *row.ExternalSystem, ID: *row.ExternalID}
+	}
+	return msg
+}
+
+func scanMessages(rows pgx.Rows) ([]model.Message, error) {
+	stored, err := pgx.CollectRows(rows, pgx.RowToStructByName[messageRow])
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []model.Message
+	for _, row := range stored {
+		messages = append(messages, row.message())
+	}
+	return messages, nil
+}
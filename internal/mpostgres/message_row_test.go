@@ -0,0 +1,19 @@
+package mpostgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRowColumns(t *testing.T) {
+	columns := []string{
+		colID, colContent, colRecipientPhone, colStatus, colSentAt, colCreatedAt, colUpdatedAt,
+		colProviderMessageID, colScheduledAt, colPriority, colChannel, colRecipientEmail,
+		colDeliveryStatus, colDeliveredAt, colTenant, colHeld, colModerationDecision,
+		colModerationReason, colExternalSystem, colExternalID, colBackfilled, colDryRun, colAttempts,
+	}
+
+	assert.Equal(t, columns, columnsOf(reflect.TypeFor[messageRow]()))
+}
@@ -2,148 +2,1129 @@ package mpostgres
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"message-service/internal/model"
+	"message-service/internal/pkg/instance"
+	"message-service/internal/pkg/logctx"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/useinsider/go-pkg/inslogger"
 )
 
+// defaultClaimLease is how long rows picked by GetUnsentMessages stay reserved for this
+// instance. A message that is neither sent nor released in time is picked up again.
+const defaultClaimLease = 5 * time.Minute
+
+// ErrMessageNotFound is returned when no message has the requested ID.
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrInvalidStatusTransition is returned when a message is not in a state that allows
+// the requested transition, e.g. marking an already sent message as sending.
+var ErrInvalidStatusTransition = errors.New("invalid message status transition")
+
+// ErrMessageHeld is returned when a held message would be sent. It has to be released first.
+var ErrMessageHeld = errors.New("message is held for review")
+
+// ErrExternalRefConflict is returned when an external reference is already used by another
+// message of the tenant, or a message already has a different one.
+var ErrExternalRefConflict = errors.New("external reference conflict")
+
 type MessageService interface {
-	GetUnsentMessages(ctx context.Context, limit int) ([]model.Message, error)
+	// WithTx runs fn with a MessageService whose statements all run in one transaction,
+	// committed when fn returns nil and rolled back otherwise. Nested calls use savepoints.
+	WithTx(ctx context.Context, fn func(tx MessageService) error) error
+	// GetMessage returns the message with id, or ErrMessageNotFound.
+	GetMessage(ctx context.Context, id uint) (model.Message, error)
+	// CreateMessage stores message as a new pending message, SMS unless it has a channel,
+	// and returns it with its ID. Messages are normally inserted by their producers, this
+	// is for tooling such as the smoke test.
+	CreateMessage(ctx context.Context, message model.Message) (model.Message, error)
+	// GetMessageByExternalRef returns the message of tenant with ref, or ErrMessageNotFound.
+	GetMessageByExternalRef(ctx context.Context, tenant string, ref model.ExternalRef) (model.Message, error)
+	// SetMessageExternalRef records ref on a message. Setting the reference a message
+	// already has is a no-op, any other change returns ErrExternalRefConflict, as does a
+	// reference used by another message of the same tenant.
+	SetMessageExternalRef(ctx context.Context, id uint, ref model.ExternalRef) error
+	// ImportMessages stores messages a legacy system already sent as sent and backfilled,
+	// keeping their created_at, sent_at, provider message ID and delivery status, and
+	// returns them with their IDs. They are never claimed. Either all or none are stored;
+	// an external reference in use returns ErrExternalRefConflict.
+	ImportMessages(ctx context.Context, messages []model.Message) ([]model.Message, error)
+	// GetUnsentMessages claims messages of channel for this instance, of every channel when it is empty.
+	GetUnsentMessages(ctx context.Context, channel model.Channel, limit int) ([]model.Message, error)
+	// ClaimMessagesByID claims the messages among ids that GetUnsentMessages would claim,
+	// pending, due and not held, and skips the others.
+	ClaimMessagesByID(ctx context.Context, ids []uint) ([]model.Message, error)
 	UpdateMessageSent(ctx context.Context, id uint) error
+	// UpdateMessageSentWithProviderID stores a message sent with a simulated
+	// model.DryRunProviderIDPrefix ID as dry run.
+	UpdateMessageSentWithProviderID(ctx context.Context, id uint, providerMessageID string) error
+	// MarkMessageSending moves a pending message to sending and leases it to this instance
+	// like a claim, so a send that never finishes is reaped once the lease expired.
+	MarkMessageSending(ctx context.Context, id uint) error
+	// MarkMessageFailed moves a sending message to failed and counts the failed attempt.
+	MarkMessageFailed(ctx context.Context, id uint) error
+	// RequeueMessage returns a sending message to pending without counting an attempt and
+	// releases its lease, for sends that were put off rather than tried.
+	RequeueMessage(ctx context.Context, id uint) error
+	// ReapExpiredClaims returns sending messages whose lease expired, because the replica or
+	// worker sending them died, to pending and reports their IDs. Sending messages without a
+	// lease count as expired once unchanged for a lease.
+	ReapExpiredClaims(ctx context.Context) ([]uint, error)
+	// DeferMessage returns a sending message to pending, scheduled for until, and releases
+	// its lease, e.g. when it was claimed during quiet hours.
+	DeferMessage(ctx context.Context, id uint, until time.Time) error
+	// RetryFailedMessages moves up to limit failed messages of channel, of every channel when
+	// it is empty, back to pending. Messages that failed maxAttempts times stay failed.
+	RetryFailedMessages(ctx context.Context, channel model.Channel, limit, maxAttempts int) (int64, error)
+	CancelMessage(ctx context.Context, id uint) error
+	// SetMessageHold holds or releases a pending or failed message. Held messages are not
+	// claimed and MarkMessageSending returns ErrMessageHeld for them.
+	SetMessageHold(ctx context.Context, id uint, held bool) error
+	// RecordModeration stores the moderation result of a pending or sending message. A hold
+	// also holds the message and returns it to pending, a rejection cancels it.
+	RecordModeration(ctx context.Context, id uint, result model.ModerationResult) error
+	ScheduleMessage(ctx context.Context, id uint, scheduledAt time.Time) error
+	// SetMessagePriority sets the priority of a pending message, see model.EffectivePriority.
+	SetMessagePriority(ctx context.Context, id uint, priority int) error
 	GetSentMessages(ctx context.Context) ([]model.Message, error)
+	// ListMessages returns the page of messages matching filter, newest first. It fetches
+	// up to one message more than the page size so callers can tell whether a next page exists.
+	ListMessages(ctx context.Context, filter model.MessageFilter) ([]model.Message, error)
+	ClaimMessages(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Message, error)
+	CompleteClaimedMessage(ctx context.Context, workerID string, result model.WorkerResult) (bool, error)
+	// UpdateDeliveryStatus records the outcome the provider reported for the message it knows
+	// as providerMessageID. DeliveredAt is set to at when the message was delivered.
+	UpdateDeliveryStatus(ctx context.Context, providerMessageID string, status model.DeliveryStatus, at time.Time) error
+	// PurgeRecipient removes every message sent to phone, anonymized ones included when mode
+	// is model.PurgeDelete, and returns the IDs of the purged messages.
+	PurgeRecipient(ctx context.Context, phone string, mode model.PurgeMode) ([]uint, error)
+	// PurgeMessagesBefore removes the sent, failed and cancelled messages created before cutoff.
+	PurgeMessagesBefore(ctx context.Context, cutoff time.Time, mode model.PurgeMode) (int64, error)
+	// GetBacklog counts the pending messages that are due, i.e. not scheduled for later, and
+	// not held.
+	GetBacklog(ctx context.Context) (model.Backlog, error)
+	// CountStuckMessages counts the sending messages that have not changed since before,
+	// e.g. because the replica sending them died.
+	CountStuckMessages(ctx context.Context, before time.Time) (int64, error)
+	// GetMessageStats counts the messages by status and the live messages sent per hour
+	// since, only hours with sends are returned.
+	GetMessageStats(ctx context.Context, since time.Time) (model.MessageStats, error)
+}
+
+// dbtx is what the message repository runs statements on: the pool, or a transaction of
+// WithTx.
+type dbtx interface {
+	querier
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// querier is what the listings run on.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 }
 
 type message struct {
-	pool   *pgxpool.Pool
-	logger inslogger.Interface
+	pool dbtx
+	// reads serves the listings, which may lag behind the primary.
+	reads      querier
+	logger     inslogger.Interface
+	instanceID string
+	claimLease time.Duration
+	// priorityAging is how long a pending message waits to gain one priority level.
+	priorityAging time.Duration
+	// clockSkew is how early a scheduled message becomes claimable.
+	clockSkew time.Duration
+	// tenantBatchLimits caps the messages of a tenant one claim picks.
+	tenantBatchLimits map[string]int
 }
 
-func NewMessageService(pool *pgxpool.Pool, logger inslogger.Interface) MessageService {
+// NewMessageService returns the PostgreSQL repository. GetSentMessages and ListMessages
+// query reads, everything else pool. Claims prefer higher priorities and raise the priority
+// of waiting messages by one level per priorityAging; zero disables aging. Scheduled
+// messages become claimable clockSkew before their scheduled_at. A claim picks at most
+// tenantBatchLimits[tenant] messages of a tenant, any number of the tenants it lacks.
+func NewMessageService(pool *pgxpool.Pool, reads *ReadPool, priorityAging, clockSkew time.Duration, tenantBatchLimits map[string]int, logger inslogger.Interface) MessageService {
 	return &message{
-		pool:   pool,
-		logger: logger,
+		pool:              pool,
+		reads:             reads,
+		logger:            logger,
+		instanceID:        instance.ID(),
+		claimLease:        defaultClaimLease,
+		priorityAging:     priorityAging,
+		clockSkew:         clockSkew,
+		tenantBatchLimits: tenantBatchLimits,
+	}
+}
+
+// psql builds the statements of the repository with PostgreSQL placeholders. Subqueries are
+// built with sq.Select instead, since numbering their placeholders again would clash with
+// those of the statement they are nested in.
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Conditions and values shared by the statements of the repository.
+var (
+	now        = sq.Expr("NOW()")
+	notHeld    = sq.Expr("NOT " + colHeld)
+	notDeleted = sq.Eq{colDeletedAt: nil}
+)
+
+// tenantScope restricts a statement to the tenant of the key in ctx, and to nothing for
+// keys without one.
+func tenantScope(ctx context.Context) sq.Eq {
+	if tenant := logctx.TenantScope(ctx); tenant != "" {
+		return sq.Eq{colTenant: tenant}
 	}
+	return sq.Eq{}
 }
 
-func (r *message) GetUnsentMessages(ctx context.Context, limit int) ([]model.Message, error) {
-	var messages []model.Message
+// leaseFor is the lease expiry of a message claimed for lease.
+func leaseFor(lease time.Duration) sq.Sqlizer {
+	return sq.Expr("NOW() + make_interval(secs => ?)", lease.Seconds())
+}
+
+// due matches the messages that are not scheduled, or scheduled no later than skew from now.
+func due(skew time.Duration) sq.Sqlizer {
+	return sq.Or{sq.Eq{colScheduledAt: nil}, sq.Expr(colScheduledAt+" <= NOW() + make_interval(secs => ?)", skew.Seconds())}
+}
 
-	query := `
-		SELECT id, content, recipient_phone, sent, sent_at, created_at, updated_at 
-		FROM messages 
-		WHERE sent = $1 
-		LIMIT $2
-	`
-	rows, err := r.pool.Query(ctx, query, false, limit)
+func exec(ctx context.Context, db dbtx, stmt sq.Sqlizer) (pgconn.CommandTag, error) {
+	sql, args, err := stmt.ToSql()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return db.Exec(ctx, sql, args...)
+}
+
+func query(ctx context.Context, db querier, stmt sq.Sqlizer) (pgx.Rows, error) {
+	sql, args, err := stmt.ToSql()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return db.Query(ctx, sql, args...)
+}
 
-	for rows.Next() {
-		var msg model.Message
-		var sentAt, createdAt, updatedAt *time.Time
-
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Content,
-			&msg.RecipientPhone,
-			&msg.Sent,
-			&sentAt,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
+func scanRow(ctx context.Context, db dbtx, stmt sq.Sqlizer, dest ...any) error {
+	sql, args, err := stmt.ToSql()
+	if err != nil {
+		return err
+	}
+	return db.QueryRow(ctx, sql, args...).Scan(dest...)
+}
 
-		if sentAt != nil {
-			msg.SentAt = *sentAt
-		}
-		if createdAt != nil {
-			msg.CreatedAt = *createdAt
-		}
-		if updatedAt != nil {
-			msg.UpdatedAt = *updatedAt
-		}
+// WithTx runs fn on a copy of the repository bound to a transaction. The listings read
+// the transaction too, so fn sees its own changes.
+func (r *message) WithTx(ctx context.Context, fn func(tx MessageService) error) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		scoped := *r
+		scoped.pool = tx
+		scoped.reads = tx
+		return fn(&scoped)
+	})
+}
+
+// GetUnsentMessages claims up to limit unsent messages for this instance. Rows are
+// locked with FOR UPDATE SKIP LOCKED and leased, so replicas sharing the table never
+// pick the same message.
+func (r *message) GetUnsentMessages(ctx context.Context, channel model.Channel, limit int) ([]model.Message, error) {
+	return r.claim(ctx, r.instanceID, channel, limit, r.claimLease)
+}
 
-		messages = append(messages, msg)
+func (r *message) ClaimMessagesByID(ctx context.Context, ids []uint) ([]model.Message, error) {
+	if len(ids) == 0 {
+		return nil, nil
 	}
 
-	if err := rows.Err(); err != nil {
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = int64(id)
+	}
+
+	claimable := sq.Select(colID).
+		From("messages").
+		Where(sq.Expr(colID+" = ANY(?)", keys)).
+		Where(sq.Eq{colStatus: model.StatusPending}).
+		Where(notHeld).
+		Where(due(r.clockSkew)).
+		Where(notDeleted).
+		Where(tenantScope(ctx)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusSending).
+		Set(colClaimedBy, r.instanceID).
+		Set(colLeaseExpiresAt, leaseFor(r.claimLease)).
+		Set(colUpdatedAt, now).
+		Where(sq.Expr(colID+" IN (?)", claimable)).
+		Suffix(returningMessage)
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
 		return nil, err
 	}
 
+	logctx.Logger(ctx, r.logger).Logf("Claimed %d of %d queued messages", len(messages), len(ids))
 	return messages, nil
 }
 
 func (r *message) UpdateMessageSent(ctx context.Context, id uint) error {
-	now := time.Now()
-	query := `
-        UPDATE messages 
-        SET sent = $1, sent_at = $2, updated_at = $3 
-        WHERE id = $4
-    `
+	return r.UpdateMessageSentWithProviderID(ctx, id, "")
+}
+
+// UpdateMessageSentWithProviderID moves a sending message to sent, as dry run when
+// providerMessageID was simulated.
+func (r *message) UpdateMessageSentWithProviderID(ctx context.Context, id uint, providerMessageID string) error {
+	sentAt := time.Now().UTC()
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusSent).
+		Set(colSentAt, sentAt).
+		Set(colUpdatedAt, sentAt).
+		Set(colProviderMessageID, sq.Expr("NULLIF(?, '')", providerMessageID)).
+		Set(colDryRun, model.IsDryRunProviderID(providerMessageID)).
+		Set(colClaimedBy, nil).
+		Set(colLeaseExpiresAt, nil).
+		Where(sq.Eq{colID: id, colStatus: model.StatusSending}).
+		Where(tenantScope(ctx))
 
-	_, err := r.pool.Exec(ctx, query, true, now, now, id)
+	tag, err := exec(ctx, r.pool, stmt)
 	if err != nil {
-		r.logger.Errorf("Failed to update message with ID %d: %v", id, err)
+		logctx.Logger(ctx, r.logger).Errorf("Failed to update message with ID %d: %v", id, err)
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, model.StatusSending)
+	}
 
-	r.logger.Logf("Message with ID %d updated successfully", id)
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d updated successfully", id)
 	return nil
 }
 
-func (r *message) GetSentMessages(ctx context.Context) ([]model.Message, error) {
-	var messages []model.Message
+// MarkMessageSending moves a pending message to sending before it is handed to the provider.
+func (r *message) MarkMessageSending(ctx context.Context, id uint) error {
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusSending).
+		Set(colClaimedBy, r.instanceID).
+		Set(colLeaseExpiresAt, leaseFor(r.claimLease)).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: model.StatusPending}).
+		Where(notHeld).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to move message with ID %d to %s: %v", id, model.StatusSending, err)
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil
+	}
+	err = fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, model.StatusPending)
+
+	var held bool
+	lookup := psql.Select(colHeld).
+		From("messages").
+		Where(sq.Eq{colID: id, colStatus: model.StatusPending}).
+		Where(tenantScope(ctx))
+	if lookupErr := scanRow(ctx, r.pool, lookup, &held); lookupErr == nil && held {
+		return fmt.Errorf("%w: message %d", ErrMessageHeld, id)
+	}
+	return err
+}
+
+// MarkMessageFailed moves a sending message to failed, counts the attempt and releases its
+// lease.
+func (r *message) MarkMessageFailed(ctx context.Context, id uint) error {
+	return r.transition(ctx, id, model.StatusSending, model.StatusFailed)
+}
+
+// RequeueMessage moves a sending message back to pending and releases its lease.
+func (r *message) RequeueMessage(ctx context.Context, id uint) error {
+	return r.transition(ctx, id, model.StatusSending, model.StatusPending)
+}
+
+func (r *message) DeferMessage(ctx context.Context, id uint, until time.Time) error {
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusPending).
+		Set(colScheduledAt, until.UTC()).
+		Set(colClaimedBy, nil).
+		Set(colLeaseExpiresAt, nil).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: model.StatusSending}).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to defer message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, model.StatusSending)
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d deferred until %s", id, until.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// RetryFailedMessages moves up to limit failed messages with attempts left back to pending,
+// the oldest failures first.
+func (r *message) RetryFailedMessages(ctx context.Context, channel model.Channel, limit, maxAttempts int) (int64, error) {
+	retryable := sq.Select(colID).
+		From("messages").
+		Where(sq.Eq{colStatus: model.StatusFailed}).
+		Where(sq.Lt{colAttempts: maxAttempts}).
+		Where(tenantScope(ctx)).
+		OrderBy(colUpdatedAt).
+		Limit(uint64(max(limit, 0))).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	if channel != "" {
+		retryable = retryable.Where(sq.Eq{colChannel: channel})
+	}
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusPending).
+		Set(colUpdatedAt, now).
+		Where(sq.Expr(colID+" IN (?)", retryable))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// statusOf returns the status of message id, or ErrMessageNotFound. It explains why a
+// conditional update matched no row.
+func (r *message) statusOf(ctx context.Context, id uint, where ...sq.Sqlizer) (model.MessageStatus, error) {
+	lookup := psql.Select(colStatus).
+		From("messages").
+		Where(sq.Eq{colID: id}).
+		Where(tenantScope(ctx))
+	for _, condition := range where {
+		lookup = lookup.Where(condition)
+	}
+
+	var status model.MessageStatus
+	err := scanRow(ctx, r.pool, lookup, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrMessageNotFound
+	}
+	return status, err
+}
+
+// CancelMessage moves a pending or failed message to cancelled so the scheduler skips it.
+// It fails with ErrInvalidStatusTransition once the message is sending or sent.
+func (r *message) CancelMessage(ctx context.Context, id uint) error {
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusCancelled).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: []model.MessageStatus{model.StatusPending, model.StatusFailed}}).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to cancel message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		logctx.Logger(ctx, r.logger).Logf("Message with ID %d cancelled", id)
+		return nil
+	}
+
+	status, err := r.statusOf(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: message %d is %s", ErrInvalidStatusTransition, id, status)
+}
+
+func (r *message) SetMessageHold(ctx context.Context, id uint, held bool) error {
+	stmt := psql.Update("messages").
+		Set(colHeld, held).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: []model.MessageStatus{model.StatusPending, model.StatusFailed}}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to update the hold of message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		logctx.Logger(ctx, r.logger).Logf("Message with ID %d held: %t", id, held)
+		return nil
+	}
+
+	status, err := r.statusOf(ctx, id, notDeleted)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: message %d is %s", ErrInvalidStatusTransition, id, status)
+}
+
+func (r *message) RecordModeration(ctx context.Context, id uint, result model.ModerationResult) error {
+	if !result.Decision.Valid() {
+		return fmt.Errorf("unknown moderation decision %q", result.Decision)
+	}
+
+	stmt := psql.Update("messages").
+		Set(colModerationDecision, result.Decision).
+		Set(colModerationReason, sq.Expr("NULLIF(?, '')", result.Reason)).
+		Set(colUpdatedAt, now)
+	switch result.Decision {
+	case model.ModerationHold:
+		stmt = stmt.Set(colStatus, model.StatusPending).Set(colHeld, true).Set(colClaimedBy, nil).Set(colLeaseExpiresAt, nil)
+	case model.ModerationReject:
+		stmt = stmt.Set(colStatus, model.StatusCancelled).Set(colClaimedBy, nil).Set(colLeaseExpiresAt, nil)
+	}
+	stmt = stmt.
+		Where(sq.Eq{colID: id, colStatus: []model.MessageStatus{model.StatusPending, model.StatusSending}}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to record the moderation of message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		logctx.Logger(ctx, r.logger).Logf("Message with ID %d moderated: %s", id, result.Decision)
+		return nil
+	}
+
+	status, err := r.statusOf(ctx, id, notDeleted)
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: message %d is %s", ErrInvalidStatusTransition, id, status)
+}
 
-	query := `
-		SELECT id, content, recipient_phone, sent, sent_at, created_at, updated_at 
-		FROM messages 
-		WHERE sent = $1
-	`
-	rows, err := r.pool.Query(ctx, query, true)
+// SetMessagePriority sets the priority a pending message is claimed with.
+func (r *message) SetMessagePriority(ctx context.Context, id uint, priority int) error {
+	stmt := psql.Update("messages").
+		Set(colPriority, priority).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: model.StatusPending}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to set the priority of message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, model.StatusPending)
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d has priority %d", id, priority)
+	return nil
+}
+
+// ScheduleMessage sets when a pending message becomes due.
+func (r *message) ScheduleMessage(ctx context.Context, id uint, scheduledAt time.Time) error {
+	stmt := psql.Update("messages").
+		Set(colScheduledAt, scheduledAt.UTC()).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id, colStatus: model.StatusPending}).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to schedule message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, model.StatusPending)
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Message with ID %d scheduled for %s", id, scheduledAt.Format(time.RFC3339))
+	return nil
+}
+
+// transition moves a message that is not held from one status to another, counting an
+// attempt when it moves to failed. Only pending and failed messages can be held, so this only
+// refuses held messages leaving pending.
+func (r *message) transition(ctx context.Context, id uint, from, to model.MessageStatus) error {
+	stmt := psql.Update("messages").
+		Set(colStatus, to).
+		Set(colUpdatedAt, now).
+		Set(colClaimedBy, nil).
+		Set(colLeaseExpiresAt, nil)
+	if to == model.StatusFailed {
+		stmt = stmt.Set(colAttempts, sq.Expr(colAttempts+" + 1"))
+	}
+	stmt = stmt.
+		Where(sq.Eq{colID: id, colStatus: from}).
+		Where(notHeld).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to move message with ID %d to %s: %v", id, to, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: message %d is not %s", ErrInvalidStatusTransition, id, from)
+	}
+
+	return nil
+}
+
+func (r *message) GetMessage(ctx context.Context, id uint) (model.Message, error) {
+	stmt := psql.Select(messageColumns...).
+		From("messages").
+		Where(sq.Eq{colID: id}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return model.Message{}, err
+	}
+	if len(messages) == 0 {
+		return model.Message{}, ErrMessageNotFound
+	}
+	return messages[0], nil
+}
+
+func (r *message) CreateMessage(ctx context.Context, message model.Message) (model.Message, error) {
+	channel := message.Channel
+	if channel == "" {
+		channel = model.ChannelSMS
+	}
+
+	var externalSystem, externalID *string
+	if message.ExternalRef != nil {
+		externalSystem, externalID = &message.ExternalRef.System, &message.ExternalRef.ID
+	}
+
+	stmt := psql.Insert("messages").
+		SetMap(map[string]any{
+			colContent:        message.Content,
+			colRecipientPhone: message.RecipientPhone,
+			colRecipientEmail: message.RecipientEmail,
+			colChannel:        channel,
+			colTenant:         message.Tenant,
+			colPriority:       message.Priority,
+			colScheduledAt:    message.ScheduledAt,
+			colHeld:           message.Held,
+			colStatus:         model.StatusPending,
+			colExternalSystem: externalSystem,
+			colExternalID:     externalID,
+		}).
+		Suffix(returningMessage)
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	created, err := scanMessages(rows)
+	if isUniqueViolation(err) {
+		return model.Message{}, fmt.Errorf("%w: %s/%s is used by another message", ErrExternalRefConflict, message.ExternalRef.System, message.ExternalRef.ID)
+	}
+	if err != nil {
+		return model.Message{}, err
+	}
+	return created[0], nil
+}
+
+func (r *message) ImportMessages(ctx context.Context, messages []model.Message) ([]model.Message, error) {
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback(ctx)
 
-	for rows.Next() {
-		var msg model.Message
-		var sentAt, createdAt, updatedAt *time.Time
-
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Content,
-			&msg.RecipientPhone,
-			&msg.Sent,
-			&sentAt,
-			&createdAt,
-			&updatedAt,
-		)
-		if err != nil {
-			return nil, err
+	imported := make([]model.Message, 0, len(messages))
+	for _, message := range messages {
+		channel := message.Channel
+		if channel == "" {
+			channel = model.ChannelSMS
+		}
+		createdAt := message.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = message.SentAt
+		}
+		var externalSystem, externalID *string
+		if message.ExternalRef != nil {
+			externalSystem, externalID = &message.ExternalRef.System, &message.ExternalRef.ID
 		}
 
-		if sentAt != nil {
-			msg.SentAt = *sentAt
+		stmt := psql.Insert("messages").
+			SetMap(map[string]any{
+				colContent:           message.Content,
+				colRecipientPhone:    message.RecipientPhone,
+				colRecipientEmail:    message.RecipientEmail,
+				colChannel:           channel,
+				colTenant:            message.Tenant,
+				colStatus:            model.StatusSent,
+				colCreatedAt:         createdAt.UTC(),
+				colUpdatedAt:         now,
+				colSentAt:            message.SentAt.UTC(),
+				colProviderMessageID: sq.Expr("NULLIF(?, '')", message.ProviderMessageID),
+				colDeliveryStatus:    sq.Expr("NULLIF(?, '')", string(message.DeliveryStatus)),
+				colDeliveredAt:       message.DeliveredAt,
+				colExternalSystem:    externalSystem,
+				colExternalID:        externalID,
+				colBackfilled:        true,
+			}).
+			Suffix(returningMessage)
+		rows, err := query(ctx, tx, stmt)
+		if err != nil {
+			return nil, err
 		}
-		if createdAt != nil {
-			msg.CreatedAt = *createdAt
+		stored, err := scanMessages(rows)
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: %s/%s is used by another message", ErrExternalRefConflict, message.ExternalRef.System, message.ExternalRef.ID)
 		}
-		if updatedAt != nil {
-			msg.UpdatedAt = *updatedAt
+		if err != nil {
+			return nil, err
 		}
+		imported = append(imported, stored[0])
+	}
 
-		messages = append(messages, msg)
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
+	logctx.Logger(ctx, r.logger).Logf("Imported %d backfilled messages", len(imported))
+	return imported, nil
+}
+
+func (r *message) GetMessageByExternalRef(ctx context.Context, tenant string, ref model.ExternalRef) (model.Message, error) {
+	stmt := psql.Select(messageColumns...).
+		From("messages").
+		Where(sq.Eq{colTenant: tenant, colExternalSystem: ref.System, colExternalID: ref.ID}).
+		Where(notDeleted)
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return model.Message{}, err
+	}
+	if len(messages) == 0 {
+		return model.Message{}, ErrMessageNotFound
+	}
+	return messages[0], nil
+}
+
+func (r *message) SetMessageExternalRef(ctx context.Context, id uint, ref model.ExternalRef) error {
+	stmt := psql.Update("messages").
+		Set(colExternalSystem, ref.System).
+		Set(colExternalID, ref.ID).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colID: id}).
+		Where(notDeleted).
+		Where(tenantScope(ctx)).
+		Where(sq.Or{sq.Eq{colExternalSystem: nil}, sq.Eq{colExternalSystem: ref.System, colExternalID: ref.ID}})
+	tag, err := exec(ctx, r.pool, stmt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s/%s is used by another message", ErrExternalRefConflict, ref.System, ref.ID)
+	}
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to set the external reference of message with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 1 {
+		return nil
+	}
+
+	if _, err := r.statusOf(ctx, id, notDeleted); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: message %d has another external reference", ErrExternalRefConflict, id)
+}
+
+func (r *message) GetSentMessages(ctx context.Context) ([]model.Message, error) {
+	stmt := psql.Select(messageColumns...).
+		From("messages").
+		Where(sq.Eq{colStatus: model.StatusSent}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	rows, err := query(ctx, r.reads, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+func (r *message) ListMessages(ctx context.Context, filter model.MessageFilter) ([]model.Message, error) {
+	stmt := psql.Select(messageColumns...).
+		From("messages").
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+
+	if len(filter.Statuses) > 0 {
+		stmt = stmt.Where(sq.Eq{colStatus: filter.Statuses})
+	}
+	if filter.From != nil {
+		stmt = stmt.Where(sq.GtOrEq{colCreatedAt: filter.From.UTC()})
+	}
+	if filter.To != nil {
+		stmt = stmt.Where(sq.Lt{colCreatedAt: filter.To.UTC()})
+	}
+	if filter.RecipientPhone != "" {
+		stmt = stmt.Where(sq.Eq{colRecipientPhone: filter.RecipientPhone})
+	}
+	if filter.Held != nil {
+		stmt = stmt.Where(sq.Eq{colHeld: *filter.Held})
+	}
+	if filter.Backfilled != nil {
+		stmt = stmt.Where(sq.Eq{colBackfilled: *filter.Backfilled})
+	}
+
+	stmt = stmt.
+		OrderBy(colCreatedAt+" DESC", colID+" DESC").
+		Limit(uint64(max(filter.PageSize+1, 0))).
+		Offset(uint64(max((filter.Page-1)*filter.PageSize, 0)))
+	rows, err := query(ctx, r.reads, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// ClaimMessages moves up to limit pending messages that are due to sending and leases them to
+// workerID. Sending messages whose lease expired are claimable again, so work abandoned
+// by a crashed worker is picked up. Messages are claimed by model.EffectivePriority, then
+// oldest first.
+func (r *message) ClaimMessages(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Message, error) {
+	return r.claim(ctx, workerID, "", limit, lease)
+}
+
+// claimable is the condition of the messages claim may pick: pending messages that are
+// due and not held, and sending ones whose lease expired.
+func (r *message) claimable(ctx context.Context, channel model.Channel) sq.And {
+	condition := sq.And{
+		sq.Or{
+			sq.And{sq.Eq{colStatus: model.StatusPending}, notHeld, due(r.clockSkew)},
+			sq.And{sq.Eq{colStatus: model.StatusSending}, sq.Expr(colLeaseExpiresAt + " < NOW()")},
+		},
+		notDeleted,
+		tenantScope(ctx),
+	}
+	if channel != "" {
+		condition = append(condition, sq.Eq{colChannel: channel})
+	}
+	return condition
+}
+
+// claimOrder is the order claim picks messages in, by model.EffectivePriority, then oldest
+// first.
+func (r *message) claimOrder() sq.Sqlizer {
+	priority := colPriority
+	var args []any
+	if r.priorityAging > 0 {
+		priority += ` + GREATEST(FLOOR(EXTRACT(EPOCH FROM NOW() - COALESCE(` + colScheduledAt + `, ` + colCreatedAt + `)) / ?), 0)`
+		args = append(args, r.priorityAging.Seconds())
+	}
+	args = append(args, model.MaxPriority)
+	return sq.Expr(`LEAST(`+priority+`, ?) DESC, `+colCreatedAt+`, `+colID, args...)
+}
+
+// claim implements ClaimMessages restricted to channel, or to no channel when it is empty.
+func (r *message) claim(ctx context.Context, workerID string, channel model.Channel, limit int, lease time.Duration) ([]model.Message, error) {
+	rows, err := query(ctx, r.pool, r.claimStatement(ctx, workerID, channel, limit, lease))
+	if err != nil {
 		return nil, err
 	}
 
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Worker %s claimed %d messages", workerID, len(messages))
 	return messages, nil
 }
+
+// claimStatement is the statement of claim. With tenant batch limits a tenant gets at most
+// its limit of the claimed messages.
+func (r *message) claimStatement(ctx context.Context, workerID string, channel model.Channel, limit int, lease time.Duration) sq.UpdateBuilder {
+	candidates := r.claimable(ctx, channel)
+	if len(r.tenantBatchLimits) > 0 {
+		tenants := make([]string, 0, len(r.tenantBatchLimits))
+		limits := make([]int32, 0, len(r.tenantBatchLimits))
+		for tenant, batchLimit := range r.tenantBatchLimits {
+			tenants, limits = append(tenants, tenant), append(limits, int32(batchLimit))
+		}
+		// FOR UPDATE refuses window functions, so the messages within the limit of their
+		// tenant are ranked in a subquery and only locked by the outer one.
+		ranked := sq.Select(colID, colTenant).
+			Column(sq.Expr("ROW_NUMBER() OVER (PARTITION BY "+colTenant+" ORDER BY ?) AS tenant_rank", r.claimOrder())).
+			From("messages").
+			Where(r.claimable(ctx, channel))
+		withinLimit := sq.Select(colID).
+			FromSelect(ranked, "ranked").
+			JoinClause("LEFT JOIN unnest(?::text[], ?::int[]) AS tenant_limit(tenant, batch_limit) USING (tenant)", tenants, limits).
+			Where("tenant_limit.batch_limit IS NULL OR ranked.tenant_rank <= tenant_limit.batch_limit")
+		candidates = append(candidates, sq.Expr(colID+" IN (?)", withinLimit))
+	}
+
+	picked := sq.Select(colID).
+		From("messages").
+		Where(candidates).
+		OrderByClause(r.claimOrder()).
+		Limit(uint64(max(limit, 0))).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	return psql.Update("messages").
+		Set(colStatus, model.StatusSending).
+		Set(colClaimedBy, workerID).
+		Set(colLeaseExpiresAt, leaseFor(lease)).
+		Set(colUpdatedAt, now).
+		Where(sq.Expr(colID+" IN (?)", picked)).
+		Suffix(returningMessage)
+}
+
+// CompleteClaimedMessage finalizes a message leased to workerID: sending→sent on success,
+// sending→failed otherwise. It reports false when the worker no longer holds the lease.
+func (r *message) CompleteClaimedMessage(ctx context.Context, workerID string, result model.WorkerResult) (bool, error) {
+	stmt := psql.Update("messages")
+	if result.Success {
+		stmt = stmt.
+			Set(colStatus, model.StatusSent).
+			Set(colSentAt, now).
+			Set(colProviderMessageID, sq.Expr("NULLIF(?, '')", result.ProviderMessageID))
+	} else {
+		stmt = stmt.
+			Set(colStatus, model.StatusFailed).
+			Set(colAttempts, sq.Expr(colAttempts+" + 1"))
+	}
+	stmt = stmt.
+		Set(colUpdatedAt, now).
+		Set(colClaimedBy, nil).
+		Set(colLeaseExpiresAt, nil).
+		Where(sq.Eq{colID: result.ID, colStatus: model.StatusSending, colClaimedBy: workerID}).
+		Where(sq.Expr(colLeaseExpiresAt + " >= NOW()")).
+		Where(tenantScope(ctx))
+
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to complete message with ID %d: %v", result.ID, err)
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *message) UpdateDeliveryStatus(ctx context.Context, providerMessageID string, status model.DeliveryStatus, at time.Time) error {
+	stmt := psql.Update("messages").
+		Set(colDeliveryStatus, status).
+		Set(colUpdatedAt, now)
+	if status == model.DeliveryDelivered {
+		stmt = stmt.Set(colDeliveredAt, at.UTC())
+	}
+	stmt = stmt.
+		Where(sq.Eq{colProviderMessageID: providerMessageID}).
+		Where(tenantScope(ctx))
+	tag, err := exec(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to update delivery status of provider message %s: %v", providerMessageID, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMessageNotFound
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Provider message %s is %s", providerMessageID, status)
+	return nil
+}
+
+// anonymizedRecipient is the SQL expression an anonymized recipient column is replaced with.
+// Empty recipients stay empty so SMS and email messages remain distinguishable.
+func anonymizedRecipient(column string) sq.Sqlizer {
+	return sq.Expr(`CASE WHEN ` + column + ` = '' THEN '' ELSE 'sha256:' || encode(sha256(convert_to(` + column + `, 'UTF8')), 'hex') END`)
+}
+
+// PurgeRecipient matches phone both in clear and hashed, so a delete also removes the
+// messages an earlier anonymization left behind.
+func (r *message) PurgeRecipient(ctx context.Context, phone string, mode model.PurgeMode) ([]uint, error) {
+	condition := sq.Expr(colRecipientPhone+` IN (?::text, 'sha256:' || encode(sha256(convert_to(?::text, 'UTF8')), 'hex'))`, phone, phone)
+	ids, err := r.purge(ctx, condition, mode)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to purge messages of a recipient: %v", err)
+		return nil, err
+	}
+
+	logctx.Logger(ctx, r.logger).Logf("Purged %d messages of a recipient (%s)", len(ids), mode)
+	return ids, nil
+}
+
+func (r *message) PurgeMessagesBefore(ctx context.Context, cutoff time.Time, mode model.PurgeMode) (int64, error) {
+	condition := sq.And{
+		sq.Lt{colCreatedAt: cutoff.UTC()},
+		sq.Eq{colStatus: []model.MessageStatus{model.StatusSent, model.StatusFailed, model.StatusCancelled}},
+	}
+	ids, err := r.purge(ctx, condition, mode)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to purge messages created before %s: %v", cutoff.Format(time.RFC3339), err)
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
+
+func (r *message) GetBacklog(ctx context.Context) (model.Backlog, error) {
+	stmt := psql.Select("COUNT(*)", "MIN(COALESCE("+colScheduledAt+", "+colCreatedAt+"))").
+		From("messages").
+		Where(sq.Eq{colStatus: model.StatusPending}).
+		Where(notHeld).
+		Where(due(0)).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	var backlog model.Backlog
+	if err := scanRow(ctx, r.pool, stmt, &backlog.Pending, &backlog.OldestDueAt); err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to count pending messages: %v", err)
+		return model.Backlog{}, err
+	}
+
+	return backlog, nil
+}
+
+func (r *message) ReapExpiredClaims(ctx context.Context) ([]uint, error) {
+	expired := sq.Or{
+		sq.Expr(colLeaseExpiresAt + " < NOW()"),
+		sq.And{sq.Eq{colLeaseExpiresAt: nil}, sq.Expr(colUpdatedAt+" < NOW() - make_interval(secs => ?)", r.claimLease.Seconds())},
+	}
+	stmt := psql.Update("messages").
+		Set(colStatus, model.StatusPending).
+		Set(colClaimedBy, nil).
+		Set(colLeaseExpiresAt, nil).
+		Set(colUpdatedAt, now).
+		Where(sq.Eq{colStatus: model.StatusSending}).
+		Where(notDeleted).
+		Where(tenantScope(ctx)).
+		Where(expired).
+		Suffix("RETURNING " + colID)
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to reap expired claims: %v", err)
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uint])
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *message) CountStuckMessages(ctx context.Context, before time.Time) (int64, error) {
+	stmt := psql.Select("COUNT(*)").
+		From("messages").
+		Where(sq.Eq{colStatus: model.StatusSending}).
+		Where(sq.Lt{colUpdatedAt: before.UTC()}).
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	var count int64
+	if err := scanRow(ctx, r.pool, stmt, &count); err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to count stuck messages: %v", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// countWhere is a COUNT(*) column of the rows matching condition.
+func countWhere(condition sq.Sqlizer) sq.Sqlizer {
+	return sq.Expr("COUNT(*) FILTER (WHERE ?)", condition)
+}
+
+func (r *message) GetMessageStats(ctx context.Context, since time.Time) (model.MessageStats, error) {
+	stmt := psql.Select().
+		Column(countWhere(sq.Eq{colStatus: model.StatusSent})).
+		Column(countWhere(sq.Eq{colStatus: []model.MessageStatus{model.StatusPending, model.StatusSending, model.StatusFailed}})).
+		Column(countWhere(sq.Eq{colStatus: model.StatusFailed})).
+		From("messages").
+		Where(notDeleted).
+		Where(tenantScope(ctx))
+	var stats model.MessageStats
+	if err := scanRow(ctx, r.pool, stmt, &stats.Sent, &stats.Unsent, &stats.Failed); err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to count messages by status: %v", err)
+		return model.MessageStats{}, err
+	}
+
+	hourly := psql.Select("date_trunc('hour', "+colSentAt+")", "COUNT(*)").
+		From("messages").
+		Where(sq.Eq{colStatus: model.StatusSent, colBackfilled: false}).
+		Where(sq.GtOrEq{colSentAt: since.UTC()}).
+		Where(notDeleted).
+		Where(tenantScope(ctx)).
+		GroupBy("1").
+		OrderBy("1")
+	rows, err := query(ctx, r.pool, hourly)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to count sends per hour: %v", err)
+		return model.MessageStats{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hour model.HourlyCount
+		if err := rows.Scan(&hour.Hour, &hour.Count); err != nil {
+			return model.MessageStats{}, err
+		}
+		hour.Hour = hour.Hour.UTC()
+		stats.SentPerHour = append(stats.SentPerHour, hour)
+	}
+	return stats, rows.Err()
+}
+
+// purge deletes or anonymizes the messages matching condition and returns their IDs.
+// Anonymizing skips messages that already are.
+func (r *message) purge(ctx context.Context, condition sq.Sqlizer, mode model.PurgeMode) ([]uint, error) {
+	var stmt sq.Sqlizer
+	switch mode {
+	case model.PurgeDelete:
+		stmt = psql.Delete("messages").
+			Where(condition).
+			Where(tenantScope(ctx)).
+			Suffix("RETURNING " + colID)
+	case model.PurgeAnonymize:
+		stmt = psql.Update("messages").
+			Set(colContent, "").
+			Set(colRecipientPhone, anonymizedRecipient(colRecipientPhone)).
+			Set(colRecipientEmail, anonymizedRecipient(colRecipientEmail)).
+			Set(colStatus, sq.Expr("CASE WHEN ? THEN ? ELSE "+colStatus+" END",
+				sq.Eq{colStatus: []model.MessageStatus{model.StatusPending, model.StatusFailed}}, model.StatusCancelled)).
+			Set(colDeletedAt, now).
+			Set(colUpdatedAt, now).
+			Where(condition).
+			Where(tenantScope(ctx)).
+			Where(notDeleted).
+			Suffix("RETURNING " + colID)
+	default:
+		return nil, fmt.Errorf("unknown purge mode %q", mode)
+	}
+
+	rows, err := query(ctx, r.pool, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
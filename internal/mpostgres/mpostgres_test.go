@@ -0,0 +1,36 @@
+package mpostgres
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaimStatementNumbersPlaceholdersOnce checks that the subqueries of a claim share the
+// placeholder numbering of the statement instead of starting again at $1.
+func TestClaimStatementNumbersPlaceholdersOnce(t *testing.T) {
+	r := &message{priorityAging: time.Hour, clockSkew: time.Second, tenantBatchLimits: map[string]int{"acme": 10}}
+	ctx := logctx.WithTenantScope(context.Background(), "acme")
+
+	sql, args, err := r.claimStatement(ctx, "worker-1", model.ChannelSMS, 5, time.Minute).ToSql()
+	require.NoError(t, err)
+
+	var numbers []string
+	for _, match := range regexp.MustCompile(`\$(\d+)`).FindAllStringSubmatch(sql, -1) {
+		numbers = append(numbers, match[1])
+	}
+	want := make([]string, len(args))
+	for i := range args {
+		want[i] = strconv.Itoa(i + 1)
+	}
+	assert.Equal(t, want, numbers)
+	assert.NotContains(t, sql, "?")
+}
@@ -0,0 +1,151 @@
+package mpostgres
+
+import (
+	"context"
+	"errors"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// providerConfigColumns is the column list scanned by scanProviderConfig.
+const providerConfigColumns = `id, tenant, webhook_url, auth_key, created_at, updated_at`
+
+// ErrProviderConfigNotFound is returned when no provider config has the requested ID or tenant.
+var ErrProviderConfigNotFound = errors.New("provider config not found")
+
+// ErrProviderConfigTenantTaken is returned when another provider config already belongs to the tenant.
+var ErrProviderConfigTenantTaken = errors.New("tenant already has a provider config")
+
+type ProviderConfigRepository interface {
+	CreateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error)
+	GetProviderConfig(ctx context.Context, id uint) (model.ProviderConfig, error)
+	// GetTenantProviderConfig returns the config of tenant, ErrProviderConfigNotFound when
+	// it has none.
+	GetTenantProviderConfig(ctx context.Context, tenant string) (model.ProviderConfig, error)
+	ListProviderConfigs(ctx context.Context) ([]model.ProviderConfig, error)
+	UpdateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error)
+	DeleteProviderConfig(ctx context.Context, id uint) error
+}
+
+type providerConfigRepository struct {
+	pool   *pgxpool.Pool
+	logger inslogger.Interface
+}
+
+func NewProviderConfigRepository(pool *pgxpool.Pool, logger inslogger.Interface) ProviderConfigRepository {
+	return &providerConfigRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *providerConfigRepository) CreateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error) {
+	query := `
+		INSERT INTO provider_configs (tenant, webhook_url, auth_key)
+		VALUES ($1, $2, $3)
+		RETURNING ` + providerConfigColumns + `
+	`
+	created, err := scanProviderConfig(r.pool.QueryRow(ctx, query, config.Tenant, config.WebhookURL, config.AuthKey))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.ProviderConfig{}, ErrProviderConfigTenantTaken
+		}
+		logctx.Logger(ctx, r.logger).Errorf("Failed to create provider config of tenant %q: %v", config.Tenant, err)
+		return model.ProviderConfig{}, err
+	}
+
+	return created, nil
+}
+
+func (r *providerConfigRepository) GetProviderConfig(ctx context.Context, id uint) (model.ProviderConfig, error) {
+	query := `SELECT ` + providerConfigColumns + ` FROM provider_configs WHERE id = $1`
+	config, err := scanProviderConfig(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ProviderConfig{}, ErrProviderConfigNotFound
+	}
+	return config, err
+}
+
+func (r *providerConfigRepository) GetTenantProviderConfig(ctx context.Context, tenant string) (model.ProviderConfig, error) {
+	query := `SELECT ` + providerConfigColumns + ` FROM provider_configs WHERE tenant = $1`
+	config, err := scanProviderConfig(r.pool.QueryRow(ctx, query, tenant))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.ProviderConfig{}, ErrProviderConfigNotFound
+	}
+	return config, err
+}
+
+func (r *providerConfigRepository) ListProviderConfigs(ctx context.Context) ([]model.ProviderConfig, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+providerConfigColumns+` FROM provider_configs ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := []model.ProviderConfig{}
+	for rows.Next() {
+		config, err := scanProviderConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+func (r *providerConfigRepository) UpdateProviderConfig(ctx context.Context, config model.ProviderConfig) (model.ProviderConfig, error) {
+	query := `
+		UPDATE provider_configs
+		SET tenant = $1, webhook_url = $2, auth_key = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING ` + providerConfigColumns + `
+	`
+	updated, err := scanProviderConfig(r.pool.QueryRow(ctx, query, config.Tenant, config.WebhookURL, config.AuthKey, config.ID))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return model.ProviderConfig{}, ErrProviderConfigNotFound
+	case isUniqueViolation(err):
+		return model.ProviderConfig{}, ErrProviderConfigTenantTaken
+	case err != nil:
+		logctx.Logger(ctx, r.logger).Errorf("Failed to update provider config with ID %d: %v", config.ID, err)
+		return model.ProviderConfig{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *providerConfigRepository) DeleteProviderConfig(ctx context.Context, id uint) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM provider_configs WHERE id = $1`, id)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to delete provider config with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProviderConfigNotFound
+	}
+
+	return nil
+}
+
+func scanProviderConfig(row pgx.Row) (model.ProviderConfig, error) {
+	var config model.ProviderConfig
+	err := row.Scan(
+		&config.ID,
+		&config.Tenant,
+		&config.WebhookURL,
+		&config.AuthKey,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	return config, err
+}
@@ -0,0 +1,67 @@
+package mpostgres
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// ReadPool runs read-only queries on a read replica when their context allows replication
+// lag, see WithEventualReads, and on the primary otherwise. When Query on the replica fails,
+// the query is run on the primary instead and the replica is skipped for retryAfter, so an
+// unavailable replica costs one failed attempt per retryAfter. Only errors of Query itself
+// fall back: an error while reading the rows, reported by rows.Err(), reaches the caller.
+type ReadPool struct {
+	primary    *pgxpool.Pool
+	replica    *pgxpool.Pool
+	retryAfter time.Duration
+	logger     inslogger.Interface
+	// skipUntil is the Unix nano time until which queries go to the primary.
+	skipUntil atomic.Int64
+}
+
+// NewReadPool queries replica, falling back to primary. A nil replica sends every query to
+// primary.
+func NewReadPool(primary, replica *pgxpool.Pool, retryAfter time.Duration, logger inslogger.Interface) *ReadPool {
+	return &ReadPool{
+		primary:    primary,
+		replica:    replica,
+		retryAfter: retryAfter,
+		logger:     logger,
+	}
+}
+
+type eventualReadsKey struct{}
+
+// WithEventualReads marks ctx as accepting results that lag behind the primary, so a ReadPool
+// may answer its queries from the replica. Without it reads stay on the primary and see every
+// write made before them.
+func WithEventualReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, eventualReadsKey{}, true)
+}
+
+func eventualReads(ctx context.Context) bool {
+	eventual, _ := ctx.Value(eventualReadsKey{}).(bool)
+	return eventual
+}
+
+func (p *ReadPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if p.replica == nil || !eventualReads(ctx) || time.Now().UnixNano() < p.skipUntil.Load() {
+		return p.primary.Query(ctx, sql, args...)
+	}
+
+	rows, err := p.replica.Query(ctx, sql, args...)
+	if err == nil || ctx.Err() != nil {
+		return rows, err
+	}
+
+	p.skipUntil.Store(time.Now().Add(p.retryAfter).UnixNano())
+	logctx.Logger(ctx, p.logger).Warnf("Read replica query failed, using the primary for %s: %v", p.retryAfter, err)
+	return p.primary.Query(ctx, sql, args...)
+}
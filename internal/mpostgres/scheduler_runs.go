@@ -0,0 +1,104 @@
+package mpostgres
+
+import (
+	"context"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type SchedulerRunService interface {
+	// RecordRun stores run and deletes runs older than the retention period.
+	RecordRun(ctx context.Context, run model.SchedulerRun) error
+	// ListRuns returns the latest runs, newest first.
+	ListRuns(ctx context.Context, limit int) ([]model.SchedulerRun, error)
+}
+
+type schedulerRun struct {
+	pool      *pgxpool.Pool
+	retention time.Duration
+	logger    inslogger.Interface
+}
+
+// NewSchedulerRunService keeps runs for retention. A retention of zero or less keeps them forever.
+func NewSchedulerRunService(pool *pgxpool.Pool, retention time.Duration, logger inslogger.Interface) SchedulerRunService {
+	return &schedulerRun{
+		pool:      pool,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+func (r *schedulerRun) RecordRun(ctx context.Context, run model.SchedulerRun) error {
+	query := `
+		INSERT INTO scheduler_runs (instance_id, channel, started_at, finished_at, batch_size, claimed, sent, failed, skipped, errors) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.pool.Exec(ctx, query, run.InstanceID, run.Channel, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.BatchSize,
+		run.Claimed, run.Sent, run.Failed, run.Skipped, run.Errors)
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to record scheduler run: %v", err)
+		return err
+	}
+
+	if r.retention <= 0 {
+		return nil
+	}
+
+	tag, err := r.pool.Exec(ctx, `DELETE FROM scheduler_runs WHERE started_at < $1`, time.Now().UTC().Add(-r.retention))
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Warnf("Failed to prune scheduler runs: %v", err)
+		return nil
+	}
+	if tag.RowsAffected() > 0 {
+		logctx.Logger(ctx, r.logger).Logf("Pruned %d scheduler runs", tag.RowsAffected())
+	}
+
+	return nil
+}
+
+func (r *schedulerRun) ListRuns(ctx context.Context, limit int) ([]model.SchedulerRun, error) {
+	query := `
+		SELECT id, instance_id, channel, started_at, finished_at, batch_size, claimed, sent, failed, skipped, errors 
+		FROM scheduler_runs 
+		ORDER BY started_at DESC, id DESC 
+		LIMIT $1
+	`
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []model.SchedulerRun{}
+	for rows.Next() {
+		var run model.SchedulerRun
+		err := rows.Scan(
+			&run.ID,
+			&run.InstanceID,
+			&run.Channel,
+			&run.StartedAt,
+			&run.FinishedAt,
+			&run.BatchSize,
+			&run.Claimed,
+			&run.Sent,
+			&run.Failed,
+			&run.Skipped,
+			&run.Errors,
+		)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
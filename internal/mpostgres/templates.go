@@ -0,0 +1,154 @@
+package mpostgres
+
+import (
+	"context"
+	"errors"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// templateColumns is the column list scanned by scanTemplate.
+const templateColumns = `id, tenant, name, body, variables, optional_variables, created_at, updated_at`
+
+// uniqueViolation is the PostgreSQL error code for a unique constraint violation.
+const uniqueViolation = "23505"
+
+// ErrTemplateNotFound is returned when no template has the requested ID.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// ErrTemplateNameTaken is returned when another template of the tenant already uses the name.
+var ErrTemplateNameTaken = errors.New("template name already exists")
+
+// TemplateRepository stores message templates. Like messages, a template belongs to a
+// tenant, and with a tenant scope on ctx only the templates of that tenant are found, see
+// logctx.TenantScope.
+type TemplateRepository interface {
+	CreateTemplate(ctx context.Context, template model.Template) (model.Template, error)
+	GetTemplate(ctx context.Context, id uint) (model.Template, error)
+	ListTemplates(ctx context.Context) ([]model.Template, error)
+	UpdateTemplate(ctx context.Context, template model.Template) (model.Template, error)
+	DeleteTemplate(ctx context.Context, id uint) error
+}
+
+type templateRepository struct {
+	pool   *pgxpool.Pool
+	logger inslogger.Interface
+}
+
+func NewTemplateRepository(pool *pgxpool.Pool, logger inslogger.Interface) TemplateRepository {
+	return &templateRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *templateRepository) CreateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	query := `
+		INSERT INTO templates (tenant, name, body, variables, optional_variables) 
+		VALUES ($1, $2, $3, $4, $5) 
+		RETURNING ` + templateColumns + `
+	`
+	created, err := scanTemplate(r.pool.QueryRow(ctx, query, template.Tenant, template.Name, template.Body, template.Variables, template.OptionalVariables))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.Template{}, ErrTemplateNameTaken
+		}
+		logctx.Logger(ctx, r.logger).Errorf("Failed to create template %q: %v", template.Name, err)
+		return model.Template{}, err
+	}
+
+	return created, nil
+}
+
+func (r *templateRepository) GetTemplate(ctx context.Context, id uint) (model.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE id = $1 AND ($2::text = '' OR tenant = $2)`
+	template, err := scanTemplate(r.pool.QueryRow(ctx, query, id, logctx.TenantScope(ctx)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Template{}, ErrTemplateNotFound
+	}
+	return template, err
+}
+
+func (r *templateRepository) ListTemplates(ctx context.Context) ([]model.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE ($1::text = '' OR tenant = $1) ORDER BY id`
+	rows, err := r.pool.Query(ctx, query, logctx.TenantScope(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []model.Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (r *templateRepository) UpdateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	query := `
+		UPDATE templates 
+		SET name = $1, body = $2, variables = $3, optional_variables = $4, updated_at = NOW() 
+		WHERE id = $5 AND ($6::text = '' OR tenant = $6) 
+		RETURNING ` + templateColumns + `
+	`
+	updated, err := scanTemplate(r.pool.QueryRow(ctx, query, template.Name, template.Body, template.Variables, template.OptionalVariables, template.ID, logctx.TenantScope(ctx)))
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return model.Template{}, ErrTemplateNotFound
+	case isUniqueViolation(err):
+		return model.Template{}, ErrTemplateNameTaken
+	case err != nil:
+		logctx.Logger(ctx, r.logger).Errorf("Failed to update template with ID %d: %v", template.ID, err)
+		return model.Template{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *templateRepository) DeleteTemplate(ctx context.Context, id uint) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM templates WHERE id = $1 AND ($2::text = '' OR tenant = $2)`, id, logctx.TenantScope(ctx))
+	if err != nil {
+		logctx.Logger(ctx, r.logger).Errorf("Failed to delete template with ID %d: %v", id, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+func scanTemplate(row pgx.Row) (model.Template, error) {
+	var template model.Template
+	err := row.Scan(
+		&template.ID,
+		&template.Tenant,
+		&template.Name,
+		&template.Body,
+		&template.Variables,
+		&template.OptionalVariables,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	return template, err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
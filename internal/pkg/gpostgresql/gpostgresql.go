@@ -3,8 +3,8 @@ package gpostgresql
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
-	"time"
 
 	"message-service/internal/config"
 
@@ -15,6 +15,15 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// queryExecModes maps DB_STATEMENT_CACHE_MODE to the pgx query exec mode.
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
 type ExecQueryRower interface {
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
@@ -23,26 +32,27 @@ type ExecQueryRower interface {
 func NewDBConnection(ctx context.Context, dbConfig *config.DatabaseConfig, logger inslogger.Interface) (*pgxpool.Pool, error) {
 	var db *pgxpool.Pool
 
-	connString := strings.TrimSpace(fmt.Sprintf(
-		"user=%s password=%s dbname=%s host=%s port=%s",
-		dbConfig.User,
-		dbConfig.Password,
-		dbConfig.Name,
-		dbConfig.Host,
-		fmt.Sprintf("%d", dbConfig.Port),
-	))
-
-	parseConfig, err := pgxpool.ParseConfig(connString)
+	parseConfig, err := pgxpool.ParseConfig(connString(dbConfig))
 	if err != nil {
 		logger.Errorf("Error parsing pool parseConfig: %v", err)
 		return nil, err
 	}
 
-	parseConfig.MaxConns = 10
-	parseConfig.MinConns = 2
-	parseConfig.MaxConnLifetime = 30 * time.Minute
-	parseConfig.MaxConnIdleTime = 10 * time.Minute
-	parseConfig.HealthCheckPeriod = 2 * time.Minute
+	execMode, ok := queryExecModes[dbConfig.StatementCacheMode]
+	if !ok {
+		return nil, fmt.Errorf("unknown statement cache mode %q", dbConfig.StatementCacheMode)
+	}
+
+	parseConfig.MaxConns = int32(dbConfig.MaxConns)
+	parseConfig.MinConns = int32(dbConfig.MinConns)
+	parseConfig.MaxConnLifetime = dbConfig.MaxConnLifetime
+	parseConfig.MaxConnIdleTime = dbConfig.MaxConnIdleTime
+	parseConfig.HealthCheckPeriod = dbConfig.HealthCheckPeriod
+	parseConfig.ConnConfig.DefaultQueryExecMode = execMode
+	parseConfig.ConnConfig.ConnectTimeout = dbConfig.ConnectTimeout
+	// The columns are TIMESTAMP without a zone and pgx writes Go times by their wall clock,
+	// so NOW() must be UTC like the times the service passes in.
+	parseConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
 
 	db, err = pgxpool.NewWithConfig(ctx, parseConfig)
 	if err != nil {
@@ -50,10 +60,41 @@ func NewDBConnection(ctx context.Context, dbConfig *config.DatabaseConfig, logge
 		return nil, err
 	}
 
-	logger.Log("connected to PostgreSQL")
+	logger.Logf("connected to PostgreSQL: max_conns=%d min_conns=%d max_conn_lifetime=%s max_conn_idle_time=%s health_check_period=%s statement_cache_mode=%s connect_timeout=%s",
+		parseConfig.MaxConns, parseConfig.MinConns, parseConfig.MaxConnLifetime, parseConfig.MaxConnIdleTime,
+		parseConfig.HealthCheckPeriod, parseConfig.ConnConfig.DefaultQueryExecMode, parseConfig.ConnConfig.ConnectTimeout)
 	return db, nil
 }
 
+// connString returns DATABASE_URL when it is set and a keyword/value connection string of
+// the discrete fields otherwise. Values are quoted so passwords and paths may hold spaces.
+func connString(dbConfig *config.DatabaseConfig) string {
+	if dbConfig.URL != "" {
+		return dbConfig.URL
+	}
+
+	params := []string{
+		"user=" + quoteConnValue(dbConfig.User),
+		"password=" + quoteConnValue(dbConfig.Password),
+		"dbname=" + quoteConnValue(dbConfig.Name),
+		"host=" + quoteConnValue(dbConfig.Host),
+		"port=" + strconv.Itoa(dbConfig.Port),
+	}
+	if dbConfig.SSLMode != "" {
+		params = append(params, "sslmode="+dbConfig.SSLMode)
+	}
+	if dbConfig.SSLRootCert != "" {
+		params = append(params, "sslrootcert="+quoteConnValue(dbConfig.SSLRootCert))
+	}
+	return strings.Join(params, " ")
+}
+
+func quoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
 func Close(ctx context.Context, pool *pgxpool.Pool, logger inslogger.Interface) {
 	if pool != nil {
 		logger.Log("Closing PostgreSQL connection pool")
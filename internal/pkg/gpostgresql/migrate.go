@@ -0,0 +1,149 @@
+package gpostgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// migrationLockID is the advisory lock that keeps concurrent migrate runs from racing.
+const migrationLockID = 7_336_001
+
+// ErrUntrackedSchema is returned when the schema exists but no migration was recorded, e.g.
+// a database created by the container init scripts. Migrate must then be given a baseline.
+var ErrUntrackedSchema = errors.New("database has tables but no recorded migrations")
+
+type migration struct {
+	version int
+	name    string
+}
+
+// Migrate applies the NNN_name.sql files of files whose version is not recorded in
+// schema_migrations yet, in version order and each in its own transaction. Versions up to
+// baseline are recorded without running them. It returns how many migrations ran.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, files fs.FS, baseline int, logger inslogger.Interface) (int, error) {
+	migrations, err := readMigrations(files)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			logger.Warnf("Failed to release the migration lock: %v", err)
+		}
+	}()
+
+	var untracked bool
+	err = conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NULL AND to_regclass('messages') IS NOT NULL`).Scan(&untracked)
+	if err != nil {
+		return 0, err
+	}
+	if untracked && baseline == 0 {
+		return 0, ErrUntrackedSchema
+	}
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := conn.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	ran := 0
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		if m.version <= baseline {
+			if _, err := conn.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+				return ran, err
+			}
+			logger.Logf("Recorded migration %s as applied", m.name)
+			continue
+		}
+
+		script, err := fs.ReadFile(files, m.name)
+		if err != nil {
+			return ran, err
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+		logger.Logf("Applied migration %s", m.name)
+		ran++
+	}
+
+	return ran, nil
+}
+
+func readMigrations(files fs.FS) ([]migration, error) {
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(names))
+	seen := map[int]string{}
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s does not start with a version number", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+		migrations = append(migrations, migration{version: version, name: name})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
@@ -0,0 +1,47 @@
+// Package pgtest provides throwaway PostgreSQL databases for the integration tests, which
+// run with -tags integration against the server of INTEGRATION_DATABASE_URL.
+package pgtest
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// AdminURL returns INTEGRATION_DATABASE_URL and skips t when it is not set.
+func AdminURL(t *testing.T) string {
+	adminURL := os.Getenv("INTEGRATION_DATABASE_URL")
+	if adminURL == "" {
+		t.Skip("INTEGRATION_DATABASE_URL is not set")
+	}
+	return adminURL
+}
+
+// CreateDatabase creates an empty database next to the one of adminURL and returns its URL.
+// The database is dropped when the test ends.
+func CreateDatabase(t *testing.T, adminURL string) string {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, adminURL)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+	name := "message_service_it_" + logctx.NewID()[:12]
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if _, err := conn.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("failed to drop %s: %v", name, err)
+		}
+	})
+
+	u, err := url.Parse(adminURL)
+	require.NoError(t, err)
+	u.Path = "/" + name
+	return u.String()
+}
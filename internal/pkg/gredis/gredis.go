@@ -0,0 +1,56 @@
+package gredis
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"message-service/internal/config"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// NewRedisClient connects to the standalone server, sentinel-managed master or cluster
+// selected by redisConfig.Mode. Connections are made lazily, the caller pings to check them.
+func NewRedisClient(redisConfig *config.RedisConfig) (insredis.RedisInterface, error) {
+	var tlsConfig *tls.Config
+	if redisConfig.TLS {
+		// The server name is taken from the address of each node.
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	switch redisConfig.Mode {
+	case config.RedisModeStandalone:
+		return redis.NewClient(&redis.Options{
+			Addr:        fmt.Sprintf("%s:%d", redisConfig.Host, redisConfig.Port),
+			Password:    redisConfig.Password,
+			PoolSize:    redisConfig.PoolSize,
+			DialTimeout: redisConfig.DialTimeout,
+			ReadTimeout: redisConfig.ReadTimeout,
+			MaxRetries:  redisConfig.MaxRetries,
+			TLSConfig:   tlsConfig,
+		}), nil
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    redisConfig.MasterName,
+			SentinelAddrs: redisConfig.Addrs,
+			Password:      redisConfig.Password,
+			PoolSize:      redisConfig.PoolSize,
+			DialTimeout:   redisConfig.DialTimeout,
+			ReadTimeout:   redisConfig.ReadTimeout,
+			MaxRetries:    redisConfig.MaxRetries,
+			TLSConfig:     tlsConfig,
+		}), nil
+	case config.RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:       redisConfig.Addrs,
+			Password:    redisConfig.Password,
+			PoolSize:    redisConfig.PoolSize,
+			DialTimeout: redisConfig.DialTimeout,
+			ReadTimeout: redisConfig.ReadTimeout,
+			MaxRetries:  redisConfig.MaxRetries,
+			TLSConfig:   tlsConfig,
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown Redis mode %q", redisConfig.Mode)
+}
@@ -0,0 +1,311 @@
+package httptape
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mode selects what the Transport does with outbound requests.
+type Mode string
+
+const (
+	// ModeOff passes requests through untouched.
+	ModeOff Mode = "off"
+	// ModeRecord forwards requests and writes each sanitized exchange to a golden file.
+	ModeRecord Mode = "record"
+	// ModeReplay answers requests from golden files without touching the network.
+	ModeReplay Mode = "replay"
+)
+
+// ErrNoRecording is returned in replay mode when no golden file matches a request,
+// which usually means the outbound payload changed.
+var ErrNoRecording = errors.New("no recording matches request")
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders are recorded as REDACTED so golden files only prove they were sent.
+var sensitiveHeaders = map[string]bool{
+	"authorization":  true,
+	"x-ins-auth-key": true,
+	"x-request-id":   true,
+	"x-api-key":      true,
+	// Signatures are made over the current time, so they differ between runs.
+	"x-signature":           true,
+	"x-signature-timestamp": true,
+}
+
+// sensitiveFields are the body fields holding recipients or message content, matched case
+// insensitively in JSON objects at any depth and in form bodies. Their values are recorded
+// as a fingerprint, see mask.
+var sensitiveFields = map[string]bool{
+	"to":              true,
+	"content":         true,
+	"body":            true,
+	"text":            true,
+	"phone":           true,
+	"email":           true,
+	"recipient":       true,
+	"recipients":      true,
+	"recipient_phone": true,
+	"recipient_email": true,
+}
+
+// Exchange is one recorded request/response pair as stored on disk.
+type Exchange struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body,omitempty"`
+}
+
+// Transport records or replays provider traffic depending on its mode.
+type Transport struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+}
+
+// NewTransport wraps next. In ModeOff next is returned as is.
+func NewTransport(mode Mode, dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	switch mode {
+	case "", ModeOff:
+		return next, nil
+	case ModeRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create recording dir: %w", err)
+		}
+	case ModeReplay:
+	default:
+		return nil, fmt.Errorf("unknown http tape mode %q", mode)
+	}
+
+	return &Transport{mode: mode, dir: dir, next: next}, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(t.dir, recorded.key()+".json")
+
+	if t.mode == ModeReplay {
+		return t.replay(req, path)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	exchange := Exchange{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    sanitizeHeaders(resp.Header),
+			Body:       sanitizeBody(body, resp.Header.Get("Content-Type")),
+		},
+	}
+
+	data, err := json.MarshalIndent(exchange, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoRecording, req.Method, req.URL.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var exchange Exchange
+	if err := json.Unmarshal(data, &exchange); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", path, err)
+	}
+
+	header := http.Header{}
+	for name, value := range exchange.Response.Headers {
+		header.Set(name, value)
+	}
+
+	body := []byte(exchange.Response.Body)
+	var text string
+	if len(body) > 0 && body[0] == '"' && json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	}
+
+	return &http.Response{
+		StatusCode: exchange.Response.StatusCode,
+		Status:     fmt.Sprintf("%d %s", exchange.Response.StatusCode, http.StatusText(exchange.Response.StatusCode)),
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}, nil
+}
+
+func recordRequest(req *http.Request) (RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return RecordedRequest{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return RecordedRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Headers: sanitizeHeaders(req.Header),
+		Body:    sanitizeBody(body, req.Header.Get("Content-Type")),
+	}, nil
+}
+
+// key identifies a request by its method, path, non-sensitive headers and sanitized body,
+// so a payload change produces a different key and fails replay.
+func (r RecordedRequest) key() string {
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func sanitizeHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name := range header {
+		lower := strings.ToLower(name)
+		if sensitiveHeaders[lower] {
+			out[lower] = redacted
+			continue
+		}
+		// Hop-by-hop and volatile headers would make recordings differ between runs.
+		if lower == "date" || lower == "content-length" || lower == "user-agent" || lower == "accept-encoding" {
+			continue
+		}
+		out[lower] = header.Get(name)
+	}
+	return out
+}
+
+// sanitizeBody masks the sensitiveFields of JSON and form bodies. Other bodies are recorded
+// as they are.
+func sanitizeBody(body []byte, contentType string) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for name, values := range form {
+				if !sensitiveFields[strings.ToLower(name)] {
+					continue
+				}
+				for i, value := range values {
+					values[i] = mask([]byte(value))
+				}
+			}
+			return jsonOrString([]byte(form.Encode()))
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return jsonOrString(body)
+	}
+	sanitized, err := json.Marshal(maskFields(value))
+	if err != nil {
+		return jsonOrString(body)
+	}
+	return sanitized
+}
+
+// maskFields replaces the values of sensitiveFields inside a decoded JSON value.
+func maskFields(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, field := range v {
+			if sensitiveFields[strings.ToLower(name)] {
+				data, _ := json.Marshal(field)
+				v[name] = mask(data)
+				continue
+			}
+			v[name] = maskFields(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = maskFields(item)
+		}
+	}
+	return value
+}
+
+// mask records value as REDACTED followed by a short SHA-256 fingerprint. The same value
+// always gets the same fingerprint, so recordings and their keys are stable and a changed
+// recipient or content still fails replay. A fingerprint is no encryption: short values
+// such as phone numbers can be guessed from it, so record against test recipients only.
+func mask(value []byte) string {
+	sum := sha256.Sum256(value)
+	return redacted + ":" + hex.EncodeToString(sum[:6])
+}
+
+// jsonOrString keeps JSON bodies readable in golden files and quotes anything else.
+func jsonOrString(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err == nil {
+			return compact.Bytes()
+		}
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
@@ -0,0 +1,108 @@
+package httptape
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// send posts body through a client with transport and returns the response body.
+func send(t *testing.T, transport http.RoundTripper, url, contentType, body string) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ins-auth-key", "secret-auth-key")
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(data), nil
+}
+
+func recordings(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestRecordRedactsAndReplays(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, `{"messageId":"abc","to":"+905551111111","message":"Accepted"}`)
+	}))
+	defer provider.Close()
+	dir := t.TempDir()
+	payload := `{"to":"+905551111111","content":"Your code is 1234","metadata":{"message_id":1}}`
+
+	recorder, err := NewTransport(ModeRecord, dir, http.DefaultTransport)
+	require.NoError(t, err)
+	body, err := send(t, recorder, provider.URL+"/send", "application/json", payload)
+	require.NoError(t, err)
+	assert.Contains(t, body, "+905551111111", "the caller gets the real response")
+
+	files := recordings(t, dir)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	for _, secret := range []string{"+905551111111", "Your code is 1234", "secret-auth-key"} {
+		assert.NotContains(t, string(data), secret)
+	}
+	assert.Contains(t, string(data), `"message_id": 1`, "other fields are kept")
+
+	// Recording the same request again overwrites the same file.
+	_, err = send(t, recorder, provider.URL+"/send", "application/json", payload)
+	require.NoError(t, err)
+	assert.Equal(t, files, recordings(t, dir), "keys are stable")
+
+	replayer, err := NewTransport(ModeReplay, dir, nil)
+	require.NoError(t, err)
+	body, err = send(t, replayer, "http://provider.invalid/send", "application/json", payload)
+	require.NoError(t, err)
+	assert.Contains(t, body, `"abc"`)
+
+	_, err = send(t, replayer, "http://provider.invalid/send", "application/json", strings.Replace(payload, "1234", "5678", 1))
+	assert.True(t, errors.Is(err, ErrNoRecording), "a changed content has no recording")
+}
+
+func TestRecordRedactsFormBodies(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer provider.Close()
+	dir := t.TempDir()
+
+	recorder, err := NewTransport(ModeRecord, dir, nil)
+	require.NoError(t, err)
+	_, err = send(t, recorder, provider.URL+"/Messages.json", "application/x-www-form-urlencoded", "To=%2B905551111111&From=INSIDER&Body=Hello")
+	require.NoError(t, err)
+
+	files := recordings(t, dir)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "905551111111")
+	assert.NotContains(t, string(data), "Hello")
+	assert.Contains(t, string(data), "From=INSIDER")
+}
+
+func TestReplayWithoutRecording(t *testing.T) {
+	replayer, err := NewTransport(ModeReplay, t.TempDir(), nil)
+	require.NoError(t, err)
+
+	_, err = send(t, replayer, "http://provider.invalid/send", "application/json", `{"to":"+905551111111"}`)
+
+	assert.True(t, errors.Is(err, ErrNoRecording))
+}
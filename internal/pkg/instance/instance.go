@@ -0,0 +1,25 @@
+package instance
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	once sync.Once
+	id   string
+)
+
+// ID identifies this process among the service replicas, e.g. as the owner of claimed
+// rows or of the scheduler leader lock.
+func ID() string {
+	once.Do(func() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "unknown"
+		}
+		id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	})
+	return id
+}
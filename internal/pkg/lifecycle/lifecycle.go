@@ -0,0 +1,147 @@
+// Package lifecycle starts and stops the long-running components of a command in a fixed
+// order, so every new subsystem gets the same shutdown handling instead of its own wiring.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// Hook is a component the Manager starts and stops. Start must not block, long-running
+// work belongs in a goroutine, see Background. Either function may be nil.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+	// Timeout bounds Stop on top of the shutdown context. Zero leaves only the context.
+	Timeout time.Duration
+}
+
+// Manager runs hooks in the order they were appended and stops them in reverse, so a
+// component is stopped before everything it was started after, e.g. the scheduler before
+// the pools its last batch writes to.
+type Manager struct {
+	logger inslogger.Interface
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+func New(logger inslogger.Interface) *Manager {
+	return &Manager{logger: logger}
+}
+
+// Append registers hook. Hooks appended after Start are not started.
+func (m *Manager) Append(hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook)
+}
+
+// Start runs the Start functions in order. When one fails, the hooks started before it
+// are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.started < len(m.hooks) {
+		hook := m.hooks[m.started]
+		if hook.Start != nil {
+			m.logger.Logf("Starting %s...", hook.Name)
+			if err := hook.Start(ctx); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				return errors.Join(err, m.stop(ctx))
+			}
+		}
+		m.started++
+	}
+
+	return nil
+}
+
+// Stop runs the Stop functions of the started hooks in reverse order. A hook that does not
+// return within its timeout is abandoned and the next one is stopped. Every error is
+// returned, joined.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stop(ctx)
+}
+
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		hook := m.hooks[m.started-1]
+		if hook.Stop == nil {
+			continue
+		}
+
+		m.logger.Logf("Stopping %s...", hook.Name)
+		if err := stopHook(ctx, hook); err != nil {
+			m.logger.Errorf("Failed to stop %s: %v", hook.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func stopHook(ctx context.Context, hook Hook) error {
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("did not stop in time: %w", ctx.Err())
+	}
+}
+
+// Background returns a hook that runs run in a goroutine until it is stopped. Stop cancels
+// the context run was given and waits for it to return.
+func Background(name string, run func(ctx context.Context)) Hook {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func recordingHook(name string, calls *[]string) Hook {
+	return Hook{
+		Name: name,
+		Start: func(context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	var calls []string
+	manager := New(inslogger.NewNopLogger())
+	manager.Append(recordingHook("database", &calls))
+	manager.Append(recordingHook("scheduler", &calls))
+	manager.Append(Hook{Name: "stop only", Stop: func(context.Context) error {
+		calls = append(calls, "stop stop only")
+		return nil
+	}})
+
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.NoError(t, manager.Stop(context.Background()))
+	assert.NoError(t, manager.Stop(context.Background()), "stopping twice is a no-op")
+
+	assert.Equal(t, []string{"start database", "start scheduler", "stop stop only", "stop scheduler", "stop database"}, calls)
+}
+
+func TestManagerUnwindsFailedStart(t *testing.T) {
+	var calls []string
+	manager := New(inslogger.NewNopLogger())
+	manager.Append(recordingHook("database", &calls))
+	manager.Append(Hook{Name: "broker", Start: func(context.Context) error { return errors.New("refused") }})
+	manager.Append(recordingHook("scheduler", &calls))
+
+	err := manager.Start(context.Background())
+
+	assert.ErrorContains(t, err, "failed to start broker: refused")
+	assert.Equal(t, []string{"start database", "stop database"}, calls)
+}
+
+func TestManagerAbandonsSlowStop(t *testing.T) {
+	var calls []string
+	manager := New(inslogger.NewNopLogger())
+	manager.Append(recordingHook("database", &calls))
+	manager.Append(Hook{
+		Name:    "stuck",
+		Stop:    func(context.Context) error { select {} },
+		Timeout: 10 * time.Millisecond,
+	})
+
+	assert.NoError(t, manager.Start(context.Background()))
+	err := manager.Stop(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"start database", "stop database"}, calls, "later hooks are still stopped")
+}
+
+func TestBackground(t *testing.T) {
+	stopped := make(chan struct{})
+	hook := Background("job", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	assert.NoError(t, hook.Start(context.Background()))
+	assert.NoError(t, hook.Stop(context.Background()))
+
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("Stop returned before the job did")
+	}
+}
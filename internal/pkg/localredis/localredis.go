@@ -0,0 +1,211 @@
+package localredis
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// Client is an in-process stand-in for Redis used in local mode and without Redis. It
+// implements the plain key/value commands the service uses for caching; any other command
+// panics, so components that need scripts or sorted sets get a local implementation instead.
+type Client struct {
+	insredis.RedisInterface
+
+	mu   sync.Mutex
+	data map[string]entry
+	now  func() time.Time
+	// maxKeys bounds the keys with an expiry, the least recently used is evicted first like
+	// with the volatile-lru policy of Redis. Keys without an expiry are never evicted.
+	maxKeys int
+	// volatile orders the keys with an expiry, most recently used first.
+	volatile *list.List
+}
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+	// element is the place of the key in volatile, nil without an expiry.
+	element *list.Element
+}
+
+func New() *Client {
+	return NewWithMaxKeys(0)
+}
+
+// NewWithMaxKeys returns a Client that keeps at most maxKeys keys with an expiry, or any
+// number when maxKeys is zero.
+func NewWithMaxKeys(maxKeys int) *Client {
+	return &Client{
+		data:     make(map[string]entry),
+		now:      time.Now,
+		maxKeys:  maxKeys,
+		volatile: list.New(),
+	}
+}
+
+func (c *Client) Ping() *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) Get(key string) *redis.StringCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(e.value, nil)
+}
+
+func (c *Client) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.set(key, value, expiration)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *Client) SetNX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.get(key); ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	c.set(key, value, expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *Client) Del(keys ...string) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted int64
+	for _, key := range keys {
+		if _, ok := c.get(key); ok {
+			c.delete(key)
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (c *Client) Exists(keys ...string) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var found int64
+	for _, key := range keys {
+		if _, ok := c.get(key); ok {
+			found++
+		}
+	}
+	return redis.NewIntResult(found, nil)
+}
+
+func (c *Client) IncrBy(key string, value int64) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var current int64
+	e, ok := c.get(key)
+	if ok {
+		if _, err := fmt.Sscan(e.value, &current); err != nil {
+			return redis.NewIntResult(0, fmt.Errorf("ERR value is not an integer or out of range"))
+		}
+	}
+	current += value
+	// Like Redis, an increment keeps the expiry of the key.
+	e.value = toString(current)
+	c.data[key] = e
+	return redis.NewIntResult(current, nil)
+}
+
+func (c *Client) Expire(key string, expiration time.Duration) *redis.BoolCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key)
+	if !ok {
+		return redis.NewBoolResult(false, nil)
+	}
+	e.expiresAt = c.now().Add(expiration)
+	c.store(key, e)
+	return redis.NewBoolResult(true, nil)
+}
+
+// get returns a live entry, dropping it if it expired. Callers must hold mu.
+func (c *Client) get(key string) (entry, bool) {
+	e, ok := c.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt) {
+		c.delete(key)
+		return entry{}, false
+	}
+	if e.element != nil {
+		c.volatile.MoveToFront(e.element)
+	}
+	return e, true
+}
+
+// set stores value the way go-redis would send it. Callers must hold mu.
+func (c *Client) set(key string, value interface{}, expiration time.Duration) {
+	e := entry{value: toString(value)}
+	if old, ok := c.data[key]; ok {
+		e.element = old.element
+	}
+	if expiration > 0 {
+		e.expiresAt = c.now().Add(expiration)
+	}
+	c.store(key, e)
+}
+
+// store puts e at key as the most recently used key and evicts keys with an expiry beyond
+// maxKeys. Callers must hold mu.
+func (c *Client) store(key string, e entry) {
+	switch {
+	case e.expiresAt.IsZero() && e.element != nil:
+		c.volatile.Remove(e.element)
+		e.element = nil
+	case !e.expiresAt.IsZero() && e.element == nil:
+		e.element = c.volatile.PushFront(key)
+	case e.element != nil:
+		c.volatile.MoveToFront(e.element)
+	}
+	c.data[key] = e
+
+	for c.maxKeys > 0 && c.volatile.Len() > c.maxKeys {
+		c.delete(c.volatile.Back().Value.(string))
+	}
+}
+
+// delete drops key. Callers must hold mu.
+func (c *Client) delete(key string) {
+	if e, ok := c.data[key]; ok && e.element != nil {
+		c.volatile.Remove(e.element)
+	}
+	delete(c.data, key)
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
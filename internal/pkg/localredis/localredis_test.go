@@ -0,0 +1,42 @@
+package localredis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxKeysEvictsLeastRecentlyUsedVolatileKey(t *testing.T) {
+	client := NewWithMaxKeys(2)
+	client.Set("state", "running", 0)
+	client.Set("a", "1", time.Minute)
+	client.Set("b", "2", time.Minute)
+	client.Get("a")
+	client.Set("c", "3", time.Minute)
+
+	_, err := client.Get("b").Result()
+	assert.ErrorIs(t, err, redis.Nil, "b was used least recently")
+	for key, value := range map[string]string{"state": "running", "a": "1", "c": "3"} {
+		got, err := client.Get(key).Result()
+		assert.NoError(t, err)
+		assert.Equal(t, value, got)
+	}
+
+	client.Set("c", "3", 0)
+	client.Set("d", "4", time.Minute)
+	_, err = client.Get("c").Result()
+	assert.NoError(t, err, "keys without an expiry are never evicted")
+}
+
+func TestExpiredKeysAreDropped(t *testing.T) {
+	now := time.Now()
+	client := New()
+	client.now = func() time.Time { return now }
+	client.Set("a", "1", time.Second)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, int64(0), client.Exists("a").Val())
+	assert.Equal(t, 0, client.volatile.Len())
+}
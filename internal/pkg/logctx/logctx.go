@@ -0,0 +1,173 @@
+package logctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// RequestIDHeader is the header used to propagate correlation IDs between services.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+type apiKeyNameKey struct{}
+
+type tenantKey struct{}
+
+type tenantScopeKey struct{}
+
+type actorKey struct{}
+
+// NewID returns a random correlation ID.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID stored in ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithAPIKeyName records which API key authenticated the request.
+func WithAPIKeyName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiKeyNameKey{}, name)
+}
+
+// APIKeyName returns the authenticated API key name stored in ctx, or an empty string.
+func APIKeyName(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	name, _ := ctx.Value(apiKeyNameKey{}).(string)
+	return name
+}
+
+// WithTenant records the tenant the authenticated API key belongs to.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant stored in ctx, or an empty string.
+func Tenant(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithTenantScope restricts the message reads and changes made with ctx to the messages
+// of tenant, for API keys that may not see other tenants.
+func WithTenantScope(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantScopeKey{}, tenant)
+}
+
+// TenantScope returns the tenant the messages of ctx are restricted to, or an empty string
+// when ctx may see every tenant.
+func TenantScope(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantScopeKey{}).(string)
+	return tenant
+}
+
+// WithActor records the component acting on messages outside of an API request, e.g. the
+// scheduler.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns who acts in ctx: the authenticated API key name, else the component stored
+// with WithActor, else an empty string.
+func Actor(ctx context.Context) string {
+	if name := APIKeyName(ctx); name != "" {
+		return name
+	}
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Logger returns a logger that tags every entry with the correlation ID and API key
+// name from ctx. The base logger is returned unchanged when ctx carries neither.
+func Logger(ctx context.Context, logger inslogger.Interface) inslogger.Interface {
+	prefix := ""
+	if id := RequestID(ctx); id != "" {
+		prefix += fmt.Sprintf("request_id=%s ", id)
+	}
+	if name := APIKeyName(ctx); name != "" {
+		prefix += fmt.Sprintf("api_key=%s ", name)
+	}
+	if prefix == "" {
+		return logger
+	}
+
+	return &correlatedLogger{
+		Interface: logger,
+		prefix:    prefix,
+	}
+}
+
+type correlatedLogger struct {
+	inslogger.Interface
+	prefix string
+}
+
+func (l *correlatedLogger) Log(i interface{}) {
+	l.Interface.Log(l.prefix + fmt.Sprint(i))
+}
+
+func (l *correlatedLogger) Logf(format string, args ...interface{}) {
+	l.Interface.Logf(l.prefix+format, args...)
+}
+
+func (l *correlatedLogger) Warn(i interface{}) {
+	l.Interface.Warn(l.prefix + fmt.Sprint(i))
+}
+
+func (l *correlatedLogger) Warnf(format string, args ...interface{}) {
+	l.Interface.Warnf(l.prefix+format, args...)
+}
+
+func (l *correlatedLogger) Error(err error) {
+	l.Interface.Errorf(l.prefix+"%v", err)
+}
+
+func (l *correlatedLogger) Errorf(format string, args ...interface{}) {
+	l.Interface.Errorf(l.prefix+format, args...)
+}
+
+func (l *correlatedLogger) Debug(i interface{}) {
+	l.Interface.Debug(l.prefix + fmt.Sprint(i))
+}
+
+func (l *correlatedLogger) Debugf(format string, args ...interface{}) {
+	l.Interface.Debugf(l.prefix+format, args...)
+}
+
+func (l *correlatedLogger) Fatal(err error) {
+	l.Interface.Fatalf(l.prefix+"%v", err)
+}
+
+func (l *correlatedLogger) Fatalf(format string, args ...interface{}) {
+	l.Interface.Fatalf(l.prefix+format, args...)
+}
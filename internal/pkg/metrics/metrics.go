@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the OpenMetrics text exposition content type.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// DefaultRegistry is the registry used by the package level constructors.
+var DefaultRegistry = NewRegistry()
+
+type metricType string
+
+const (
+	counterType metricType = "counter"
+	gaugeType   metricType = "gauge"
+)
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metrics and renders them in the OpenMetrics text format.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collectors[c.name()]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write renders every registered metric followed by the EOF marker.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	collectors := make([]collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+	fmt.Fprint(w, "# EOF\n")
+}
+
+// Handler serves the registry for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.Write(w)
+	})
+}
+
+// vec is a set of float values keyed by label values.
+type vec struct {
+	metricName string
+	help       string
+	typ        metricType
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+func newVec(registry *Registry, name, help string, typ metricType, labels []string) *vec {
+	v := &vec{
+		metricName: name,
+		help:       help,
+		typ:        typ,
+		labels:     labels,
+		values:     make(map[string]*sample),
+	}
+	registry.register(v)
+	return v
+}
+
+func (v *vec) name() string {
+	return v.metricName
+}
+
+func (v *vec) add(delta float64, labelValues []string) {
+	v.update(labelValues, func(s *sample) { s.value += delta })
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+	v.update(labelValues, func(s *sample) { s.value = value })
+}
+
+func (v *vec) update(labelValues []string, fn func(s *sample)) {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.values[key]
+	if !ok {
+		s = &sample{labelValues: append([]string(nil), labelValues...)}
+		v.values[key] = s
+	}
+	fn(s)
+}
+
+func (v *vec) get(labelValues []string) float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if s, ok := v.values[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.metricName, v.typ)
+	fmt.Fprintf(w, "# HELP %s %s\n", v.metricName, v.help)
+
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	suffix := ""
+	if v.typ == counterType {
+		suffix = "_total"
+	}
+
+	for _, key := range keys {
+		s := v.values[key]
+		fmt.Fprintf(w, "%s%s%s %s\n", v.metricName, suffix, formatLabels(v.labels, s.labelValues), formatValue(s.value))
+	}
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, values[i])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+// The name must not carry the _total suffix, it is added on exposition.
+type CounterVec struct {
+	*vec
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return DefaultRegistry.NewCounterVec(name, help, labels...)
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(r, name, help, counterType, labels)}
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.add(1, labelValues)
+}
+
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.add(delta, labelValues)
+}
+
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return c.get(labelValues)
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	*vec
+}
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return DefaultRegistry.NewGaugeVec(name, help, labels...)
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{newVec(r, name, help, gaugeType, labels)}
+}
+
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.add(1, labelValues)
+}
+
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.add(-1, labelValues)
+}
+
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.add(delta, labelValues)
+}
+
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	return g.get(labelValues)
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	sent := registry.NewCounterVec("messages", "Messages sent.", "status")
+	inFlight := registry.NewGaugeVec("in_flight", "Sends in flight.")
+
+	sent.Inc("delivered")
+	sent.Add(2, "failed")
+	inFlight.Inc()
+
+	var buf bytes.Buffer
+	registry.Write(&buf)
+
+	assert.Equal(t, `# TYPE in_flight gauge
+# HELP in_flight Sends in flight.
+in_flight 1
+# TYPE messages counter
+# HELP messages Messages sent.
+messages_total{status="delivered"} 1
+messages_total{status="failed"} 2
+# EOF
+`, buf.String())
+}
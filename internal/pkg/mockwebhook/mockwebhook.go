@@ -0,0 +1,165 @@
+// Package mockwebhook emulates the webhook SMS gateway, so the whole send flow can be run
+// locally without a real gateway or a request bin. Failures and latency are simulated per
+// request through query parameters of WEBHOOK_URL:
+//
+//   - latency=250ms delays every answer
+//   - error_rate=0.2 answers this share of the requests with 429
+//   - retry_after=2 sets the Retry-After header of those answers, in seconds
+//   - status=500 answers every request with this status code
+package mockwebhook
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/pkg/logctx"
+)
+
+// MessageIDPrefix starts the message ID of every accepted payload.
+const MessageIDPrefix = "mock-"
+
+// maxReceived bounds the payloads kept for GET /messages, the oldest is dropped first.
+const maxReceived = 100
+
+// Received is a payload the server accepted.
+type Received struct {
+	MessageID  string          `json:"messageId"`
+	Payload    json.RawMessage `json:"payload"`
+	ReceivedAt time.Time       `json:"receivedAt"`
+}
+
+// Server answers sends posted to any path like the webhook gateway and lists the accepted
+// payloads on GET /messages, newest last.
+type Server struct {
+	// authKey is the x-ins-auth-key every send must present, any key is accepted while empty.
+	authKey string
+	logger  inslogger.Interface
+	// random returns a number in [0, 1) and decides which requests fail with error_rate.
+	random func() float64
+
+	mu       sync.Mutex
+	received []Received
+}
+
+// NewServer returns a Server that rejects sends without authKey with 401, unless authKey is
+// empty.
+func NewServer(authKey string, logger inslogger.Interface) *Server {
+	return &Server{authKey: authKey, logger: logger, random: rand.Float64}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/messages":
+		s.list(w)
+	case r.Method == http.MethodPost:
+		s.send(w, r)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "Method not allowed"})
+	}
+}
+
+func (s *Server) send(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	latency, err := durationParam(query.Get("latency"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "latency must be a duration, e.g. 250ms"})
+		return
+	}
+	errorRate, err := floatParam(query.Get("error_rate"))
+	if err != nil || errorRate < 0 || errorRate > 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "error_rate must be between 0 and 1"})
+		return
+	}
+	status, err := intParam(query.Get("status"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "status must be an HTTP status code"})
+		return
+	}
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if s.authKey != "" && r.Header.Get("x-ins-auth-key") != s.authKey {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+		return
+	}
+	if status != 0 {
+		writeJSON(w, status, map[string]string{"message": http.StatusText(status)})
+		return
+	}
+	if errorRate > 0 && s.random() < errorRate {
+		if retryAfter := query.Get("retry_after"); retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "Too many requests"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(body) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Body must be JSON"})
+		return
+	}
+
+	received := Received{MessageID: MessageIDPrefix + logctx.NewID(), Payload: body, ReceivedAt: time.Now().UTC()}
+	s.mu.Lock()
+	s.received = append(s.received, received)
+	if len(s.received) > maxReceived {
+		s.received = s.received[len(s.received)-maxReceived:]
+	}
+	s.mu.Unlock()
+
+	s.logger.Logf("Accepted %s: %s", received.MessageID, body)
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Accepted", "messageId": received.MessageID})
+}
+
+func (s *Server) list(w http.ResponseWriter) {
+	s.mu.Lock()
+	received := append([]Received{}, s.received...)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, received)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func durationParam(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func floatParam(raw string) (float64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+func intParam(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	status, err := strconv.Atoi(raw)
+	if err == nil && (status < 100 || status > 599) {
+		return 0, strconv.ErrRange
+	}
+	return status, err
+}
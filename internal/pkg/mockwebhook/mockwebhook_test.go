@@ -0,0 +1,57 @@
+package mockwebhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func post(server *Server, target, authKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("x-ins-auth-key", authKey)
+	resp := httptest.NewRecorder()
+	server.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestServerAcceptsPayloads(t *testing.T) {
+	server := NewServer("secret", inslogger.NewNopLogger())
+
+	resp := post(server, "/send", "secret", `{"to":"+905551111111","content":"hi"}`)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	var accepted map[string]string
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &accepted))
+	assert.Equal(t, "Accepted", accepted["message"])
+	assert.True(t, strings.HasPrefix(accepted["messageId"], MessageIDPrefix))
+
+	assert.Equal(t, http.StatusUnauthorized, post(server, "/send", "wrong", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(server, "/send", "secret", `not json`).Code)
+
+	list := httptest.NewRecorder()
+	server.ServeHTTP(list, httptest.NewRequest(http.MethodGet, "/messages", nil))
+	var received []Received
+	assert.NoError(t, json.Unmarshal(list.Body.Bytes(), &received))
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, accepted["messageId"], received[0].MessageID)
+		assert.JSONEq(t, `{"to":"+905551111111","content":"hi"}`, string(received[0].Payload))
+	}
+}
+
+func TestServerSimulatesFailures(t *testing.T) {
+	server := NewServer("", inslogger.NewNopLogger())
+	server.random = func() float64 { return 0.5 }
+
+	resp := post(server, "/send?error_rate=0.6&retry_after=2", "", `{}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusAccepted, post(server, "/send?error_rate=0.4", "", `{}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, post(server, "/send?status=503", "", `{}`).Code)
+	assert.Equal(t, http.StatusAccepted, post(server, "/send?latency=1ms", "", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(server, "/send?latency=soon", "", `{}`).Code)
+}
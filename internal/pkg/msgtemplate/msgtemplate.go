@@ -0,0 +1,264 @@
+package msgtemplate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrMissingVariables is returned when a template is rendered without all required variables.
+var ErrMissingVariables = errors.New("missing template variables")
+
+// ErrInvalidTemplate is returned when a template body does not parse or uses unsupported constructs.
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// Funcs is the allow-list of functions templates may call. Anything else is rejected at lint time.
+var Funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"title": func(s string) string {
+		first, size := utf8.DecodeRuneInString(s)
+		if size == 0 {
+			return s
+		}
+		return string(unicode.ToUpper(first)) + s[size:]
+	},
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// Manifest lists the variables a template needs. It is stored next to the template
+// so sends can be validated without parsing the body again. Optional variables are only
+// tested by {{if}} or given as the value of default, they render as empty when absent.
+type Manifest struct {
+	Variables []string `json:"variables"`
+	Optional  []string `json:"optional,omitempty"`
+}
+
+// bareVariable matches a lone identifier action such as {{name}}.
+var bareVariable = regexp.MustCompile(`{{(-?\s*)([A-Za-z_][A-Za-z0-9_]*)(\s*-?)}}`)
+
+// keywords are identifiers that are valid on their own in an action.
+var keywords = map[string]bool{
+	"end": true, "else": true, "break": true, "continue": true,
+	"nil": true, "true": true, "false": true,
+}
+
+// Lint parses body with the allowed function set and returns its variable manifest.
+// Templates reference variables as {{.name}} or the shorthand {{name}}; nested fields
+// are not supported.
+func Lint(body string) (Manifest, error) {
+	tmpl, err := compile(body)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	c := collector{required: map[string]struct{}{}, optional: map[string]struct{}{}}
+	if tmpl.Tree != nil {
+		if err := c.collect(tmpl.Tree.Root, nil); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	manifest := Manifest{Variables: make([]string, 0, len(c.required)), Optional: []string{}}
+	for name := range c.required {
+		manifest.Variables = append(manifest.Variables, name)
+	}
+	for name := range c.optional {
+		if _, ok := c.required[name]; !ok {
+			manifest.Optional = append(manifest.Optional, name)
+		}
+	}
+	sort.Strings(manifest.Variables)
+	sort.Strings(manifest.Optional)
+	return manifest, nil
+}
+
+// Validate reports every variable from the manifest that is absent from vars.
+func (m Manifest) Validate(vars map[string]string) error {
+	var missing []string
+	for _, name := range m.Variables {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingVariables, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Render executes body with vars after checking them against the manifest.
+func Render(body string, manifest Manifest, vars map[string]string) (string, error) {
+	if err := manifest.Validate(vars); err != nil {
+		return "", err
+	}
+
+	tmpl, err := compile(body)
+	if err != nil {
+		return "", err
+	}
+
+	// Absent optional variables are empty, missingkey=error still catches everything else.
+	data := make(map[string]string, len(vars)+len(manifest.Optional))
+	for _, name := range manifest.Optional {
+		data[name] = ""
+	}
+	for name, value := range vars {
+		data[name] = value
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func compile(body string) (*template.Template, error) {
+	tmpl, err := template.New("message").Funcs(Funcs).Option("missingkey=error").Parse(expandShorthand(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// expandShorthand rewrites {{name}} to {{.name}} unless name is a function or keyword.
+func expandShorthand(body string) string {
+	return bareVariable.ReplaceAllStringFunc(body, func(action string) string {
+		m := bareVariable.FindStringSubmatch(action)
+		if _, isFunc := Funcs[m[2]]; isFunc || keywords[m[2]] {
+			return action
+		}
+		return "{{" + m[1] + "." + m[2] + m[3] + "}}"
+	})
+}
+
+// collector sorts the fields of a template into the variables it requires and the ones it
+// only tests or falls back from.
+type collector struct {
+	required map[string]struct{}
+	optional map[string]struct{}
+}
+
+// collect walks node. guarded holds the variables tested by the enclosing {{if}} blocks,
+// which their bodies may use without requiring them.
+func (c *collector) collect(node parse.Node, guarded map[string]bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.collect(child, guarded); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return c.collectPipe(n.Pipe, guarded, false)
+	case *parse.IfNode:
+		return c.collectIf(n, guarded)
+	case *parse.RangeNode:
+		return fmt.Errorf("%w: range is not supported", ErrInvalidTemplate)
+	case *parse.WithNode:
+		return fmt.Errorf("%w: with is not supported", ErrInvalidTemplate)
+	case *parse.TemplateNode:
+		return fmt.Errorf("%w: nested templates are not supported", ErrInvalidTemplate)
+	}
+	return nil
+}
+
+// collectPipe collects the commands of pipe. A lone field piped into default, as in
+// {{.name | default "there"}}, is its value and optional like {{default "there" .name}}.
+func (c *collector) collectPipe(pipe *parse.PipeNode, guarded map[string]bool, optional bool) error {
+	if pipe == nil {
+		return nil
+	}
+	for i, cmd := range pipe.Cmds {
+		piped := i+1 < len(pipe.Cmds) && len(cmd.Args) == 1 && isDefault(pipe.Cmds[i+1])
+		if err := c.collectCommand(cmd, guarded, optional || piped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectCommand(cmd *parse.CommandNode, guarded map[string]bool, optional bool) error {
+	for i, arg := range cmd.Args {
+		// The value of default is its last argument, the fallback comes before it.
+		valueOfDefault := isDefault(cmd) && i == len(cmd.Args)-1 && i > 1
+		if err := c.collectArg(arg, guarded, optional || valueOfDefault); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *collector) collectArg(arg parse.Node, guarded map[string]bool, optional bool) error {
+	switch n := arg.(type) {
+	case *parse.IdentifierNode:
+		// Only the allow-listed functions may be called, not builtins such as call, index,
+		// printf or js.
+		if _, ok := Funcs[n.Ident]; !ok {
+			return fmt.Errorf("%w: function %s is not allowed", ErrInvalidTemplate, n.Ident)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) != 1 {
+			return fmt.Errorf("%w: nested field %s is not supported", ErrInvalidTemplate, n.String())
+		}
+		name := n.Ident[0]
+		if optional || guarded[name] {
+			c.optional[name] = struct{}{}
+		} else {
+			c.required[name] = struct{}{}
+		}
+	case *parse.PipeNode:
+		return c.collectPipe(n, guarded, optional)
+	case *parse.ChainNode:
+		return fmt.Errorf("%w: field chain %s is not supported", ErrInvalidTemplate, n.String())
+	}
+	return nil
+}
+
+// collectIf collects an {{if}} block. The fields it tests are optional, and so are their
+// uses inside the block: they only render when set.
+func (c *collector) collectIf(n *parse.IfNode, guarded map[string]bool) error {
+	if err := c.collectPipe(n.Pipe, guarded, true); err != nil {
+		return err
+	}
+
+	inner := make(map[string]bool, len(guarded)+1)
+	for name := range guarded {
+		inner[name] = true
+	}
+	if len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+		if field, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); ok && len(field.Ident) == 1 {
+			inner[field.Ident[0]] = true
+		}
+	}
+	if err := c.collect(n.List, inner); err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return c.collect(n.ElseList, guarded)
+	}
+	return nil
+}
+
+func isDefault(cmd *parse.CommandNode) bool {
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "default"
+}
@@ -0,0 +1,87 @@
+package msgtemplate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	manifest, err := Lint(`Hi {{title .name}}, your code is {{.code}}{{if .promo}} ({{upper .promo}}){{end}}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"code", "name"}, manifest.Variables)
+	assert.Equal(t, []string{"promo"}, manifest.Optional)
+}
+
+func TestLintRejectsUnknownFunctions(t *testing.T) {
+	_, err := Lint(`{{exec .cmd}}`)
+
+	assert.Error(t, err)
+}
+
+func TestLintRejectsSyntaxErrors(t *testing.T) {
+	_, err := Lint(`Hi {{.name`)
+
+	assert.Error(t, err)
+}
+
+func TestRenderFailsFastOnMissingVariables(t *testing.T) {
+	body := `Hi {{.name}}, your code is {{.code}}`
+	manifest, err := Lint(body)
+	assert.NoError(t, err)
+
+	_, err = Render(body, manifest, map[string]string{"name": "Ada"})
+	assert.True(t, errors.Is(err, ErrMissingVariables))
+
+	out, err := Render(body, manifest, map[string]string{"name": "Ada", "code": "1234"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Ada, your code is 1234", out)
+}
+
+func TestShorthandVariables(t *testing.T) {
+	body := `Hi {{name}}, your code is {{ code }}{{if .promo}}!{{end}}`
+
+	manifest, err := Lint(body)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"code", "name"}, manifest.Variables)
+	assert.Equal(t, []string{"promo"}, manifest.Optional)
+
+	out, err := Render(body, manifest, map[string]string{"name": "Ada", "code": "1234", "promo": ""})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Ada, your code is 1234", out)
+}
+
+func TestOptionalVariables(t *testing.T) {
+	body := `Hi {{.name | default "there"}}{{if .promo}}, use {{.promo}}{{else}}, {{.code}}{{end}} {{default .fallback .sign}}`
+	manifest, err := Lint(body)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"code", "fallback"}, manifest.Variables)
+	assert.Equal(t, []string{"name", "promo", "sign"}, manifest.Optional)
+
+	out, err := Render(body, manifest, map[string]string{"code": "1234", "fallback": "Acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi there, 1234 Acme", out)
+
+	out, err = Render(body, manifest, map[string]string{"name": "Ada", "promo": "SPRING", "code": "1234", "fallback": "Acme", "sign": "Bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hi Ada, use SPRING Bob", out)
+}
+
+func TestLintRejectsBuiltins(t *testing.T) {
+	for _, body := range []string{
+		`{{call .fn}}`, `{{printf "%v" .name}}`, `{{index .name 0}}`, `{{slice .name 1}}`,
+		`{{js .name}}`, `{{html .name}}`, `{{urlquery .name}}`, `{{print .name}}`, `{{.name | printf "%q"}}`,
+	} {
+		_, err := Lint(body)
+		assert.ErrorIs(t, err, ErrInvalidTemplate, body)
+	}
+}
+
+func TestTitleUpperCasesTheFirstRune(t *testing.T) {
+	title := Funcs["title"].(func(string) string)
+
+	assert.Equal(t, "Ömer", title("ömer"))
+	assert.Equal(t, "", title(""))
+}
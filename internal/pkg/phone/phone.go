@@ -0,0 +1,43 @@
+package phone
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalid is returned when a number cannot be turned into a valid E.164 number.
+var ErrInvalid = errors.New("invalid phone number")
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// separators are the characters people commonly type between digit groups.
+var separators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "", "\t", "")
+
+// Normalize returns raw in E.164 form, e.g. "+90 555 111 11 11" and "05551111111" both
+// become "+905551111111" with defaultCountryCode "90".
+//
+// Numbers starting with + or the 00 international prefix already carry a country code.
+// A leading 0 is the national trunk prefix and is replaced by defaultCountryCode; other
+// numbers are taken as national numbers without the trunk prefix.
+func Normalize(raw, defaultCountryCode string) (string, error) {
+	number := separators.Replace(strings.TrimSpace(raw))
+	defaultCountryCode = strings.TrimPrefix(defaultCountryCode, "+")
+
+	switch {
+	case strings.HasPrefix(number, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = "+" + number[2:]
+	case defaultCountryCode == "":
+		return "", ErrInvalid
+	case strings.HasPrefix(number, "0"):
+		number = "+" + defaultCountryCode + number[1:]
+	default:
+		number = "+" + defaultCountryCode + number
+	}
+
+	if !e164.MatchString(number) {
+		return "", ErrInvalid
+	}
+	return number, nil
+}
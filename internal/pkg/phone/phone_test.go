@@ -0,0 +1,45 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+		err      error
+	}{
+		{raw: "+905551111111", expected: "+905551111111"},
+		{raw: "+90 555 111 11 11", expected: "+905551111111"},
+		{raw: "05551111111", expected: "+905551111111"},
+		{raw: "(0555) 111-11-11", expected: "+905551111111"},
+		{raw: "5551111111", expected: "+905551111111"},
+		{raw: "00905551111111", expected: "+905551111111"},
+		{raw: "+1 415 555 2671", expected: "+14155552671"},
+		{raw: "", err: ErrInvalid},
+		{raw: "+90555abc", err: ErrInvalid},
+		{raw: "123", err: ErrInvalid},
+		{raw: "+0905551111111", err: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			number, err := Normalize(tt.raw, "90")
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.expected, number)
+		})
+	}
+}
+
+func TestNormalizeWithoutDefaultCountryCode(t *testing.T) {
+	_, err := Normalize("05551111111", "")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	number, err := Normalize("+90 555 111 11 11", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "+905551111111", number)
+}
@@ -0,0 +1,263 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// The age v1 format, see https://age-encryption.org/v1. Only X25519 recipients are
+// supported, which is what age-keygen produces.
+const (
+	ageVersionLine    = "age-encryption.org/v1"
+	ageX25519Label    = "age-encryption.org/v1/X25519"
+	ageIdentityPrefix = "age-secret-key-"
+	ageFileKeySize    = 16
+	ageNonceSize      = 16
+	ageChunkSize      = 64 * 1024
+	ageColumnsPerLine = 64
+)
+
+// ErrNoMatchingIdentity is returned when no identity can open an age file.
+var ErrNoMatchingIdentity = errors.New("no identity matched any of the recipients")
+
+// Identity is an age X25519 identity, AGE-SECRET-KEY-1... in an identity file.
+type Identity struct {
+	key *ecdh.PrivateKey
+}
+
+// ParseIdentity parses an AGE-SECRET-KEY-1... string.
+func ParseIdentity(s string) (*Identity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age identity: %w", err)
+	}
+	if hrp != ageIdentityPrefix {
+		return nil, fmt.Errorf("malformed age identity: unexpected prefix %q", hrp)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age identity: %w", err)
+	}
+	return &Identity{key: key}, nil
+}
+
+// ReadIdentities reads the identities of an age identity file, one per line. Blank lines
+// and # comments are skipped, as age-keygen writes them.
+func ReadIdentities(path string) ([]*Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []*Identity
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		identity, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, n+1, err)
+		}
+		identities = append(identities, identity)
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("%s contains no age identities", path)
+	}
+	return identities, nil
+}
+
+// ageStanza is one recipient entry of an age header.
+type ageStanza struct {
+	typ  string
+	args []string
+	body []byte
+}
+
+func decryptAge(file []byte, identities []*Identity) ([]byte, error) {
+	stanzas, headerForMAC, mac, payload, err := parseAgeHeader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileKey []byte
+	for _, stanza := range stanzas {
+		if stanza.typ != "X25519" {
+			continue
+		}
+		for _, identity := range identities {
+			if fileKey, err = identity.unwrap(stanza); err == nil {
+				break
+			}
+		}
+		if fileKey != nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, ErrNoMatchingIdentity
+	}
+
+	hmacKey, err := hkdf.Key(sha256.New, fileKey, nil, "header", 32)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, hmacKey)
+	h.Write(headerForMAC)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, errors.New("age header MAC mismatch")
+	}
+
+	return decryptAgePayload(fileKey, payload)
+}
+
+// unwrap recovers the file key from an X25519 stanza addressed to i.
+func (i *Identity) unwrap(stanza ageStanza) ([]byte, error) {
+	if len(stanza.args) != 1 {
+		return nil, errors.New("malformed X25519 stanza")
+	}
+	share, err := base64.RawStdEncoding.Strict().DecodeString(stanza.args[0])
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := i.key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := append(append([]byte{}, share...), i.key.PublicKey().Bytes()...)
+	wrapKey, err := hkdf.Key(sha256.New, shared, salt, ageX25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(stanza.body) != ageFileKeySize+aead.Overhead() {
+		return nil, errors.New("malformed X25519 stanza body")
+	}
+	return aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), stanza.body, nil)
+}
+
+// parseAgeHeader splits an age file into its stanzas, the header bytes covered by the MAC,
+// the MAC and the payload.
+func parseAgeHeader(file []byte) (stanzas []ageStanza, headerForMAC, mac, payload []byte, err error) {
+	reader := bufio.NewReader(bytes.NewReader(file))
+	offset := 0
+	readLine := func() (string, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", errors.New("truncated age header")
+		}
+		offset += len(line)
+		return strings.TrimSuffix(line, "\n"), nil
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if line != ageVersionLine {
+		return nil, nil, nil, nil, fmt.Errorf("unsupported age version %q", line)
+	}
+
+	for {
+		lineStart := offset
+		if line, err = readLine(); err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		if encoded, ok := strings.CutPrefix(line, "--- "); ok {
+			mac, err = base64.RawStdEncoding.Strict().DecodeString(encoded)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("malformed age header MAC: %w", err)
+			}
+			headerForMAC = file[:lineStart+len("---")]
+			return stanzas, headerForMAC, mac, file[offset:], nil
+		}
+
+		fields, ok := strings.CutPrefix(line, "-> ")
+		if !ok {
+			return nil, nil, nil, nil, fmt.Errorf("malformed age header line %q", line)
+		}
+		args := strings.Split(fields, " ")
+		stanza := ageStanza{typ: args[0], args: args[1:]}
+
+		// The body is wrapped at 64 columns and ends with the first shorter line.
+		var body strings.Builder
+		for {
+			if line, err = readLine(); err != nil {
+				return nil, nil, nil, nil, err
+			}
+			body.WriteString(line)
+			if len(line) < ageColumnsPerLine {
+				break
+			}
+		}
+		if stanza.body, err = base64.RawStdEncoding.Strict().DecodeString(body.String()); err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("malformed age stanza body: %w", err)
+		}
+		stanzas = append(stanzas, stanza)
+	}
+}
+
+// decryptAgePayload opens the STREAM encrypted payload: a nonce, then 64 KiB chunks whose
+// nonce is their big-endian counter with the last byte flagging the final chunk.
+func decryptAgePayload(fileKey, payload []byte) ([]byte, error) {
+	if len(payload) < ageNonceSize {
+		return nil, errors.New("truncated age payload")
+	}
+	key, err := hkdf.Key(sha256.New, fileKey, payload[:ageNonceSize], "payload", chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext []byte
+	rest := payload[ageNonceSize:]
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := uint64(0); ; counter++ {
+		chunk := rest
+		last := len(rest) <= ageChunkSize+aead.Overhead()
+		if !last {
+			chunk = rest[:ageChunkSize+aead.Overhead()]
+		}
+		rest = rest[len(chunk):]
+
+		for i := 0; i < 8; i++ {
+			nonce[10-i] = byte(counter >> (8 * i))
+		}
+		nonce[11] = 0
+		if last {
+			nonce[11] = 1
+		}
+
+		opened, err := aead.Open(nil, nonce, chunk, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt age payload: %w", err)
+		}
+		plaintext = append(plaintext, opened...)
+		if last {
+			return plaintext, nil
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const kmsService = "kms"
+
+// KMSCredentials are the static AWS credentials requests are signed with.
+type KMSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// KMS decrypts ciphertext blobs with the AWS KMS Decrypt API. Requests are signed with
+// Signature Version 4, so no AWS SDK is needed for the few values read at startup.
+type KMS struct {
+	endpoint    string
+	region      string
+	keyID       string
+	credentials KMSCredentials
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewKMS returns a KMS client for region. endpoint defaults to the regional KMS endpoint;
+// keyID, when set, makes KMS reject blobs encrypted under another key.
+func NewKMS(region, endpoint, keyID string, credentials KMSCredentials, client *http.Client) *KMS {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", region)
+	}
+	return &KMS{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      region,
+		keyID:       keyID,
+		credentials: credentials,
+		client:      client,
+		now:         time.Now,
+	}
+}
+
+type kmsDecryptRequest struct {
+	CiphertextBlob []byte `json:"CiphertextBlob"`
+	KeyID          string `json:"KeyId,omitempty"`
+}
+
+type kmsDecryptResponse struct {
+	Plaintext []byte `json:"Plaintext"`
+}
+
+type kmsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// Decrypt returns the plaintext of a KMS ciphertext blob.
+func (k *KMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	body, err := json.Marshal(kmsDecryptRequest{CiphertextBlob: ciphertext, KeyID: k.keyID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	signV4(req, body, k.credentials, k.region, kmsService, k.now())
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr kmsError
+		_ = json.Unmarshal(data, &kmsErr)
+		return nil, fmt.Errorf("kms decrypt: unexpected status code %d: %s %s", resp.StatusCode, kmsErr.Type, kmsErr.Message)
+	}
+
+	var decrypted kmsDecryptResponse
+	if err := json.Unmarshal(data, &decrypted); err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return decrypted.Plaintext, nil
+}
+
+// signV4 adds the Signature Version 4 headers to req. Every header set so far is signed.
+func signV4(req *http.Request, body []byte, credentials KMSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,77 @@
+// Package secrets decrypts configuration values written as ENC[<scheme>:<base64 ciphertext>].
+// The age scheme holds a binary age file encrypted to an X25519 identity, the kms scheme an
+// AWS KMS ciphertext blob.
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	SchemeAge = "age"
+	SchemeKMS = "kms"
+)
+
+// ErrNoKey is returned for an encrypted value whose scheme has no key configured.
+var ErrNoKey = errors.New("no decryption key configured")
+
+// ErrMalformed is returned for an ENC[...] value that cannot be parsed.
+var ErrMalformed = errors.New("malformed encrypted value")
+
+// Decrypter decrypts ENC[...] values with the configured keys. The zero value passes plain
+// values through and rejects encrypted ones.
+type Decrypter struct {
+	identities []*Identity
+	kms        *KMS
+}
+
+// NewDecrypter returns a Decrypter using identities for age values and kms, which may be
+// nil, for KMS values.
+func NewDecrypter(identities []*Identity, kms *KMS) *Decrypter {
+	return &Decrypter{identities: identities, kms: kms}
+}
+
+// IsEncrypted reports whether value is written as ENC[...].
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, "ENC[") && strings.HasSuffix(value, "]")
+}
+
+// Decrypt returns the plaintext of an ENC[...] value and any other value unchanged.
+func (d *Decrypter) Decrypt(ctx context.Context, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	scheme, encoded, ok := strings.Cut(value[len("ENC["):len(value)-1], ":")
+	if !ok {
+		return "", fmt.Errorf("%w: missing scheme", ErrMalformed)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	var plaintext []byte
+	switch scheme {
+	case SchemeAge:
+		if len(d.identities) == 0 {
+			return "", fmt.Errorf("%w for %s", ErrNoKey, scheme)
+		}
+		plaintext, err = decryptAge(ciphertext, d.identities)
+	case SchemeKMS:
+		if d.kms == nil {
+			return "", fmt.Errorf("%w for %s", ErrNoKey, scheme)
+		}
+		plaintext, err = d.kms.Decrypt(ctx, ciphertext)
+	default:
+		return "", fmt.Errorf("%w: unknown scheme %q", ErrMalformed, scheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
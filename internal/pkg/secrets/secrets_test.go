@@ -0,0 +1,222 @@
+package secrets
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// encryptAge writes an age file to recipient following the spec, as age -r would.
+func encryptAge(t *testing.T, recipient *ecdh.PublicKey, plaintext []byte) []byte {
+	t.Helper()
+	fileKey := make([]byte, ageFileKeySize)
+	_, _ = rand.Read(fileKey)
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := ephemeral.PublicKey().Bytes()
+	wrapKey, err := hkdf.Key(sha256.New, shared, append(append([]byte{}, share...), recipient.Bytes()...), ageX25519Label, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := chacha20poly1305.New(wrapKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil)
+
+	header := ageVersionLine + "\n-> X25519 " + base64.RawStdEncoding.EncodeToString(share) + "\n" +
+		base64.RawStdEncoding.EncodeToString(body) + "\n---"
+	hmacKey, err := hkdf.Key(sha256.New, fileKey, nil, "header", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write([]byte(header))
+	file := []byte(header + " " + base64.RawStdEncoding.EncodeToString(mac.Sum(nil)) + "\n")
+
+	nonce := make([]byte, ageNonceSize)
+	_, _ = rand.Read(nonce)
+	payloadKey, err := hkdf.Key(sha256.New, fileKey, nonce, "payload", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err = chacha20poly1305.New(payloadKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunkNonce := make([]byte, chacha20poly1305.NonceSize)
+	chunkNonce[11] = 1
+	file = append(file, nonce...)
+	return aead.Seal(file, chunkNonce, plaintext, nil)
+}
+
+// encodeIdentity formats key as AGE-SECRET-KEY-1..., as age-keygen does.
+func encodeIdentity(t *testing.T, key *ecdh.PrivateKey) string {
+	t.Helper()
+	data, err := convertBits(key.Bytes(), 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := append(bech32HRPExpand(ageIdentityPrefix), data...)
+	checksum := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		data = append(data, byte(checksum>>(5*(5-i))&31))
+	}
+
+	var encoded strings.Builder
+	encoded.WriteString(ageIdentityPrefix + "1")
+	for _, v := range data {
+		encoded.WriteByte(bech32Charset[v])
+	}
+	return strings.ToUpper(encoded.String())
+}
+
+func TestDecryptAge(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "identity.txt")
+	if err := os.WriteFile(path, []byte("# created: 2025-01-01T00:00:00Z\n"+encodeIdentity(t, key)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	identities, err := ReadIdentities(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypter := NewDecrypter(identities, nil)
+
+	value := "ENC[age:" + base64.StdEncoding.EncodeToString(encryptAge(t, key.PublicKey(), []byte("s3cret"))) + "]"
+	plaintext, err := decrypter.Decrypt(context.Background(), value)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", plaintext)
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value = "ENC[age:" + base64.StdEncoding.EncodeToString(encryptAge(t, other.PublicKey(), []byte("s3cret"))) + "]"
+	_, err = decrypter.Decrypt(context.Background(), value)
+	assert.ErrorIs(t, err, ErrNoMatchingIdentity)
+}
+
+// TestDecryptAgeKnownAnswer decrypts files written by the age v1.2.1 command line tools, so
+// a misreading of the spec shared by decryptAge and encryptAge cannot pass unnoticed. The
+// identity is a throwaway made for the test:
+//
+//	age-keygen -o testdata/identity.txt
+//	printf 's3cret-from-age' | age -r <identity> -R <ssh-rsa key> -o testdata/wrapped.age
+//	age -r <identity> -o testdata/multichunk.age <'0123456789abcdef' 4102 times>
+//
+// The ssh-rsa stanza of wrapped.age has a body wrapped at 64 columns, the payload of
+// multichunk.age spans two chunks.
+func TestDecryptAgeKnownAnswer(t *testing.T) {
+	identities, err := ReadIdentities(filepath.Join("testdata", "identity.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped, err := os.ReadFile(filepath.Join("testdata", "wrapped.age"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, string(wrapped), "\n-> ssh-rsa ")
+	plaintext, err := NewDecrypter(identities, nil).Decrypt(context.Background(), "ENC[age:"+base64.StdEncoding.EncodeToString(wrapped)+"]")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret-from-age", plaintext)
+
+	multichunk, err := os.ReadFile(filepath.Join("testdata", "multichunk.age"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := decryptAge(multichunk, identities)
+	assert.NoError(t, err)
+	assert.Greater(t, len(payload), ageChunkSize)
+	assert.Equal(t, strings.Repeat("0123456789abcdef", 4102), string(payload))
+
+	// A flipped bit in the second chunk fails its authentication.
+	multichunk[len(multichunk)-1] ^= 1
+	_, err = decryptAge(multichunk, identities)
+	assert.Error(t, err)
+}
+
+func TestDecryptKMS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+
+		var req kmsDecryptRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if string(req.CiphertextBlob) != "blob" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"InvalidCiphertextException"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(kmsDecryptResponse{Plaintext: []byte("s3cret")})
+	}))
+	defer server.Close()
+
+	kms := NewKMS("eu-west-1", server.URL, "", KMSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, server.Client())
+	decrypter := NewDecrypter(nil, kms)
+
+	plaintext, err := decrypter.Decrypt(context.Background(), "ENC[kms:"+base64.StdEncoding.EncodeToString([]byte("blob"))+"]")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", plaintext)
+
+	_, err = decrypter.Decrypt(context.Background(), "ENC[kms:"+base64.StdEncoding.EncodeToString([]byte("other"))+"]")
+	assert.ErrorContains(t, err, "InvalidCiphertextException")
+}
+
+func TestDecryptPlainAndMalformed(t *testing.T) {
+	decrypter := NewDecrypter(nil, nil)
+	ctx := context.Background()
+
+	plaintext, err := decrypter.Decrypt(ctx, "postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres", plaintext)
+
+	_, err = decrypter.Decrypt(ctx, "ENC[age:AAAA]")
+	assert.ErrorIs(t, err, ErrNoKey)
+	_, err = decrypter.Decrypt(ctx, "ENC[AAAA]")
+	assert.ErrorIs(t, err, ErrMalformed)
+	_, err = decrypter.Decrypt(ctx, "ENC[vault:AAAA]")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+// TestSignV4 checks the signer against the example of the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	credentials := KMSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(req, nil, credentials, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"message-service/internal/mpostgres"
+)
+
+// afterCommit holds the side effects a decorator has for changes made in a transaction:
+// audit entries, cache invalidations, queue entries. They run once the transaction
+// committed, never for one rolled back, and not before other connections see the changes.
+// A nil afterCommit runs them right away.
+type afterCommit struct {
+	mu      sync.Mutex
+	effects []func()
+}
+
+// do runs effect now, or after the commit when a is set.
+func (a *afterCommit) do(effect func()) {
+	if a == nil {
+		effect()
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.effects = append(a.effects, effect)
+}
+
+func (a *afterCommit) run() {
+	a.mu.Lock()
+	effects := a.effects
+	a.effects = nil
+	a.mu.Unlock()
+
+	for _, effect := range effects {
+		effect()
+	}
+}
+
+// withTx runs fn in a transaction of messages and then the effects fn collected. Inside
+// the transaction of parent, the effects wait for parent instead.
+func withTx(ctx context.Context, messages mpostgres.MessageService, parent *afterCommit, fn func(tx mpostgres.MessageService, effects *afterCommit) error) error {
+	effects := &afterCommit{}
+	err := messages.WithTx(ctx, func(tx mpostgres.MessageService) error {
+		return fn(tx, effects)
+	})
+	if err != nil {
+		return err
+	}
+
+	parent.do(effects.run)
+	return nil
+}
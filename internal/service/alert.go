@@ -0,0 +1,25 @@
+package service
+
+import (
+	"time"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// Alert describes an operational condition that needs attention from an operator.
+type Alert struct {
+	Name    string
+	Message string
+	Err     error
+	Time    time.Time
+}
+
+// AlertHook is invoked whenever the service raises an alert.
+type AlertHook func(alert Alert)
+
+// NewLogAlertHook returns an AlertHook that writes alerts to the logger.
+func NewLogAlertHook(logger inslogger.Interface) AlertHook {
+	return func(alert Alert) {
+		logger.Errorf("ALERT [%s] %s: %v", alert.Name, alert.Message, alert.Err)
+	}
+}
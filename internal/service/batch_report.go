@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// batchReportingRuns posts a model.BatchReport of every recorded scheduler run to an ops
+// endpoint. Reports are best effort: a failed post is logged and never fails the run.
+type batchReportingRuns struct {
+	mpostgres.SchedulerRunService
+	url        string
+	timeout    time.Duration
+	httpClient *http.Client
+	logger     inslogger.Interface
+}
+
+// NewBatchReportingRunService reports every run recorded in runs to url, waiting at most
+// timeout per report. It returns runs unchanged when url is empty.
+func NewBatchReportingRunService(runs mpostgres.SchedulerRunService, url string, timeout time.Duration, httpClient *http.Client, logger inslogger.Interface) mpostgres.SchedulerRunService {
+	if url == "" {
+		return runs
+	}
+
+	return &batchReportingRuns{
+		SchedulerRunService: runs,
+		url:                 url,
+		timeout:             timeout,
+		httpClient:          httpClient,
+		logger:              logger,
+	}
+}
+
+func (r *batchReportingRuns) RecordRun(ctx context.Context, run model.SchedulerRun) error {
+	err := r.SchedulerRunService.RecordRun(ctx, run)
+	if reportErr := r.report(ctx, newBatchReport(run)); reportErr != nil {
+		r.logger.Warnf("Failed to post %s batch report: %v", run.Channel, reportErr)
+	}
+	return err
+}
+
+func (r *batchReportingRuns) report(ctx context.Context, report model.BatchReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func newBatchReport(run model.SchedulerRun) model.BatchReport {
+	errors := []string{}
+	if run.Errors != "" {
+		errors = strings.Split(run.Errors, "\n")
+	}
+
+	return model.BatchReport{
+		InstanceID: run.InstanceID,
+		Channel:    run.Channel,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		DurationMS: run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+		BatchSize:  run.BatchSize,
+		Claimed:    run.Claimed,
+		Sent:       run.Sent,
+		Failed:     run.Failed,
+		Skipped:    run.Skipped,
+		Errors:     errors,
+	}
+}
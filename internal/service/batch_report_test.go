@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestBatchReportingRunServicePostsReport(t *testing.T) {
+	reports := make(chan model.BatchReport, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report model.BatchReport
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&report))
+		reports <- report
+	}))
+	defer server.Close()
+
+	runs := mmemory.NewSchedulerRunService(0)
+	reporting := NewBatchReportingRunService(runs, server.URL, time.Second, server.Client(), inslogger.NewNopLogger())
+	startedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	err := reporting.RecordRun(context.Background(), model.SchedulerRun{
+		Channel:    model.ChannelSMS,
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(1500 * time.Millisecond),
+		BatchSize:  10,
+		Claimed:    4,
+		Sent:       2,
+		Failed:     2,
+		Errors:     "unexpected status code: 500\nconnection reset",
+	})
+
+	assert.NoError(t, err)
+	report := <-reports
+	assert.Equal(t, int64(1500), report.DurationMS)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, []string{"unexpected status code: 500", "connection reset"}, report.Errors)
+	listed, _ := runs.ListRuns(context.Background(), 10)
+	assert.Len(t, listed, 1, "the run is still recorded")
+}
+
+func TestBatchReportingRunServiceIgnoresReportFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	reporting := NewBatchReportingRunService(mmemory.NewSchedulerRunService(0), server.URL, time.Second, server.Client(), inslogger.NewNopLogger())
+
+	assert.NoError(t, reporting.RecordRun(context.Background(), model.SchedulerRun{Channel: model.ChannelSMS}))
+}
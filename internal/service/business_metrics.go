@@ -0,0 +1,93 @@
+package service
+
+import (
+	"strings"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/metrics"
+)
+
+const defaultTenant = "default"
+
+// BusinessMetrics records product level KPIs about message delivery.
+type BusinessMetrics interface {
+	RecordSend(message model.Message, err error)
+}
+
+type businessMetrics struct {
+	messages  *metrics.CounterVec
+	countries *metrics.CounterVec
+}
+
+type nopBusinessMetrics struct{}
+
+func (nopBusinessMetrics) RecordSend(model.Message, error) {}
+
+// NewBusinessMetrics registers the KPI counters on the default registry when enabled,
+// and returns a no-op recorder otherwise.
+func NewBusinessMetrics(enabled bool) BusinessMetrics {
+	if !enabled {
+		return nopBusinessMetrics{}
+	}
+
+	return &businessMetrics{
+		messages: metrics.NewCounterVec(
+			"business_messages",
+			"Messages handed to the provider by tenant, channel and outcome.",
+			"tenant", "channel", "status",
+		),
+		countries: metrics.NewCounterVec(
+			"business_deliveries_by_country",
+			"Delivery attempts by recipient country and outcome.",
+			"country", "status",
+		),
+	}
+}
+
+func (m *businessMetrics) RecordSend(message model.Message, err error) {
+	status := "delivered"
+	if err != nil {
+		status = "failed"
+	}
+
+	tenant := message.Tenant
+	if tenant == "" {
+		tenant = defaultTenant
+	}
+	m.messages.Inc(tenant, string(message.DeliveryChannel()), status)
+	m.countries.Inc(countryFromPhone(message.RecipientPhone), status)
+}
+
+// callingCodes maps international calling codes to ISO country codes.
+// Longer codes are matched first so that e.g. +90 is not mistaken for +9.
+var callingCodes = []struct {
+	prefix  string
+	country string
+}{
+	{"+971", "AE"},
+	{"+966", "SA"},
+	{"+90", "TR"},
+	{"+86", "CN"},
+	{"+81", "JP"},
+	{"+61", "AU"},
+	{"+55", "BR"},
+	{"+49", "DE"},
+	{"+44", "GB"},
+	{"+39", "IT"},
+	{"+34", "ES"},
+	{"+33", "FR"},
+	{"+31", "NL"},
+	{"+91", "IN"},
+	{"+7", "RU"},
+	{"+1", "US"},
+}
+
+func countryFromPhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	for _, code := range callingCodes {
+		if strings.HasPrefix(phone, code.prefix) {
+			return code.country
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessMetricsRecordSendByChannel(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := &businessMetrics{
+		messages:  registry.NewCounterVec("business_messages", "", "tenant", "channel", "status"),
+		countries: registry.NewCounterVec("business_deliveries_by_country", "", "country", "status"),
+	}
+
+	m.RecordSend(model.Message{Tenant: "acme", Channel: model.ChannelEmail, RecipientEmail: "a@example.com"}, nil)
+	m.RecordSend(model.Message{RecipientPhone: "+905551111111"}, errors.New("gateway down"))
+
+	assert.Equal(t, 1.0, m.messages.Value("acme", "email", "delivered"))
+	assert.Equal(t, 0.0, m.messages.Value("acme", "sms", "delivered"))
+	assert.Equal(t, 1.0, m.messages.Value(defaultTenant, "sms", "failed"), "messages without a channel are SMS")
+}
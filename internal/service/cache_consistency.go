@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// Kinds of divergence between Redis and the database.
+const (
+	// divergenceMessage is a cached message whose status differs from the stored one.
+	divergenceMessage = "message"
+	// divergenceSentMarker is a sent marker of a message the database does not have as sent.
+	divergenceSentMarker = "sent_marker"
+)
+
+// maxDivergenceExamples bounds the divergent messages logged per check.
+const maxDivergenceExamples = 5
+
+var (
+	cacheDivergences = metrics.NewCounterVec(
+		"cache_divergences_total",
+		"Sampled messages whose Redis state disagreed with the database, by kind.",
+		"kind",
+	)
+	cacheDivergent = metrics.NewGaugeVec(
+		"cache_divergent_messages",
+		"Divergent messages found by the latest cache consistency check, by kind.",
+		"kind",
+	)
+)
+
+// CacheDivergence is a message whose Redis state disagrees with the database.
+type CacheDivergence struct {
+	ID     uint
+	Kind   string
+	Cached string
+	Stored model.MessageStatus
+}
+
+// CacheConsistencyChecker compares what Redis holds about the latest messages, the cached
+// message and the sent marker of the dispatcher, with the database, so cache bugs show up
+// in metrics before they reach customers.
+type CacheConsistencyChecker struct {
+	messages    mpostgres.MessageService
+	redisClient insredis.RedisInterface
+	elector     LeaderElector
+	sample      int
+	interval    time.Duration
+	logger      inslogger.Interface
+}
+
+// NewCacheConsistencyChecker checks the latest sample messages of messages, which must
+// read the database and not the cache, every interval.
+func NewCacheConsistencyChecker(messages mpostgres.MessageService, redisClient insredis.RedisInterface, elector LeaderElector, sample int, interval time.Duration, logger inslogger.Interface) *CacheConsistencyChecker {
+	return &CacheConsistencyChecker{
+		messages:    messages,
+		redisClient: redisClient,
+		elector:     elector,
+		sample:      sample,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+// Run checks on every tick until ctx is done, on the scheduler leader only. It returns at
+// once when the interval is zero.
+func (c *CacheConsistencyChecker) Run(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.elector.IsLeader() {
+				c.logCheck(ctx)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *CacheConsistencyChecker) logCheck(ctx context.Context) {
+	divergences, err := c.Check(ctx)
+	if err != nil {
+		c.logger.Warnf("Failed to check the message cache: %v", err)
+		return
+	}
+	if len(divergences) == 0 {
+		return
+	}
+
+	c.logger.Warnf("Cache consistency: %d of the latest %d messages diverge from the database", len(divergences), c.sample)
+	for i, divergence := range divergences {
+		if i == maxDivergenceExamples {
+			break
+		}
+		c.logger.Warnf("Cache consistency: message ID %d %s is %q in Redis, %s in the database",
+			divergence.ID, divergence.Kind, divergence.Cached, divergence.Stored)
+	}
+}
+
+// Check compares the latest messages with Redis once and updates the metrics. Messages
+// Redis holds nothing about are consistent.
+func (c *CacheConsistencyChecker) Check(ctx context.Context) ([]CacheDivergence, error) {
+	messages, err := c.messages.ListMessages(ctx, model.MessageFilter{Page: 1, PageSize: c.sample})
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) > c.sample {
+		messages = messages[:c.sample]
+	}
+
+	var divergences []CacheDivergence
+	for _, message := range messages {
+		found := c.compare(message)
+		if len(found) == 0 {
+			continue
+		}
+		// The listing may come from a lagging read replica, so divergences are confirmed
+		// against the primary.
+		if stored, err := c.messages.GetMessage(ctx, message.ID); err == nil {
+			found = c.compare(stored)
+		}
+		divergences = append(divergences, found...)
+	}
+
+	counts := map[string]int{divergenceMessage: 0, divergenceSentMarker: 0}
+	for _, divergence := range divergences {
+		counts[divergence.Kind]++
+	}
+	for kind, count := range counts {
+		cacheDivergences.Add(float64(count), kind)
+		cacheDivergent.Set(float64(count), kind)
+	}
+	return divergences, nil
+}
+
+// compare returns how Redis diverges from message.
+func (c *CacheConsistencyChecker) compare(message model.Message) []CacheDivergence {
+	var divergences []CacheDivergence
+	if status, ok := c.cachedStatus(message.ID); ok && status != message.Status {
+		divergences = append(divergences, CacheDivergence{ID: message.ID, Kind: divergenceMessage, Cached: string(status), Stored: message.Status})
+	}
+	// The marker is set once the provider accepted the message, which may be shortly
+	// before the database records it as sent.
+	if sentAt, err := SentAt(c.redisClient, message.ID); err == nil && message.Status != model.StatusSent && message.Status != model.StatusSending {
+		divergences = append(divergences, CacheDivergence{ID: message.ID, Kind: divergenceSentMarker, Cached: sentAt.Format(time.RFC3339), Stored: message.Status})
+	}
+	return divergences
+}
+
+// cachedStatus returns the status of the cached message id, if one is cached.
+func (c *CacheConsistencyChecker) cachedStatus(id uint) (model.MessageStatus, bool) {
+	cached, err := c.redisClient.Get(messageCacheKey(id)).Result()
+	if err != nil {
+		if err != redis.Nil {
+			c.logger.Warnf("Failed to read cached message ID %d: %v", id, err)
+		}
+		return "", false
+	}
+
+	var message model.Message
+	if err := json.Unmarshal([]byte(cached), &message); err != nil {
+		return "", false
+	}
+	return message.Status, true
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+)
+
+const (
+	// MaxScheduleBatchSize bounds how many messages one scheduled batch may claim.
+	MaxScheduleBatchSize = 1000
+	// MinScheduleInterval keeps a misconfigured channel from hammering the database.
+	MinScheduleInterval = time.Second
+)
+
+// ErrUnscheduledChannel is returned when a channel has no batch loop, usually because
+// no provider is registered for it.
+var ErrUnscheduledChannel = errors.New("channel is not scheduled")
+
+// ErrInvalidChannelSettings is returned for a batch size or interval out of range.
+var ErrInvalidChannelSettings = errors.New("invalid channel settings")
+
+// ChannelSettings is how many messages a channel's batches claim and how often they run.
+type ChannelSettings struct {
+	BatchSize int
+	Interval  time.Duration
+}
+
+// WarmupSettings soften the start of the scheduler, so a deploy does not hand a full
+// backlog to the providers at once.
+type WarmupSettings struct {
+	// Batches is how many batches a channel takes to grow to its batch size after Start,
+	// zero starts at full size.
+	Batches int
+	// SkipFirstBatch waits one interval for the first batch instead of running it on Start.
+	SkipFirstBatch bool
+}
+
+// batchSize returns the size of the batch after ran batches since Start, growing linearly
+// from target/Batches to target.
+func (w WarmupSettings) batchSize(target, ran int) int {
+	if ran >= w.Batches {
+		return target
+	}
+	return (target*(ran+1) + w.Batches - 1) / w.Batches
+}
+
+func (s ChannelSettings) Validate() error {
+	if s.BatchSize < 1 || s.BatchSize > MaxScheduleBatchSize {
+		return fmt.Errorf("%w: batch size must be between 1 and %d", ErrInvalidChannelSettings, MaxScheduleBatchSize)
+	}
+	if s.Interval < MinScheduleInterval {
+		return fmt.Errorf("%w: interval must be at least %s", ErrInvalidChannelSettings, MinScheduleInterval)
+	}
+	return nil
+}
+
+// ResolveChannelSettings returns the settings of every channel in channels: defaults,
+// replaced by overrides ("batch/interval", e.g. "500/5m") and then by the JSON file, e.g.
+// {"email": {"batch_size": 500, "interval": "5m"}}. Overrides of channels that are not in
+// channels are ignored so settings can be shared by deployments without every provider.
+func ResolveChannelSettings(channels []model.Channel, defaults ChannelSettings, overrides map[string]string, file string) (map[model.Channel]ChannelSettings, error) {
+	if err := defaults.Validate(); err != nil {
+		return nil, fmt.Errorf("default settings: %w", err)
+	}
+
+	resolved := make(map[model.Channel]ChannelSettings, len(channels))
+	for _, channel := range channels {
+		resolved[channel] = defaults
+	}
+
+	apply := func(name string, settings ChannelSettings) error {
+		channel := model.Channel(name)
+		if !channel.Valid() {
+			return fmt.Errorf("unknown channel %q", name)
+		}
+		if err := settings.Validate(); err != nil {
+			return fmt.Errorf("channel %s: %w", name, err)
+		}
+		if _, ok := resolved[channel]; ok {
+			resolved[channel] = settings
+		}
+		return nil
+	}
+
+	for name, raw := range overrides {
+		settings, err := parseChannelOverride(raw)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w", name, err)
+		}
+		if err := apply(name, settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if file != "" {
+		fromFile, err := readChannelSettingsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, settings := range fromFile {
+			if err := apply(name, settings); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+func parseChannelOverride(raw string) (ChannelSettings, error) {
+	batch, interval, ok := strings.Cut(raw, "/")
+	if !ok {
+		return ChannelSettings{}, fmt.Errorf("%w: %q is not batch/interval", ErrInvalidChannelSettings, raw)
+	}
+
+	batchSize, err := strconv.Atoi(strings.TrimSpace(batch))
+	if err != nil {
+		return ChannelSettings{}, fmt.Errorf("%w: batch size %q", ErrInvalidChannelSettings, batch)
+	}
+	every, err := time.ParseDuration(strings.TrimSpace(interval))
+	if err != nil {
+		return ChannelSettings{}, fmt.Errorf("%w: interval %q", ErrInvalidChannelSettings, interval)
+	}
+
+	return ChannelSettings{BatchSize: batchSize, Interval: every}, nil
+}
+
+func readChannelSettingsFile(path string) (map[string]ChannelSettings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]struct {
+		BatchSize int    `json:"batch_size"`
+		Interval  string `json:"interval"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid channel settings file %s: %w", path, err)
+	}
+
+	settings := make(map[string]ChannelSettings, len(raw))
+	for name, entry := range raw {
+		interval, err := time.ParseDuration(entry.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("channel %s: %w: interval %q", name, ErrInvalidChannelSettings, entry.Interval)
+		}
+		settings[name] = ChannelSettings{BatchSize: entry.BatchSize, Interval: interval}
+	}
+	return settings, nil
+}
+
+func sortedChannels(schedules map[model.Channel]ChannelSettings) []model.Channel {
+	channels := make([]model.Channel, 0, len(schedules))
+	for channel := range schedules {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+	return channels
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"message-service/internal/pkg/metrics"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+var circuitBreakerState = metrics.NewGaugeVec(
+	"circuit_breaker_state",
+	"Circuit breaker state per breaker: 0 closed, 1 half-open, 2 open.",
+	"breaker",
+)
+
+// ErrCircuitOpen is returned instead of calling the provider while the breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calls to a failing dependency. After threshold consecutive
+// failures it opens and rejects calls for cooldown, then lets a single probe through
+// (half-open); a successful probe closes it again, a failed one reopens it.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	logger    inslogger.Interface
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker returns a closed breaker. A threshold of zero or less disables it.
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration, logger inslogger.Interface) *CircuitBreaker {
+	circuitBreakerState.Set(float64(CircuitClosed), name)
+
+	return &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// State returns the current state. An open breaker whose cooldown elapsed reports
+// half-open even before the probe is let through.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Allow returns ErrCircuitOpen when the call must not be made. Every allowed call must be
+// followed by Record with its outcome.
+func (b *CircuitBreaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *CircuitBreaker) Record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitHalfOpen:
+		b.probing = false
+		if success {
+			b.failures = 0
+			b.setState(CircuitClosed)
+		} else {
+			b.open()
+		}
+	case CircuitClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.openedAt = b.now()
+	b.setState(CircuitOpen)
+}
+
+// setState logs and exports transitions only, so an outage produces a single event. Callers must hold mu.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if state == b.state {
+		return
+	}
+
+	if state == CircuitOpen {
+		b.logger.Warnf("Circuit breaker %s changed from %s to %s after %d consecutive failures, retrying in %s",
+			b.name, b.state, state, b.failures, b.cooldown)
+	} else {
+		b.logger.Logf("Circuit breaker %s changed from %s to %s", b.name, b.state, state)
+	}
+
+	b.state = state
+	circuitBreakerState.Set(float64(state), b.name)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker("test-breaker", 3, time.Minute, inslogger.NewNopLogger())
+	breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, breaker.Allow())
+		breaker.Record(false)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	assert.Equal(t, float64(CircuitOpen), circuitBreakerState.Value("test-breaker"))
+
+	// After the cooldown a single probe is let through.
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+
+	// A failed probe reopens the breaker for another cooldown.
+	breaker.Record(false)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	breaker.Record(true)
+	assert.Equal(t, CircuitClosed, breaker.State())
+	assert.Equal(t, float64(CircuitClosed), circuitBreakerState.Value("test-breaker"))
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	breaker := NewCircuitBreaker("test-reset", 2, time.Minute, inslogger.NewNopLogger())
+
+	breaker.Record(false)
+	breaker.Record(true)
+	breaker.Record(false)
+
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := NewCircuitBreaker("test-disabled", 0, time.Minute, inslogger.NewNopLogger())
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, breaker.Allow())
+		breaker.Record(false)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+var claimsReaped = metrics.NewCounterVec(
+	"claims_reaped_total",
+	"Sending messages returned to pending after their claim lease expired.",
+)
+
+// ClaimReaper returns messages stuck in sending, because the replica sending them crashed
+// between claiming and finalizing, to pending so the scheduler picks them up again. Only the
+// scheduler leader runs it.
+//
+// A reaped message may already have reached the provider, so it can be delivered twice:
+// sending is at-least-once, never at-most-once.
+type ClaimReaper struct {
+	messages mpostgres.MessageService
+	elector  LeaderElector
+	interval time.Duration
+	logger   inslogger.Interface
+}
+
+// NewClaimReaper reaps expired claims every interval. A zero interval disables the reaper.
+func NewClaimReaper(messages mpostgres.MessageService, elector LeaderElector, interval time.Duration, logger inslogger.Interface) *ClaimReaper {
+	return &ClaimReaper{
+		messages: messages,
+		elector:  elector,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run reaps on every tick until ctx is done. It returns at once when the reaper is disabled.
+func (r *ClaimReaper) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Reap(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reap returns the expired claims to pending once if this replica is the leader.
+func (r *ClaimReaper) Reap(ctx context.Context) {
+	if !r.elector.IsLeader() {
+		return
+	}
+
+	ids, err := r.messages.ReapExpiredClaims(ctx)
+	if err != nil {
+		r.logger.Errorf("Claim reaper failed: %v", err)
+		return
+	}
+	if len(ids) > 0 {
+		claimsReaped.Add(float64(len(ids)))
+		r.logger.Warnf("Returned %d messages with expired claims to pending, they may be sent twice: %v", len(ids), ids)
+	}
+}
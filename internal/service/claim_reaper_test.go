@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestClaimReaperReturnsExpiredClaimsToPending(t *testing.T) {
+	stale := time.Now().Add(-time.Hour)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Status: model.StatusSending, CreatedAt: stale},
+		model.Message{ID: 2, Status: model.StatusPending, CreatedAt: stale},
+		model.Message{ID: 3, Status: model.StatusSent, CreatedAt: stale},
+	)
+	require.NoError(t, messages.MarkMessageSending(context.Background(), 2))
+
+	NewClaimReaper(messages, stubElector(false), time.Minute, inslogger.NewNopLogger()).Reap(context.Background())
+	message, err := messages.Message(1)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusSending, message.Status, "only the leader reaps")
+
+	NewClaimReaper(messages, stubElector(true), time.Minute, inslogger.NewNopLogger()).Reap(context.Background())
+
+	message, err = messages.Message(1)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusPending, message.Status, "a stale send without a lease is reaped")
+	message, err = messages.Message(2)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusSending, message.Status, "a send within its lease is kept")
+	message, err = messages.Message(3)
+	require.NoError(t, err)
+	assert.Equal(t, model.StatusSent, message.Status)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/httptape"
+)
+
+// Diagnostics runs the live checks of GET /api/admin/diagnostics: dependency latencies,
+// provider reachability, the backlog, stuck messages, circuit breakers, the scheduler and
+// settings that are unusual for production.
+type Diagnostics struct {
+	pings     map[string]func(ctx context.Context) error
+	providers *ProviderRegistry
+	messages  mpostgres.MessageService
+	breakers  []*CircuitBreaker
+	scheduler SchedulerService
+	elector   LeaderElector
+	config    *config.App
+	// instanceID names the replica that ran the checks in the report.
+	instanceID string
+	now        func() time.Time
+}
+
+// NewDiagnostics times every ping, e.g. the database and Redis, probes the providers of
+// providers that support it and reads the backlog from messages.
+func NewDiagnostics(pings map[string]func(ctx context.Context) error, providers *ProviderRegistry, messages mpostgres.MessageService,
+	breakers []*CircuitBreaker, scheduler SchedulerService, elector LeaderElector, config *config.App, instanceID string) *Diagnostics {
+	return &Diagnostics{
+		pings:      pings,
+		providers:  providers,
+		messages:   messages,
+		breakers:   breakers,
+		scheduler:  scheduler,
+		elector:    elector,
+		config:     config,
+		instanceID: instanceID,
+		now:        time.Now,
+	}
+}
+
+type diagnosticCheck struct {
+	name string
+	run  func(ctx context.Context) model.DiagnosticCheck
+}
+
+// Run runs every check at once, each for at most DIAGNOSTICS_TIMEOUT, and returns them in
+// a fixed order with the worst status as the status of the report.
+func (d *Diagnostics) Run(ctx context.Context) model.DiagnosticsReport {
+	checks := d.checks()
+	results := make([]model.DiagnosticCheck, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, d.config.Diagnostics.Timeout)
+			defer cancel()
+
+			result := check.run(checkCtx)
+			result.Name = check.name
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	report := model.DiagnosticsReport{
+		Status:      model.DiagnosticOK,
+		InstanceID:  d.instanceID,
+		GeneratedAt: d.now().UTC(),
+		Checks:      results,
+	}
+	for _, result := range results {
+		if result.Status.Worse(report.Status) {
+			report.Status = result.Status
+		}
+	}
+	return report
+}
+
+func (d *Diagnostics) checks() []diagnosticCheck {
+	var checks []diagnosticCheck
+	for _, name := range []string{"database", "redis"} {
+		if ping, ok := d.pings[name]; ok {
+			checks = append(checks, diagnosticCheck{name: name, run: d.timed(ping)})
+		}
+	}
+	for _, provider := range d.providers.Providers() {
+		prober, ok := provider.(ProviderProber)
+		if !ok {
+			continue
+		}
+		checks = append(checks, diagnosticCheck{name: "provider " + provider.Name(), run: d.timed(prober.Probe)})
+	}
+	checks = append(checks,
+		diagnosticCheck{name: "backlog", run: d.checkBacklog},
+		diagnosticCheck{name: "stuck messages", run: d.checkStuck},
+	)
+	for _, breaker := range d.breakers {
+		checks = append(checks, diagnosticCheck{name: "circuit " + breaker.Name(), run: checkBreaker(breaker)})
+	}
+	return append(checks,
+		diagnosticCheck{name: "scheduler", run: d.checkScheduler},
+		diagnosticCheck{name: "config", run: d.checkConfig},
+	)
+}
+
+// timed runs call and reports its latency, a warning when it is slower than
+// DIAGNOSTICS_SLOW_THRESHOLD. It stops waiting once ctx is done, as some clients, e.g. the
+// Redis ping, ignore ctx.
+func (d *Diagnostics) timed(call func(ctx context.Context) error) func(ctx context.Context) model.DiagnosticCheck {
+	return func(ctx context.Context) model.DiagnosticCheck {
+		start := time.Now()
+		done := make(chan error, 1)
+		go func() { done <- call(ctx) }()
+
+		var err error
+		select {
+		case err = <-done:
+		case <-ctx.Done():
+			err = fmt.Errorf("no answer: %w", ctx.Err())
+		}
+		latency := time.Since(start)
+		latencyMs := float64(latency.Microseconds()) / 1000
+
+		switch {
+		case err != nil:
+			return model.DiagnosticCheck{Status: model.DiagnosticFail, LatencyMs: &latencyMs, Detail: err.Error()}
+		case latency > d.config.Diagnostics.SlowThreshold:
+			return model.DiagnosticCheck{Status: model.DiagnosticWarn, LatencyMs: &latencyMs,
+				Detail: fmt.Sprintf("slower than %s", d.config.Diagnostics.SlowThreshold)}
+		}
+		return model.DiagnosticCheck{Status: model.DiagnosticOK, LatencyMs: &latencyMs}
+	}
+}
+
+func (d *Diagnostics) checkBacklog(ctx context.Context) model.DiagnosticCheck {
+	backlog, err := d.messages.GetBacklog(ctx)
+	if err != nil {
+		return model.DiagnosticCheck{Status: model.DiagnosticFail, Detail: err.Error()}
+	}
+	if backlog.OldestDueAt == nil {
+		return model.DiagnosticCheck{Status: model.DiagnosticOK, Detail: "no pending messages"}
+	}
+
+	age := d.now().Sub(*backlog.OldestDueAt).Truncate(time.Second)
+	result := model.DiagnosticCheck{Status: model.DiagnosticOK, Detail: fmt.Sprintf("%d pending, oldest due %s ago", backlog.Pending, age)}
+	if age > d.config.Diagnostics.BacklogAge {
+		result.Status = model.DiagnosticWarn
+	}
+	return result
+}
+
+func (d *Diagnostics) checkStuck(ctx context.Context) model.DiagnosticCheck {
+	stuckAfter := d.config.Diagnostics.StuckAfter
+	stuck, err := d.messages.CountStuckMessages(ctx, d.now().Add(-stuckAfter))
+	if err != nil {
+		return model.DiagnosticCheck{Status: model.DiagnosticFail, Detail: err.Error()}
+	}
+	if stuck > 0 {
+		return model.DiagnosticCheck{Status: model.DiagnosticWarn, Detail: fmt.Sprintf("%d messages sending for more than %s", stuck, stuckAfter)}
+	}
+	return model.DiagnosticCheck{Status: model.DiagnosticOK}
+}
+
+func checkBreaker(breaker *CircuitBreaker) func(ctx context.Context) model.DiagnosticCheck {
+	return func(context.Context) model.DiagnosticCheck {
+		state := breaker.State()
+		switch state {
+		case CircuitOpen:
+			return model.DiagnosticCheck{Status: model.DiagnosticFail, Detail: state.String()}
+		case CircuitHalfOpen:
+			return model.DiagnosticCheck{Status: model.DiagnosticWarn, Detail: state.String()}
+		}
+		return model.DiagnosticCheck{Status: model.DiagnosticOK, Detail: state.String()}
+	}
+}
+
+func (d *Diagnostics) checkScheduler(context.Context) model.DiagnosticCheck {
+	role := "follower"
+	if d.elector.IsLeader() {
+		role = "leader"
+	}
+
+	if reason := d.scheduler.PauseReason(); reason != nil {
+		return model.DiagnosticCheck{Status: model.DiagnosticWarn, Detail: fmt.Sprintf("paused: %v (%s)", reason, role)}
+	}
+	if !d.scheduler.IsRunning() {
+		return model.DiagnosticCheck{Status: model.DiagnosticWarn, Detail: "stopped (" + role + ")"}
+	}
+	return model.DiagnosticCheck{Status: model.DiagnosticOK, Detail: "running (" + role + ")"}
+}
+
+// checkConfig warns about settings that are fine for development but unusual in production.
+func (d *Diagnostics) checkConfig(context.Context) model.DiagnosticCheck {
+	var anomalies []string
+	if d.config.Local.Enabled {
+		anomalies = append(anomalies, "LOCAL_MODE keeps messages in memory")
+	}
+	if !d.config.Local.Enabled && !d.config.Redis.Enabled {
+		anomalies = append(anomalies, "REDIS_ENABLED is false, locks and limits only hold for this replica")
+	}
+	if d.config.Auth.Disabled {
+		anomalies = append(anomalies, "AUTH_DISABLED is true, /api is not authenticated")
+	}
+	if mode := httptape.Mode(d.config.Tape.Mode); mode != httptape.ModeOff {
+		anomalies = append(anomalies, fmt.Sprintf("PROVIDER_TAPE_MODE is %s", mode))
+	}
+	if d.config.Server.GinMode == "debug" {
+		anomalies = append(anomalies, "GIN_MODE is debug")
+	}
+	if d.config.SMTP.Host == "" {
+		anomalies = append(anomalies, "SMTP_HOST is empty, email messages cannot be sent")
+	}
+
+	if len(anomalies) > 0 {
+		return model.DiagnosticCheck{Status: model.DiagnosticWarn, Detail: strings.Join(anomalies, "; ")}
+	}
+	return model.DiagnosticCheck{Status: model.DiagnosticOK}
+}
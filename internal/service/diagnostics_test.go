@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestDiagnostics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = server.URL
+	cfg.Auth.APIKeys = map[string]string{"ops": "k"}
+	cfg.Tape.Mode = "off"
+	cfg.SMTP.Host = "smtp.example.com"
+	cfg.Redis.Enabled = true
+	cfg.Diagnostics = config.DiagnosticsConfig{Timeout: time.Second, SlowThreshold: time.Second, BacklogAge: 10 * time.Minute, StuckAfter: 10 * time.Minute}
+
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, NewWebhookProvider(NewCircuitBreaker("webhook", 0, 0, inslogger.NewNopLogger()), &http.Client{}, cfg, inslogger.NewNopLogger()))
+	providers.Register(model.ChannelPush, &stubProvider{name: "push"})
+
+	old := time.Now().Add(-time.Hour)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Status: model.StatusPending, CreatedAt: old},
+		model.Message{ID: 2, Status: model.StatusSending, CreatedAt: old, UpdatedAt: old},
+	)
+	breaker := NewCircuitBreaker("webhook", 1, time.Hour, inslogger.NewNopLogger())
+	breaker.Record(false)
+	scheduler := NewSchedulerService(&stubSender{}, stubElector(true), mmemory.NewSchedulerRunService(0), nil, WarmupSettings{}, inslogger.NewNopLogger())
+	pings := map[string]func(ctx context.Context) error{
+		"database": func(context.Context) error { return nil },
+		"redis":    func(context.Context) error { return errors.New("connection refused") },
+	}
+
+	report := NewDiagnostics(pings, providers, messages, []*CircuitBreaker{breaker}, scheduler, stubElector(true), cfg, "replica-1").Run(context.Background())
+
+	assert.Equal(t, model.DiagnosticFail, report.Status, "the worst check decides")
+	assert.Equal(t, "replica-1", report.InstanceID)
+	statuses := map[string]model.DiagnosticStatus{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, map[string]model.DiagnosticStatus{
+		"database":         model.DiagnosticOK,
+		"redis":            model.DiagnosticFail,
+		"provider webhook": model.DiagnosticOK,
+		"backlog":          model.DiagnosticWarn,
+		"stuck messages":   model.DiagnosticWarn,
+		"circuit webhook":  model.DiagnosticFail,
+		"scheduler":        model.DiagnosticWarn,
+		"config":           model.DiagnosticOK,
+	}, statuses, "the push provider cannot be probed")
+	assert.Equal(t, "database", report.Checks[0].Name, "checks keep their order")
+	assert.NotNil(t, report.Checks[0].LatencyMs)
+}
+
+func TestDiagnosticsProviderUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = url
+	cfg.Auth.Disabled = true
+	cfg.Diagnostics = config.DiagnosticsConfig{Timeout: time.Second, SlowThreshold: time.Second, BacklogAge: time.Minute, StuckAfter: time.Minute}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, NewWebhookProvider(NewCircuitBreaker("webhook", 0, 0, inslogger.NewNopLogger()), &http.Client{}, cfg, inslogger.NewNopLogger()))
+	scheduler := NewSchedulerService(&stubSender{}, stubElector(true), mmemory.NewSchedulerRunService(0), nil, WarmupSettings{}, inslogger.NewNopLogger())
+
+	report := NewDiagnostics(nil, providers, mmemory.NewMessageService(inslogger.NewNopLogger()), nil, scheduler, stubElector(false), cfg, "replica-1").Run(context.Background())
+
+	if assert.Equal(t, "provider webhook", report.Checks[0].Name) {
+		assert.Equal(t, model.DiagnosticFail, report.Checks[0].Status)
+		assert.Contains(t, report.Checks[0].Detail, "unreachable")
+	}
+	assert.Equal(t, model.DiagnosticWarn, report.Checks[len(report.Checks)-1].Status, "AUTH_DISABLED is set and SMTP_HOST is empty")
+}
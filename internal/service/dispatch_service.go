@@ -0,0 +1,540 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+var scheduledPastDue = metrics.NewCounterVec(
+	"scheduled_messages_past_due",
+	"Claimed messages whose scheduled_at was further in the past than SCHEDULER_PAST_DUE_THRESHOLD, by channel.",
+	"channel",
+)
+
+// DispatchService sends messages through the provider registered for their channel.
+type DispatchService interface {
+	// SendMessages sends one batch of up to count pending messages of channel, of every
+	// channel when it is empty, and reports what happened to them. Cancelling ctx ends the
+	// batch, messages not sent by then are requeued.
+	SendMessages(ctx context.Context, channel model.Channel, count int) (model.BatchResult, error)
+	// SendMessageIDs sends the messages among ids that are pending, due and not held, e.g.
+	// IDs read from the queue stream. It fails without claiming anything only when the
+	// claim itself fails.
+	SendMessageIDs(ctx context.Context, ids []uint) (model.BatchResult, error)
+	SendMessage(ctx context.Context, message model.Message) (string, error)
+}
+
+type dispatchService struct {
+	logger         inslogger.Interface
+	messageService mpostgres.MessageService
+	redisClient    insredis.RedisInterface
+	retryLimiter   RetryLimiter
+	kpis           BusinessMetrics
+	inFlight       *InFlightLimiter
+	providers      *ProviderRegistry
+	// events receives the outcome of every send, nil disables events.
+	events EventPublisher
+	// moderation runs before every scheduled send, nil disables it.
+	moderation *ModerationService
+	// outbound caps sends over every path, nil disables the cap.
+	outbound OutboundLimiter
+	// quietHours defers batch messages while it is night for their recipient, nil disables it.
+	quietHours *QuietHours
+	// sendLock keeps two paths from sending the same message at once, nil disables it.
+	sendLock SendLock
+	// sendLockTTL is how long a send holds its lock at most, it outlives sendTimeout.
+	sendLockTTL time.Duration
+	// stats counts provider calls for GET /api/stats, nil disables it.
+	stats *StatsCounters
+	// audit records every send attempt in the audit trail, nil disables it.
+	audit *MessageAudit
+	// concurrency is how many messages of a batch are sent at once.
+	concurrency int
+	// maxAttempts is how many failed sends a message gets before batches stop retrying it.
+	maxAttempts int
+	// sendTimeout bounds one send including the waits for its limits, zero means no bound.
+	sendTimeout time.Duration
+	// pastDueThreshold is how late a claimed scheduled message may be before it is reported.
+	pastDueThreshold time.Duration
+	// dryRun skips the provider call of sends whose ctx does not override it, see WithDryRun.
+	dryRun bool
+	now    func() time.Time
+}
+
+// NewDispatchService picks the provider of every send from providers by the message channel
+// and publishes a message.sent or message.failed event to events. Scheduled batches run
+// every message through moderation first and defer the ones claimed during quietHours.
+// Every send waits for a token of outbound and its attempt is recorded in audit. Events,
+// moderation, outbound, quietHours and audit may be nil.
+func NewDispatchService(service mpostgres.MessageService, redisClient insredis.RedisInterface, providers *ProviderRegistry, events EventPublisher, moderation *ModerationService, outbound OutboundLimiter, quietHours *QuietHours, audit *MessageAudit, config *config.App, logger inslogger.Interface) DispatchService {
+	dispatcher := &dispatchService{
+		logger:           logger,
+		messageService:   service,
+		redisClient:      redisClient,
+		kpis:             NewBusinessMetrics(config.Metrics.BusinessEnabled),
+		inFlight:         NewInFlightLimiter(config.Provider.MaxInFlight, config.Provider.MaxInFlightOverrides),
+		providers:        providers,
+		events:           events,
+		moderation:       moderation,
+		outbound:         outbound,
+		quietHours:       quietHours,
+		audit:            audit,
+		stats:            NewStatsCounters(redisClient),
+		concurrency:      config.Scheduler.SendConcurrency,
+		maxAttempts:      config.Scheduler.MaxAttempts,
+		sendTimeout:      config.Provider.SendTimeout,
+		sendLockTTL:      config.Provider.SendLockTTL,
+		pastDueThreshold: config.Scheduler.PastDueThreshold,
+		dryRun:           config.DryRun(),
+		now:              time.Now,
+	}
+
+	if config.RedisInProcess() {
+		dispatcher.retryLimiter = NewLocalRetryLimiter(config.Retry.Rate, config.Retry.Burst)
+		dispatcher.sendLock = NewLocalSendLock()
+	} else {
+		dispatcher.retryLimiter = NewRedisRetryLimiter(redisClient, config.Retry.Rate, config.Retry.Burst)
+		dispatcher.sendLock = NewRedisSendLock(redisClient)
+	}
+
+	return dispatcher
+}
+
+func (s *dispatchService) SendMessages(ctx context.Context, channel model.Channel, count int) (model.BatchResult, error) {
+	var result model.BatchResult
+
+	// Each scheduled batch gets its own correlation ID so its sends can be traced in logs.
+	ctx = logctx.WithRequestID(ctx, logctx.NewID())
+	ctx = logctx.WithActor(ctx, "scheduler")
+	logger := logctx.Logger(ctx, s.logger)
+
+	retried, err := s.messageService.RetryFailedMessages(ctx, channel, count, s.maxAttempts)
+	if err != nil {
+		logger.Warnf("Failed to requeue failed messages: %v", err)
+	} else if retried > 0 {
+		logger.Logf("Requeued %d failed messages", retried)
+	}
+
+	logger.Log("Fetching unsent messages...")
+	messages, err := s.messageService.GetUnsentMessages(ctx, channel, count)
+	if err != nil {
+		logger.Log(fmt.Errorf("failed to get unsent messages: %v", err))
+		return result, err
+	}
+	logger.Logf("Fetched %d unsent messages", len(messages))
+	result.Claimed = len(messages)
+
+	if len(messages) == 0 {
+		logger.Log("No unsent messages found.")
+		return result, nil
+	}
+
+	s.reportPastDue(ctx, messages)
+	return s.sendBatch(ctx, messages, result)
+}
+
+func (s *dispatchService) SendMessageIDs(ctx context.Context, ids []uint) (model.BatchResult, error) {
+	var result model.BatchResult
+
+	messages, err := s.messageService.ClaimMessagesByID(ctx, ids)
+	if err != nil {
+		return result, fmt.Errorf("failed to claim queued messages: %w", err)
+	}
+	result.Claimed = len(messages)
+
+	return s.sendBatch(ctx, messages, result)
+}
+
+// batchState is the outcome of one batch, shared by its workers.
+type batchState struct {
+	mu     sync.Mutex
+	result model.BatchResult
+	// deferred holds the channels whose circuit breaker opened during this batch.
+	deferred map[model.Channel]bool
+	// stopErr ends the batch, messages not started yet are requeued without sending.
+	stopErr error
+}
+
+func (b *batchState) update(fn func(result *model.BatchResult)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(&b.result)
+}
+
+// sendBatch sends claimed messages with up to concurrency workers and adds the outcome
+// to result. Messages are started in claim order.
+func (s *dispatchService) sendBatch(ctx context.Context, messages []model.Message, result model.BatchResult) (model.BatchResult, error) {
+	batch := &batchState{result: result, deferred: make(map[model.Channel]bool)}
+
+	queue := make(chan model.Message)
+	var wg sync.WaitGroup
+	for range min(max(s.concurrency, 1), len(messages)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for message := range queue {
+				s.sendBatchMessage(ctx, message, batch)
+			}
+		}()
+	}
+	for _, message := range messages {
+		queue <- message
+	}
+	close(queue)
+	wg.Wait()
+
+	return batch.result, batch.stopErr
+}
+
+// sendBatchMessage sends one message of batch and records what happened to it.
+func (s *dispatchService) sendBatchMessage(ctx context.Context, message model.Message, batch *batchState) {
+	logger := logctx.Logger(ctx, s.logger)
+	msgChannel := message.DeliveryChannel()
+	// Recording the outcome outlives a cancelled batch, a message the provider accepted
+	// must not be left in sending.
+	bookkeeping := context.WithoutCancel(ctx)
+
+	batch.mu.Lock()
+	stopped, deferred := batch.stopErr != nil, batch.deferred[msgChannel]
+	batch.mu.Unlock()
+	if stopped || deferred || ctx.Err() != nil {
+		s.requeue(bookkeeping, message)
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+
+	if s.deferQuiet(bookkeeping, message) {
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+
+	if !s.moderate(ctx, message) {
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+
+	if !s.retryAllowed(ctx, message) {
+		logger.Logf("Retry budget exhausted, deferring message ID: %d", message.ID)
+		s.requeue(bookkeeping, message)
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+
+	logger.Log(fmt.Sprintf("Sending message ID: %d", message.ID))
+	providerMessageID, err := s.SendMessage(ctx, message)
+	if errors.Is(err, ErrCircuitOpen) {
+		// The provider is down, leave the rest of its channel for a later batch instead
+		// of hammering it. Other channels keep sending.
+		logger.Warnf("Circuit breaker is open, deferring the remaining %s messages", msgChannel)
+		s.requeue(bookkeeping, message)
+		batch.mu.Lock()
+		batch.deferred[msgChannel] = true
+		batch.result.Skipped++
+		batch.result.AddError(err)
+		batch.mu.Unlock()
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		// The batch was cancelled mid-send, leave the message to a later batch.
+		s.requeue(bookkeeping, message)
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+	if errors.Is(err, ErrSendInProgress) {
+		// Another path is sending the message and records its outcome, leave it alone.
+		logger.Logf("Message ID %d is already being sent, skipping it", message.ID)
+		batch.update(func(result *model.BatchResult) { result.Skipped++ })
+		return
+	}
+	if errors.Is(err, ErrOutboundRateLimited) {
+		logger.Warnf("Outbound rate limit reached, deferring message ID: %d", message.ID)
+		s.requeue(bookkeeping, message)
+		batch.update(func(result *model.BatchResult) {
+			result.Skipped++
+			result.AddError(err)
+		})
+		return
+	}
+	if errors.Is(err, ErrProviderUnauthorized) {
+		// Every remaining message would fail the same way, stop the batch here.
+		s.markFailed(bookkeeping, message)
+		batch.mu.Lock()
+		batch.stopErr = err
+		batch.result.Failed++
+		batch.result.AddError(err)
+		batch.mu.Unlock()
+		return
+	}
+	if err != nil {
+		logger.Log(fmt.Errorf("failed to send message ID %d: %v", message.ID, err))
+		s.markFailed(bookkeeping, message)
+		s.markForRetry(bookkeeping, message)
+		batch.update(func(result *model.BatchResult) {
+			result.Failed++
+			result.AddError(err)
+		})
+		return
+	}
+	s.clearRetry(bookkeeping, message)
+	batch.update(func(result *model.BatchResult) { result.Sent++ })
+
+	if err := s.messageService.UpdateMessageSentWithProviderID(bookkeeping, message.ID, providerMessageID); err != nil {
+		logger.Log(fmt.Errorf("failed to update message ID %d status: %v", message.ID, err))
+	}
+}
+
+// reportPastDue logs and counts messages scheduled further in the past than pastDueThreshold.
+// Being a little late is normal, hours late usually means a client sent local time as UTC.
+func (s *dispatchService) reportPastDue(ctx context.Context, messages []model.Message) {
+	if s.pastDueThreshold <= 0 {
+		return
+	}
+
+	now := s.now()
+	for _, message := range messages {
+		if message.ScheduledAt == nil {
+			continue
+		}
+		if late := now.Sub(*message.ScheduledAt); late > s.pastDueThreshold {
+			logctx.Logger(ctx, s.logger).Warnf("Message ID %d was scheduled for %s, %s in the past, check the sender's timezone",
+				message.ID, message.ScheduledAt.UTC().Format(time.RFC3339), late.Round(time.Second))
+			scheduledPastDue.Inc(string(message.DeliveryChannel()))
+		}
+	}
+}
+
+// deferQuiet returns a claimed message to pending until the quiet hours of its recipient
+// end, and reports whether it did. Messages that cannot be deferred are requeued.
+func (s *dispatchService) deferQuiet(ctx context.Context, message model.Message) bool {
+	if s.quietHours == nil {
+		return false
+	}
+	until, quiet := s.quietHours.Until(message, s.now())
+	if !quiet {
+		return false
+	}
+
+	logger := logctx.Logger(ctx, s.logger)
+	if err := s.messageService.DeferMessage(ctx, message.ID, until); err != nil {
+		logger.Warnf("Failed to defer message ID %d to the end of quiet hours: %v", message.ID, err)
+		s.requeue(ctx, message)
+		return true
+	}
+	logger.Logf("Quiet hours for the recipient of message ID %d, deferred until %s", message.ID, until.UTC().Format(time.RFC3339))
+	quietHoursDeferred.Inc(string(message.DeliveryChannel()))
+	return true
+}
+
+// moderate reports whether a claimed message passed moderation. Held and rejected messages
+// have already left sending, messages whose decision could not be recorded are requeued.
+func (s *dispatchService) moderate(ctx context.Context, message model.Message) bool {
+	if s.moderation == nil {
+		return true
+	}
+
+	result, err := s.moderation.Review(ctx, message)
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to record the moderation of message ID %d, deferring it: %v", message.ID, err)
+		s.requeue(ctx, message)
+		return false
+	}
+	return result.Decision == model.ModerationApprove
+}
+
+// retryAllowed takes a token from the shared retry bucket for messages that failed before.
+// First attempts are never throttled.
+func (s *dispatchService) retryAllowed(ctx context.Context, message model.Message) bool {
+	if s.retryLimiter == nil {
+		return true
+	}
+
+	exists, err := s.redisClient.Exists(retryKey(message.ID)).Result()
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to check retry state for message ID %d: %v", message.ID, err)
+		return true
+	}
+	if exists == 0 {
+		return true
+	}
+
+	allowed, err := s.retryLimiter.Allow()
+	if err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to take retry token for message ID %d: %v", message.ID, err)
+		return true
+	}
+
+	return allowed
+}
+
+// markFailed moves a claimed message whose send failed to failed, a later batch retries it
+// while it has attempts left.
+func (s *dispatchService) markFailed(ctx context.Context, message model.Message) {
+	if err := s.messageService.MarkMessageFailed(ctx, message.ID); err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to mark message ID %d as failed: %v", message.ID, err)
+	}
+}
+
+// requeue returns a claimed message that was not sent to pending for a later batch, without
+// counting an attempt. A message the requeue misses is reaped once its lease expired.
+func (s *dispatchService) requeue(ctx context.Context, message model.Message) {
+	if err := s.messageService.RequeueMessage(ctx, message.ID); err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to requeue message ID %d: %v", message.ID, err)
+	}
+}
+
+func (s *dispatchService) markForRetry(ctx context.Context, message model.Message) {
+	if err := s.redisClient.Set(retryKey(message.ID), time.Now().UTC().Format(time.RFC3339), 24*time.Hour).Err(); err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to mark message ID %d for retry: %v", message.ID, err)
+	}
+}
+
+func (s *dispatchService) clearRetry(ctx context.Context, message model.Message) {
+	if err := s.redisClient.Del(retryKey(message.ID)).Err(); err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to clear retry state for message ID %d: %v", message.ID, err)
+	}
+}
+
+func retryKey(id uint) string {
+	return fmt.Sprintf("message:retry:%d", id)
+}
+
+func (s *dispatchService) SendMessage(ctx context.Context, message model.Message) (string, error) {
+	providerMessageID, err := s.send(ctx, message)
+	// An open circuit, the rate limit or a cancelled batch defer the message, and a send in
+	// progress elsewhere publishes its own outcome, none of them is an outcome.
+	if !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, ErrOutboundRateLimited) && !errors.Is(err, ErrSendInProgress) && !errors.Is(err, context.Canceled) {
+		s.publishOutcome(ctx, message, providerMessageID, err)
+	}
+	return providerMessageID, err
+}
+
+// publishOutcome publishes message.sent, or message.failed when sendErr is set. Events
+// are best effort for the send, failing to queue one is only logged.
+func (s *dispatchService) publishOutcome(ctx context.Context, message model.Message, providerMessageID string, sendErr error) {
+	if s.events == nil {
+		return
+	}
+
+	event := NewMessageEvent(model.EventMessageSent, message)
+	event.ProviderMessageID = providerMessageID
+	if sendErr != nil {
+		event.Type = model.EventMessageFailed
+		event.Error = sendErr.Error()
+	}
+	if err := s.events.Publish(ctx, event); err != nil {
+		logctx.Logger(ctx, s.logger).Warnf("Failed to publish %s event of message ID %d: %v", event.Type, message.ID, err)
+	}
+}
+
+func (s *dispatchService) send(ctx context.Context, message model.Message) (string, error) {
+	unlock, err := s.lockSend(ctx, message)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if s.sendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.sendTimeout)
+		defer cancel()
+	}
+
+	channel := message.DeliveryChannel()
+	provider, err := s.providers.Pick(channel)
+	if err != nil {
+		return "", err
+	}
+
+	if isDryRun(ctx, s.dryRun) {
+		// Dry runs never reach the provider, so neither its limits nor its metrics apply.
+		providerMessageID := simulatedProviderID()
+		logctx.Logger(ctx, s.logger).Logf("Message dry run: %v, channel: %s, provider: %s, provider message ID: %s",
+			message.ID, channel, provider.Name(), providerMessageID)
+		return providerMessageID, nil
+	}
+
+	if err := s.waitOutbound(ctx, channel); err != nil {
+		return "", err
+	}
+
+	release, err := s.inFlight.Acquire(ctx, provider.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire in-flight slot: %w", err)
+	}
+	defer release()
+
+	start := time.Now()
+	sendCtx, responseCode := withProviderResponse(ctx)
+	result, err := provider.Send(sendCtx, message)
+	if errors.Is(err, ErrCircuitOpen) {
+		return "", err
+	}
+	if s.audit != nil {
+		s.audit.RecordSendAttempt(ctx, message.ID, provider.Name(), *responseCode, err)
+	}
+	s.providers.Observe(channel, provider, time.Since(start), err)
+	if s.stats != nil {
+		s.stats.RecordProviderCall(time.Since(start))
+	}
+	s.kpis.RecordSend(message, err)
+	if err != nil {
+		return "", err
+	}
+
+	logger := logctx.Logger(ctx, s.logger)
+	logger.Logf("Message sent successfully: %v, channel: %s, provider: %s, provider message ID: %s, provider status: %s",
+		message.ID, channel, provider.Name(), result.MessageID, result.Status)
+
+	return result.MessageID, nil
+}
+
+// lockSend locks message against sends over other paths. The lock fails open, a send goes
+// ahead when the lock cannot be taken for other reasons than another send holding it.
+func (s *dispatchService) lockSend(ctx context.Context, message model.Message) (func(), error) {
+	if s.sendLock == nil {
+		return func() {}, nil
+	}
+
+	unlock, err := s.sendLock.Acquire(message.ID, s.sendLockTTL)
+	switch {
+	case err == nil:
+		return unlock, nil
+	case errors.Is(err, ErrSendInProgress):
+		return nil, err
+	}
+	logctx.Logger(ctx, s.logger).Warnf("Failed to lock message ID %d, sending anyway: %v", message.ID, err)
+	return func() {}, nil
+}
+
+// waitOutbound takes a token of the outbound rate limit. The limit fails open, a send goes
+// ahead when the token cannot be taken for other reasons than the limit itself.
+func (s *dispatchService) waitOutbound(ctx context.Context, channel model.Channel) error {
+	if s.outbound == nil {
+		return nil
+	}
+
+	err := s.outbound.Wait(ctx)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrOutboundRateLimited):
+		outboundRateLimited.Inc(string(channel))
+		return err
+	case ctx.Err() != nil:
+		return err
+	}
+	logctx.Logger(ctx, s.logger).Warnf("Failed to take an outbound rate limit token, sending anyway: %v", err)
+	return nil
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/pkg/localredis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type stubProvider struct {
+	name string
+	err  error
+	sent []uint
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	if p.err != nil {
+		return ProviderResult{}, p.err
+	}
+	p.sent = append(p.sent, message.ID)
+	return ProviderResult{MessageID: p.name + "-id"}, nil
+}
+
+func newTestDispatcher(messageService *mmemory.MessageService, providers *ProviderRegistry) *dispatchService {
+	return &dispatchService{
+		logger:         inslogger.NewNopLogger(),
+		messageService: messageService,
+		redisClient:    localredis.New(),
+		kpis:           NewBusinessMetrics(false),
+		inFlight:       NewInFlightLimiter(0, nil),
+		providers:      providers,
+	}
+}
+
+func TestSendMessagePicksProviderByChannel(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	email := &stubProvider{name: "email"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	providers.Register(model.ChannelEmail, email)
+	dispatcher := newTestDispatcher(mmemory.NewMessageService(inslogger.NewNopLogger()), providers)
+
+	id, err := dispatcher.SendMessage(context.Background(), model.Message{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "sms-id", id, "messages without a channel are SMS")
+
+	id, err = dispatcher.SendMessage(context.Background(), model.Message{ID: 2, Channel: model.ChannelEmail})
+	assert.NoError(t, err)
+	assert.Equal(t, "email-id", id)
+
+	_, err = dispatcher.SendMessage(context.Background(), model.Message{ID: 3, Channel: model.ChannelPush})
+	assert.ErrorIs(t, err, ErrNoProvider)
+}
+
+func TestSendMessagesDefersOnlyTheChannelWithAnOpenCircuit(t *testing.T) {
+	sms := &stubProvider{name: "sms", err: ErrCircuitOpen}
+	email := &stubProvider{name: "email"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	providers.Register(model.ChannelEmail, email)
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Channel: model.ChannelSMS},
+		model.Message{ID: 2, Channel: model.ChannelSMS},
+		model.Message{ID: 3, Channel: model.ChannelEmail},
+	)
+	dispatcher := newTestDispatcher(messageService, providers)
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 3, Sent: 1, Skipped: 2, Errors: []string{ErrCircuitOpen.Error()}}, result)
+	assert.Equal(t, []uint{3}, email.sent)
+	msg, _ := messageService.Message(2)
+	assert.Equal(t, model.StatusPending, msg.Status, "deferred messages are left to a later batch")
+	assert.Zero(t, msg.Attempts, "a deferral is no attempt")
+	msg, _ = messageService.Message(3)
+	assert.Equal(t, model.StatusSent, msg.Status)
+}
+
+func TestSendMessagesStopsOnUnauthorized(t *testing.T) {
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, &stubProvider{name: "sms", err: ErrProviderUnauthorized})
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 2})
+	dispatcher := newTestDispatcher(messageService, providers)
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.True(t, errors.Is(err, ErrProviderUnauthorized))
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.Skipped)
+	msg, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusFailed, msg.Status)
+	msg, _ = messageService.Message(2)
+	assert.Equal(t, model.StatusPending, msg.Status, "messages not started yet are not sent")
+}
+
+func TestSendMessagesRequeuesWhenCancelled(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 2})
+	dispatcher := newTestDispatcher(messageService, providers)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := dispatcher.SendMessages(ctx, "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 2, Skipped: 2}, result)
+	assert.Empty(t, sms.sent)
+	msg, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusPending, msg.Status, "a later batch sends it")
+}
+
+func TestSendMessagesStopsRetryingAfterMaxAttempts(t *testing.T) {
+	sms := &stubProvider{name: "sms", err: errors.New("unexpected status code: 500")}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	providers.Register(model.ChannelEmail, &stubProvider{name: "email"})
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1},
+		model.Message{ID: 2, Channel: model.ChannelEmail, Status: model.StatusFailed},
+	)
+	dispatcher := newTestDispatcher(messageService, providers)
+	dispatcher.maxAttempts = 2
+
+	for range 3 {
+		_, err := dispatcher.SendMessages(context.Background(), model.ChannelSMS, 10)
+		assert.NoError(t, err)
+	}
+
+	msg, _ := messageService.Message(1)
+	assert.Equal(t, model.StatusFailed, msg.Status, "failed is final once the attempts are used up")
+	assert.Equal(t, 2, msg.Attempts)
+	msg, _ = messageService.Message(2)
+	assert.Equal(t, model.StatusFailed, msg.Status, "an SMS batch leaves other channels alone")
+}
+
+// slowProvider takes delay per send and records the most sends it saw at once.
+type slowProvider struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	current int
+	peak    int
+}
+
+func (p *slowProvider) Name() string { return "slow" }
+
+func (p *slowProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	p.mu.Lock()
+	p.current++
+	p.peak = max(p.peak, p.current)
+	p.mu.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	p.current--
+	p.mu.Unlock()
+	return ProviderResult{MessageID: "slow-id"}, nil
+}
+
+func TestSendMessagesSendsConcurrently(t *testing.T) {
+	slow := &slowProvider{delay: 20 * time.Millisecond}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, slow)
+	var messages []model.Message
+	for id := uint(1); id <= 8; id++ {
+		messages = append(messages, model.Message{ID: id})
+	}
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(), messages...)
+	dispatcher := newTestDispatcher(messageService, providers)
+	dispatcher.concurrency = 4
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 8, Sent: 8}, result)
+	assert.Equal(t, 4, slow.peak)
+	for id := uint(1); id <= 8; id++ {
+		msg, _ := messageService.Message(id)
+		assert.Equal(t, model.StatusSent, msg.Status)
+	}
+}
+
+func TestSendMessagesReportsPastDueMessages(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	lastMinute := now.Add(-time.Minute)
+	threeHoursAgo := now.Add(-3 * time.Hour)
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, &stubProvider{name: "sms"})
+	providers.Register(model.ChannelEmail, &stubProvider{name: "email"})
+	messageService := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, ScheduledAt: &lastMinute},
+		model.Message{ID: 2, Channel: model.ChannelEmail, ScheduledAt: &threeHoursAgo},
+		model.Message{ID: 3},
+	)
+	dispatcher := newTestDispatcher(messageService, providers)
+	dispatcher.pastDueThreshold = time.Hour
+	dispatcher.now = func() time.Time { return now }
+	before := scheduledPastDue.Value("email")
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Sent, "past due messages are still sent")
+	assert.Equal(t, before+1, scheduledPastDue.Value("email"))
+	assert.Zero(t, scheduledPastDue.Value("sms"))
+}
+
+func TestSendMessagesSkipsMessagesSentElsewhere(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 2})
+	dispatcher := newTestDispatcher(messages, providers)
+	dispatcher.sendLock = NewLocalSendLock()
+	dispatcher.sendLockTTL = time.Minute
+
+	// A direct send of message 1 is in progress.
+	unlock, err := dispatcher.sendLock.Acquire(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := dispatcher.SendMessages(context.Background(), model.ChannelSMS, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 2, Sent: 1, Skipped: 1}, result)
+	assert.Equal(t, []uint{2}, sms.sent)
+	locked, _ := messages.Message(1)
+	assert.Equal(t, model.StatusSending, locked.Status, "the path holding the lock records the outcome")
+
+	_, err = dispatcher.SendMessage(context.Background(), model.Message{ID: 1})
+	assert.ErrorIs(t, err, ErrSendInProgress)
+
+	unlock()
+	_, err = dispatcher.SendMessage(context.Background(), model.Message{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2, 1}, sms.sent)
+}
+
+func TestSendMessagesDryRunSkipsTheProvider(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1})
+	dispatcher := newTestDispatcher(messages, providers)
+	dispatcher.dryRun = true
+
+	result, err := dispatcher.SendMessages(context.Background(), model.ChannelSMS, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 1, Sent: 1}, result)
+	assert.Empty(t, sms.sent)
+	sent, _ := messages.Message(1)
+	assert.Equal(t, model.StatusSent, sent.Status)
+	assert.True(t, sent.DryRun)
+	assert.True(t, model.IsDryRunProviderID(sent.ProviderMessageID))
+
+	id, err := dispatcher.SendMessage(WithDryRun(context.Background(), false), model.Message{ID: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "sms-id", id, "a live override reaches the provider")
+	assert.Equal(t, []uint{2}, sms.sent)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun returns a ctx whose sends are dry runs when dryRun is true and live when it is
+// false, overriding SEND_MODE.
+func WithDryRun(ctx context.Context, dryRun bool) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dryRun)
+}
+
+// isDryRun reports whether sends in ctx are dry runs, fallback unless WithDryRun overrode it.
+func isDryRun(ctx context.Context, fallback bool) bool {
+	if dryRun, ok := ctx.Value(dryRunKey{}).(bool); ok {
+		return dryRun
+	}
+	return fallback
+}
+
+// simulatedProviderID returns a provider message ID for a dry-run send, so the message is
+// stored as dry run once it is marked sent.
+func simulatedProviderID() string {
+	return model.DryRunProviderIDPrefix + logctx.NewID()
+}
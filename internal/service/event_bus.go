@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/metrics"
+)
+
+var eventBusDropped = metrics.NewCounterVec(
+	"event_bus_dropped_total",
+	"Message events a subscriber of the event bus missed because it fell behind, by type.",
+	"type",
+)
+
+// EventBus hands message events to the subscribers of this replica, e.g. the clients of
+// GET /api/messages/stream. Publish never blocks: a subscriber that falls behind by more
+// than its buffer misses the events that do not fit.
+type EventBus struct {
+	buffer int
+
+	mu          sync.Mutex
+	subscribers map[chan model.MessageEvent]struct{}
+	closed      bool
+}
+
+// NewEventBus returns an EventBus that buffers up to buffer events per subscriber.
+func NewEventBus(buffer int) *EventBus {
+	return &EventBus{buffer: buffer, subscribers: make(map[chan model.MessageEvent]struct{})}
+}
+
+// Publish hands event to every subscriber that has room for it.
+func (b *EventBus) Publish(_ context.Context, event model.MessageEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			eventBusDropped.Inc(string(event.Type))
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the events published from now on. Call unsubscribe once done, it
+// closes events. events is closed right away once the bus is closed.
+func (b *EventBus) Subscribe() (events <-chan model.MessageEvent, unsubscribe func()) {
+	ch := make(chan model.MessageEvent, b.buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the events of every subscriber, so streams end on shutdown instead of
+// holding the server open until the grace period ends.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// EventStreamTail publishes the events every replica adds to the Redis event stream to a
+// bus, so the subscribers of one replica also see the sends of the others, e.g. of a
+// separate worker deployment.
+type EventStreamTail struct {
+	redisClient insredis.RedisInterface
+	stream      string
+	bus         *EventBus
+	// block is how long one read waits for new entries, it bounds how long Run takes to
+	// notice its ctx ended.
+	block  time.Duration
+	logger inslogger.Interface
+}
+
+// NewEventStreamTail tails stream, the EVENTS_STREAM every replica publishes to, into bus.
+func NewEventStreamTail(redisClient insredis.RedisInterface, stream string, bus *EventBus, logger inslogger.Interface) *EventStreamTail {
+	return &EventStreamTail{redisClient: redisClient, stream: stream, bus: bus, block: time.Second, logger: logger}
+}
+
+// Run publishes the entries added to the stream after it started until ctx ends.
+func (t *EventStreamTail) Run(ctx context.Context) {
+	lastID := "$"
+	for ctx.Err() == nil {
+		streams, err := t.redisClient.XRead(&redis.XReadArgs{
+			Streams: []string{t.stream, lastID},
+			Count:   100,
+			Block:   t.block,
+		}).Result()
+		switch {
+		case err == redis.Nil:
+			continue
+		case err != nil:
+			t.logger.Warnf("Failed to read the event stream %s: %v", t.stream, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				lastID = entry.ID
+				payload, _ := entry.Values["event"].(string)
+				var event model.MessageEvent
+				if err := json.Unmarshal([]byte(payload), &event); err != nil {
+					t.logger.Warnf("Skipping malformed entry %s of the event stream %s: %v", entry.ID, t.stream, err)
+					continue
+				}
+				_ = t.bus.Publish(ctx, event)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"message-service/internal/model"
+)
+
+func TestEventBusDropsWhatASubscriberHasNoRoomFor(t *testing.T) {
+	bus := NewEventBus(1)
+	events, unsubscribe := bus.Subscribe()
+
+	_ = bus.Publish(context.Background(), model.MessageEvent{ID: "1"})
+	_ = bus.Publish(context.Background(), model.MessageEvent{ID: "2"})
+	unsubscribe()
+	unsubscribe()
+
+	var ids []string
+	for event := range events {
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{"1"}, ids)
+
+	bus.Close()
+	closed, _ := bus.Subscribe()
+	_, ok := <-closed
+	assert.False(t, ok)
+}
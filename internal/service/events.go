@@ -0,0 +1,307 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+var messageEvents = metrics.NewCounterVec(
+	"message_events_total",
+	"Message lifecycle events by type and result: published, retried (a failed attempt that is retried) or dropped.",
+	"type", "result",
+)
+
+var (
+	// ErrEventBufferFull is returned when an event arrives while the buffer is full, e.g.
+	// because Redis has been down for a while. The event is dropped.
+	ErrEventBufferFull = errors.New("event buffer is full")
+	// ErrEventPublisherStopped is returned for events published during shutdown.
+	ErrEventPublisherStopped = errors.New("event publisher is stopped")
+	// ErrEventRejected is returned by a sink that will never accept the event, e.g. an HTTP
+	// collector answering 400. The event is dropped instead of retried.
+	ErrEventRejected = errors.New("event was rejected")
+)
+
+// EventPublisher publishes message lifecycle events to downstream consumers.
+type EventPublisher interface {
+	Publish(ctx context.Context, event model.MessageEvent) error
+}
+
+// NewMessageEvent returns an event of eventType about message with a new ID.
+func NewMessageEvent(eventType model.MessageEventType, message model.Message) model.MessageEvent {
+	return model.MessageEvent{
+		ID:                logctx.NewID(),
+		Type:              eventType,
+		MessageID:         message.ID,
+		Channel:           message.DeliveryChannel(),
+		Tenant:            message.Tenant,
+		ProviderMessageID: message.ProviderMessageID,
+		OccurredAt:        time.Now().UTC(),
+	}
+}
+
+type redisStreamPublisher struct {
+	redisClient insredis.RedisInterface
+	stream      string
+	maxLen      int64
+}
+
+// NewRedisStreamPublisher adds every event to stream as an entry with a type and an event
+// field, the JSON encoded event. The stream is trimmed to about maxLen entries, 0 keeps
+// every entry.
+func NewRedisStreamPublisher(redisClient insredis.RedisInterface, stream string, maxLen int64) EventPublisher {
+	return &redisStreamPublisher{redisClient: redisClient, stream: stream, maxLen: maxLen}
+}
+
+func (p *redisStreamPublisher) Publish(_ context.Context, event model.MessageEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	err = p.redisClient.XAdd(&redis.XAddArgs{
+		Stream: p.stream,
+		// Approximate trimming lets Redis drop whole nodes, much cheaper than an exact length.
+		MaxLenApprox: p.maxLen,
+		Values:       map[string]interface{}{"type": string(event.Type), "event": payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to add event to stream %s: %w", p.stream, err)
+	}
+	return nil
+}
+
+type httpEventPublisher struct {
+	httpClient *http.Client
+	url        string
+	format     string
+	timeout    time.Duration
+}
+
+// NewHTTPEventPublisher posts every event to url, an analytics collector or a Kafka REST
+// Proxy topic, in format (see config.EventsFormatJSON), waiting at most timeout per event. Answers 408, 429 and 5xx are
+// retried, any other 4xx rejects the event.
+func NewHTTPEventPublisher(httpClient *http.Client, url, format string, timeout time.Duration) EventPublisher {
+	return &httpEventPublisher{httpClient: httpClient, url: url, format: format, timeout: timeout}
+}
+
+func (p *httpEventPublisher) Publish(ctx context.Context, event model.MessageEvent) error {
+	contentType := "application/json"
+	var body interface{} = event
+	if p.format == config.EventsFormatKafkaREST {
+		contentType = "application/vnd.kafka.json.v2+json"
+		body = kafkaRESTRecords{Records: []kafkaRESTRecord{{Key: fmt.Sprint(event.MessageID), Value: event}}}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("%w: status code %d", ErrEventRejected, resp.StatusCode)
+	}
+	return nil
+}
+
+// kafkaRESTRecords is the body of a Kafka REST Proxy v2 produce request.
+type kafkaRESTRecords struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+type kafkaRESTRecord struct {
+	// Key is the message ID so the events of a message stay on one partition.
+	Key   string             `json:"key"`
+	Value model.MessageEvent `json:"value"`
+}
+
+type fanoutEventPublisher []EventPublisher
+
+// NewFanoutEventPublisher publishes every event to each of publishers, which should be
+// asynchronous so a slow sink does not hold back the others.
+func NewFanoutEventPublisher(publishers ...EventPublisher) EventPublisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return fanoutEventPublisher(publishers)
+}
+
+func (p fanoutEventPublisher) Publish(ctx context.Context, event model.MessageEvent) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type logEventPublisher struct {
+	logger inslogger.Interface
+}
+
+// NewLogEventPublisher logs events instead of publishing them, for local mode.
+func NewLogEventPublisher(logger inslogger.Interface) EventPublisher {
+	return &logEventPublisher{logger: logger}
+}
+
+func (p *logEventPublisher) Publish(ctx context.Context, event model.MessageEvent) error {
+	logctx.Logger(ctx, p.logger).Logf("Event %s: %s of message ID %d", event.ID, event.Type, event.MessageID)
+	return nil
+}
+
+// AsyncEventPublisher buffers events in memory and publishes them to sink in the
+// background, so a slow or unavailable sink never delays a send. An event is retried
+// until sink accepts it, which may publish it twice when an attempt fails after it was
+// written. Events are only lost when the buffer is full, Stop gives up or sink rejects
+// them, and every lost event is logged.
+type AsyncEventPublisher struct {
+	sink    EventPublisher
+	backoff time.Duration
+	logger  inslogger.Interface
+
+	mu      sync.RWMutex
+	stopped bool
+	queue   chan model.MessageEvent
+	// abort is closed when Stop runs out of time, the events still queued are dropped.
+	abort chan struct{}
+	done  chan struct{}
+}
+
+// NewAsyncEventPublisher buffers up to bufferSize events and waits backoff between the
+// attempts of an event. Events are published between Start and Stop.
+func NewAsyncEventPublisher(sink EventPublisher, bufferSize int, backoff time.Duration, logger inslogger.Interface) *AsyncEventPublisher {
+	return &AsyncEventPublisher{
+		sink:    sink,
+		backoff: backoff,
+		logger:  logger,
+		queue:   make(chan model.MessageEvent, bufferSize),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Publish queues event. It never blocks, the event is dropped when the buffer is full.
+func (p *AsyncEventPublisher) Publish(ctx context.Context, event model.MessageEvent) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		p.drop(ctx, event, ErrEventPublisherStopped)
+		return ErrEventPublisherStopped
+	}
+
+	select {
+	case p.queue <- event:
+		return nil
+	default:
+		p.drop(ctx, event, ErrEventBufferFull)
+		return ErrEventBufferFull
+	}
+}
+
+// Start publishes queued events until Stop.
+func (p *AsyncEventPublisher) Start(context.Context) error {
+	go p.run()
+	return nil
+}
+
+// Stop refuses new events and waits until the queued ones are published. When ctx ends
+// first, the remaining events are dropped and logged.
+func (p *AsyncEventPublisher) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		close(p.abort)
+		<-p.done
+		return fmt.Errorf("queued events were not published: %w", ctx.Err())
+	}
+}
+
+func (p *AsyncEventPublisher) run() {
+	defer close(p.done)
+
+	ctx := context.Background()
+	for event := range p.queue {
+		p.deliver(ctx, event)
+	}
+}
+
+// deliver publishes event, retrying every backoff until it is accepted or Stop aborts.
+func (p *AsyncEventPublisher) deliver(ctx context.Context, event model.MessageEvent) {
+	for {
+		select {
+		case <-p.abort:
+			p.drop(ctx, event, ErrEventPublisherStopped)
+			return
+		default:
+		}
+
+		err := p.sink.Publish(ctx, event)
+		if err == nil {
+			messageEvents.Inc(string(event.Type), "published")
+			return
+		}
+		if errors.Is(err, ErrEventRejected) {
+			p.drop(ctx, event, err)
+			return
+		}
+		messageEvents.Inc(string(event.Type), "retried")
+		p.logger.Warnf("Failed to publish event %s, retrying in %s: %v", event.ID, p.backoff, err)
+
+		select {
+		case <-time.After(p.backoff):
+		case <-p.abort:
+			p.drop(ctx, event, ErrEventPublisherStopped)
+			return
+		}
+	}
+}
+
+// drop counts and logs an event that will not be published, with enough detail to
+// replay it by hand.
+func (p *AsyncEventPublisher) drop(ctx context.Context, event model.MessageEvent, reason error) {
+	messageEvents.Inc(string(event.Type), "dropped")
+	payload, _ := json.Marshal(event)
+	logctx.Logger(ctx, p.logger).Errorf("Dropped event %s: %v: %s", event.ID, reason, payload)
+}
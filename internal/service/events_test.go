@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// recordingPublisher fails the first failures attempts and records the accepted events.
+type recordingPublisher struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	events   []model.MessageEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event model.MessageEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	if p.attempts <= p.failures {
+		return errors.New("redis is down")
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) published() []model.MessageEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]model.MessageEvent(nil), p.events...)
+}
+
+func TestAsyncEventPublisherRetriesUntilPublished(t *testing.T) {
+	sink := &recordingPublisher{failures: 2}
+	publisher := NewAsyncEventPublisher(sink, 10, time.Millisecond, inslogger.NewNopLogger())
+	assert.NoError(t, publisher.Start(context.Background()))
+
+	event := NewMessageEvent(model.EventMessageCreated, model.Message{ID: 1})
+	assert.NoError(t, publisher.Publish(context.Background(), event))
+	assert.NoError(t, publisher.Stop(context.Background()))
+
+	assert.Equal(t, []model.MessageEvent{event}, sink.published())
+	assert.Equal(t, 3, sink.attempts)
+}
+
+func TestAsyncEventPublisherDrainsOnStop(t *testing.T) {
+	sink := &recordingPublisher{}
+	publisher := NewAsyncEventPublisher(sink, 10, time.Millisecond, inslogger.NewNopLogger())
+	for id := uint(1); id <= 3; id++ {
+		assert.NoError(t, publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: id})))
+	}
+
+	assert.NoError(t, publisher.Start(context.Background()))
+	assert.NoError(t, publisher.Stop(context.Background()))
+
+	assert.Len(t, sink.published(), 3, "events queued before Stop are published")
+	err := publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: 4}))
+	assert.ErrorIs(t, err, ErrEventPublisherStopped)
+}
+
+func TestAsyncEventPublisherDropsWhenFull(t *testing.T) {
+	publisher := NewAsyncEventPublisher(&recordingPublisher{}, 1, time.Millisecond, inslogger.NewNopLogger())
+
+	assert.NoError(t, publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: 1})))
+	err := publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: 2}))
+
+	assert.ErrorIs(t, err, ErrEventBufferFull)
+}
+
+func TestAsyncEventPublisherStopGivesUpAtDeadline(t *testing.T) {
+	sink := &recordingPublisher{failures: 1 << 30}
+	publisher := NewAsyncEventPublisher(sink, 10, time.Millisecond, inslogger.NewNopLogger())
+	assert.NoError(t, publisher.Start(context.Background()))
+	assert.NoError(t, publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: 1})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := publisher.Stop(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, sink.published())
+}
+
+func TestHTTPEventPublisher(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusBadRequest}
+	var mu sync.Mutex
+	var bodies []kafkaRESTRecords
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		var body kafkaRESTRecords
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+
+	sink := NewHTTPEventPublisher(server.Client(), server.URL, config.EventsFormatKafkaREST, time.Second)
+	publisher := NewAsyncEventPublisher(sink, 10, time.Millisecond, inslogger.NewNopLogger())
+	assert.NoError(t, publisher.Start(context.Background()))
+
+	sent := NewMessageEvent(model.EventMessageSent, model.Message{ID: 7})
+	assert.NoError(t, publisher.Publish(context.Background(), sent))
+	assert.NoError(t, publisher.Publish(context.Background(), NewMessageEvent(model.EventMessageSent, model.Message{ID: 8})))
+	assert.NoError(t, publisher.Stop(context.Background()))
+
+	assert.Len(t, bodies, 3, "a 503 is retried, a 400 is not")
+	assert.Equal(t, "7", bodies[1].Records[0].Key)
+	assert.Equal(t, sent.ID, bodies[1].Records[0].Value.ID)
+	assert.Equal(t, "8", bodies[2].Records[0].Key)
+}
+
+func TestSendMessagePublishesOutcome(t *testing.T) {
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, &stubProvider{name: "sms"})
+	providers.Register(model.ChannelEmail, &stubProvider{name: "email", err: errors.New("mailbox full")})
+	providers.Register(model.ChannelPush, &stubProvider{name: "push", err: ErrCircuitOpen})
+	events := &recordingPublisher{}
+	dispatcher := newTestDispatcher(mmemory.NewMessageService(inslogger.NewNopLogger()), providers)
+	dispatcher.events = events
+
+	_, _ = dispatcher.SendMessage(context.Background(), model.Message{ID: 1, Tenant: "acme"})
+	_, _ = dispatcher.SendMessage(context.Background(), model.Message{ID: 2, Channel: model.ChannelEmail})
+	_, _ = dispatcher.SendMessage(context.Background(), model.Message{ID: 3, Channel: model.ChannelPush})
+
+	published := events.published()
+	if assert.Len(t, published, 2, "a deferred send publishes nothing") {
+		assert.Equal(t, model.EventMessageSent, published[0].Type)
+		assert.Equal(t, uint(1), published[0].MessageID)
+		assert.Equal(t, model.ChannelSMS, published[0].Channel)
+		assert.Equal(t, "acme", published[0].Tenant)
+		assert.Equal(t, "sms-id", published[0].ProviderMessageID)
+		assert.Equal(t, model.EventMessageFailed, published[1].Type)
+		assert.Equal(t, "mailbox full", published[1].Error)
+	}
+}
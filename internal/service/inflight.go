@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"message-service/internal/pkg/metrics"
+)
+
+var providerInFlight = metrics.NewGaugeVec(
+	"provider_in_flight",
+	"Sends currently in flight per provider.",
+	"provider",
+)
+
+// InFlightLimiter caps the number of concurrent sends per provider, independent of
+// rate limits, for providers that limit open connections.
+type InFlightLimiter struct {
+	defaultLimit int
+	overrides    map[string]int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewInFlightLimiter returns a limiter allowing defaultLimit concurrent sends for every
+// provider except those listed in overrides. A limit of zero or less means unlimited.
+func NewInFlightLimiter(defaultLimit int, overrides map[string]int) *InFlightLimiter {
+	return &InFlightLimiter{
+		defaultLimit: defaultLimit,
+		overrides:    overrides,
+		slots:        make(map[string]chan struct{}),
+	}
+}
+
+// Acquire blocks until a slot for provider is free or ctx is done.
+// The returned release func must be called once the send finishes.
+func (l *InFlightLimiter) Acquire(ctx context.Context, provider string) (func(), error) {
+	slots := l.slotsFor(provider)
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	providerInFlight.Inc(provider)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			providerInFlight.Dec(provider)
+			if slots != nil {
+				<-slots
+			}
+		})
+	}, nil
+}
+
+func (l *InFlightLimiter) slotsFor(provider string) chan struct{} {
+	limit := l.defaultLimit
+	if override, ok := l.overrides[provider]; ok {
+		limit = override
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.slots[provider]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		l.slots[provider] = slots
+	}
+	return slots
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync acquires a slot in the background and delivers its release func once it got one.
+func acquireAsync(ctx context.Context, limiter *InFlightLimiter, provider string) <-chan func() {
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := limiter.Acquire(ctx, provider)
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	return acquired
+}
+
+func TestInFlightLimiterBlocksAtTheLimit(t *testing.T) {
+	limiter := NewInFlightLimiter(2, nil)
+	var releases []func()
+	for range 2 {
+		release, err := limiter.Acquire(context.Background(), "webhook")
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+
+	acquired := acquireAsync(context.Background(), limiter, "webhook")
+	select {
+	case <-acquired:
+		t.Fatal("a third send got a slot while two are in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release, err := limiter.Acquire(context.Background(), "twilio")
+	require.NoError(t, err, "providers have their own slots")
+	release()
+
+	releases[0]()
+	select {
+	case release := <-acquired:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("the third send did not get the released slot")
+	}
+	releases[1]()
+}
+
+func TestInFlightLimiterOverrides(t *testing.T) {
+	limiter := NewInFlightLimiter(1, map[string]int{"twilio": 2, "messagebird": 0})
+
+	for provider, limit := range map[string]int{"webhook": 1, "twilio": 2} {
+		for range limit {
+			_, err := limiter.Acquire(context.Background(), provider)
+			require.NoError(t, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := limiter.Acquire(ctx, provider)
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "%s allows %d sends", provider, limit)
+	}
+
+	for range 10 {
+		_, err := limiter.Acquire(context.Background(), "messagebird")
+		require.NoError(t, err, "a limit of zero is unlimited")
+	}
+}
+
+func TestInFlightLimiterWithoutLimit(t *testing.T) {
+	limiter := NewInFlightLimiter(0, nil)
+
+	for range 10 {
+		_, err := limiter.Acquire(context.Background(), "webhook")
+		require.NoError(t, err)
+	}
+}
+
+func TestInFlightLimiterReleasesOnce(t *testing.T) {
+	limiter := NewInFlightLimiter(1, nil)
+	release, err := limiter.Acquire(context.Background(), "webhook")
+	require.NoError(t, err)
+	release()
+
+	_, err = limiter.Acquire(context.Background(), "webhook")
+	require.NoError(t, err)
+	release()
+
+	// Releasing the first send again must not free the slot of the second.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "webhook")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestInFlightLimiterStopsWaitingWhenCancelled(t *testing.T) {
+	limiter := NewInFlightLimiter(1, nil)
+	_, err := limiter.Acquire(context.Background(), "webhook")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := limiter.Acquire(ctx, "webhook")
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("Acquire kept waiting after its context was cancelled")
+	}
+}
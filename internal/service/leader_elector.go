@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+const schedulerLeaderKey = "scheduler:leader"
+
+// renewLeaderScript extends the lock only if it is still held by the caller.
+const renewLeaderScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`
+
+// releaseLeaderScript deletes the lock only if it is still held by the caller.
+const releaseLeaderScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`
+
+// LeaderElector decides which replica runs scheduled batches.
+type LeaderElector interface {
+	IsLeader() bool
+	// Leader returns the ID of the current leader, or an empty string if there is none.
+	Leader() string
+}
+
+// RedisLeaderElector elects a single scheduler leader across replicas through Redis.
+type RedisLeaderElector struct {
+	redisClient insredis.RedisInterface
+	id          string
+	ttl         time.Duration
+	logger      inslogger.Interface
+
+	mu       sync.RWMutex
+	isLeader bool
+}
+
+// NewRedisLeaderElector returns an elector backed by a SET NX lock with a TTL.
+// Run must be started for the instance to ever become leader.
+func NewRedisLeaderElector(redisClient insredis.RedisInterface, id string, ttl time.Duration, logger inslogger.Interface) *RedisLeaderElector {
+	return &RedisLeaderElector{
+		redisClient: redisClient,
+		id:          id,
+		ttl:         ttl,
+		logger:      logger,
+	}
+}
+
+// Run acquires or renews the lock every third of the TTL until ctx is done, then
+// releases it so another replica can take over without waiting for expiry.
+func (e *RedisLeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	e.tick()
+	for {
+		select {
+		case <-ticker.C:
+			e.tick()
+		case <-ctx.Done():
+			e.release()
+			return
+		}
+	}
+}
+
+func (e *RedisLeaderElector) tick() {
+	wasLeader := e.IsLeader()
+
+	var leader bool
+	var err error
+	if wasLeader {
+		leader, err = e.renew()
+	}
+	if !leader && err == nil {
+		leader, err = e.redisClient.SetNX(schedulerLeaderKey, e.id, e.ttl).Result()
+	}
+	if err != nil {
+		// Without Redis we cannot prove we still hold the lock, step down to be safe.
+		e.logger.Warnf("Leader election failed: %v", err)
+		leader = false
+	}
+
+	e.mu.Lock()
+	e.isLeader = leader
+	e.mu.Unlock()
+
+	if leader != wasLeader {
+		if leader {
+			e.logger.Logf("Instance %s became scheduler leader", e.id)
+		} else {
+			e.logger.Logf("Instance %s lost scheduler leadership", e.id)
+		}
+	}
+}
+
+func (e *RedisLeaderElector) renew() (bool, error) {
+	cmd := redis.NewCmd("eval", renewLeaderScript, 1, schedulerLeaderKey, e.id, e.ttl.Milliseconds())
+	if err := e.redisClient.Process(cmd); err != nil {
+		return false, err
+	}
+	renewed, err := cmd.Int64()
+	return renewed == 1, err
+}
+
+func (e *RedisLeaderElector) release() {
+	e.mu.Lock()
+	wasLeader := e.isLeader
+	e.isLeader = false
+	e.mu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	cmd := redis.NewCmd("eval", releaseLeaderScript, 1, schedulerLeaderKey, e.id)
+	if err := e.redisClient.Process(cmd); err != nil {
+		e.logger.Warnf("Failed to release scheduler leadership: %v", err)
+	}
+}
+
+func (e *RedisLeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.isLeader
+}
+
+func (e *RedisLeaderElector) Leader() string {
+	leader, err := e.redisClient.Get(schedulerLeaderKey).Result()
+	if err != nil {
+		return ""
+	}
+	return leader
+}
+
+// LocalLeaderElector is used when the service runs as a single process without Redis.
+// The instance is always the leader.
+type LocalLeaderElector struct {
+	id string
+}
+
+func NewLocalLeaderElector(id string) *LocalLeaderElector {
+	return &LocalLeaderElector{id: id}
+}
+
+// Run blocks until ctx is done so it can be started like RedisLeaderElector.Run.
+func (e *LocalLeaderElector) Run(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func (e *LocalLeaderElector) IsLeader() bool {
+	return true
+}
+
+func (e *LocalLeaderElector) Leader() string {
+	return e.id
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"message-service/internal/pkg/logctx"
+)
+
+// localProviderURL is the webhook URL used in local mode when WEBHOOK_URL is not set.
+const localProviderURL = "http://local-provider/send"
+
+// localProvider accepts every send without network access, so local mode can run the
+// scheduler end to end. Responses have the same shape as the real webhook.
+type localProvider struct{}
+
+func (localProvider) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	body, err := json.Marshal(MessageResponse{
+		Message:   "Accepted",
+		MessageID: "local-" + logctx.NewID(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusAccepted,
+		Status:     "202 Accepted",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
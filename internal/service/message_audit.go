@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// MessageAudit appends to the audit trail of messages. Recording is best effort, a failed
+// write is logged and never fails the change it describes.
+type MessageAudit struct {
+	store  mpostgres.MessageAuditService
+	logger inslogger.Interface
+	now    func() time.Time
+	// afterCommit holds entries back while recording changes of a transaction.
+	afterCommit *afterCommit
+}
+
+func NewMessageAudit(store mpostgres.MessageAuditService, logger inslogger.Interface) *MessageAudit {
+	return &MessageAudit{store: store, logger: logger, now: time.Now}
+}
+
+// Record stores entry, stamped with the actor of ctx and the current time.
+func (a *MessageAudit) Record(ctx context.Context, entry model.MessageAuditEntry) {
+	entry.Actor = logctx.Actor(ctx)
+	if entry.Actor == "" {
+		entry.Actor = "system"
+	}
+	entry.OccurredAt = a.now().UTC()
+	a.afterCommit.do(func() {
+		if err := a.store.RecordMessageEvent(ctx, entry); err != nil {
+			logctx.Logger(ctx, a.logger).Warnf("Failed to record %s of message ID %d: %v", entry.Kind, entry.MessageID, err)
+		}
+	})
+}
+
+// RecordStatus records that message id changed to status, or kept it when status is empty.
+func (a *MessageAudit) RecordStatus(ctx context.Context, id uint, status model.MessageStatus, detail string) {
+	a.Record(ctx, model.MessageAuditEntry{MessageID: id, Kind: model.AuditStatusChange, Status: status, Detail: detail})
+}
+
+// RecordSendAttempt records that message id was handed to provider, which answered with
+// responseCode, zero when it answered no HTTP status, and failed with sendErr, if set.
+func (a *MessageAudit) RecordSendAttempt(ctx context.Context, id uint, provider string, responseCode int, sendErr error) {
+	entry := model.MessageAuditEntry{MessageID: id, Kind: model.AuditSendAttempt, Provider: provider}
+	if responseCode != 0 {
+		entry.ResponseCode = &responseCode
+	}
+	if sendErr != nil {
+		entry.Error = sendErr.Error()
+	}
+	a.Record(ctx, entry)
+}
+
+type providerResponseKey struct{}
+
+// withProviderResponse returns a ctx in which doProviderRequest stores the HTTP status code
+// of the provider response in the returned int.
+func withProviderResponse(ctx context.Context) (context.Context, *int) {
+	code := new(int)
+	return context.WithValue(ctx, providerResponseKey{}, code), code
+}
+
+func recordProviderResponse(ctx context.Context, statusCode int) {
+	if code, ok := ctx.Value(providerResponseKey{}).(*int); ok {
+		*code = statusCode
+	}
+}
+
+// auditedMessageService records every change it makes to a message by ID in the audit
+// trail. Changes made without a message ID, requeued failures, delivery status callbacks
+// and retention purges, are not recorded.
+type auditedMessageService struct {
+	mpostgres.MessageService
+	audit *MessageAudit
+}
+
+// NewAuditedMessageService wraps messages so their changes are recorded in audit. It
+// returns messages unchanged when audit is nil.
+func NewAuditedMessageService(messages mpostgres.MessageService, audit *MessageAudit) mpostgres.MessageService {
+	if audit == nil {
+		return messages
+	}
+	return &auditedMessageService{MessageService: messages, audit: audit}
+}
+
+// WithTx records the changes fn makes once the transaction committed, the entries
+// reference messages other connections cannot see before.
+func (s *auditedMessageService) WithTx(ctx context.Context, fn func(tx mpostgres.MessageService) error) error {
+	return withTx(ctx, s.MessageService, s.audit.afterCommit, func(tx mpostgres.MessageService, effects *afterCommit) error {
+		audit := *s.audit
+		audit.afterCommit = effects
+		return fn(&auditedMessageService{MessageService: tx, audit: &audit})
+	})
+}
+
+func (s *auditedMessageService) CreateMessage(ctx context.Context, message model.Message) (model.Message, error) {
+	created, err := s.MessageService.CreateMessage(ctx, message)
+	if err == nil {
+		s.audit.RecordStatus(ctx, created.ID, created.Status, "created")
+	}
+	return created, err
+}
+
+func (s *auditedMessageService) ImportMessages(ctx context.Context, messages []model.Message) ([]model.Message, error) {
+	imported, err := s.MessageService.ImportMessages(ctx, messages)
+	for _, message := range imported {
+		s.audit.RecordStatus(ctx, message.ID, model.StatusSent, "backfilled")
+	}
+	return imported, err
+}
+
+func (s *auditedMessageService) GetUnsentMessages(ctx context.Context, channel model.Channel, limit int) ([]model.Message, error) {
+	messages, err := s.MessageService.GetUnsentMessages(ctx, channel, limit)
+	s.recordAll(ctx, messages, model.StatusSending, "claimed")
+	return messages, err
+}
+
+func (s *auditedMessageService) ClaimMessagesByID(ctx context.Context, ids []uint) ([]model.Message, error) {
+	messages, err := s.MessageService.ClaimMessagesByID(ctx, ids)
+	s.recordAll(ctx, messages, model.StatusSending, "claimed")
+	return messages, err
+}
+
+func (s *auditedMessageService) ClaimMessages(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Message, error) {
+	messages, err := s.MessageService.ClaimMessages(ctx, workerID, limit, lease)
+	s.recordAll(ctx, messages, model.StatusSending, "claimed by worker "+workerID)
+	return messages, err
+}
+
+func (s *auditedMessageService) ReapExpiredClaims(ctx context.Context) ([]uint, error) {
+	ids, err := s.MessageService.ReapExpiredClaims(ctx)
+	for _, id := range ids {
+		s.audit.RecordStatus(ctx, id, model.StatusPending, "claim expired")
+	}
+	return ids, err
+}
+
+func (s *auditedMessageService) CompleteClaimedMessage(ctx context.Context, workerID string, result model.WorkerResult) (bool, error) {
+	ok, err := s.MessageService.CompleteClaimedMessage(ctx, workerID, result)
+	if err == nil && ok {
+		status, detail := model.StatusFailed, "completed by worker "+workerID
+		if result.Success {
+			status = model.StatusSent
+			detail += ", provider message ID " + result.ProviderMessageID
+		}
+		s.audit.RecordStatus(ctx, result.ID, status, detail)
+	}
+	return ok, err
+}
+
+func (s *auditedMessageService) UpdateMessageSent(ctx context.Context, id uint) error {
+	err := s.MessageService.UpdateMessageSent(ctx, id)
+	s.record(ctx, err, id, model.StatusSent, "")
+	return err
+}
+
+func (s *auditedMessageService) UpdateMessageSentWithProviderID(ctx context.Context, id uint, providerMessageID string) error {
+	err := s.MessageService.UpdateMessageSentWithProviderID(ctx, id, providerMessageID)
+	s.record(ctx, err, id, model.StatusSent, "provider message ID "+providerMessageID)
+	return err
+}
+
+func (s *auditedMessageService) MarkMessageSending(ctx context.Context, id uint) error {
+	err := s.MessageService.MarkMessageSending(ctx, id)
+	s.record(ctx, err, id, model.StatusSending, "")
+	return err
+}
+
+func (s *auditedMessageService) MarkMessageFailed(ctx context.Context, id uint) error {
+	err := s.MessageService.MarkMessageFailed(ctx, id)
+	s.record(ctx, err, id, model.StatusFailed, "")
+	return err
+}
+
+func (s *auditedMessageService) RequeueMessage(ctx context.Context, id uint) error {
+	err := s.MessageService.RequeueMessage(ctx, id)
+	s.record(ctx, err, id, model.StatusPending, "requeued")
+	return err
+}
+
+func (s *auditedMessageService) DeferMessage(ctx context.Context, id uint, until time.Time) error {
+	err := s.MessageService.DeferMessage(ctx, id, until)
+	s.record(ctx, err, id, model.StatusPending, "deferred until "+until.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *auditedMessageService) CancelMessage(ctx context.Context, id uint) error {
+	err := s.MessageService.CancelMessage(ctx, id)
+	s.record(ctx, err, id, model.StatusCancelled, "")
+	return err
+}
+
+func (s *auditedMessageService) SetMessageHold(ctx context.Context, id uint, held bool) error {
+	err := s.MessageService.SetMessageHold(ctx, id, held)
+	detail := "released"
+	if held {
+		detail = "held"
+	}
+	s.record(ctx, err, id, "", detail)
+	return err
+}
+
+func (s *auditedMessageService) RecordModeration(ctx context.Context, id uint, result model.ModerationResult) error {
+	err := s.MessageService.RecordModeration(ctx, id, result)
+	var status model.MessageStatus
+	switch result.Decision {
+	case model.ModerationHold:
+		status = model.StatusPending
+	case model.ModerationReject:
+		status = model.StatusCancelled
+	}
+	detail := fmt.Sprintf("moderation %s", result.Decision)
+	if result.Reason != "" {
+		detail += ": " + result.Reason
+	}
+	s.record(ctx, err, id, status, detail)
+	return err
+}
+
+func (s *auditedMessageService) ScheduleMessage(ctx context.Context, id uint, scheduledAt time.Time) error {
+	err := s.MessageService.ScheduleMessage(ctx, id, scheduledAt)
+	s.record(ctx, err, id, "", "scheduled for "+scheduledAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *auditedMessageService) SetMessagePriority(ctx context.Context, id uint, priority int) error {
+	err := s.MessageService.SetMessagePriority(ctx, id, priority)
+	s.record(ctx, err, id, "", fmt.Sprintf("priority set to %d", priority))
+	return err
+}
+
+// record records the change of message id unless err says it was not made.
+func (s *auditedMessageService) record(ctx context.Context, err error, id uint, status model.MessageStatus, detail string) {
+	if err == nil {
+		s.audit.RecordStatus(ctx, id, status, detail)
+	}
+}
+
+func (s *auditedMessageService) recordAll(ctx context.Context, messages []model.Message, status model.MessageStatus, detail string) {
+	for _, message := range messages {
+		s.audit.RecordStatus(ctx, message.ID, status, detail)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestMessageAuditRecordsChangesAndSendAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = server.URL
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, NewWebhookProvider(NewCircuitBreaker("webhook", 0, 0, inslogger.NewNopLogger()), &http.Client{}, cfg, inslogger.NewNopLogger()))
+
+	memory := mmemory.NewMessageService(inslogger.NewNopLogger())
+	store := mmemory.NewMessageAuditService()
+	audit := NewMessageAudit(store, inslogger.NewNopLogger())
+	messages := NewAuditedMessageService(memory, audit)
+	dispatcher := newTestDispatcher(memory, providers)
+	dispatcher.messageService = messages
+	dispatcher.audit = audit
+
+	created, err := messages.CreateMessage(logctx.WithAPIKeyName(context.Background(), "ops"), model.Message{Content: "hi", RecipientPhone: "+905551111111"})
+	assert.NoError(t, err)
+	_, err = dispatcher.SendMessages(context.Background(), model.ChannelSMS, 10)
+	assert.NoError(t, err)
+
+	entries, err := store.ListMessageEvents(context.Background(), created.ID)
+	assert.NoError(t, err)
+	if assert.GreaterOrEqual(t, len(entries), 4) {
+		assert.Equal(t, model.MessageAuditEntry{ID: entries[0].ID, MessageID: created.ID, Kind: model.AuditStatusChange,
+			Status: model.StatusPending, Detail: "created", Actor: "ops", OccurredAt: entries[0].OccurredAt}, entries[0])
+		assert.Equal(t, model.StatusSending, entries[1].Status)
+		assert.Equal(t, "scheduler", entries[1].Actor)
+
+		attempt := entries[2]
+		assert.Equal(t, model.AuditSendAttempt, attempt.Kind)
+		assert.Equal(t, webhookProviderName, attempt.Provider)
+		if assert.NotNil(t, attempt.ResponseCode) {
+			assert.Equal(t, http.StatusInternalServerError, *attempt.ResponseCode)
+		}
+		assert.Equal(t, "unexpected status code: 500", attempt.Error)
+		assert.Equal(t, model.AuditStatusChange, entries[3].Kind, "the outcome of the attempt is recorded")
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+	"golang.org/x/sync/singleflight"
+)
+
+// messageLoadTimeout bounds a database read shared by concurrent cache misses, which no
+// longer ends with the request that started it.
+const messageLoadTimeout = 10 * time.Second
+
+// cachedMessageService holds every Redis cache of messages: it caches GetMessage results,
+// drops the cached entry of every message whose status it changes and marks the send time
+// of messages it stores as sent. Changes made without a message ID, requeued failures,
+// delivery status callbacks and retention purges, become visible once the entry expires.
+type cachedMessageService struct {
+	mpostgres.MessageService
+	redisClient insredis.RedisInterface
+	ttl         time.Duration
+	sentTTL     time.Duration
+	logger      inslogger.Interface
+	// stats counts hits and misses for GET /api/stats.
+	stats *StatsCounters
+	// loads lets concurrent misses of the same message share one database read.
+	loads singleflight.Group
+	// afterCommit holds invalidations and sent markers back inside a transaction, which
+	// also reads around the cache.
+	afterCommit *afterCommit
+}
+
+// NewCachedMessageService wraps messages with a per-ID Redis cache of GetMessage kept for
+// ttl and keeps the send time of sent messages for sentTTL. It returns messages unchanged,
+// caching nothing, when ttl is zero.
+func NewCachedMessageService(messages mpostgres.MessageService, redisClient insredis.RedisInterface, ttl, sentTTL time.Duration, logger inslogger.Interface) mpostgres.MessageService {
+	if ttl <= 0 {
+		return messages
+	}
+
+	return &cachedMessageService{
+		MessageService: messages,
+		redisClient:    redisClient,
+		ttl:            ttl,
+		sentTTL:        sentTTL,
+		logger:         logger,
+		stats:          NewStatsCounters(redisClient),
+	}
+}
+
+// WithTx drops the cached messages fn changes once the transaction committed, so entries
+// cached from the old rows meanwhile do not outlive it.
+func (s *cachedMessageService) WithTx(ctx context.Context, fn func(tx mpostgres.MessageService) error) error {
+	return withTx(ctx, s.MessageService, s.afterCommit, func(tx mpostgres.MessageService, effects *afterCommit) error {
+		return fn(&cachedMessageService{
+			MessageService: tx,
+			redisClient:    s.redisClient,
+			ttl:            s.ttl,
+			sentTTL:        s.sentTTL,
+			logger:         s.logger,
+			stats:          s.stats,
+			afterCommit:    effects,
+		})
+	})
+}
+
+func (s *cachedMessageService) GetMessage(ctx context.Context, id uint) (model.Message, error) {
+	if s.afterCommit != nil {
+		// Transactions see their own changes, which neither the cache nor loads do.
+		return s.MessageService.GetMessage(ctx, id)
+	}
+	logger := logctx.Logger(ctx, s.logger)
+
+	cached, err := s.redisClient.Get(messageCacheKey(id)).Result()
+	switch {
+	case err == nil:
+		var message model.Message
+		if err := json.Unmarshal([]byte(cached), &message); err == nil {
+			s.stats.RecordCacheLookup(true)
+			// The entry is shared by every tenant, the scope is checked on each hit.
+			if scope := logctx.TenantScope(ctx); scope != "" && message.Tenant != scope {
+				return model.Message{}, mpostgres.ErrMessageNotFound
+			}
+			return message, nil
+		}
+		logger.Warnf("Discarding malformed cache entry of message ID %d", id)
+	case err != redis.Nil:
+		logger.Warnf("Failed to read cached message ID %d: %v", id, err)
+	}
+
+	s.stats.RecordCacheLookup(false)
+	// Loads are only shared within a tenant scope, a scoped load does not find the messages
+	// of other tenants.
+	load := logctx.TenantScope(ctx) + ":" + strconv.FormatUint(uint64(id), 10)
+	// The load outlives the caller that started it, the others waiting for it may still
+	// want the message when that caller gives up.
+	loading := s.loads.DoChan(load, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), messageLoadTimeout)
+		defer cancel()
+		message, err := s.MessageService.GetMessage(loadCtx, id)
+		if err != nil {
+			return message, err
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return message, nil
+		}
+		if err := s.redisClient.Set(messageCacheKey(id), data, s.ttl).Err(); err != nil {
+			logger.Warnf("Failed to cache message ID %d: %v", id, err)
+		}
+		return message, nil
+	})
+	select {
+	case result := <-loading:
+		return result.Val.(model.Message), result.Err
+	case <-ctx.Done():
+		return model.Message{}, ctx.Err()
+	}
+}
+
+func (s *cachedMessageService) GetUnsentMessages(ctx context.Context, channel model.Channel, limit int) ([]model.Message, error) {
+	messages, err := s.MessageService.GetUnsentMessages(ctx, channel, limit)
+	s.invalidate(ctx, idsOf(messages)...)
+	return messages, err
+}
+
+func (s *cachedMessageService) UpdateMessageSent(ctx context.Context, id uint) error {
+	defer s.invalidate(ctx, id)
+	err := s.MessageService.UpdateMessageSent(ctx, id)
+	if err == nil {
+		s.markSent(ctx, id)
+	}
+	return err
+}
+
+func (s *cachedMessageService) UpdateMessageSentWithProviderID(ctx context.Context, id uint, providerMessageID string) error {
+	defer s.invalidate(ctx, id)
+	err := s.MessageService.UpdateMessageSentWithProviderID(ctx, id, providerMessageID)
+	if err == nil {
+		s.markSent(ctx, id)
+	}
+	return err
+}
+
+func (s *cachedMessageService) MarkMessageSending(ctx context.Context, id uint) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.MarkMessageSending(ctx, id)
+}
+
+func (s *cachedMessageService) MarkMessageFailed(ctx context.Context, id uint) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.MarkMessageFailed(ctx, id)
+}
+
+func (s *cachedMessageService) RequeueMessage(ctx context.Context, id uint) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.RequeueMessage(ctx, id)
+}
+
+func (s *cachedMessageService) DeferMessage(ctx context.Context, id uint, until time.Time) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.DeferMessage(ctx, id, until)
+}
+
+func (s *cachedMessageService) CancelMessage(ctx context.Context, id uint) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.CancelMessage(ctx, id)
+}
+
+func (s *cachedMessageService) SetMessageHold(ctx context.Context, id uint, held bool) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.SetMessageHold(ctx, id, held)
+}
+
+func (s *cachedMessageService) RecordModeration(ctx context.Context, id uint, result model.ModerationResult) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.RecordModeration(ctx, id, result)
+}
+
+func (s *cachedMessageService) SetMessageExternalRef(ctx context.Context, id uint, ref model.ExternalRef) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.SetMessageExternalRef(ctx, id, ref)
+}
+
+func (s *cachedMessageService) ScheduleMessage(ctx context.Context, id uint, scheduledAt time.Time) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.ScheduleMessage(ctx, id, scheduledAt)
+}
+
+func (s *cachedMessageService) SetMessagePriority(ctx context.Context, id uint, priority int) error {
+	defer s.invalidate(ctx, id)
+	return s.MessageService.SetMessagePriority(ctx, id, priority)
+}
+
+func (s *cachedMessageService) ClaimMessagesByID(ctx context.Context, ids []uint) ([]model.Message, error) {
+	messages, err := s.MessageService.ClaimMessagesByID(ctx, ids)
+	s.invalidate(ctx, idsOf(messages)...)
+	return messages, err
+}
+
+func (s *cachedMessageService) ClaimMessages(ctx context.Context, workerID string, limit int, lease time.Duration) ([]model.Message, error) {
+	messages, err := s.MessageService.ClaimMessages(ctx, workerID, limit, lease)
+	s.invalidate(ctx, idsOf(messages)...)
+	return messages, err
+}
+
+func (s *cachedMessageService) CompleteClaimedMessage(ctx context.Context, workerID string, result model.WorkerResult) (bool, error) {
+	defer s.invalidate(ctx, result.ID)
+	ok, err := s.MessageService.CompleteClaimedMessage(ctx, workerID, result)
+	if err == nil && ok && result.Success {
+		s.markSent(ctx, result.ID)
+	}
+	return ok, err
+}
+
+func (s *cachedMessageService) PurgeRecipient(ctx context.Context, phone string, mode model.PurgeMode) ([]uint, error) {
+	ids, err := s.MessageService.PurgeRecipient(ctx, phone, mode)
+	s.invalidate(ctx, ids...)
+	return ids, err
+}
+
+func (s *cachedMessageService) ReapExpiredClaims(ctx context.Context) ([]uint, error) {
+	ids, err := s.MessageService.ReapExpiredClaims(ctx)
+	s.invalidate(ctx, ids...)
+	return ids, err
+}
+
+func (s *cachedMessageService) invalidate(ctx context.Context, ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, messageCacheKey(id))
+	}
+	s.afterCommit.do(func() {
+		if err := deleteKeys(s.redisClient, keys); err != nil {
+			logctx.Logger(ctx, s.logger).Warnf("Failed to invalidate %d cached messages: %v", len(ids), err)
+		}
+	})
+}
+
+// deleteKeys deletes keys in one command, or one command per key in a pipeline on a Redis
+// Cluster, which refuses commands on keys of several hash slots.
+func deleteKeys(redisClient insredis.RedisInterface, keys []string) error {
+	if _, ok := redisClient.(*redis.ClusterClient); !ok || len(keys) == 1 {
+		return redisClient.Del(keys...).Err()
+	}
+
+	_, err := redisClient.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(key)
+		}
+		return nil
+	})
+	return err
+}
+
+// markSent records the send time of message id for SentAt.
+func (s *cachedMessageService) markSent(ctx context.Context, id uint) {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	s.afterCommit.do(func() {
+		if err := s.redisClient.Set(sentCacheKey(id), timestamp, s.sentTTL).Err(); err != nil {
+			logctx.Logger(ctx, s.logger).Warnf("Failed to mark message ID %d as sent: %v", id, err)
+		}
+	})
+}
+
+// CachedMessage returns the copy of message id cached by NewCachedMessageService, or
+// redis.Nil when none is cached.
+func CachedMessage(redisClient insredis.RedisInterface, id uint) (model.Message, error) {
+	cached, err := redisClient.Get(messageCacheKey(id)).Result()
+	if err != nil {
+		return model.Message{}, err
+	}
+
+	var message model.Message
+	if err := json.Unmarshal([]byte(cached), &message); err != nil {
+		return model.Message{}, fmt.Errorf("malformed cache entry of message ID %d: %w", id, err)
+	}
+	return message, nil
+}
+
+func messageCacheKey(id uint) string {
+	return fmt.Sprintf("message:detail:%d", id)
+}
+
+func sentCacheKey(id uint) string {
+	return fmt.Sprintf("message:%d", id)
+}
+
+// SentAt returns the send time NewCachedMessageService marked for message id, or redis.Nil
+// when none is marked.
+func SentAt(redisClient insredis.RedisInterface, id uint) (time.Time, error) {
+	cached, err := redisClient.Get(sentCacheKey(id)).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, cached)
+}
+
+func idsOf(messages []model.Message) []uint {
+	ids := make([]uint, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/localredis"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestCachedMessageServiceInvalidatesOnStatusChange(t *testing.T) {
+	ctx := context.Background()
+	redisClient := localredis.New()
+	messages := NewCachedMessageService(mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 3, Status: model.StatusSending}),
+		redisClient, time.Minute, time.Hour, inslogger.NewNopLogger())
+
+	msg, err := messages.GetMessage(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatusPending, msg.Status)
+	exists, _ := redisClient.Exists(messageCacheKey(1)).Result()
+	assert.Equal(t, int64(1), exists)
+
+	assert.NoError(t, messages.CancelMessage(ctx, 1))
+	msg, err = messages.GetMessage(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, model.StatusCancelled, msg.Status)
+	_, err = SentAt(redisClient, 1)
+	assert.ErrorIs(t, err, redis.Nil)
+
+	assert.NoError(t, messages.UpdateMessageSentWithProviderID(ctx, 3, "SM1"))
+	_, err = SentAt(redisClient, 3)
+	assert.NoError(t, err, "the send time is marked")
+
+	_, err = messages.GetMessage(ctx, 2)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+}
+
+func TestCachedMessageServiceKeepsTenantsApart(t *testing.T) {
+	redisClient := localredis.New()
+	messages := NewCachedMessageService(mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1, Tenant: "acme"}),
+		redisClient, time.Minute, time.Hour, inslogger.NewNopLogger())
+
+	_, err := messages.GetMessage(logctx.WithTenantScope(context.Background(), "acme"), 1)
+	assert.NoError(t, err)
+
+	// The message is cached now, the scope still applies to the cached copy.
+	_, err = messages.GetMessage(logctx.WithTenantScope(context.Background(), "globex"), 1)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+}
+
+func TestCacheConsistencyChecker(t *testing.T) {
+	ctx := context.Background()
+	redisClient := localredis.New()
+	store := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1}, model.Message{ID: 2, Status: model.StatusFailed}, model.Message{ID: 3, Status: model.StatusSent})
+	cached := NewCachedMessageService(store, redisClient, time.Minute, time.Hour, inslogger.NewNopLogger())
+	checker := NewCacheConsistencyChecker(store, redisClient, stubElector(true), 10, time.Minute, inslogger.NewNopLogger())
+
+	for _, id := range []uint{1, 2, 3} {
+		if _, err := cached.GetMessage(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []uint{2, 3} {
+		redisClient.Set(sentCacheKey(id), time.Now().UTC().Format(time.RFC3339), time.Hour)
+	}
+
+	divergences, err := checker.Check(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, divergences, 1, "a sent message may carry a marker") {
+		assert.Equal(t, uint(2), divergences[0].ID)
+		assert.Equal(t, divergenceSentMarker, divergences[0].Kind)
+		assert.Equal(t, model.StatusFailed, divergences[0].Stored)
+	}
+
+	// A change the cache was not told about.
+	assert.NoError(t, store.CancelMessage(ctx, 1))
+
+	divergences, err = checker.Check(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, divergences, CacheDivergence{ID: 1, Kind: divergenceMessage, Cached: string(model.StatusPending), Stored: model.StatusCancelled})
+	assert.Equal(t, float64(1), cacheDivergent.Value(divergenceMessage))
+	assert.Equal(t, float64(1), cacheDivergent.Value(divergenceSentMarker))
+}
+
+// gatedMessageService holds GetMessage until release is closed or its context ends.
+type gatedMessageService struct {
+	mpostgres.MessageService
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *gatedMessageService) GetMessage(ctx context.Context, id uint) (model.Message, error) {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	select {
+	case <-s.release:
+		return s.MessageService.GetMessage(ctx, id)
+	case <-ctx.Done():
+		return model.Message{}, ctx.Err()
+	}
+}
+
+func TestCachedMessageServiceLoadOutlivesCancelledCaller(t *testing.T) {
+	gated := &gatedMessageService{
+		MessageService: mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1, Content: "hi"}),
+		started:        make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	messages := NewCachedMessageService(gated, localredis.New(), time.Minute, time.Hour, inslogger.NewNopLogger())
+
+	firstCtx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := messages.GetMessage(firstCtx, 1)
+		first <- err
+	}()
+	<-gated.started
+
+	second := make(chan model.Message, 1)
+	go func() {
+		msg, err := messages.GetMessage(context.Background(), 1)
+		assert.NoError(t, err)
+		second <- msg
+	}()
+	// Let the second caller join the load before the first one gives up.
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	close(gated.release)
+	assert.Equal(t, "hi", (<-second).Content, "the load goes on for the callers still waiting")
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/phone"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+const messageBirdProviderName = "messagebird"
+
+type messageBirdRequest struct {
+	Originator string   `json:"originator"`
+	Recipients []string `json:"recipients"`
+	Body       string   `json:"body"`
+}
+
+// messageBirdResponse is the part of MessageBird's message object and error body the driver reads.
+type messageBirdResponse struct {
+	ID         string `json:"id"`
+	Recipients struct {
+		Items []struct {
+			Status string `json:"status"`
+		} `json:"items"`
+	} `json:"recipients"`
+	Errors []struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"errors"`
+}
+
+// messageBirdProvider sends SMS through MessageBird's REST messages API.
+type messageBirdProvider struct {
+	logger      inslogger.Interface
+	breaker     *CircuitBreaker
+	httpClient  *http.Client
+	messagesURL string
+	accessKey   string
+	originator  string
+	countryCode string
+}
+
+func NewMessageBirdProvider(breaker *CircuitBreaker, httpClient *http.Client, cfg *config.App, logger inslogger.Interface) Provider {
+	messageBird := cfg.SMS.MessageBird
+	return &messageBirdProvider{
+		logger:      logger,
+		breaker:     breaker,
+		httpClient:  withTransport(httpClient, newProviderTransport(httpClient.Transport, cfg, logger)),
+		messagesURL: strings.TrimRight(messageBird.BaseURL, "/") + "/messages",
+		accessKey:   messageBird.AccessKey,
+		originator:  messageBird.Originator,
+		countryCode: cfg.Phone.DefaultCountryCode,
+	}
+}
+
+func (p *messageBirdProvider) Name() string {
+	return messageBirdProviderName
+}
+
+// Probe checks that the API accepts connections.
+func (p *messageBirdProvider) Probe(ctx context.Context) error {
+	return probeURL(ctx, p.messagesURL)
+}
+
+// Send returns MessageBird's message ID and the status of its only recipient.
+func (p *messageBirdProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	recipientPhone, err := phone.Normalize(message.RecipientPhone, p.countryCode)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("recipient %q: %w", message.RecipientPhone, err)
+	}
+
+	// MessageBird takes MSISDNs, which are E.164 numbers without the leading plus.
+	payloadBytes, err := json.Marshal(messageBirdRequest{
+		Originator: p.originator,
+		Recipients: []string{strings.TrimPrefix(recipientPhone, "+")},
+		Body:       message.Content,
+	})
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.messagesURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "AccessKey "+p.accessKey)
+
+	resp, err := doProviderRequest(p.breaker, p.httpClient, req, p.logger)
+	if err != nil {
+		return ProviderResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var response messageBirdResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && len(response.Errors) > 0 {
+			return ProviderResult{}, fmt.Errorf("messagebird error %d: %s: status code %d",
+				response.Errors[0].Code, response.Errors[0].Description, resp.StatusCode)
+		}
+		return ProviderResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return ProviderResult{}, fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+
+	result := ProviderResult{MessageID: response.ID}
+	if len(response.Recipients.Items) > 0 {
+		result.Status = response.Recipients.Items[0].Status
+	}
+	return result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+var (
+	moderationDecisions = metrics.NewCounterVec(
+		"moderation_decisions_total",
+		"Moderation decisions recorded before sending, by decision.",
+		"decision",
+	)
+	moderationErrors = metrics.NewCounterVec(
+		"moderation_errors_total",
+		"Messages whose moderator failed and that got MODERATION_ON_ERROR instead, by channel.",
+		"channel",
+	)
+)
+
+// Moderator decides whether a message may be sent.
+type Moderator interface {
+	Moderate(ctx context.Context, message model.Message) (model.ModerationResult, error)
+}
+
+type webhookModerator struct {
+	url        string
+	timeout    time.Duration
+	httpClient *http.Client
+}
+
+// NewWebhookModerator posts a model.ModerationRequest to url, typically an internal
+// moderation service, and expects a model.ModerationResult back.
+func NewWebhookModerator(url string, timeout time.Duration, httpClient *http.Client) Moderator {
+	return &webhookModerator{url: url, timeout: timeout, httpClient: httpClient}
+}
+
+func (m *webhookModerator) Moderate(ctx context.Context, message model.Message) (model.ModerationResult, error) {
+	body, err := json.Marshal(model.ModerationRequest{
+		MessageID: message.ID,
+		Tenant:    message.Tenant,
+		Channel:   message.DeliveryChannel(),
+		Content:   message.Content,
+	})
+	if err != nil {
+		return model.ModerationResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return model.ModerationResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return model.ModerationResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return model.ModerationResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var result model.ModerationResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return model.ModerationResult{}, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	if !result.Decision.Valid() {
+		return model.ModerationResult{}, fmt.Errorf("unknown moderation decision %q", result.Decision)
+	}
+	return result, nil
+}
+
+// ModerationRule holds or rejects messages whose content matches Pattern.
+type ModerationRule struct {
+	Pattern  *regexp.Regexp
+	Decision model.ModerationDecision
+	Reason   string
+}
+
+type ruleModerator struct {
+	rules []ModerationRule
+}
+
+// NewRuleModerator decides by the first rule matching the content and approves messages
+// no rule matches.
+func NewRuleModerator(rules []ModerationRule) Moderator {
+	return &ruleModerator{rules: rules}
+}
+
+func (m *ruleModerator) Moderate(_ context.Context, message model.Message) (model.ModerationResult, error) {
+	for _, rule := range m.rules {
+		if rule.Pattern.MatchString(message.Content) {
+			return model.ModerationResult{Decision: rule.Decision, Reason: rule.Reason}, nil
+		}
+	}
+	return model.ModerationResult{Decision: model.ModerationApprove}, nil
+}
+
+// ReadModerationRules reads a JSON array of rules, e.g.
+// [{"pattern": "(?i)\\bcasino\\b", "decision": "reject", "reason": "gambling"}].
+// Keyword lists are patterns too, e.g. "(?i)\\b(loan|credit)\\b".
+func ReadModerationRules(path string) ([]ModerationRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []struct {
+		Pattern  string                   `json:"pattern"`
+		Decision model.ModerationDecision `json:"decision"`
+		Reason   string                   `json:"reason"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid moderation rules file %s: %w", path, err)
+	}
+
+	rules := make([]ModerationRule, 0, len(raw))
+	for i, entry := range raw {
+		pattern, err := regexp.Compile(entry.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("moderation rule %d: %w", i, err)
+		}
+		if !entry.Decision.Valid() {
+			return nil, fmt.Errorf("moderation rule %d: unknown decision %q", i, entry.Decision)
+		}
+		rules = append(rules, ModerationRule{Pattern: pattern, Decision: entry.Decision, Reason: entry.Reason})
+	}
+	return rules, nil
+}
+
+// ModerationService runs the moderator of a message's tenant and channel before it is
+// sent and records the decision on the message.
+type ModerationService struct {
+	scopes   map[string]Moderator
+	messages mpostgres.MessageService
+	onError  model.ModerationDecision
+	logger   inslogger.Interface
+}
+
+// NewModerationService moderates messages with the moderator of the most specific scope
+// in scopes: tenant/channel, tenant/*, */channel and then */*. A nil moderator turns
+// moderation off for its scope. Messages whose moderator fails get onError.
+func NewModerationService(scopes map[string]Moderator, messages mpostgres.MessageService, onError model.ModerationDecision, logger inslogger.Interface) *ModerationService {
+	return &ModerationService{
+		scopes:   scopes,
+		messages: messages,
+		onError:  onError,
+		logger:   logger,
+	}
+}
+
+// Review moderates message, records the result on it and returns it. Messages that were
+// moderated before, e.g. held ones a reviewer released, and messages no scope moderates
+// are approved without recording anything.
+func (s *ModerationService) Review(ctx context.Context, message model.Message) (model.ModerationResult, error) {
+	approved := model.ModerationResult{Decision: model.ModerationApprove}
+	if message.ModerationDecision != "" {
+		return approved, nil
+	}
+	moderator := s.moderatorOf(message)
+	if moderator == nil {
+		return approved, nil
+	}
+
+	logger := logctx.Logger(ctx, s.logger)
+	result, err := moderator.Moderate(ctx, message)
+	if err != nil {
+		logger.Warnf("Failed to moderate message ID %d, deciding %s: %v", message.ID, s.onError, err)
+		moderationErrors.Inc(string(message.DeliveryChannel()))
+		result = model.ModerationResult{Decision: s.onError, Reason: "moderation failed: " + err.Error()}
+	}
+
+	if err := s.messages.RecordModeration(ctx, message.ID, result); err != nil {
+		return result, err
+	}
+	moderationDecisions.Inc(string(result.Decision))
+	if result.Decision != model.ModerationApprove {
+		logger.Logf("Moderation decided %s for message ID %d: %s", result.Decision, message.ID, result.Reason)
+	}
+	return result, nil
+}
+
+func (s *ModerationService) moderatorOf(message model.Message) Moderator {
+	channel := string(message.DeliveryChannel())
+	for _, scope := range []string{
+		message.Tenant + "/" + channel,
+		message.Tenant + "/*",
+		"*/" + channel,
+		"*/*",
+	} {
+		if moderator, ok := s.scopes[scope]; ok {
+			return moderator
+		}
+	}
+	return nil
+}
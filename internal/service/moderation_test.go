@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestRuleModeratorFirstMatchDecides(t *testing.T) {
+	moderator := NewRuleModerator([]ModerationRule{
+		{Pattern: regexp.MustCompile(`(?i)\bcasino\b`), Decision: model.ModerationReject, Reason: "gambling"},
+		{Pattern: regexp.MustCompile(`(?i)\b(loan|credit)\b`), Decision: model.ModerationHold, Reason: "financial offer"},
+	})
+
+	for content, want := range map[string]model.ModerationResult{
+		"Your code is 1234":         {Decision: model.ModerationApprove},
+		"Cheap LOAN today":          {Decision: model.ModerationHold, Reason: "financial offer"},
+		"Casino credit for you too": {Decision: model.ModerationReject, Reason: "gambling"},
+	} {
+		result, err := moderator.Moderate(context.Background(), model.Message{Content: content})
+		assert.NoError(t, err)
+		assert.Equal(t, want, result, content)
+	}
+}
+
+func TestReadModerationRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`[{"pattern": "(?i)casino", "decision": "reject", "reason": "gambling"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := ReadModerationRules(path)
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 1) {
+		assert.True(t, rules[0].Pattern.MatchString("CASINO"))
+		assert.Equal(t, model.ModerationReject, rules[0].Decision)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"pattern": "casino", "decision": "block"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadModerationRules(path)
+	assert.ErrorContains(t, err, "unknown decision")
+}
+
+func TestWebhookModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req model.ModerationRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, model.ModerationRequest{MessageID: 7, Tenant: "acme", Channel: model.ChannelSMS, Content: "hi"}, req)
+		_ = json.NewEncoder(w).Encode(model.ModerationResult{Decision: model.ModerationHold, Reason: "new sender"})
+	}))
+	defer server.Close()
+
+	moderator := NewWebhookModerator(server.URL, time.Second, server.Client())
+	result, err := moderator.Moderate(context.Background(), model.Message{ID: 7, Tenant: "acme", Content: "hi"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.ModerationResult{Decision: model.ModerationHold, Reason: "new sender"}, result)
+}
+
+func TestModerationServiceRecordsDecisionByScope(t *testing.T) {
+	rejectAll := NewRuleModerator([]ModerationRule{{Pattern: regexp.MustCompile(``), Decision: model.ModerationReject, Reason: "blocked"}})
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Tenant: "acme"},
+		model.Message{ID: 2, Tenant: "acme", Channel: model.ChannelEmail},
+		model.Message{ID: 3, Tenant: "globex"},
+	)
+	moderation := NewModerationService(map[string]Moderator{
+		"acme/*":     rejectAll,
+		"acme/email": nil,
+	}, messages, model.ModerationHold, inslogger.NewNopLogger())
+
+	for id, want := range map[uint]model.MessageStatus{1: model.StatusCancelled, 2: model.StatusPending, 3: model.StatusPending} {
+		message, _ := messages.Message(id)
+		_, err := moderation.Review(context.Background(), message)
+		assert.NoError(t, err)
+
+		stored, _ := messages.Message(id)
+		assert.Equal(t, want, stored.Status, "message %d", id)
+	}
+	rejected, _ := messages.Message(1)
+	assert.Equal(t, model.ModerationReject, rejected.ModerationDecision)
+	assert.Equal(t, "blocked", rejected.ModerationReason)
+	unmoderated, _ := messages.Message(2)
+	assert.Empty(t, unmoderated.ModerationDecision, "acme/email is more specific than acme/*")
+}
+
+func TestModerationServiceAppliesOnErrorDecision(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1})
+	moderation := NewModerationService(map[string]Moderator{
+		"*/*": NewWebhookModerator(server.URL, time.Second, server.Client()),
+	}, messages, model.ModerationHold, inslogger.NewNopLogger())
+
+	message, _ := messages.Message(1)
+	result, err := moderation.Review(context.Background(), message)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.ModerationHold, result.Decision)
+	stored, _ := messages.Message(1)
+	assert.True(t, stored.Held)
+	assert.Contains(t, stored.ModerationReason, "502")
+}
+
+func TestSendMessagesModeratesBeforeSending(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Content: "Your code is 1234"},
+		model.Message{ID: 2, Content: "Cheap loan"},
+		model.Message{ID: 3, Content: "Casino night"},
+		// Released by a reviewer after an earlier hold, it is not moderated again.
+		model.Message{ID: 4, Content: "Cheap loan", ModerationDecision: model.ModerationHold},
+	)
+	dispatcher := newTestDispatcher(messages, providers)
+	dispatcher.moderation = NewModerationService(map[string]Moderator{
+		"*/*": NewRuleModerator([]ModerationRule{
+			{Pattern: regexp.MustCompile(`(?i)casino`), Decision: model.ModerationReject},
+			{Pattern: regexp.MustCompile(`(?i)loan`), Decision: model.ModerationHold},
+		}),
+	}, messages, model.ModerationHold, inslogger.NewNopLogger())
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 4, Sent: 2, Skipped: 2}, result)
+	assert.ElementsMatch(t, []uint{1, 4}, sms.sent)
+	held, _ := messages.Message(2)
+	assert.Equal(t, model.StatusPending, held.Status)
+	assert.True(t, held.Held)
+	rejected, _ := messages.Message(3)
+	assert.Equal(t, model.StatusCancelled, rejected.Status)
+}
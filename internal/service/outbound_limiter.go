@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// The bucket and the limits set at runtime share a hash tag so the script can use both
+// on Redis Cluster.
+const (
+	outboundBucketKey = "{outbound}:bucket"
+	outboundLimitsKey = "{outbound}:limits"
+)
+
+// outboundBucketScript refills the bucket based on elapsed time and takes one token if
+// available. It returns 0 when it took one, otherwise the milliseconds until the next
+// token. Limits stored in KEYS[2] by SetLimits win over the configured ones.
+// KEYS[1] bucket hash, KEYS[2] limits hash, ARGV[1] rate per second, ARGV[2] burst,
+// ARGV[3] now in milliseconds.
+const outboundBucketScript = `
+local rate = tonumber(redis.call("HGET", KEYS[2], "rate") or ARGV[1])
+local burst = tonumber(redis.call("HGET", KEYS[2], "burst") or ARGV[2])
+local now = tonumber(ARGV[3])
+if rate <= 0 then
+	return 0
+end
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + (math.max(0, now - ts) / 1000) * rate)
+
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return wait
+`
+
+var outboundRateLimited = metrics.NewCounterVec(
+	"outbound_rate_limited_total",
+	"Sends given up because no outbound rate limit token became available within OUTBOUND_MAX_WAIT, by channel.",
+	"channel",
+)
+
+// ErrOutboundRateLimited is returned when no send token became available within the
+// maximum wait. The message was not handed to its provider.
+var ErrOutboundRateLimited = errors.New("outbound rate limit reached")
+
+// ErrInvalidOutboundLimits is returned by SetLimits for a negative rate or a burst below one.
+var ErrInvalidOutboundLimits = errors.New("invalid outbound limits")
+
+// OutboundLimiter caps how fast messages are handed to the providers, over every send path.
+type OutboundLimiter interface {
+	// Wait blocks until a send may start. It returns ErrOutboundRateLimited when that would
+	// take longer than the maximum wait, or the error of ctx once it is done.
+	Wait(ctx context.Context) error
+	// Limits returns the limits in effect.
+	Limits() (model.OutboundLimits, error)
+	// SetLimits replaces the configured limits until ResetLimits is called.
+	SetLimits(limits model.OutboundLimits) error
+	// ResetLimits returns to the configured limits.
+	ResetLimits() error
+}
+
+type redisOutboundLimiter struct {
+	redisClient insredis.RedisInterface
+	defaults    model.OutboundLimits
+	maxWait     time.Duration
+}
+
+// NewRedisOutboundLimiter returns a token bucket shared by every replica through Redis,
+// starting with defaults. Limits set at runtime are stored in Redis too, so they apply to
+// every replica and survive restarts.
+func NewRedisOutboundLimiter(redisClient insredis.RedisInterface, defaults model.OutboundLimits, maxWait time.Duration) OutboundLimiter {
+	return &redisOutboundLimiter{
+		redisClient: redisClient,
+		defaults:    defaults,
+		maxWait:     maxWait,
+	}
+}
+
+func (l *redisOutboundLimiter) Wait(ctx context.Context) error {
+	return waitForToken(ctx, l.maxWait, l.take)
+}
+
+func (l *redisOutboundLimiter) take() (time.Duration, error) {
+	cmd := redis.NewCmd("eval", outboundBucketScript, 2, outboundBucketKey, outboundLimitsKey,
+		l.defaults.Rate, l.defaults.Burst, time.Now().UnixMilli())
+	if err := l.redisClient.Process(cmd); err != nil {
+		return 0, fmt.Errorf("failed to take outbound token: %w", err)
+	}
+
+	wait, err := cmd.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbound token: %w", err)
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func (l *redisOutboundLimiter) Limits() (model.OutboundLimits, error) {
+	values, err := l.redisClient.HMGet(outboundLimitsKey, "rate", "burst").Result()
+	if err != nil {
+		return model.OutboundLimits{}, fmt.Errorf("failed to read outbound limits: %w", err)
+	}
+
+	limits := l.defaults
+	if rate, ok := values[0].(string); ok {
+		if limits.Rate, err = strconv.ParseFloat(rate, 64); err != nil {
+			return model.OutboundLimits{}, fmt.Errorf("invalid stored outbound rate %q: %w", rate, err)
+		}
+	}
+	if burst, ok := values[1].(string); ok {
+		if limits.Burst, err = strconv.Atoi(burst); err != nil {
+			return model.OutboundLimits{}, fmt.Errorf("invalid stored outbound burst %q: %w", burst, err)
+		}
+	}
+	return limits, nil
+}
+
+func (l *redisOutboundLimiter) SetLimits(limits model.OutboundLimits) error {
+	if err := validateOutboundLimits(limits); err != nil {
+		return err
+	}
+	err := l.redisClient.HMSet(outboundLimitsKey, map[string]interface{}{
+		"rate":  limits.Rate,
+		"burst": limits.Burst,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to store outbound limits: %w", err)
+	}
+	return nil
+}
+
+func (l *redisOutboundLimiter) ResetLimits() error {
+	if err := l.redisClient.Del(outboundLimitsKey).Err(); err != nil {
+		return fmt.Errorf("failed to reset outbound limits: %w", err)
+	}
+	return nil
+}
+
+type localOutboundLimiter struct {
+	defaults model.OutboundLimits
+	maxWait  time.Duration
+
+	mu     sync.Mutex
+	limits model.OutboundLimits
+	tokens float64
+	ts     time.Time
+}
+
+// NewLocalOutboundLimiter returns the same token bucket kept in process memory, for local
+// mode where a single replica runs without Redis.
+func NewLocalOutboundLimiter(defaults model.OutboundLimits, maxWait time.Duration) OutboundLimiter {
+	return &localOutboundLimiter{
+		defaults: defaults,
+		maxWait:  maxWait,
+		limits:   defaults,
+		tokens:   float64(defaults.Burst),
+		ts:       time.Now(),
+	}
+}
+
+func (l *localOutboundLimiter) Wait(ctx context.Context) error {
+	return waitForToken(ctx, l.maxWait, l.take)
+}
+
+func (l *localOutboundLimiter) take() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limits.Rate <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	l.tokens = math.Min(float64(l.limits.Burst), l.tokens+now.Sub(l.ts).Seconds()*l.limits.Rate)
+	l.ts = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, nil
+	}
+	return time.Duration(math.Ceil((1 - l.tokens) / l.limits.Rate * float64(time.Second))), nil
+}
+
+func (l *localOutboundLimiter) Limits() (model.OutboundLimits, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limits, nil
+}
+
+func (l *localOutboundLimiter) SetLimits(limits model.OutboundLimits) error {
+	if err := validateOutboundLimits(limits); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	l.tokens = math.Min(l.tokens, float64(limits.Burst))
+	return nil
+}
+
+func (l *localOutboundLimiter) ResetLimits() error {
+	return l.SetLimits(l.defaults)
+}
+
+func validateOutboundLimits(limits model.OutboundLimits) error {
+	if limits.Rate < 0 || limits.Burst < 1 {
+		return fmt.Errorf("%w: rate must not be negative and burst must be at least 1", ErrInvalidOutboundLimits)
+	}
+	return nil
+}
+
+// waitForToken calls take until it hands out a token, sleeping for the wait it reports in
+// between, and gives up once the next token is further away than maxWait.
+func waitForToken(ctx context.Context, maxWait time.Duration, take func() (time.Duration, error)) error {
+	deadline := time.Now().Add(maxWait)
+	for {
+		wait, err := take()
+		if err != nil {
+			return err
+		}
+		if wait <= 0 {
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return ErrOutboundRateLimited
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestLocalOutboundLimiterWaitsAfterBurst(t *testing.T) {
+	limiter := NewLocalOutboundLimiter(model.OutboundLimits{Rate: 50, Burst: 2}, time.Second)
+	start := time.Now()
+
+	for range 4 {
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+
+	// Two sends of the burst, then one every 20ms.
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestLocalOutboundLimiterLimitsCanChange(t *testing.T) {
+	defaults := model.OutboundLimits{Rate: 1, Burst: 1}
+	limiter := NewLocalOutboundLimiter(defaults, 10*time.Millisecond)
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.ErrorIs(t, limiter.Wait(context.Background()), ErrOutboundRateLimited, "the next token is a second away")
+
+	assert.ErrorIs(t, limiter.SetLimits(model.OutboundLimits{Rate: 1, Burst: 0}), ErrInvalidOutboundLimits)
+	assert.NoError(t, limiter.SetLimits(model.OutboundLimits{Rate: 0, Burst: 1}))
+	assert.NoError(t, limiter.Wait(context.Background()), "a zero rate does not limit")
+
+	assert.NoError(t, limiter.ResetLimits())
+	limits, err := limiter.Limits()
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, limits)
+}
+
+func TestSendMessagesDefersRateLimitedMessages(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}, model.Message{ID: 2})
+	dispatcher := newTestDispatcher(messages, providers)
+	dispatcher.outbound = NewLocalOutboundLimiter(model.OutboundLimits{Rate: 1, Burst: 1}, 10*time.Millisecond)
+
+	result, err := dispatcher.SendMessages(context.Background(), "", 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 2, Sent: 1, Skipped: 1, Errors: []string{ErrOutboundRateLimited.Error()}}, result)
+	assert.Equal(t, []uint{1}, sms.sent)
+	deferred, _ := messages.Message(2)
+	assert.Equal(t, model.StatusPending, deferred.Status, "deferred messages are left to a later batch")
+}
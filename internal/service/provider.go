@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"message-service/internal/model"
+)
+
+// ErrNoProvider is returned when no provider is registered for a message's channel.
+var ErrNoProvider = errors.New("no provider registered for channel")
+
+// ErrProviderUnauthorized is returned when a provider rejects the configured credentials.
+var ErrProviderUnauthorized = errors.New("provider rejected authentication")
+
+// ProviderResult is what a provider reports about an accepted message.
+type ProviderResult struct {
+	// MessageID is the provider's ID of the message, used to match delivery callbacks.
+	MessageID string
+	// Status is the provider's initial status of the message, e.g. "queued", if it reports one.
+	Status string
+}
+
+// Provider delivers messages of one channel, e.g. the webhook SMS gateway or SMTP.
+type Provider interface {
+	// Name identifies the provider in metrics and in-flight limits.
+	Name() string
+	// Send hands message to the provider.
+	Send(ctx context.Context, message model.Message) (ProviderResult, error)
+}
+
+// ProviderProber is implemented by providers that can check that their endpoint is
+// reachable without sending anything.
+type ProviderProber interface {
+	Probe(ctx context.Context) error
+}
+
+// probeURL checks that the host of rawURL accepts TCP connections.
+func probeURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid provider URL: %w", err)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return probeAddr(ctx, net.JoinHostPort(u.Hostname(), port))
+}
+
+// probeAddr checks that addr, a host:port, accepts TCP connections.
+func probeAddr(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("%s is unreachable: %w", addr, err)
+	}
+	return conn.Close()
+}
+
+// ProviderRegistry holds the providers of every channel. When a channel has several
+// providers, each send picks one through the channel's ProviderRouter.
+type ProviderRegistry struct {
+	channels map[model.Channel]*channelProviders
+}
+
+type channelProviders struct {
+	router    *ProviderRouter
+	providers map[string]Provider
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{channels: make(map[model.Channel]*channelProviders)}
+}
+
+// Register adds provider to channel. It is meant to be called during startup, before sends begin.
+func (r *ProviderRegistry) Register(channel model.Channel, provider Provider) {
+	cp, ok := r.channels[channel]
+	if !ok {
+		cp = &channelProviders{providers: make(map[string]Provider)}
+		r.channels[channel] = cp
+	}
+	cp.providers[provider.Name()] = provider
+
+	names := make([]string, 0, len(cp.providers))
+	for name := range cp.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cp.router = NewProviderRouter(names...)
+}
+
+// Channels returns the channels that have at least one provider, sorted.
+func (r *ProviderRegistry) Channels() []model.Channel {
+	channels := make([]model.Channel, 0, len(r.channels))
+	for channel := range r.channels {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+	return channels
+}
+
+// Providers returns every registered provider, by channel and name.
+func (r *ProviderRegistry) Providers() []Provider {
+	var providers []Provider
+	for _, channel := range r.Channels() {
+		cp := r.channels[channel]
+		names := make([]string, 0, len(cp.providers))
+		for name := range cp.providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			providers = append(providers, cp.providers[name])
+		}
+	}
+	return providers
+}
+
+// Pick selects the provider for the next send on channel.
+func (r *ProviderRegistry) Pick(channel model.Channel) (Provider, error) {
+	cp, ok := r.channels[channel]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoProvider, channel)
+	}
+	return cp.providers[cp.router.Pick()], nil
+}
+
+// Observe feeds the outcome of a send into the routing of channel.
+func (r *ProviderRegistry) Observe(channel model.Channel, provider Provider, latency time.Duration, err error) {
+	if cp, ok := r.channels[channel]; ok {
+		cp.router.Observe(provider.Name(), latency, err)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// ProviderConfigService manages the webhook gateways of tenants. Sends pick up a change
+// within TENANT_WEBHOOK_CONFIG_TTL.
+type ProviderConfigService interface {
+	Create(ctx context.Context, req model.ProviderConfigRequest) (model.ProviderConfig, error)
+	Get(ctx context.Context, id uint) (model.ProviderConfig, error)
+	List(ctx context.Context) ([]model.ProviderConfig, error)
+	Update(ctx context.Context, id uint, req model.ProviderConfigRequest) (model.ProviderConfig, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type providerConfigService struct {
+	repo   mpostgres.ProviderConfigRepository
+	logger inslogger.Interface
+}
+
+func NewProviderConfigService(repo mpostgres.ProviderConfigRepository, logger inslogger.Interface) ProviderConfigService {
+	return &providerConfigService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *providerConfigService) Create(ctx context.Context, req model.ProviderConfigRequest) (model.ProviderConfig, error) {
+	return s.repo.CreateProviderConfig(ctx, providerConfig(req))
+}
+
+func (s *providerConfigService) Get(ctx context.Context, id uint) (model.ProviderConfig, error) {
+	return s.repo.GetProviderConfig(ctx, id)
+}
+
+func (s *providerConfigService) List(ctx context.Context) ([]model.ProviderConfig, error) {
+	return s.repo.ListProviderConfigs(ctx)
+}
+
+func (s *providerConfigService) Update(ctx context.Context, id uint, req model.ProviderConfigRequest) (model.ProviderConfig, error) {
+	config := providerConfig(req)
+	config.ID = id
+	return s.repo.UpdateProviderConfig(ctx, config)
+}
+
+func (s *providerConfigService) Delete(ctx context.Context, id uint) error {
+	return s.repo.DeleteProviderConfig(ctx, id)
+}
+
+func providerConfig(req model.ProviderConfigRequest) model.ProviderConfig {
+	return model.ProviderConfig{Tenant: req.Tenant, WebhookURL: req.WebhookURL, AuthKey: req.AuthKey}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"message-service/internal/pkg/metrics"
+)
+
+const (
+	// emaAlpha is the weight of the newest observation in the moving averages.
+	emaAlpha = 0.2
+	// weightHysteresis is how far a computed weight must drift before routing changes,
+	// so that noise between two similar providers does not flip traffic back and forth.
+	weightHysteresis = 0.1
+	// referenceLatency is the latency at which a provider's score is halved.
+	referenceLatency = time.Second
+	// minWeight keeps a trickle of traffic on unhealthy providers so recovery is noticed.
+	minWeight = 0.05
+)
+
+var providerRoutingWeight = metrics.NewGaugeVec(
+	"provider_routing_weight",
+	"Share of traffic currently routed to each provider.",
+	"provider",
+)
+
+var providerSuccessRate = metrics.NewGaugeVec(
+	"provider_success_rate_ema",
+	"Exponential moving average of send success per provider.",
+	"provider",
+)
+
+var providerLatency = metrics.NewGaugeVec(
+	"provider_latency_ema_seconds",
+	"Exponential moving average of send latency per provider.",
+	"provider",
+)
+
+type providerHealth struct {
+	successEMA float64
+	latencyEMA float64
+	weight     float64
+}
+
+// ProviderRouter biases traffic toward the providers with the best recent success
+// rate and latency.
+type ProviderRouter struct {
+	mu        sync.Mutex
+	providers []string
+	health    map[string]*providerHealth
+	rand      *rand.Rand
+}
+
+// NewProviderRouter starts every provider as fully healthy with an equal share.
+func NewProviderRouter(providers ...string) *ProviderRouter {
+	r := &ProviderRouter{
+		providers: providers,
+		health:    make(map[string]*providerHealth, len(providers)),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, provider := range providers {
+		r.health[provider] = &providerHealth{
+			successEMA: 1,
+			weight:     1 / float64(len(providers)),
+		}
+		r.publish(provider)
+	}
+
+	return r
+}
+
+// Pick returns a provider chosen at random according to the current weights.
+func (r *ProviderRouter) Pick() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.providers) == 1 {
+		return r.providers[0]
+	}
+
+	target := r.rand.Float64()
+	for _, provider := range r.providers {
+		target -= r.health[provider].weight
+		if target <= 0 {
+			return provider
+		}
+	}
+	return r.providers[len(r.providers)-1]
+}
+
+// Observe feeds the outcome of one send into the provider's moving averages.
+func (r *ProviderRouter) Observe(provider string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health, ok := r.health[provider]
+	if !ok {
+		return
+	}
+
+	success := 1.0
+	if err != nil {
+		success = 0
+	}
+	health.successEMA = emaAlpha*success + (1-emaAlpha)*health.successEMA
+	health.latencyEMA = emaAlpha*latency.Seconds() + (1-emaAlpha)*health.latencyEMA
+
+	r.rebalance()
+}
+
+// rebalance recomputes weights and applies them only if one moved past the hysteresis band.
+func (r *ProviderRouter) rebalance() {
+	scores := make(map[string]float64, len(r.providers))
+	total := 0.0
+	for _, provider := range r.providers {
+		health := r.health[provider]
+		score := health.successEMA / (1 + health.latencyEMA/referenceLatency.Seconds())
+		score = math.Max(score, minWeight)
+		scores[provider] = score
+		total += score
+	}
+
+	changed := false
+	for _, provider := range r.providers {
+		if math.Abs(scores[provider]/total-r.health[provider].weight) >= weightHysteresis {
+			changed = true
+			break
+		}
+	}
+
+	for _, provider := range r.providers {
+		if changed {
+			r.health[provider].weight = scores[provider] / total
+		}
+		r.publish(provider)
+	}
+}
+
+func (r *ProviderRouter) publish(provider string) {
+	health := r.health[provider]
+	providerRoutingWeight.Set(health.weight, provider)
+	providerSuccessRate.Set(health.successEMA, provider)
+	providerLatency.Set(health.latencyEMA, provider)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderRouterShiftsTrafficAwayFromFailingProvider(t *testing.T) {
+	router := NewProviderRouter("primary", "secondary")
+	// A fixed seed keeps the picks from flaking.
+	router.rand = rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		router.Observe("primary", 100*time.Millisecond, errors.New("timeout"))
+		router.Observe("secondary", 100*time.Millisecond, nil)
+	}
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		picks[router.Pick()]++
+	}
+
+	assert.Greater(t, picks["secondary"], picks["primary"]*5)
+}
+
+func TestProviderRouterHysteresis(t *testing.T) {
+	router := NewProviderRouter("primary", "secondary")
+
+	// A single slow send moves the score slightly, not enough to reroute.
+	router.Observe("primary", 150*time.Millisecond, nil)
+
+	assert.Equal(t, 0.5, router.health["primary"].weight)
+	assert.Equal(t, 0.5, router.health["secondary"].weight)
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// queueIDField is the stream entry field holding the message ID.
+const queueIDField = "id"
+
+var queueEntries = metrics.NewCounterVec(
+	"queue_entries_total",
+	"Queue stream entries handled by this instance, by result: processed, reclaimed (taken over from another consumer) or invalid.",
+	"result",
+)
+
+// MessageQueue announces messages that are ready to be sent.
+type MessageQueue interface {
+	Enqueue(ctx context.Context, ids ...uint) error
+}
+
+type redisMessageQueue struct {
+	redisClient insredis.RedisInterface
+	stream      string
+	maxLen      int64
+}
+
+// NewRedisMessageQueue adds one entry with an id field per message to stream, trimmed to
+// about maxLen entries. Producers outside the service add the same entries, e.g.
+// XADD messages:queue * id 42, after inserting the message.
+func NewRedisMessageQueue(redisClient insredis.RedisInterface, stream string, maxLen int64) MessageQueue {
+	return &redisMessageQueue{redisClient: redisClient, stream: stream, maxLen: maxLen}
+}
+
+func (q *redisMessageQueue) Enqueue(_ context.Context, ids ...uint) error {
+	for _, id := range ids {
+		err := q.redisClient.XAdd(&redis.XAddArgs{
+			Stream:       q.stream,
+			MaxLenApprox: q.maxLen,
+			Values:       map[string]interface{}{queueIDField: id},
+		}).Err()
+		if err != nil {
+			return fmt.Errorf("failed to enqueue message %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// queueingMessageService enqueues messages that become ready through it, so the queue
+// consumer picks them up right away. Requeued failures are left to the scheduler.
+type queueingMessageService struct {
+	mpostgres.MessageService
+	queue  MessageQueue
+	logger inslogger.Interface
+	// afterCommit holds entries back inside a transaction, the consumer would find the
+	// messages unchanged before it committed.
+	afterCommit *afterCommit
+}
+
+// NewQueueingMessageService wraps messages to enqueue released messages on queue.
+func NewQueueingMessageService(messages mpostgres.MessageService, queue MessageQueue, logger inslogger.Interface) mpostgres.MessageService {
+	return &queueingMessageService{MessageService: messages, queue: queue, logger: logger}
+}
+
+func (s *queueingMessageService) WithTx(ctx context.Context, fn func(tx mpostgres.MessageService) error) error {
+	return withTx(ctx, s.MessageService, s.afterCommit, func(tx mpostgres.MessageService, effects *afterCommit) error {
+		return fn(&queueingMessageService{MessageService: tx, queue: s.queue, logger: s.logger, afterCommit: effects})
+	})
+}
+
+func (s *queueingMessageService) CreateMessage(ctx context.Context, message model.Message) (model.Message, error) {
+	created, err := s.MessageService.CreateMessage(ctx, message)
+	if err != nil || created.Held || created.ScheduledAt != nil {
+		return created, err
+	}
+	s.afterCommit.do(func() {
+		if err := s.queue.Enqueue(ctx, created.ID); err != nil {
+			logctx.Logger(ctx, s.logger).Warnf("Failed to enqueue message ID %d: %v", created.ID, err)
+		}
+	})
+	return created, nil
+}
+
+func (s *queueingMessageService) SetMessageHold(ctx context.Context, id uint, held bool) error {
+	if err := s.MessageService.SetMessageHold(ctx, id, held); err != nil {
+		return err
+	}
+	if !held {
+		s.afterCommit.do(func() {
+			if err := s.queue.Enqueue(ctx, id); err != nil {
+				// The scheduler still finds the message, only later.
+				logctx.Logger(ctx, s.logger).Warnf("Failed to enqueue released message ID %d: %v", id, err)
+			}
+		})
+	}
+	return nil
+}
+
+// QueueConsumer sends the messages announced on the queue stream as they arrive. Every
+// instance reads through the same consumer group, so each entry goes to one of them.
+// Entries are acknowledged once their messages were handled; entries of a consumer that
+// died are taken over after reclaimIdle.
+type QueueConsumer struct {
+	redisClient insredis.RedisInterface
+	dispatcher  DispatchService
+	// active reports whether sending is on, the consumer idles while it is not.
+	active      func() bool
+	stream      string
+	group       string
+	consumer    string
+	batchSize   int64
+	block       time.Duration
+	reclaimIdle time.Duration
+	logger      inslogger.Interface
+}
+
+// NewQueueConsumer reads up to batchSize entries of stream at a time as consumer of
+// group, waiting at most block for new ones. It only reads while active returns true.
+func NewQueueConsumer(redisClient insredis.RedisInterface, dispatcher DispatchService, active func() bool, stream, group, consumer string, batchSize int, block, reclaimIdle time.Duration, logger inslogger.Interface) *QueueConsumer {
+	return &QueueConsumer{
+		redisClient: redisClient,
+		dispatcher:  dispatcher,
+		active:      active,
+		stream:      stream,
+		group:       group,
+		consumer:    consumer,
+		batchSize:   int64(batchSize),
+		block:       block,
+		reclaimIdle: reclaimIdle,
+		logger:      logger,
+	}
+}
+
+// Run consumes the stream until ctx is cancelled, which also cancels the sends in flight.
+// A read in progress is not interrupted, so stopping may take up to block.
+func (c *QueueConsumer) Run(ctx context.Context) {
+	c.createGroup()
+
+	reclaim := time.NewTicker(c.reclaimIdle)
+	defer reclaim.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reclaim.C:
+			if c.active() {
+				c.reclaim(ctx)
+			}
+		default:
+		}
+
+		if !c.active() {
+			c.wait(ctx, c.block)
+			continue
+		}
+
+		streams, err := c.redisClient.XReadGroup(&redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    c.batchSize,
+			Block:    c.block,
+		}).Result()
+		switch {
+		case err == redis.Nil:
+			continue
+		case err != nil && strings.HasPrefix(err.Error(), "NOGROUP"):
+			c.createGroup()
+			continue
+		case err != nil:
+			c.logger.Warnf("Failed to read the queue stream %s: %v", c.stream, err)
+			c.wait(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			c.handle(ctx, stream.Messages, "processed")
+		}
+	}
+}
+
+// createGroup creates the consumer group, reading the stream from its start so entries
+// added before the first consumer started are not skipped.
+func (c *QueueConsumer) createGroup() {
+	err := c.redisClient.XGroupCreateMkStream(c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		c.logger.Warnf("Failed to create consumer group %s of %s: %v", c.group, c.stream, err)
+	}
+}
+
+// reclaim takes over entries that were delivered but not acknowledged for reclaimIdle,
+// e.g. because their consumer crashed or the database was unavailable.
+func (c *QueueConsumer) reclaim(ctx context.Context) {
+	pending, err := c.redisClient.XPendingExt(&redis.XPendingExtArgs{
+		Stream: c.stream,
+		Group:  c.group,
+		Start:  "-",
+		End:    "+",
+		Count:  c.batchSize,
+	}).Result()
+	if err != nil {
+		c.logger.Warnf("Failed to list pending entries of %s: %v", c.stream, err)
+		return
+	}
+
+	var stale []string
+	for _, entry := range pending {
+		if entry.Idle >= c.reclaimIdle {
+			stale = append(stale, entry.Id)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	entries, err := c.redisClient.XClaim(&redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		MinIdle:  c.reclaimIdle,
+		Messages: stale,
+	}).Result()
+	if err != nil {
+		c.logger.Warnf("Failed to reclaim pending entries of %s: %v", c.stream, err)
+		return
+	}
+	c.handle(ctx, entries, "reclaimed")
+}
+
+// handle sends the messages of entries and acknowledges them. Entries stay pending when
+// the messages could not be claimed, so they are retried after reclaimIdle. Messages not
+// sent before ctx is cancelled are requeued for the scheduler.
+func (c *QueueConsumer) handle(ctx context.Context, entries []redis.XMessage, result string) {
+	if len(entries) == 0 {
+		return
+	}
+
+	// Each batch gets its own correlation ID, like the scheduler's.
+	ctx = logctx.WithRequestID(ctx, logctx.NewID())
+	ctx = logctx.WithActor(ctx, "queue consumer")
+	logger := logctx.Logger(ctx, c.logger)
+
+	entryIDs := make([]string, 0, len(entries))
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		entryIDs = append(entryIDs, entry.ID)
+		id, err := strconv.ParseUint(fmt.Sprint(entry.Values[queueIDField]), 10, 64)
+		if err != nil || id == 0 {
+			logger.Warnf("Dropping queue entry %s without a valid %s field", entry.ID, queueIDField)
+			queueEntries.Inc("invalid")
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+
+	if len(ids) > 0 {
+		batch, err := c.dispatcher.SendMessageIDs(ctx, ids)
+		if err != nil && batch.Claimed == 0 {
+			logger.Warnf("Failed to send %d queued messages, leaving them pending: %v", len(ids), err)
+			return
+		}
+		logger.Logf("Queued messages: %d of %d claimed, %d sent, %d failed, %d skipped",
+			batch.Claimed, len(ids), batch.Sent, batch.Failed, batch.Skipped)
+		queueEntries.Add(float64(len(ids)), result)
+	}
+
+	if err := c.redisClient.XAck(c.stream, c.group, entryIDs...).Err(); err != nil {
+		logger.Warnf("Failed to acknowledge %d queue entries: %v", len(entryIDs), err)
+	}
+}
+
+func (c *QueueConsumer) wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
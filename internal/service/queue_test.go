@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// ackRecorder records XAck calls, every other Redis command panics.
+type ackRecorder struct {
+	insredis.RedisInterface
+	acked []string
+}
+
+func (r *ackRecorder) XAck(stream, group string, ids ...string) *redis.IntCmd {
+	r.acked = append(r.acked, ids...)
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+// idSender records the IDs passed to SendMessageIDs.
+type idSender struct {
+	stubSender
+	ids []uint
+}
+
+func (s *idSender) SendMessageIDs(_ context.Context, ids []uint) (model.BatchResult, error) {
+	s.ids = append(s.ids, ids...)
+	return s.result, s.err
+}
+
+type recordingQueue struct {
+	ids []uint
+}
+
+func (q *recordingQueue) Enqueue(_ context.Context, ids ...uint) error {
+	q.ids = append(q.ids, ids...)
+	return nil
+}
+
+func newTestQueueConsumer(redisClient insredis.RedisInterface, sender DispatchService) *QueueConsumer {
+	return NewQueueConsumer(redisClient, sender, func() bool { return true }, "messages:queue", "message-senders", "test",
+		10, time.Second, time.Minute, inslogger.NewNopLogger())
+}
+
+func TestQueueConsumerSendsAndAcknowledgesEntries(t *testing.T) {
+	redisClient := &ackRecorder{}
+	sender := &idSender{stubSender: stubSender{result: model.BatchResult{Claimed: 2, Sent: 2}}}
+	consumer := newTestQueueConsumer(redisClient, sender)
+
+	consumer.handle(context.Background(), []redis.XMessage{
+		{ID: "1-0", Values: map[string]interface{}{"id": "5"}},
+		{ID: "1-1", Values: map[string]interface{}{"id": "not-a-number"}},
+		{ID: "1-2", Values: map[string]interface{}{"id": "7"}},
+	}, "processed")
+
+	assert.Equal(t, []uint{5, 7}, sender.ids)
+	assert.Equal(t, []string{"1-0", "1-1", "1-2"}, redisClient.acked, "invalid entries are dropped too")
+}
+
+func TestQueueConsumerLeavesEntriesPendingWhenClaimFails(t *testing.T) {
+	redisClient := &ackRecorder{}
+	sender := &idSender{stubSender: stubSender{err: errors.New("connection refused")}}
+	consumer := newTestQueueConsumer(redisClient, sender)
+
+	consumer.handle(context.Background(), []redis.XMessage{{ID: "1-0", Values: map[string]interface{}{"id": "5"}}}, "processed")
+
+	assert.Equal(t, []uint{5}, sender.ids)
+	assert.Empty(t, redisClient.acked, "the entry is reclaimed later")
+}
+
+func TestSendMessageIDsSendsOnlyReadyMessages(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	later := time.Now().Add(time.Hour)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1},
+		model.Message{ID: 2, Status: model.StatusSent},
+		model.Message{ID: 3, Held: true},
+		model.Message{ID: 4, ScheduledAt: &later},
+		model.Message{ID: 5},
+	)
+	dispatcher := newTestDispatcher(messages, providers)
+
+	result, err := dispatcher.SendMessageIDs(context.Background(), []uint{1, 2, 3, 4, 99})
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 1, Sent: 1}, result)
+	assert.Equal(t, []uint{1}, sms.sent)
+	untouched, _ := messages.Message(5)
+	assert.Equal(t, model.StatusPending, untouched.Status, "messages that were not queued are left to the scheduler")
+}
+
+func TestQueueingMessageServiceEnqueuesReleasedMessages(t *testing.T) {
+	queue := &recordingQueue{}
+	messages := NewQueueingMessageService(mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1}), queue, inslogger.NewNopLogger())
+
+	assert.NoError(t, messages.SetMessageHold(context.Background(), 1, true))
+	assert.Empty(t, queue.ids)
+	assert.NoError(t, messages.SetMessageHold(context.Background(), 1, false))
+	assert.Equal(t, []uint{1}, queue.ids)
+	assert.Error(t, messages.SetMessageHold(context.Background(), 2, false))
+	assert.Equal(t, []uint{1}, queue.ids, "unknown messages are not enqueued")
+}
+
+func TestQueueingMessageServiceEnqueuesCreatedMessages(t *testing.T) {
+	queue := &recordingQueue{}
+	messages := NewQueueingMessageService(mmemory.NewMessageService(inslogger.NewNopLogger()), queue, inslogger.NewNopLogger())
+	later := time.Now().Add(time.Hour)
+
+	ready, err := messages.CreateMessage(context.Background(), model.Message{Content: "now"})
+	assert.NoError(t, err)
+	_, err = messages.CreateMessage(context.Background(), model.Message{Content: "held", Held: true})
+	assert.NoError(t, err)
+	_, err = messages.CreateMessage(context.Background(), model.Message{Content: "later", ScheduledAt: &later})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []uint{ready.ID}, queue.ids, "held and scheduled messages are left to the scheduler")
+}
+
+func TestWithTxRunsDecoratorEffectsAfterCommit(t *testing.T) {
+	memory := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1, Held: true}, model.Message{ID: 2, Held: true})
+	store := mmemory.NewMessageAuditService()
+	queue := &recordingQueue{}
+	messages := NewQueueingMessageService(NewAuditedMessageService(memory, NewMessageAudit(store, inslogger.NewNopLogger())), queue, inslogger.NewNopLogger())
+	ctx := context.Background()
+
+	failed := errors.New("failed")
+	err := messages.WithTx(ctx, func(tx mpostgres.MessageService) error {
+		assert.NoError(t, tx.SetMessageHold(ctx, 1, false))
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+	rolledBack, _ := memory.Message(1)
+	assert.True(t, rolledBack.Held, "a failed transaction is rolled back")
+	assert.Empty(t, queue.ids, "rolled back changes are not enqueued")
+	entries, _ := store.ListMessageEvents(ctx, 1)
+	assert.Empty(t, entries, "rolled back changes are not audited")
+
+	err = messages.WithTx(ctx, func(tx mpostgres.MessageService) error {
+		assert.NoError(t, tx.SetMessageHold(ctx, 2, false))
+		assert.Empty(t, queue.ids, "nothing is enqueued before the commit")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, queue.ids)
+	entries, _ = store.ListMessageEvents(ctx, 2)
+	assert.Len(t, entries, 1)
+}
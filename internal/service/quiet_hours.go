@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/metrics"
+)
+
+var quietHoursDeferred = metrics.NewCounterVec(
+	"quiet_hours_deferred_total",
+	"Claimed messages deferred because it was quiet hours for their recipient, by channel.",
+	"channel",
+)
+
+// QuietHours is a daily window, in the local time of the recipient, during which batches
+// do not send. The window may span midnight, e.g. 22:00-08:00.
+type QuietHours struct {
+	// start and end are minutes since local midnight, start inclusive and end exclusive.
+	start, end int
+	// fallback is the zone of recipients whose country has no zone in countries.
+	fallback  *time.Location
+	countries map[string]*time.Location
+}
+
+// ParseQuietHours parses window as HH:MM-HH:MM. Recipients are placed in the zone
+// countryTimezones names for the country of their phone number, an ISO code such as TR,
+// in timezone otherwise. It returns nil without error when window is empty.
+func ParseQuietHours(window, timezone string, countryTimezones map[string]string) (*QuietHours, error) {
+	if window == "" {
+		return nil, nil
+	}
+
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", window)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("quiet hours %q start and end at the same time", window)
+	}
+
+	fallback, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown quiet hours timezone %q: %w", timezone, err)
+	}
+	countries := make(map[string]*time.Location, len(countryTimezones))
+	for country, name := range countryTimezones {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown quiet hours timezone %q of %s: %w", name, country, err)
+		}
+		countries[strings.ToUpper(country)] = loc
+	}
+
+	return &QuietHours{start: start, end: end, fallback: fallback, countries: countries}, nil
+}
+
+// parseClock returns the minutes since midnight of an HH:MM time.
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid quiet hours time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Until reports whether now falls into the quiet hours of the recipient of message and,
+// if so, when they end.
+func (q *QuietHours) Until(message model.Message, now time.Time) (time.Time, bool) {
+	local := now.In(q.location(message))
+	minute := local.Hour()*60 + local.Minute()
+
+	var days int
+	switch {
+	case q.start < q.end && minute >= q.start && minute < q.end:
+	case q.start > q.end && minute < q.end:
+	case q.start > q.end && minute >= q.start:
+		days = 1
+	default:
+		return time.Time{}, false
+	}
+
+	year, month, day := local.Date()
+	return time.Date(year, month, day+days, q.end/60, q.end%60, 0, 0, local.Location()), true
+}
+
+// location returns the zone of the recipient, by the country of their phone number.
+func (q *QuietHours) location(message model.Message) *time.Location {
+	if message.RecipientPhone != "" {
+		if loc, ok := q.countries[countryFromPhone(message.RecipientPhone)]; ok {
+			return loc
+		}
+	}
+	return q.fallback
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestQuietHoursUntil(t *testing.T) {
+	quiet, err := ParseQuietHours("22:00-08:00", "UTC", map[string]string{"TR": "Europe/Istanbul"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	turkish := model.Message{RecipientPhone: "+905551111111"}
+	british := model.Message{RecipientPhone: "+447700900123"}
+
+	// 20:30 UTC is 23:30 in Istanbul.
+	until, ok := quiet.Until(turkish, time.Date(2025, 3, 10, 20, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 11, 5, 0, 0, 0, time.UTC), until.UTC(), "quiet hours end at 08:00 the next morning")
+
+	until, ok = quiet.Until(british, time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC))
+	assert.True(t, ok, "countries without a zone use QUIET_HOURS_TIMEZONE")
+	assert.Equal(t, time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC), until.UTC())
+
+	_, ok = quiet.Until(british, time.Date(2025, 3, 10, 20, 30, 0, 0, time.UTC))
+	assert.False(t, ok)
+
+	daytime, err := ParseQuietHours("12:00-13:00", "UTC", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok = daytime.Until(british, time.Date(2025, 3, 10, 13, 0, 0, 0, time.UTC))
+	assert.False(t, ok, "the end is not quiet")
+
+	for _, window := range []string{"22:00", "25:00-08:00", "08:00-08:00"} {
+		_, err := ParseQuietHours(window, "UTC", nil)
+		assert.Error(t, err, window)
+	}
+	quiet, err = ParseQuietHours("", "UTC", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, quiet)
+}
+
+func TestSendMessagesDefersQuietHours(t *testing.T) {
+	sms := &stubProvider{name: "sms"}
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, sms)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(), model.Message{ID: 1, RecipientPhone: "+905551111111"})
+	dispatcher := newTestDispatcher(messages, providers)
+	now := time.Now().UTC()
+	dispatcher.quietHours, _ = ParseQuietHours(now.Add(-time.Hour).Format("15:04")+"-"+now.Add(time.Hour).Format("15:04"), "UTC", nil)
+	dispatcher.now = func() time.Time { return now }
+
+	result, err := dispatcher.SendMessages(context.Background(), model.ChannelSMS, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BatchResult{Claimed: 1, Skipped: 1}, result)
+	assert.Empty(t, sms.sent)
+	deferred, _ := messages.Message(1)
+	assert.Equal(t, model.StatusPending, deferred.Status, "deferred messages are not failed")
+	if assert.NotNil(t, deferred.ScheduledAt) {
+		assert.True(t, deferred.ScheduledAt.After(now))
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// QuotaCounter counts the messages every API key sent per UTC day and month in Redis, so
+// quotas hold across replicas. Each period has its own key that expires a day after the
+// period ended. Keys are counted whether or not their quota is capped, for chargeback.
+type QuotaCounter struct {
+	redisClient insredis.RedisInterface
+	now         func() time.Time
+}
+
+func NewQuotaCounter(redisClient insredis.RedisInterface) *QuotaCounter {
+	return &QuotaCounter{redisClient: redisClient, now: time.Now}
+}
+
+// quotaPeriod is the counter of one period of a key.
+type quotaPeriod struct {
+	key      string
+	resetsAt time.Time
+}
+
+// periods returns the counters of the day and the month of now.
+func (c *QuotaCounter) periods(apiKey string) (day, month quotaPeriod) {
+	now := c.now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	day = quotaPeriod{key: "quota:" + apiKey + ":day:" + dayStart.Format(time.DateOnly), resetsAt: dayStart.AddDate(0, 0, 1)}
+	month = quotaPeriod{key: "quota:" + apiKey + ":month:" + monthStart.Format("2006-01"), resetsAt: monthStart.AddDate(0, 1, 0)}
+	return day, month
+}
+
+// Reserve counts one message of apiKey and returns the usage including it. release takes
+// the message back, for a send that was rejected after all or that went over quota.
+func (c *QuotaCounter) Reserve(ctx context.Context, apiKey string, quota model.Quota) (model.QuotaStatus, func(), error) {
+	day, month := c.periods(apiKey)
+
+	daily, err := c.add(day, 1)
+	if err != nil {
+		return model.QuotaStatus{}, nil, err
+	}
+	monthly, err := c.add(month, 1)
+	if err != nil {
+		_, _ = c.add(day, -1)
+		return model.QuotaStatus{}, nil, err
+	}
+
+	release := func() {
+		_, _ = c.add(day, -1)
+		_, _ = c.add(month, -1)
+	}
+	return model.QuotaStatus{
+		Daily:   model.NewQuotaPeriod(quota.Daily, int(daily), day.resetsAt),
+		Monthly: model.NewQuotaPeriod(quota.Monthly, int(monthly), month.resetsAt),
+	}, release, nil
+}
+
+// Status returns the usage of apiKey without counting a message.
+func (c *QuotaCounter) Status(ctx context.Context, apiKey string, quota model.Quota) (model.QuotaStatus, error) {
+	day, month := c.periods(apiKey)
+
+	daily, err := c.get(day)
+	if err != nil {
+		return model.QuotaStatus{}, err
+	}
+	monthly, err := c.get(month)
+	if err != nil {
+		return model.QuotaStatus{}, err
+	}
+	return model.QuotaStatus{
+		Daily:   model.NewQuotaPeriod(quota.Daily, int(daily), day.resetsAt),
+		Monthly: model.NewQuotaPeriod(quota.Monthly, int(monthly), month.resetsAt),
+	}, nil
+}
+
+func (c *QuotaCounter) add(period quotaPeriod, delta int64) (int64, error) {
+	count, err := c.redisClient.IncrBy(period.key, delta).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count quota %s: %w", period.key, err)
+	}
+	c.redisClient.Expire(period.key, period.resetsAt.Sub(c.now())+24*time.Hour)
+	return count, nil
+}
+
+func (c *QuotaCounter) get(period quotaPeriod) (int64, error) {
+	value, err := c.redisClient.Get(period.key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read quota %s: %w", period.key, err)
+	}
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quota counter %s: %q", period.key, value)
+	}
+	return count, nil
+}
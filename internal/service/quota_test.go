@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/pkg/localredis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaCounterPeriods(t *testing.T) {
+	ctx := context.Background()
+	counter := NewQuotaCounter(localredis.New())
+	now := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+	counter.now = func() time.Time { return now }
+	quota := model.Quota{Daily: 2, Monthly: 3}
+
+	_, _, err := counter.Reserve(ctx, "billing", quota)
+	require.NoError(t, err)
+	_, release, err := counter.Reserve(ctx, "billing", quota)
+	require.NoError(t, err)
+	release()
+	status, _, err := counter.Reserve(ctx, "billing", quota)
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Daily.Used, "released messages are not counted")
+	assert.Equal(t, 0, *status.Daily.Remaining)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), status.Daily.ResetsAt)
+	assert.False(t, status.Exceeded())
+
+	status, _, err = counter.Reserve(ctx, "billing", quota)
+	require.NoError(t, err)
+	assert.True(t, status.Daily.Exceeded())
+
+	now = now.Add(2 * time.Hour)
+	status, err = counter.Status(ctx, "billing", quota)
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Daily.Used, "a new day starts from zero")
+	assert.Equal(t, 0, status.Monthly.Used, "so does a new month")
+
+	status, err = counter.Status(ctx, "reporting", model.Quota{})
+	require.NoError(t, err)
+	assert.Nil(t, status.Daily.Remaining, "uncapped periods have no remaining count")
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/metrics"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+var messagesPurged = metrics.NewCounterVec(
+	"messages_purged_total",
+	"Messages anonymized or deleted by the retention job, by mode.",
+	"mode",
+)
+
+// RetentionJob purges finished messages older than a maximum age. Only the scheduler leader
+// runs it, so replicas do not race over the same rows.
+type RetentionJob struct {
+	messages mpostgres.MessageService
+	elector  LeaderElector
+	maxAge   time.Duration
+	interval time.Duration
+	mode     model.PurgeMode
+	logger   inslogger.Interface
+	now      func() time.Time
+}
+
+// NewRetentionJob purges messages created more than maxAge ago every interval. A zero
+// maxAge disables the job.
+func NewRetentionJob(messages mpostgres.MessageService, elector LeaderElector, maxAge, interval time.Duration, mode model.PurgeMode, logger inslogger.Interface) *RetentionJob {
+	return &RetentionJob{
+		messages: messages,
+		elector:  elector,
+		maxAge:   maxAge,
+		interval: interval,
+		mode:     mode,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run purges on every tick until ctx is done. It returns at once when the job is disabled.
+func (j *RetentionJob) Run(ctx context.Context) {
+	if j.maxAge <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			j.Purge(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Purge removes the expired messages once if this replica is the leader.
+func (j *RetentionJob) Purge(ctx context.Context) {
+	if !j.elector.IsLeader() {
+		return
+	}
+
+	cutoff := j.now().Add(-j.maxAge)
+	purged, err := j.messages.PurgeMessagesBefore(ctx, cutoff, j.mode)
+	if err != nil {
+		j.logger.Errorf("Retention purge failed: %v", err)
+		return
+	}
+	if purged > 0 {
+		messagesPurged.Add(float64(purged), string(j.mode))
+		j.logger.Logf("Retention purge removed %d messages created before %s (%s)", purged, cutoff.Format(time.RFC3339), j.mode)
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestRetentionJobPurgesFinishedMessages(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Status: model.StatusSent, CreatedAt: old},
+		model.Message{ID: 2, Status: model.StatusPending, CreatedAt: old},
+		model.Message{ID: 3, Status: model.StatusSent, CreatedAt: now.Add(-time.Hour)},
+	)
+
+	follower := NewRetentionJob(messages, stubElector(false), 24*time.Hour, time.Hour, model.PurgeDelete, inslogger.NewNopLogger())
+	follower.now = func() time.Time { return now }
+	follower.Purge(context.Background())
+	_, err := messages.Message(1)
+	assert.NoError(t, err, "only the leader purges")
+
+	job := NewRetentionJob(messages, stubElector(true), 24*time.Hour, time.Hour, model.PurgeDelete, inslogger.NewNopLogger())
+	job.now = func() time.Time { return now }
+	job.Purge(context.Background())
+
+	_, err = messages.Message(1)
+	assert.ErrorIs(t, err, mpostgres.ErrMessageNotFound)
+	_, err = messages.Message(2)
+	assert.NoError(t, err, "unsent messages are kept")
+	_, err = messages.Message(3)
+	assert.NoError(t, err, "recent messages are kept")
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+const retryBucketKey = "retry:bucket"
+
+// retryBucketScript refills the bucket based on elapsed time and takes one token if available.
+// KEYS[1] bucket hash, ARGV[1] rate per second, ARGV[2] burst, ARGV[3] now in milliseconds.
+const retryBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + (math.max(0, now - ts) / 1000) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return allowed
+`
+
+// RetryLimiter gates how fast previously failed messages are released for another attempt.
+type RetryLimiter interface {
+	Allow() (bool, error)
+}
+
+type redisRetryLimiter struct {
+	redisClient insredis.RedisInterface
+	rate        float64
+	burst       int
+}
+
+// NewRedisRetryLimiter returns a token bucket shared by every replica through Redis,
+// so retries after a provider outage are spread out instead of released all at once.
+func NewRedisRetryLimiter(redisClient insredis.RedisInterface, rate float64, burst int) RetryLimiter {
+	return &redisRetryLimiter{
+		redisClient: redisClient,
+		rate:        rate,
+		burst:       burst,
+	}
+}
+
+func (l *redisRetryLimiter) Allow() (bool, error) {
+	cmd := redis.NewCmd("eval", retryBucketScript, 1, retryBucketKey, l.rate, l.burst, time.Now().UnixMilli())
+	if err := l.redisClient.Process(cmd); err != nil {
+		return false, fmt.Errorf("failed to take retry token: %w", err)
+	}
+
+	allowed, err := cmd.Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to read retry token: %w", err)
+	}
+
+	return allowed == 1, nil
+}
+
+type localRetryLimiter struct {
+	rate  float64
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	ts     time.Time
+}
+
+// NewLocalRetryLimiter returns the same token bucket kept in process memory, for local mode
+// where a single replica runs without Redis.
+func NewLocalRetryLimiter(rate float64, burst int) RetryLimiter {
+	return &localRetryLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		ts:     time.Now(),
+	}
+}
+
+func (l *localRetryLimiter) Allow() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(float64(l.burst), l.tokens+now.Sub(l.ts).Seconds()*l.rate)
+	l.ts = now
+
+	if l.tokens < 1 {
+		return false, nil
+	}
+	l.tokens--
+	return true, nil
+}
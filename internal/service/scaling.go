@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+)
+
+// ScalingAdvisor sizes the pool of external delivery workers from the backlog, for an
+// autoscaler such as KEDA that polls GET /api/internal/scaling.
+type ScalingAdvisor struct {
+	messages mpostgres.MessageService
+	// workerThroughput is how many messages one worker sends per minute.
+	workerThroughput int
+	// drainTarget is how long the backlog may take to drain.
+	drainTarget time.Duration
+	minReplicas int
+	maxReplicas int
+	now         func() time.Time
+}
+
+// NewScalingAdvisor recommends enough workers of workerThroughput messages per minute to
+// drain the backlog within drainTarget, bounded by minReplicas and maxReplicas.
+func NewScalingAdvisor(messages mpostgres.MessageService, workerThroughput int, drainTarget time.Duration, minReplicas, maxReplicas int) *ScalingAdvisor {
+	return &ScalingAdvisor{
+		messages:         messages,
+		workerThroughput: workerThroughput,
+		drainTarget:      drainTarget,
+		minReplicas:      minReplicas,
+		maxReplicas:      maxReplicas,
+		now:              time.Now,
+	}
+}
+
+// Signal reports the backlog and the replica count it needs.
+func (a *ScalingAdvisor) Signal(ctx context.Context) (model.ScalingSignal, error) {
+	backlog, err := a.messages.GetBacklog(ctx)
+	if err != nil {
+		return model.ScalingSignal{}, err
+	}
+
+	signal := model.ScalingSignal{
+		BacklogDepth:        backlog.Pending,
+		RecommendedReplicas: a.replicas(backlog.Pending),
+	}
+	if backlog.OldestDueAt != nil {
+		signal.OldestPendingAgeSeconds = int64(max(a.now().Sub(*backlog.OldestDueAt), 0) / time.Second)
+	}
+
+	return signal, nil
+}
+
+func (a *ScalingAdvisor) replicas(pending int64) int {
+	perWorker := float64(a.workerThroughput) * a.drainTarget.Minutes()
+	replicas := int(math.Ceil(float64(pending) / perWorker))
+	return min(max(replicas, a.minReplicas), a.maxReplicas)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestScalingAdvisorClampsReplicas(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(n int) *mmemory.MessageService {
+		var seed []model.Message
+		for i := 1; i <= n; i++ {
+			seed = append(seed, model.Message{ID: uint(i), CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+		}
+		return mmemory.NewMessageService(inslogger.NewNopLogger(), seed...)
+	}
+
+	tests := []struct {
+		name     string
+		pending  int
+		replicas int
+	}{
+		{"empty backlog keeps the minimum", 0, 1},
+		{"partial worker rounds up", 301, 2},
+		{"large backlog is capped", 100000, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisor := NewScalingAdvisor(pending(tt.pending), 60, 5*time.Minute, 1, 10)
+			advisor.now = func() time.Time { return now }
+
+			signal, err := advisor.Signal(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(tt.pending), signal.BacklogDepth)
+			assert.Equal(t, int64(tt.pending*60), signal.OldestPendingAgeSeconds)
+			assert.Equal(t, tt.replicas, signal.RecommendedReplicas)
+		})
+	}
+}
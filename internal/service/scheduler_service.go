@@ -1,41 +1,106 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/instance"
+
 	"github.com/useinsider/go-pkg/inslogger"
 )
 
 type SchedulerService interface {
-	Start() error
-	Stop() error
+	// Start starts the channel loops. They outlive ctx, only Stop ends them.
+	Start(ctx context.Context) error
+	// Stop cancels the batches in flight and waits for the channel loops to end, or until
+	// ctx is done.
+	Stop(ctx context.Context) error
 	IsRunning() bool
+	// PauseReason returns the error that caused the scheduler to pause itself, or nil.
+	PauseReason() error
+	// Pause stops a running scheduler the way a batch rejected by the provider does: reason
+	// becomes the pause reason and the alert hooks fire. It does nothing while the scheduler
+	// is stopped or already paused.
+	Pause(reason error)
+	Status() model.SchedulerStatus
+	// Schedules returns the settings of every scheduled channel, sorted by channel.
+	Schedules() []model.ChannelSchedule
+	// SetSchedule changes the settings of a scheduled channel. A running channel applies
+	// the new interval right away and the batch size from its next batch.
+	SetSchedule(channel model.Channel, settings ChannelSettings) error
+	// RunNow sends one batch of channel, of every scheduled channel when it is empty, right
+	// away and returns the recorded runs. It works whether or not the scheduler is running
+	// and leaves the ticking batches alone.
+	RunNow(ctx context.Context, channel model.Channel) ([]model.SchedulerRun, error)
+	// Wake runs a batch of channel as soon as its current batch, if any, finished instead of
+	// on the next tick, e.g. when a due message was inserted. Wakes while a batch runs are
+	// coalesced into one batch. It does nothing while the scheduler is stopped or for
+	// channels that are not scheduled.
+	Wake(channel model.Channel)
+}
+
+// channelLoop is the batch loop of one channel. Its fields are guarded by the scheduler's runningMutex.
+type channelLoop struct {
+	channel  model.Channel
+	settings ChannelSettings
+	ticker   *time.Ticker
+	// batches counts the batches run since Start, for the warm-up.
+	batches int
+	// wake holds a pending Wake, it is created once and never closed.
+	wake chan struct{}
+}
+
+// loopGroup is the set of channel loops started by one Start.
+type loopGroup struct {
+	// cancel stops the loops and cancels their batches in flight.
+	cancel context.CancelFunc
+	// done is closed once every loop returned.
+	done chan struct{}
 }
 
 type schedulerService struct {
 	logger       inslogger.Interface
-	sender       MessageSender
-	interval     time.Duration
-	batchSize    int
-	ticker       *time.Ticker
-	stopChan     chan struct{}
+	sender       DispatchService
+	elector      LeaderElector
+	runs         mpostgres.SchedulerRunService
+	loops        []*channelLoop
+	warmup       WarmupSettings
 	isRunning    bool
+	pauseReason  error
 	runningMutex sync.Mutex
+	alertHooks   []AlertHook
+	// current holds the loops of the latest Start, nil before the first one.
+	current *loopGroup
 }
 
-func NewSchedulerService(sender MessageSender, interval time.Duration, batchSize int, logger inslogger.Interface) SchedulerService {
-	return &schedulerService{
-		logger:    logger,
-		sender:    sender,
-		interval:  interval,
-		batchSize: batchSize,
-		stopChan:  make(chan struct{}),
+// NewSchedulerService creates a scheduler that runs an independent batch loop for every
+// channel in schedules. When elector is set, batches only run while this instance is the
+// leader; a nil elector always runs. Every batch that ran is recorded in runs unless it is nil.
+// After every Start the batches of a channel grow to its batch size as set by warmup.
+func NewSchedulerService(sender DispatchService, elector LeaderElector, runs mpostgres.SchedulerRunService, schedules map[model.Channel]ChannelSettings, warmup WarmupSettings, logger inslogger.Interface, alertHooks ...AlertHook) SchedulerService {
+	s := &schedulerService{
+		logger:     logger,
+		sender:     sender,
+		elector:    elector,
+		runs:       runs,
+		warmup:     warmup,
+		alertHooks: alertHooks,
+	}
+
+	for _, channel := range sortedChannels(schedules) {
+		s.loops = append(s.loops, &channelLoop{channel: channel, settings: schedules[channel], wake: make(chan struct{}, 1)})
 	}
+
+	return s
 }
 
-func (s *schedulerService) Start() error {
+func (s *schedulerService) Start(ctx context.Context) error {
 	s.logger.Log("Starting scheduler...")
 
 	s.runningMutex.Lock()
@@ -45,54 +110,196 @@ func (s *schedulerService) Start() error {
 		return fmt.Errorf("scheduler is already running")
 	}
 
-	// Debugging logs
 	if s.sender == nil {
 		s.logger.Log("Error: sender is nil")
 		return fmt.Errorf("sender is nil")
 	}
-	if s.stopChan == nil {
-		s.logger.Log("Error: stopChan is nil")
-		return fmt.Errorf("stopChan is nil")
+	if len(s.loops) == 0 {
+		return fmt.Errorf("no channel is scheduled")
 	}
 
-	s.ticker = time.NewTicker(s.interval)
-	s.isRunning = true
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	group := &loopGroup{cancel: cancel, done: make(chan struct{})}
 
-	// Trigger the first batch immediately
+	var running sync.WaitGroup
+	for _, loop := range s.loops {
+		loop.ticker = time.NewTicker(loop.settings.Interval)
+		loop.batches = 0
+		// A wake from before Start is covered by the first batch.
+		select {
+		case <-loop.wake:
+		default:
+		}
+		running.Add(1)
+		go func(loop *channelLoop, ticker *time.Ticker) {
+			defer running.Done()
+			s.run(runCtx, loop, ticker)
+		}(loop, loop.ticker)
+	}
 	go func() {
-		s.logger.Log("Executing first batch immediately...")
-		if err := s.sender.SendMessages(s.batchSize); err != nil {
-			s.logger.Log(fmt.Errorf("error sending scheduled messages: %v", err))
+		running.Wait()
+		close(group.done)
+	}()
+
+	s.current = group
+	s.isRunning = true
+	s.pauseReason = nil
+	return nil
+}
+
+// run executes the first batch of loop immediately, unless the warm-up skips it, and then
+// one per tick or wake until ctx is cancelled.
+func (s *schedulerService) run(ctx context.Context, loop *channelLoop, ticker *time.Ticker) {
+	defer ticker.Stop()
+
+	if !s.warmup.SkipFirstBatch {
+		s.logger.Logf("Executing first %s batch immediately...", loop.channel)
+		if !s.runBatch(ctx, loop) {
+			return
 		}
+	}
 
-		// Start the ticker for subsequent intervals
-		for {
-			select {
-			case <-s.ticker.C:
-				if err := s.sender.SendMessages(s.batchSize); err != nil {
-					s.logger.Log(fmt.Errorf("error sending scheduled messages: %v", err))
-				}
-			case <-s.stopChan:
-				s.ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !s.runBatch(ctx, loop) {
 				return
 			}
+		case <-loop.wake:
+			if !s.runBatch(ctx, loop) {
+				return
+			}
+		case <-ctx.Done():
+			return
 		}
-	}()
-
-	return nil
+	}
 }
 
-func (s *schedulerService) Stop() error {
+// runBatch sends one batch of loop's channel and reports whether the scheduler should keep running.
+func (s *schedulerService) runBatch(ctx context.Context, loop *channelLoop) bool {
+	if s.elector != nil && !s.elector.IsLeader() {
+		s.logger.Debug("Not the scheduler leader, skipping batch")
+		return true
+	}
+
 	s.runningMutex.Lock()
-	defer s.runningMutex.Unlock()
+	batchSize := s.warmup.batchSize(loop.settings.BatchSize, loop.batches)
+	loop.batches++
+	s.runningMutex.Unlock()
 
+	startedAt := time.Now().UTC()
+	result, err := s.sender.SendMessages(ctx, loop.channel, batchSize)
+	if err != nil {
+		result.AddError(err)
+	}
+	s.recordRun(ctx, s.newRun(loop.channel, batchSize, startedAt, result))
+	if ctx.Err() != nil {
+		// Stopped in the middle of the batch.
+		return false
+	}
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, ErrProviderUnauthorized) {
+		s.pause(err)
+		return false
+	}
+
+	s.logger.Log(fmt.Errorf("error sending scheduled %s messages: %v", loop.channel, err))
+	return true
+}
+
+// newRun describes a batch of channel that started at startedAt and just finished.
+func (s *schedulerService) newRun(channel model.Channel, batchSize int, startedAt time.Time, result model.BatchResult) model.SchedulerRun {
+	return model.SchedulerRun{
+		InstanceID: instance.ID(),
+		Channel:    channel,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now().UTC(),
+		BatchSize:  batchSize,
+		Claimed:    result.Claimed,
+		Sent:       result.Sent,
+		Failed:     result.Failed,
+		Skipped:    result.Skipped,
+		Errors:     strings.Join(result.Errors, "\n"),
+	}
+}
+
+func (s *schedulerService) recordRun(ctx context.Context, run model.SchedulerRun) {
+	if s.runs == nil {
+		return
+	}
+
+	// A batch cut short by Stop is recorded too.
+	if err := s.runs.RecordRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.Warnf("Failed to record scheduler run: %v", err)
+	}
+}
+
+// pause stops every channel loop from inside one of them and raises an alert. It cannot
+// wait for the loops since it runs in one of them.
+func (s *schedulerService) pause(reason error) {
+	s.runningMutex.Lock()
 	if !s.isRunning {
-		return nil
+		// Stopped or already paused by another channel.
+		s.runningMutex.Unlock()
+		return
+	}
+	s.current.cancel()
+	s.isRunning = false
+	s.pauseReason = reason
+	s.runningMutex.Unlock()
+
+	s.logger.Errorf("Scheduler paused: %v", reason)
+
+	alert := Alert{
+		Name:    "webhook_auth_failed",
+		Message: "SMS provider rejected its credentials, scheduler paused",
+		Err:     reason,
+		Time:    time.Now().UTC(),
 	}
+	for _, hook := range s.alertHooks {
+		hook(alert)
+	}
+}
 
-	s.stopChan <- struct{}{}
+// Stop also waits for the loops of a scheduler that paused itself, and returns right
+// away when they already ended. It can be called any number of times.
+func (s *schedulerService) Stop(ctx context.Context) error {
+	s.runningMutex.Lock()
+	group := s.current
 	s.isRunning = false
-	return nil
+	s.runningMutex.Unlock()
+	if group == nil {
+		return nil
+	}
+
+	group.cancel()
+	select {
+	case <-group.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler batches still running: %w", ctx.Err())
+	}
+}
+
+func (s *schedulerService) Wake(channel model.Channel) {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	if !s.isRunning {
+		return
+	}
+	for _, loop := range s.loops {
+		if loop.channel != channel {
+			continue
+		}
+		select {
+		case loop.wake <- struct{}{}:
+		default:
+		}
+	}
 }
 
 func (s *schedulerService) IsRunning() bool {
@@ -100,3 +307,106 @@ func (s *schedulerService) IsRunning() bool {
 	defer s.runningMutex.Unlock()
 	return s.isRunning
 }
+
+func (s *schedulerService) Pause(reason error) {
+	s.pause(reason)
+}
+
+func (s *schedulerService) PauseReason() error {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+	return s.pauseReason
+}
+
+func (s *schedulerService) Status() model.SchedulerStatus {
+	s.runningMutex.Lock()
+	status := model.SchedulerStatus{Running: s.isRunning, IsLeader: true}
+	if s.pauseReason != nil {
+		status.PauseReason = s.pauseReason.Error()
+	}
+	s.runningMutex.Unlock()
+
+	if s.elector != nil {
+		status.Leader = s.elector.Leader()
+		status.IsLeader = s.elector.IsLeader()
+	}
+
+	return status
+}
+
+func (s *schedulerService) Schedules() []model.ChannelSchedule {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	schedules := make([]model.ChannelSchedule, 0, len(s.loops))
+	for _, loop := range s.loops {
+		schedules = append(schedules, model.ChannelSchedule{
+			Channel:   loop.channel,
+			BatchSize: loop.settings.BatchSize,
+			Interval:  loop.settings.Interval.String(),
+		})
+	}
+	return schedules
+}
+
+func (s *schedulerService) SetSchedule(channel model.Channel, settings ChannelSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	for _, loop := range s.loops {
+		if loop.channel != channel {
+			continue
+		}
+
+		loop.settings = settings
+		if s.isRunning {
+			loop.ticker.Reset(settings.Interval)
+		}
+		s.logger.Logf("Scheduler %s channel set to batches of %d every %s", channel, settings.BatchSize, settings.Interval)
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnscheduledChannel, channel)
+}
+
+// RunNow sends its batches with the full batch size of each channel, the warm-up only
+// applies to the ticking batches. An unauthorized provider is reported in the run but does
+// not pause the scheduler.
+func (s *schedulerService) RunNow(ctx context.Context, channel model.Channel) ([]model.SchedulerRun, error) {
+	if s.sender == nil {
+		return nil, fmt.Errorf("sender is nil")
+	}
+
+	s.runningMutex.Lock()
+	var due []model.ChannelSchedule
+	for _, loop := range s.loops {
+		if channel == "" || loop.channel == channel {
+			due = append(due, model.ChannelSchedule{Channel: loop.channel, BatchSize: loop.settings.BatchSize})
+		}
+	}
+	s.runningMutex.Unlock()
+	if len(due) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnscheduledChannel, channel)
+	}
+
+	runs := make([]model.SchedulerRun, 0, len(due))
+	for _, schedule := range due {
+		s.logger.Logf("Running a %s batch on demand", schedule.Channel)
+		startedAt := time.Now().UTC()
+		result, err := s.sender.SendMessages(ctx, schedule.Channel, schedule.BatchSize)
+		if err != nil {
+			result.AddError(err)
+		}
+		run := s.newRun(schedule.Channel, schedule.BatchSize, startedAt, result)
+		s.recordRun(ctx, run)
+		runs = append(runs, run)
+		if ctx.Err() != nil {
+			return runs, ctx.Err()
+		}
+	}
+	return runs, nil
+}
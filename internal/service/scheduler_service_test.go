@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+type stubSender struct {
+	result model.BatchResult
+	err    error
+}
+
+func (s *stubSender) SendMessages(context.Context, model.Channel, int) (model.BatchResult, error) {
+	return s.result, s.err
+}
+
+func (s *stubSender) SendMessageIDs(context.Context, []uint) (model.BatchResult, error) {
+	return s.result, s.err
+}
+
+func (s *stubSender) SendMessage(context.Context, model.Message) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+// blockingSender blocks every batch until it is cancelled.
+type blockingSender struct {
+	stubSender
+	started chan struct{}
+}
+
+func (s *blockingSender) SendMessages(ctx context.Context, _ model.Channel, _ int) (model.BatchResult, error) {
+	s.started <- struct{}{}
+	<-ctx.Done()
+	return model.BatchResult{Claimed: 1, Skipped: 1}, nil
+}
+
+// sizeSender records the batch sizes it was asked for.
+type sizeSender struct {
+	stubSender
+	sizes []int
+}
+
+func (s *sizeSender) SendMessages(_ context.Context, _ model.Channel, count int) (model.BatchResult, error) {
+	s.sizes = append(s.sizes, count)
+	return s.result, s.err
+}
+
+type stubElector bool
+
+func (e stubElector) IsLeader() bool { return bool(e) }
+func (e stubElector) Leader() string { return "" }
+
+func TestRunBatchRecordsRun(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	sender := &stubSender{
+		result: model.BatchResult{Claimed: 3, Sent: 1, Failed: 1, Skipped: 1, Errors: []string{"unexpected status code: 500"}},
+		err:    errors.New("connection reset"),
+	}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelEmail: {BatchSize: 3, Interval: time.Minute}}
+	scheduler := NewSchedulerService(sender, nil, runs, schedules, WarmupSettings{}, inslogger.NewNopLogger()).(*schedulerService)
+
+	assert.True(t, scheduler.runBatch(context.Background(), scheduler.loops[0]))
+
+	listed, _ := runs.ListRuns(context.Background(), 10)
+	if assert.Len(t, listed, 1) {
+		run := listed[0]
+		assert.Equal(t, model.ChannelEmail, run.Channel)
+		assert.Equal(t, 3, run.BatchSize)
+		assert.Equal(t, 3, run.Claimed)
+		assert.Equal(t, 1, run.Sent)
+		assert.Equal(t, 1, run.Failed)
+		assert.Equal(t, 1, run.Skipped)
+		assert.Equal(t, "unexpected status code: 500\nconnection reset", run.Errors)
+		assert.False(t, run.FinishedAt.Before(run.StartedAt))
+	}
+}
+
+func TestRunBatchSkipsRecordingWhenNotLeader(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 2, Interval: time.Minute}}
+	scheduler := NewSchedulerService(&stubSender{}, stubElector(false), runs, schedules, WarmupSettings{}, inslogger.NewNopLogger()).(*schedulerService)
+
+	assert.True(t, scheduler.runBatch(context.Background(), scheduler.loops[0]))
+
+	listed, _ := runs.ListRuns(context.Background(), 10)
+	assert.Empty(t, listed)
+}
+
+func TestStopCancelsBatchInFlight(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	sender := &blockingSender{started: make(chan struct{}, 1)}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: time.Minute}}
+	scheduler := NewSchedulerService(sender, nil, runs, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+
+	assert.NoError(t, scheduler.Start(context.Background()))
+	<-sender.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, scheduler.Stop(ctx))
+
+	listed, _ := runs.ListRuns(context.Background(), 10)
+	assert.Len(t, listed, 1, "the cancelled batch is recorded")
+}
+
+func TestSchedulerCanBeRestarted(t *testing.T) {
+	sender := &blockingSender{started: make(chan struct{}, 1)}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: time.Minute}}
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	assert.NoError(t, scheduler.Stop(ctx), "stopping before the first start is a no-op")
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, scheduler.Start(context.Background()))
+		<-sender.started
+		assert.NoError(t, scheduler.Stop(ctx))
+		assert.False(t, scheduler.IsRunning())
+	}
+	assert.NoError(t, scheduler.Stop(ctx), "stopping twice is a no-op")
+}
+
+func TestStopAfterPauseDoesNotBlock(t *testing.T) {
+	sender := &stubSender{err: ErrProviderUnauthorized}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: time.Minute}}
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+
+	assert.NoError(t, scheduler.Start(context.Background()))
+	assert.Eventually(t, func() bool { return scheduler.PauseReason() != nil }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, scheduler.Stop(ctx))
+	assert.NoError(t, scheduler.Start(context.Background()), "a paused scheduler can be started again")
+	assert.NoError(t, scheduler.Stop(ctx))
+}
+
+func TestRunBatchWarmsUp(t *testing.T) {
+	sender := &sizeSender{}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 100, Interval: time.Minute}}
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{Batches: 3}, inslogger.NewNopLogger()).(*schedulerService)
+
+	for i := 0; i < 4; i++ {
+		scheduler.runBatch(context.Background(), scheduler.loops[0])
+	}
+
+	assert.Equal(t, []int{34, 67, 100, 100}, sender.sizes)
+}
+
+func TestStartCanSkipFirstBatch(t *testing.T) {
+	sender := &blockingSender{started: make(chan struct{}, 1)}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: 200 * time.Millisecond}}
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{SkipFirstBatch: true}, inslogger.NewNopLogger())
+
+	assert.NoError(t, scheduler.Start(context.Background()))
+	defer scheduler.Stop(context.Background())
+
+	select {
+	case <-sender.started:
+		t.Fatal("the first batch ran on start")
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-sender.started:
+	case <-time.After(time.Second):
+		t.Fatal("no batch ran after the first interval")
+	}
+}
+
+// wakeListener notifies every channel it is given once, then fails like a lost connection.
+type wakeListener struct {
+	channels chan model.Channel
+}
+
+func (l *wakeListener) Listen(ctx context.Context, notify func(model.Channel)) error {
+	select {
+	case channel := <-l.channels:
+		notify(channel)
+		return errors.New("connection lost")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestSchedulerWakeupRunsBatchBeforeTick(t *testing.T) {
+	sender := &blockingSender{started: make(chan struct{}, 1)}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: time.Hour}}
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{SkipFirstBatch: true}, inslogger.NewNopLogger())
+	listener := &wakeListener{channels: make(chan model.Channel, 2)}
+	wakeup := NewSchedulerWakeup(listener, scheduler, time.Millisecond, inslogger.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go wakeup.Run(ctx)
+
+	// Not scheduled, and woken while stopped: nothing to run.
+	listener.channels <- model.ChannelEmail
+	scheduler.Wake(model.ChannelSMS)
+	assert.NoError(t, scheduler.Start(context.Background()))
+	defer scheduler.Stop(context.Background())
+	select {
+	case <-sender.started:
+		t.Fatal("a batch ran without a due message")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Listened on again after the lost connection.
+	listener.channels <- model.ChannelSMS
+	select {
+	case <-sender.started:
+	case <-time.After(time.Second):
+		t.Fatal("the wake did not run a batch")
+	}
+}
+
+func TestBatchResultAddError(t *testing.T) {
+	var result model.BatchResult
+	for i := 0; i < 10; i++ {
+		result.AddError(errors.New("same"))
+	}
+	assert.Equal(t, []string{"same"}, result.Errors)
+
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		result.AddError(errors.New(msg))
+	}
+	assert.Len(t, result.Errors, model.MaxBatchErrors)
+}
+
+func TestSetSchedule(t *testing.T) {
+	schedules := map[model.Channel]ChannelSettings{
+		model.ChannelSMS:   {BatchSize: 2, Interval: 2 * time.Minute},
+		model.ChannelEmail: {BatchSize: 50, Interval: time.Minute},
+	}
+	scheduler := NewSchedulerService(&stubSender{}, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+
+	assert.NoError(t, scheduler.SetSchedule(model.ChannelEmail, ChannelSettings{BatchSize: 500, Interval: 5 * time.Minute}))
+	assert.Equal(t, []model.ChannelSchedule{
+		{Channel: model.ChannelEmail, BatchSize: 500, Interval: "5m0s"},
+		{Channel: model.ChannelSMS, BatchSize: 2, Interval: "2m0s"},
+	}, scheduler.Schedules())
+
+	assert.ErrorIs(t, scheduler.SetSchedule(model.ChannelPush, ChannelSettings{BatchSize: 1, Interval: time.Minute}), ErrUnscheduledChannel)
+	assert.ErrorIs(t, scheduler.SetSchedule(model.ChannelSMS, ChannelSettings{BatchSize: 1, Interval: time.Millisecond}), ErrInvalidChannelSettings)
+}
+
+func TestSetScheduleWhileRunning(t *testing.T) {
+	schedules := map[model.Channel]ChannelSettings{
+		model.ChannelSMS:   {BatchSize: 2, Interval: time.Hour},
+		model.ChannelEmail: {BatchSize: 2, Interval: time.Hour},
+	}
+	scheduler := NewSchedulerService(&stubSender{}, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+
+	assert.NoError(t, scheduler.Start(context.Background()))
+	assert.NoError(t, scheduler.SetSchedule(model.ChannelSMS, ChannelSettings{BatchSize: 10, Interval: time.Second}))
+	assert.NoError(t, scheduler.Stop(context.Background()))
+	assert.False(t, scheduler.IsRunning())
+}
+
+func TestRunNow(t *testing.T) {
+	runs := mmemory.NewSchedulerRunService(0)
+	sender := &sizeSender{stubSender: stubSender{result: model.BatchResult{Claimed: 2, Sent: 2}}}
+	schedules := map[model.Channel]ChannelSettings{
+		model.ChannelSMS:   {BatchSize: 20, Interval: time.Hour},
+		model.ChannelEmail: {BatchSize: 50, Interval: time.Hour},
+	}
+	scheduler := NewSchedulerService(sender, stubElector(false), runs, schedules, WarmupSettings{Batches: 10}, inslogger.NewNopLogger())
+
+	ran, err := scheduler.RunNow(context.Background(), model.ChannelSMS)
+
+	assert.NoError(t, err)
+	if assert.Len(t, ran, 1) {
+		assert.Equal(t, model.ChannelSMS, ran[0].Channel)
+		assert.Equal(t, 2, ran[0].Sent)
+	}
+	assert.Equal(t, []int{20}, sender.sizes, "on demand batches skip the warm-up and run on followers too")
+	assert.False(t, scheduler.IsRunning(), "the scheduler is not started")
+
+	ran, err = scheduler.RunNow(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Len(t, ran, 2)
+	listed, _ := runs.ListRuns(context.Background(), 10)
+	assert.Len(t, listed, 3)
+
+	_, err = scheduler.RunNow(context.Background(), model.ChannelPush)
+	assert.ErrorIs(t, err, ErrUnscheduledChannel)
+}
+
+func TestResolveChannelSettings(t *testing.T) {
+	defaults := ChannelSettings{BatchSize: 2, Interval: 2 * time.Minute}
+	file := filepath.Join(t.TempDir(), "channels.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"email": {"batch_size": 500, "interval": "5m"}}`), 0o600))
+
+	resolved, err := ResolveChannelSettings(
+		[]model.Channel{model.ChannelSMS, model.ChannelEmail},
+		defaults,
+		map[string]string{"email": "100/1m", "push": "10/30s"},
+		file,
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[model.Channel]ChannelSettings{
+		model.ChannelSMS:   defaults,
+		model.ChannelEmail: {BatchSize: 500, Interval: 5 * time.Minute},
+	}, resolved, "the file wins over overrides and channels without a provider are ignored")
+}
+
+func TestResolveChannelSettingsRejectsInvalidOverrides(t *testing.T) {
+	defaults := ChannelSettings{BatchSize: 2, Interval: 2 * time.Minute}
+	channels := []model.Channel{model.ChannelSMS}
+
+	for name, overrides := range map[string]map[string]string{
+		"unknown channel":  {"fax": "1/1m"},
+		"missing interval": {"sms": "10"},
+		"batch too large":  {"sms": "5000/1m"},
+		"interval too low": {"sms": "10/10ms"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ResolveChannelSettings(channels, defaults, overrides, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPauseAlertsAndStopsTheScheduler(t *testing.T) {
+	sender := &stubSender{}
+	schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: 1, Interval: time.Minute}}
+	var alerts []Alert
+	scheduler := NewSchedulerService(sender, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger(),
+		func(alert Alert) { alerts = append(alerts, alert) })
+	reason := errors.New("provider rejected authentication")
+
+	scheduler.Pause(reason)
+	assert.Empty(t, alerts, "a stopped scheduler is not paused")
+
+	assert.NoError(t, scheduler.Start(context.Background()))
+	scheduler.Pause(reason)
+	scheduler.Pause(reason)
+
+	assert.False(t, scheduler.IsRunning())
+	assert.Equal(t, reason, scheduler.PauseReason())
+	assert.Len(t, alerts, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, scheduler.Stop(ctx))
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// schedulerStateKey holds the scheduler state operators last set, shared by every replica.
+const schedulerStateKey = "scheduler:state"
+
+const (
+	schedulerStateRunning = "running"
+	schedulerStateStopped = "stopped"
+)
+
+// SchedulerStateStore remembers whether operators last started or stopped the scheduler,
+// so a restarted replica can resume where they left it.
+type SchedulerStateStore interface {
+	// SaveRunning stores whether the scheduler was started or stopped.
+	SaveRunning(running bool) error
+	// Running returns the stored state; ok is false when none was stored yet.
+	Running() (running, ok bool, err error)
+}
+
+type redisSchedulerStateStore struct {
+	redisClient insredis.RedisInterface
+}
+
+// NewRedisSchedulerStateStore keeps the state in Redis without expiry.
+func NewRedisSchedulerStateStore(redisClient insredis.RedisInterface) SchedulerStateStore {
+	return &redisSchedulerStateStore{redisClient: redisClient}
+}
+
+func (s *redisSchedulerStateStore) SaveRunning(running bool) error {
+	state := schedulerStateStopped
+	if running {
+		state = schedulerStateRunning
+	}
+	if err := s.redisClient.Set(schedulerStateKey, state, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store the scheduler state: %w", err)
+	}
+	return nil
+}
+
+func (s *redisSchedulerStateStore) Running() (bool, bool, error) {
+	state, err := s.redisClient.Get(schedulerStateKey).Result()
+	switch {
+	case err == redis.Nil:
+		return false, false, nil
+	case err != nil:
+		return false, false, fmt.Errorf("failed to read the scheduler state: %w", err)
+	}
+
+	switch state {
+	case schedulerStateRunning:
+		return true, true, nil
+	case schedulerStateStopped:
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("invalid stored scheduler state %q", state)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"message-service/internal/mpostgres"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// SchedulerWakeup wakes the scheduler whenever a due message is inserted, so it is sent
+// within milliseconds rather than on the next tick. The ticker still sweeps up messages
+// inserted while the wakeup was reconnecting.
+type SchedulerWakeup struct {
+	listener  mpostgres.MessageListener
+	scheduler SchedulerService
+	retry     time.Duration
+	logger    inslogger.Interface
+}
+
+// NewSchedulerWakeup wakes scheduler on the notifications of listener. A lost connection
+// is listened on again after retry.
+func NewSchedulerWakeup(listener mpostgres.MessageListener, scheduler SchedulerService, retry time.Duration, logger inslogger.Interface) *SchedulerWakeup {
+	return &SchedulerWakeup{listener: listener, scheduler: scheduler, retry: retry, logger: logger}
+}
+
+// Run listens until ctx is done. Only the scheduler leader runs the batches it wakes.
+func (w *SchedulerWakeup) Run(ctx context.Context) {
+	for {
+		err := w.listener.Listen(ctx, w.scheduler.Wake)
+		if ctx.Err() != nil {
+			return
+		}
+		w.logger.Warnf("Stopped listening for new messages, retrying in %s: %v", w.retry, err)
+
+		select {
+		case <-time.After(w.retry):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"message-service/internal/pkg/logctx"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// ErrSendInProgress is returned when another path, e.g. a direct send racing a scheduled
+// batch, is already sending the message.
+var ErrSendInProgress = errors.New("message is already being sent")
+
+const sendLockKeyPrefix = "message:sending:"
+
+// releaseSendLockScript deletes the lock only if it is still held by the caller.
+const releaseSendLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`
+
+// SendLock makes sure only one path hands a message to a provider at a time.
+type SendLock interface {
+	// Acquire locks the message with id for ttl. It returns ErrSendInProgress when the
+	// message is locked already, and a release func to call once the send is over.
+	Acquire(id uint, ttl time.Duration) (func(), error)
+}
+
+type redisSendLock struct {
+	redisClient insredis.RedisInterface
+}
+
+// NewRedisSendLock keeps the locks in Redis so they are shared by every replica. A lock
+// expires after its ttl, a crashed sender does not hold the message forever.
+func NewRedisSendLock(redisClient insredis.RedisInterface) SendLock {
+	return &redisSendLock{redisClient: redisClient}
+}
+
+func (l *redisSendLock) Acquire(id uint, ttl time.Duration) (func(), error) {
+	key := fmt.Sprintf("%s%d", sendLockKeyPrefix, id)
+	token := logctx.NewID()
+
+	locked, err := l.redisClient.SetNX(key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock message %d: %w", id, err)
+	}
+	if !locked {
+		return nil, ErrSendInProgress
+	}
+
+	return func() {
+		// A failed release only delays the next send of the message until the lock expires.
+		_ = l.redisClient.Process(redis.NewCmd("eval", releaseSendLockScript, 1, key, token))
+	}, nil
+}
+
+type localSendLock struct {
+	mu    sync.Mutex
+	locks map[uint]localSendLockEntry
+}
+
+type localSendLockEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewLocalSendLock returns the same locks kept in process memory, for local mode where
+// a single replica runs without Redis.
+func NewLocalSendLock() SendLock {
+	return &localSendLock{locks: make(map[uint]localSendLockEntry)}
+}
+
+func (l *localSendLock) Acquire(id uint, ttl time.Duration) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := l.locks[id]; ok && now.Before(entry.expires) {
+		return nil, ErrSendInProgress
+	}
+	token := logctx.NewID()
+	l.locks[id] = localSendLockEntry{token: token, expires: now.Add(ttl)}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if entry, ok := l.locks[id]; ok && entry.token == token {
+			delete(l.locks, id)
+		}
+	}, nil
+}
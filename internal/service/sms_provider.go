@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"message-service/internal/config"
+	"message-service/internal/httpx"
+	"message-service/internal/pkg/httptape"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// NewSMSProvider returns the SMS driver selected by PROVIDER. Every driver sends through
+// httpClient with the provider retry policy and tape recording layered on its transport.
+func NewSMSProvider(breaker *CircuitBreaker, httpClient *http.Client, cfg *config.App, logger inslogger.Interface) (Provider, error) {
+	switch cfg.SMS.Driver {
+	case "", config.ProviderWebhook:
+		return NewWebhookProvider(breaker, httpClient, cfg, logger), nil
+	case config.ProviderTwilio:
+		return NewTwilioProvider(breaker, httpClient, cfg, logger), nil
+	case config.ProviderMessageBird:
+		return NewMessageBirdProvider(breaker, httpClient, cfg, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMS.Driver)
+	}
+}
+
+// newProviderTransport layers retries and tape recording on next. Retries sit below the
+// tape so replayed sends never retry and recordings hold the final attempt.
+func newProviderTransport(next http.RoundTripper, config *config.App, logger inslogger.Interface) http.RoundTripper {
+	next = httpx.NewTransport(next, httpx.Policy{
+		MaxAttempts: config.Provider.MaxAttempts,
+		BaseDelay:   config.Provider.RetryBaseDelay,
+		MaxDelay:    config.Provider.RetryMaxDelay,
+	}, logger)
+
+	transport, err := httptape.NewTransport(httptape.Mode(config.Tape.Mode), config.Tape.Dir, next)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("failed to configure provider http transport: %w", err))
+	}
+	return transport
+}
+
+// doProviderRequest sends req through breaker and maps the failures every HTTP driver
+// shares: DNS errors, transport errors, rate limiting and rejected credentials. The caller
+// closes the body of the returned response and checks its success status.
+func doProviderRequest(breaker *CircuitBreaker, client *http.Client, req *http.Request, logger inslogger.Interface) (*http.Response, error) {
+	if err := breaker.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	breaker.Record(err == nil && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests)
+	if errors.Is(err, httpx.ErrDNSResolution) {
+		return nil, fmt.Errorf("failed to resolve provider host: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	recordProviderResponse(req.Context(), resp.StatusCode)
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		resp.Body.Close()
+		logctx.Logger(req.Context(), logger).Warnf("Rate limit hit after %d attempts. Headers: %v", httpx.Attempts(resp), resp.Header)
+		return nil, fmt.Errorf("provider rate limited: status code %d", resp.StatusCode)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: status code %d", ErrProviderUnauthorized, resp.StatusCode)
+	}
+
+	return resp, nil
+}
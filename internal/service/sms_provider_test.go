@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func newDriverConfig(driver string) *config.App {
+	cfg := &config.App{}
+	cfg.SMS.Driver = driver
+	cfg.Provider.MaxAttempts = 1
+	cfg.Phone.DefaultCountryCode = "90"
+	return cfg
+}
+
+func newTestSMSProvider(t *testing.T, cfg *config.App) Provider {
+	provider, err := NewSMSProvider(NewCircuitBreaker(cfg.SMS.Driver, 0, 0, inslogger.NewNopLogger()), &http.Client{}, cfg, inslogger.NewNopLogger())
+	assert.NoError(t, err)
+	return provider
+}
+
+func TestNewSMSProviderSelectsDriver(t *testing.T) {
+	for driver, name := range map[string]string{
+		config.ProviderWebhook:     webhookProviderName,
+		config.ProviderTwilio:      twilioProviderName,
+		config.ProviderMessageBird: messageBirdProviderName,
+	} {
+		assert.Equal(t, name, newTestSMSProvider(t, newDriverConfig(driver)).Name())
+	}
+
+	_, err := NewSMSProvider(nil, &http.Client{}, newDriverConfig("carrier-pigeon"), inslogger.NewNopLogger())
+	assert.Error(t, err)
+}
+
+func TestTwilioProviderSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", pass)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "+905551112233", r.PostForm.Get("To"))
+		assert.Equal(t, "+15550001111", r.PostForm.Get("From"))
+		assert.Equal(t, "Hello", r.PostForm.Get("Body"))
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"sid": "SM42", "status": "queued"}`)
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderTwilio)
+	cfg.SMS.Twilio = config.TwilioConfig{AccountSID: "AC123", AuthToken: "secret", From: "+15550001111", BaseURL: server.URL + "/"}
+
+	result, err := newTestSMSProvider(t, cfg).Send(context.Background(), model.Message{ID: 1, RecipientPhone: "5551112233", Content: "Hello"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderResult{MessageID: "SM42", Status: "queued"}, result)
+}
+
+func TestTwilioProviderMapsErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"code": 21211, "message": "The 'To' number is not a valid phone number.", "status": 400}`)
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderTwilio)
+	cfg.SMS.Twilio = config.TwilioConfig{AccountSID: "AC123", AuthToken: "secret", From: "+15550001111", BaseURL: server.URL}
+	provider := newTestSMSProvider(t, cfg)
+
+	_, err := provider.Send(context.Background(), model.Message{ID: 1, RecipientPhone: "+905551112233", Content: "Hello"})
+	assert.ErrorContains(t, err, "twilio error 21211")
+
+	status = http.StatusUnauthorized
+	_, err = provider.Send(context.Background(), model.Message{ID: 1, RecipientPhone: "+905551112233", Content: "Hello"})
+	assert.ErrorIs(t, err, ErrProviderUnauthorized)
+}
+
+func TestMessageBirdProviderSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "AccessKey live_key", r.Header.Get("Authorization"))
+		var payload messageBirdRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, messageBirdRequest{Originator: "Insider", Recipients: []string{"905551112233"}, Body: "Hello"}, payload)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, `{"id": "mb-1", "recipients": {"items": [{"recipient": 905551112233, "status": "sent"}]}}`)
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderMessageBird)
+	cfg.SMS.MessageBird = config.MessageBirdConfig{AccessKey: "live_key", Originator: "Insider", BaseURL: server.URL}
+
+	result, err := newTestSMSProvider(t, cfg).Send(context.Background(), model.Message{ID: 1, RecipientPhone: "+905551112233", Content: "Hello"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderResult{MessageID: "mb-1", Status: "sent"}, result)
+}
+
+func TestMessageBirdProviderMapsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = io.WriteString(w, `{"errors": [{"code": 9, "description": "no (correct) recipients found", "parameter": "recipients"}]}`)
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderMessageBird)
+	cfg.SMS.MessageBird = config.MessageBirdConfig{AccessKey: "live_key", Originator: "Insider", BaseURL: server.URL}
+
+	_, err := newTestSMSProvider(t, cfg).Send(context.Background(), model.Message{ID: 1, RecipientPhone: "+905551112233", Content: "Hello"})
+
+	assert.ErrorContains(t, err, "messagebird error 9: no (correct) recipients found")
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// smtpProviderName is the provider name used for metrics and limits of the SMTP sender.
+const smtpProviderName = "smtp"
+
+// smtpProvider sends email messages through an SMTP relay.
+type smtpProvider struct {
+	logger  inslogger.Interface
+	addr    string
+	auth    smtp.Auth
+	from    string
+	subject string
+	now     func() time.Time
+
+	// sendMail is smtp.SendMail, replaced in tests.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPProvider(cfg *config.SMTPConfig, logger inslogger.Interface) Provider {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &smtpProvider{
+		logger:   logger,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		auth:     auth,
+		from:     cfg.From,
+		subject:  cfg.Subject,
+		now:      time.Now,
+		sendMail: smtp.SendMail,
+	}
+}
+
+func (p *smtpProvider) Name() string {
+	return smtpProviderName
+}
+
+// Probe checks that the SMTP server accepts connections.
+func (p *smtpProvider) Probe(ctx context.Context) error {
+	return probeAddr(ctx, p.addr)
+}
+
+// Send returns the generated Message-ID header as the provider message ID. net/smtp does
+// not take a context, so a cancelled ctx only stops sends that have not started yet.
+func (p *smtpProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	if err := ctx.Err(); err != nil {
+		return ProviderResult{}, err
+	}
+
+	to, err := mail.ParseAddress(message.RecipientEmail)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("recipient %q: %w", message.RecipientEmail, err)
+	}
+	from, err := mail.ParseAddress(p.from)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("sender %q: %w", p.from, err)
+	}
+
+	messageID := fmt.Sprintf("<%s@%s>", logctx.NewID(), domainOf(from.Address))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", to.String())
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", p.subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", p.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Message-ID: %s\r\n", messageID)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(message.Content, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	if err := p.sendMail(p.addr, p.auth, from.Address, []string{to.Address}, []byte(body.String())); err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to send email: %w", err)
+	}
+
+	logctx.Logger(ctx, p.logger).Logf("Email for message ID %d handed to %s", message.ID, p.addr)
+	return ProviderResult{MessageID: messageID}, nil
+}
+
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
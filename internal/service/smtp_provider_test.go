@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestSMTPProviderSend(t *testing.T) {
+	provider := NewSMTPProvider(&config.SMTPConfig{
+		Host:    "smtp.example.com",
+		Port:    587,
+		From:    "Insider <no-reply@example.com>",
+		Subject: "Your code",
+	}, inslogger.NewNopLogger()).(*smtpProvider)
+	provider.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg string
+	provider.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		return nil
+	}
+
+	result, err := provider.Send(context.Background(), model.Message{
+		ID:             1,
+		Channel:        model.ChannelEmail,
+		RecipientEmail: "ada@example.com",
+		Content:        "Your code is 1234",
+	})
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^<.+@example\.com>$`, result.MessageID)
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.Equal(t, "no-reply@example.com", gotFrom)
+	assert.Equal(t, []string{"ada@example.com"}, gotTo)
+	assert.Contains(t, gotMsg, "Subject: Your code\r\n")
+	assert.Contains(t, gotMsg, "Message-ID: "+result.MessageID+"\r\n")
+	assert.Contains(t, gotMsg, "\r\n\r\nYour code is 1234\r\n")
+}
+
+func TestSMTPProviderRejectsInvalidRecipient(t *testing.T) {
+	provider := NewSMTPProvider(&config.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "no-reply@example.com"}, inslogger.NewNopLogger()).(*smtpProvider)
+	provider.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+		return errors.New("must not be called")
+	}
+
+	_, err := provider.Send(context.Background(), model.Message{ID: 1, Channel: model.ChannelEmail, RecipientEmail: "not-an-address"})
+
+	assert.ErrorContains(t, err, "not-an-address")
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/insredis"
+)
+
+// statsWindow is how far back GET /api/stats looks.
+const statsWindow = 24 * time.Hour
+
+// Counters kept in Redis for GET /api/stats.
+const (
+	statsProviderCalls     = "provider_calls"
+	statsProviderLatencyMs = "provider_latency_ms"
+	statsCacheHits         = "cache_hits"
+	statsCacheMisses       = "cache_misses"
+)
+
+// StatsCounters keeps hourly counters in Redis shared by every replica, e.g. provider call
+// latencies and cache hits. Every hour has its own key that expires once it left the
+// stats window. Counting is best effort, a failed increment is dropped.
+type StatsCounters struct {
+	redisClient insredis.RedisInterface
+	now         func() time.Time
+}
+
+func NewStatsCounters(redisClient insredis.RedisInterface) *StatsCounters {
+	return &StatsCounters{redisClient: redisClient, now: time.Now}
+}
+
+// RecordProviderCall counts one provider call that took latency.
+func (c *StatsCounters) RecordProviderCall(latency time.Duration) {
+	c.add(statsProviderCalls, 1)
+	c.add(statsProviderLatencyMs, latency.Milliseconds())
+}
+
+// RecordCacheLookup counts one message lookup answered from the cache or not.
+func (c *StatsCounters) RecordCacheLookup(hit bool) {
+	if hit {
+		c.add(statsCacheHits, 1)
+		return
+	}
+	c.add(statsCacheMisses, 1)
+}
+
+func (c *StatsCounters) add(counter string, delta int64) {
+	key := statsKey(counter, c.now())
+	if err := c.redisClient.IncrBy(key, delta).Err(); err != nil {
+		return
+	}
+	c.redisClient.Expire(key, statsWindow+time.Hour)
+}
+
+// sum adds up counter over the hours of the stats window.
+func (c *StatsCounters) sum(counter string) (int64, error) {
+	now := c.now()
+	var total int64
+	for hours := 0; hours < int(statsWindow/time.Hour); hours++ {
+		value, err := c.redisClient.Get(statsKey(counter, now.Add(-time.Duration(hours)*time.Hour))).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the %s counter: %w", counter, err)
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s counter %q", counter, value)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func statsKey(counter string, at time.Time) string {
+	return fmt.Sprintf("stats:%s:%s", counter, at.UTC().Format("2006010215"))
+}
+
+// StatsService answers GET /api/stats from the message store and the Redis counters.
+type StatsService struct {
+	messages mpostgres.MessageService
+	counters *StatsCounters
+	now      func() time.Time
+}
+
+// NewStatsService reads the counters through counters, nil leaves the latency and the
+// cache hit rate out.
+func NewStatsService(messages mpostgres.MessageService, counters *StatsCounters) *StatsService {
+	return &StatsService{messages: messages, counters: counters, now: time.Now}
+}
+
+// Stats returns the message counts, the sends of every hour of the last 24 hours, including
+// hours without sends, and the averages of the Redis counters over the same hours.
+func (s *StatsService) Stats(ctx context.Context) (model.MessageStats, error) {
+	current := s.now().UTC().Truncate(time.Hour)
+	first := current.Add(-statsWindow + time.Hour)
+
+	stats, err := s.messages.GetMessageStats(ctx, first)
+	if err != nil {
+		return model.MessageStats{}, err
+	}
+
+	perHour := make(map[time.Time]int64, len(stats.SentPerHour))
+	for _, hour := range stats.SentPerHour {
+		perHour[hour.Hour.UTC()] = hour.Count
+	}
+	stats.SentPerHour = make([]model.HourlyCount, 0, int(statsWindow/time.Hour))
+	for hour := first; !hour.After(current); hour = hour.Add(time.Hour) {
+		stats.SentPerHour = append(stats.SentPerHour, model.HourlyCount{Hour: hour, Count: perHour[hour]})
+	}
+
+	if s.counters == nil {
+		return stats, nil
+	}
+	stats.AverageProviderLatencyMs, err = s.ratio(statsProviderLatencyMs, statsProviderCalls)
+	if err != nil {
+		return model.MessageStats{}, err
+	}
+	stats.CacheHitRate, err = s.ratio(statsCacheHits, statsCacheHits, statsCacheMisses)
+	if err != nil {
+		return model.MessageStats{}, err
+	}
+	return stats, nil
+}
+
+// ratio divides the counter numerator by the sum of the counters denominators, nil when
+// that sum is zero.
+func (s *StatsService) ratio(numerator string, denominators ...string) (*float64, error) {
+	var total int64
+	for _, denominator := range denominators {
+		count, err := s.counters.sum(denominator)
+		if err != nil {
+			return nil, err
+		}
+		total += count
+	}
+	if total == 0 {
+		return nil, nil
+	}
+
+	count, err := s.counters.sum(numerator)
+	if err != nil {
+		return nil, err
+	}
+	value := float64(count) / float64(total)
+	return &value, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+	"message-service/internal/pkg/localredis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestStats(t *testing.T) {
+	now := time.Date(2025, 3, 10, 14, 30, 0, 0, time.UTC)
+	messages := mmemory.NewMessageService(inslogger.NewNopLogger(),
+		model.Message{ID: 1, Status: model.StatusSent, SentAt: now.Add(-10 * time.Minute)},
+		model.Message{ID: 2, Status: model.StatusSent, SentAt: now.Add(-20 * time.Minute)},
+		model.Message{ID: 3, Status: model.StatusSent, SentAt: now.Add(-3 * time.Hour)},
+		model.Message{ID: 4, Status: model.StatusSent, SentAt: now.Add(-48 * time.Hour)},
+		model.Message{ID: 5, Status: model.StatusSent, SentAt: now.Add(-time.Hour), Backfilled: true},
+		model.Message{ID: 6},
+		model.Message{ID: 7, Status: model.StatusFailed},
+		model.Message{ID: 8, Status: model.StatusCancelled},
+	)
+	counters := NewStatsCounters(localredis.New())
+	counters.now = func() time.Time { return now }
+	stats := NewStatsService(messages, counters)
+	stats.now = counters.now
+
+	counters.RecordProviderCall(100 * time.Millisecond)
+	counters.RecordProviderCall(300 * time.Millisecond)
+	counters.RecordCacheLookup(true)
+	counters.RecordCacheLookup(true)
+	counters.RecordCacheLookup(true)
+	counters.RecordCacheLookup(false)
+
+	got, err := stats.Stats(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), got.Sent)
+	assert.Equal(t, int64(2), got.Unsent)
+	assert.Equal(t, int64(1), got.Failed)
+	if assert.Len(t, got.SentPerHour, 24) {
+		assert.Equal(t, model.HourlyCount{Hour: time.Date(2025, 3, 9, 15, 0, 0, 0, time.UTC)}, got.SentPerHour[0])
+		assert.Equal(t, model.HourlyCount{Hour: time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC), Count: 1}, got.SentPerHour[20])
+		assert.Equal(t, model.HourlyCount{Hour: time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC), Count: 2}, got.SentPerHour[23])
+	}
+	if assert.NotNil(t, got.AverageProviderLatencyMs) {
+		assert.Equal(t, 200.0, *got.AverageProviderLatencyMs)
+	}
+	if assert.NotNil(t, got.CacheHitRate) {
+		assert.Equal(t, 0.75, *got.CacheHitRate)
+	}
+
+	// The counters of a day ago are out of the window.
+	now = now.Add(statsWindow)
+	got, err = stats.Stats(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, got.AverageProviderLatencyMs)
+	assert.Nil(t, got.CacheHitRate)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/msgtemplate"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// TemplateService manages message templates. Bodies are linted on every write and their
+// variable manifest is stored with them, so invalid templates never reach a send. Templates
+// are created for the tenant of ctx, see logctx.Tenant.
+type TemplateService interface {
+	Create(ctx context.Context, req model.TemplateRequest) (model.Template, error)
+	Get(ctx context.Context, id uint) (model.Template, error)
+	List(ctx context.Context) ([]model.Template, error)
+	Update(ctx context.Context, id uint, req model.TemplateRequest) (model.Template, error)
+	Delete(ctx context.Context, id uint) error
+	// Render substitutes vars into the template. It fails with msgtemplate.ErrMissingVariables
+	// when a variable of the manifest is absent.
+	Render(ctx context.Context, id uint, vars map[string]string) (string, error)
+}
+
+type templateService struct {
+	repo   mpostgres.TemplateRepository
+	logger inslogger.Interface
+}
+
+func NewTemplateService(repo mpostgres.TemplateRepository, logger inslogger.Interface) TemplateService {
+	return &templateService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *templateService) Create(ctx context.Context, req model.TemplateRequest) (model.Template, error) {
+	template, err := lintTemplate(req)
+	if err != nil {
+		return model.Template{}, err
+	}
+	template.Tenant = logctx.Tenant(ctx)
+	return s.repo.CreateTemplate(ctx, template)
+}
+
+func (s *templateService) Get(ctx context.Context, id uint) (model.Template, error) {
+	return s.repo.GetTemplate(ctx, id)
+}
+
+func (s *templateService) List(ctx context.Context) ([]model.Template, error) {
+	return s.repo.ListTemplates(ctx)
+}
+
+func (s *templateService) Update(ctx context.Context, id uint, req model.TemplateRequest) (model.Template, error) {
+	template, err := lintTemplate(req)
+	if err != nil {
+		return model.Template{}, err
+	}
+	template.ID = id
+	return s.repo.UpdateTemplate(ctx, template)
+}
+
+func (s *templateService) Delete(ctx context.Context, id uint) error {
+	return s.repo.DeleteTemplate(ctx, id)
+}
+
+func (s *templateService) Render(ctx context.Context, id uint, vars map[string]string) (string, error) {
+	template, err := s.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return msgtemplate.Render(template.Body, msgtemplate.Manifest{Variables: template.Variables, Optional: template.OptionalVariables}, vars)
+}
+
+func lintTemplate(req model.TemplateRequest) (model.Template, error) {
+	manifest, err := msgtemplate.Lint(req.Body)
+	if err != nil {
+		return model.Template{}, err
+	}
+	return model.Template{Name: req.Name, Body: req.Body, Variables: manifest.Variables, OptionalVariables: manifest.Optional}, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// ErrSandboxUnavailable is returned for messages of sandbox tenants when no sandbox
+// credentials are configured. They are never sent through production credentials instead.
+var ErrSandboxUnavailable = errors.New("no sandbox provider configured")
+
+// TenantEnvironments tells sandbox tenants from production ones.
+type TenantEnvironments struct {
+	environments       map[string]string
+	defaultEnvironment string
+}
+
+// NewTenantEnvironments flags every tenant in environments, the others get defaultEnvironment.
+func NewTenantEnvironments(environments map[string]string, defaultEnvironment string) *TenantEnvironments {
+	return &TenantEnvironments{
+		environments:       environments,
+		defaultEnvironment: defaultEnvironment,
+	}
+}
+
+// Environment returns config.EnvironmentSandbox or config.EnvironmentProduction.
+func (t *TenantEnvironments) Environment(tenant string) string {
+	if environment, ok := t.environments[tenant]; ok {
+		return environment
+	}
+	return t.defaultEnvironment
+}
+
+// HasSandbox reports whether any tenant can be sandboxed.
+func (t *TenantEnvironments) HasSandbox() bool {
+	if t.defaultEnvironment == config.EnvironmentSandbox {
+		return true
+	}
+	for _, environment := range t.environments {
+		if environment == config.EnvironmentSandbox {
+			return true
+		}
+	}
+	return false
+}
+
+// environmentProvider sends messages of sandbox tenants through the sandbox provider and
+// all others through production. It keeps the production name so in-flight limits and
+// routing treat both as one provider.
+type environmentProvider struct {
+	production Provider
+	sandbox    Provider
+	tenants    *TenantEnvironments
+	logger     inslogger.Interface
+}
+
+// NewEnvironmentProvider picks production or sandbox per message by its tenant. A nil
+// sandbox makes sends of sandbox tenants fail with ErrSandboxUnavailable.
+func NewEnvironmentProvider(production, sandbox Provider, tenants *TenantEnvironments, logger inslogger.Interface) Provider {
+	return &environmentProvider{
+		production: production,
+		sandbox:    sandbox,
+		tenants:    tenants,
+		logger:     logger,
+	}
+}
+
+func (p *environmentProvider) Name() string {
+	return p.production.Name()
+}
+
+// Probe checks the production provider and the sandbox provider, those of them that can
+// be probed.
+func (p *environmentProvider) Probe(ctx context.Context) error {
+	if prober, ok := p.production.(ProviderProber); ok {
+		if err := prober.Probe(ctx); err != nil {
+			return err
+		}
+	}
+	if prober, ok := p.sandbox.(ProviderProber); ok {
+		if err := prober.Probe(ctx); err != nil {
+			return fmt.Errorf("sandbox: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *environmentProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	if p.tenants.Environment(message.Tenant) != config.EnvironmentSandbox {
+		return p.production.Send(ctx, message)
+	}
+
+	if p.sandbox == nil {
+		return ProviderResult{}, fmt.Errorf("%w: tenant %q is sandboxed", ErrSandboxUnavailable, message.Tenant)
+	}
+	logctx.Logger(ctx, p.logger).Debugf("Sending message ID %d of sandbox tenant %q through the sandbox provider", message.ID, message.Tenant)
+	return p.sandbox.Send(ctx, message)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestEnvironmentProviderRoutesSandboxTenants(t *testing.T) {
+	production := &stubProvider{name: "production"}
+	sandbox := &stubProvider{name: "sandbox"}
+	tenants := NewTenantEnvironments(map[string]string{"staging": config.EnvironmentSandbox}, config.EnvironmentProduction)
+	provider := NewEnvironmentProvider(production, sandbox, tenants, inslogger.NewNopLogger())
+
+	result, err := provider.Send(context.Background(), model.Message{ID: 1, Tenant: "staging"})
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox-id", result.MessageID)
+
+	result, err = provider.Send(context.Background(), model.Message{ID: 2, Tenant: "acme"})
+	assert.NoError(t, err)
+	assert.Equal(t, "production-id", result.MessageID)
+
+	assert.Equal(t, []uint{1}, sandbox.sent)
+	assert.Equal(t, []uint{2}, production.sent)
+	assert.Equal(t, "production", provider.Name())
+}
+
+func TestEnvironmentProviderNeverFallsBackToProduction(t *testing.T) {
+	production := &stubProvider{name: "production"}
+	tenants := NewTenantEnvironments(nil, config.EnvironmentSandbox)
+	provider := NewEnvironmentProvider(production, nil, tenants, inslogger.NewNopLogger())
+
+	_, err := provider.Send(context.Background(), model.Message{ID: 1})
+
+	assert.ErrorIs(t, err, ErrSandboxUnavailable)
+	assert.Empty(t, production.sent)
+}
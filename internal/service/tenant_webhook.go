@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+	"message-service/internal/pkg/logctx"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// tenantWebhookProvider sends the messages of tenants with a provider config to their own
+// webhook URL with their own auth key, and all others through the WEBHOOK_URL and AUTH_KEY
+// of the base provider. It keeps the base name so in-flight limits and routing treat every
+// gateway as one provider.
+type tenantWebhookProvider struct {
+	base    *webhookProvider
+	configs mpostgres.ProviderConfigRepository
+	// ttl is how long a looked up config is used before it is read again.
+	ttl time.Duration
+	// newBreaker returns the breaker of a tenant gateway, so a failing gateway only stops
+	// the sends of its tenant.
+	newBreaker func(tenant string) *CircuitBreaker
+	now        func() time.Time
+	logger     inslogger.Interface
+
+	mu       sync.Mutex
+	gateways map[string]tenantGateway
+	breakers map[string]*CircuitBreaker
+}
+
+// tenantGateway is the looked up gateway of a tenant, a nil provider when it has no config.
+type tenantGateway struct {
+	provider  *webhookProvider
+	fetchedAt time.Time
+}
+
+// NewTenantWebhookProvider routes the sends of base per tenant by the configs in configs.
+// Other drivers than the webhook are returned unchanged, provider configs only hold webhook
+// gateways.
+func NewTenantWebhookProvider(base Provider, configs mpostgres.ProviderConfigRepository, ttl time.Duration, newBreaker func(tenant string) *CircuitBreaker, logger inslogger.Interface) Provider {
+	webhook, ok := base.(*webhookProvider)
+	if !ok {
+		return base
+	}
+	return &tenantWebhookProvider{
+		base:       webhook,
+		configs:    configs,
+		ttl:        ttl,
+		newBreaker: newBreaker,
+		now:        time.Now,
+		logger:     logger,
+		gateways:   make(map[string]tenantGateway),
+		breakers:   make(map[string]*CircuitBreaker),
+	}
+}
+
+func (p *tenantWebhookProvider) Name() string {
+	return p.base.Name()
+}
+
+// Probe checks the default gateway, tenant gateways are checked by their sends.
+func (p *tenantWebhookProvider) Probe(ctx context.Context) error {
+	return p.base.Probe(ctx)
+}
+
+func (p *tenantWebhookProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	gateway, err := p.gateway(ctx, message.Tenant)
+	if err != nil {
+		return ProviderResult{}, err
+	}
+	if gateway == nil {
+		return p.base.Send(ctx, message)
+	}
+
+	result, err := gateway.Send(ctx, message)
+	if errors.Is(err, ErrProviderUnauthorized) {
+		// Only this tenant's key was rejected, the scheduler must keep sending for the others.
+		return ProviderResult{}, fmt.Errorf("webhook of tenant %q rejected its auth key: %v", message.Tenant, err)
+	}
+	return result, err
+}
+
+// gateway returns the provider of the tenant gateway, nil when tenant has no config. A
+// config that cannot be read again keeps being used, a tenant never seen before fails
+// rather than sending through the default gateway.
+func (p *tenantWebhookProvider) gateway(ctx context.Context, tenant string) (*webhookProvider, error) {
+	if tenant == "" {
+		return nil, nil
+	}
+
+	p.mu.Lock()
+	cached, ok := p.gateways[tenant]
+	p.mu.Unlock()
+	if ok && p.now().Sub(cached.fetchedAt) < p.ttl {
+		return cached.provider, nil
+	}
+
+	config, err := p.configs.GetTenantProviderConfig(ctx, tenant)
+	switch {
+	case errors.Is(err, mpostgres.ErrProviderConfigNotFound):
+		p.store(tenant, tenantGateway{fetchedAt: p.now()})
+		return nil, nil
+	case err != nil && ok:
+		logctx.Logger(ctx, p.logger).Warnf("Failed to read the provider config of tenant %q, using the one read before: %v", tenant, err)
+		return cached.provider, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read the provider config of tenant %q: %w", tenant, err)
+	}
+
+	provider := cached.provider
+	if provider == nil || provider.webhookURL != config.WebhookURL || provider.authKey != config.AuthKey {
+		gateway := *p.base
+		gateway.webhookURL = config.WebhookURL
+		gateway.authKey = config.AuthKey
+		gateway.breaker = p.breaker(tenant)
+		provider = &gateway
+	}
+	p.store(tenant, tenantGateway{provider: provider, fetchedAt: p.now()})
+	return provider, nil
+}
+
+func (p *tenantWebhookProvider) store(tenant string, gateway tenantGateway) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gateways[tenant] = gateway
+}
+
+// breaker returns the breaker of tenant, the same one for every config it is given.
+func (p *tenantWebhookProvider) breaker(tenant string) *CircuitBreaker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	breaker, ok := p.breakers[tenant]
+	if !ok {
+		breaker = p.newBreaker(tenant)
+		p.breakers[tenant] = breaker
+	}
+	return breaker
+}
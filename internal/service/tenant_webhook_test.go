@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// recordingGateway answers every send and records the auth key it was sent with.
+func recordingGateway(t *testing.T, status int) (*httptest.Server, *[]string) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("x-ins-auth-key"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"message": "Accepted", "messageId": "SM1"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &keys
+}
+
+func TestTenantWebhookProviderRoutesPerTenant(t *testing.T) {
+	ctx := context.Background()
+	global, globalKeys := recordingGateway(t, http.StatusAccepted)
+	acme, acmeKeys := recordingGateway(t, http.StatusAccepted)
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = global.URL
+	cfg.AuthKey = "global-key"
+	logger := inslogger.NewNopLogger()
+	configs := mmemory.NewProviderConfigRepository()
+	_, err := configs.CreateProviderConfig(ctx, model.ProviderConfig{Tenant: "acme", WebhookURL: acme.URL, AuthKey: "acme-key"})
+	require.NoError(t, err)
+	provider := NewTenantWebhookProvider(NewWebhookProvider(NewCircuitBreaker(webhookProviderName, 0, 0, logger), global.Client(), cfg, logger),
+		configs, 0, func(tenant string) *CircuitBreaker { return NewCircuitBreaker(tenant, 0, 0, logger) }, logger)
+
+	for _, tenant := range []string{"acme", "globex", ""} {
+		_, err := provider.Send(ctx, model.Message{ID: 1, Tenant: tenant, Content: "hi", RecipientPhone: "+905551111111"})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{"acme-key"}, *acmeKeys)
+	assert.Equal(t, []string{"global-key", "global-key"}, *globalKeys, "tenants without a config use WEBHOOK_URL and AUTH_KEY")
+	assert.Equal(t, webhookProviderName, provider.Name())
+}
+
+func TestTenantWebhookProviderKeepsRejectedTenantKeysLocal(t *testing.T) {
+	ctx := context.Background()
+	global, _ := recordingGateway(t, http.StatusAccepted)
+	acme, _ := recordingGateway(t, http.StatusUnauthorized)
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = global.URL
+	logger := inslogger.NewNopLogger()
+	configs := mmemory.NewProviderConfigRepository()
+	_, err := configs.CreateProviderConfig(ctx, model.ProviderConfig{Tenant: "acme", WebhookURL: acme.URL, AuthKey: "stale"})
+	require.NoError(t, err)
+	provider := NewTenantWebhookProvider(NewWebhookProvider(NewCircuitBreaker(webhookProviderName, 0, 0, logger), global.Client(), cfg, logger),
+		configs, 0, func(tenant string) *CircuitBreaker { return NewCircuitBreaker(tenant, 0, 0, logger) }, logger)
+
+	_, err = provider.Send(ctx, model.Message{ID: 1, Tenant: "acme", Content: "hi", RecipientPhone: "+905551111111"})
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrProviderUnauthorized, "one tenant's key must not pause the scheduler")
+}
+
+func TestTenantWebhookProviderOnlyWrapsTheWebhook(t *testing.T) {
+	base := &stubProvider{name: "twilio"}
+
+	provider := NewTenantWebhookProvider(base, mmemory.NewProviderConfigRepository(), 0, nil, inslogger.NewNopLogger())
+
+	assert.Same(t, base, provider)
+}
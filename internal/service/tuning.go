@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"message-service/internal/model"
+	"message-service/internal/mpostgres"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+const (
+	// minTuningRuns is how many runs a channel needs before the advisor judges it.
+	minTuningRuns = 3
+	// maxBacklogSamples bounds the backlog history the growth is computed from.
+	maxBacklogSamples = 12
+)
+
+type backlogSample struct {
+	at      time.Time
+	pending int64
+}
+
+// TuningAdvisor analyzes the recent scheduler runs and the backlog and suggests changes
+// to the scheduler settings: larger batches, shorter intervals, more send concurrency or
+// more workers. It only suggests, nothing is changed.
+type TuningAdvisor struct {
+	runs      mpostgres.SchedulerRunService
+	messages  mpostgres.MessageService
+	scheduler SchedulerService
+	elector   LeaderElector
+	// window is how many of the latest runs are analyzed.
+	window int
+	// drainTarget is how long the backlog may take to drain.
+	drainTarget time.Duration
+	concurrency int
+	interval    time.Duration
+	logger      inslogger.Interface
+	now         func() time.Time
+
+	mu      sync.Mutex
+	samples []backlogSample
+}
+
+// NewTuningAdvisor analyzes the latest window runs against a backlog that should drain
+// within drainTarget, with batches sending concurrency messages at once. Run logs the
+// suggestions every interval.
+func NewTuningAdvisor(runs mpostgres.SchedulerRunService, messages mpostgres.MessageService, scheduler SchedulerService, elector LeaderElector, window int, drainTarget time.Duration, concurrency int, interval time.Duration, logger inslogger.Interface) *TuningAdvisor {
+	return &TuningAdvisor{
+		runs:        runs,
+		messages:    messages,
+		scheduler:   scheduler,
+		elector:     elector,
+		window:      window,
+		drainTarget: drainTarget,
+		concurrency: concurrency,
+		interval:    interval,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Run logs a summary of the suggestions on every tick until ctx is done, on the scheduler
+// leader only. It returns at once when the interval is zero.
+func (a *TuningAdvisor) Run(ctx context.Context) {
+	if a.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if a.elector.IsLeader() {
+				a.logSummary(ctx)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *TuningAdvisor) logSummary(ctx context.Context) {
+	report, err := a.Report(ctx)
+	if err != nil {
+		a.logger.Warnf("Failed to compute tuning suggestions: %v", err)
+		return
+	}
+
+	a.logger.Logf("Tuning: backlog %d (%+.1f/min), %d suggestions", report.BacklogDepth, report.BacklogGrowthPerMinute, len(report.Suggestions))
+	for _, suggestion := range report.Suggestions {
+		target := "all channels"
+		if suggestion.Channel != "" {
+			target = string(suggestion.Channel)
+		}
+		if suggestion.Setting == "" {
+			a.logger.Logf("Tuning suggestion for %s: %s, %s", target, suggestion.Kind, suggestion.Reason)
+			continue
+		}
+		a.logger.Logf("Tuning suggestion for %s: %s %s from %s to %s, %s", target, suggestion.Kind,
+			suggestion.Setting, suggestion.Current, suggestion.Suggested, suggestion.Reason)
+	}
+}
+
+// Report analyzes the runs and the backlog now. Every call also adds a backlog sample.
+func (a *TuningAdvisor) Report(ctx context.Context) (model.TuningReport, error) {
+	runs, err := a.runs.ListRuns(ctx, a.window)
+	if err != nil {
+		return model.TuningReport{}, err
+	}
+	backlog, err := a.messages.GetBacklog(ctx)
+	if err != nil {
+		return model.TuningReport{}, err
+	}
+
+	now := a.now().UTC()
+	report := model.TuningReport{
+		GeneratedAt:            now,
+		BacklogDepth:           backlog.Pending,
+		BacklogGrowthPerMinute: a.sample(now, backlog.Pending),
+		Channels:               []model.ChannelThroughput{},
+		Suggestions:            []model.TuningSuggestion{},
+	}
+
+	for _, schedule := range a.scheduler.Schedules() {
+		interval, err := time.ParseDuration(schedule.Interval)
+		if err != nil {
+			return model.TuningReport{}, fmt.Errorf("invalid interval of channel %s: %w", schedule.Channel, err)
+		}
+
+		throughput := a.throughput(schedule, runs)
+		report.Channels = append(report.Channels, throughput)
+		if suggestion, ok := a.suggest(throughput, interval, backlog.Pending); ok {
+			report.Suggestions = append(report.Suggestions, suggestion)
+		}
+	}
+
+	// A growing backlog nothing above would fix needs more capacity.
+	if len(report.Suggestions) == 0 && report.BacklogGrowthPerMinute > 0 && backlog.Pending > 0 {
+		report.Suggestions = append(report.Suggestions, model.TuningSuggestion{
+			Kind:   model.TuneAddWorker,
+			Reason: fmt.Sprintf("the backlog grows by %.1f messages per minute", report.BacklogGrowthPerMinute),
+		})
+	}
+
+	return report, nil
+}
+
+// throughput summarizes the runs of the channel of schedule among runs.
+func (a *TuningAdvisor) throughput(schedule model.ChannelSchedule, runs []model.SchedulerRun) model.ChannelThroughput {
+	throughput := model.ChannelThroughput{
+		Channel:   schedule.Channel,
+		BatchSize: schedule.BatchSize,
+		Interval:  schedule.Interval,
+	}
+
+	var sent, claimed int
+	var busy time.Duration
+	var first, last time.Time
+	for _, run := range runs {
+		if run.Channel != schedule.Channel {
+			continue
+		}
+		throughput.Runs++
+		if run.Claimed >= schedule.BatchSize {
+			throughput.FullBatches++
+		}
+		sent += run.Sent
+		claimed += run.Claimed
+		busy += run.FinishedAt.Sub(run.StartedAt)
+		if first.IsZero() || run.StartedAt.Before(first) {
+			first = run.StartedAt
+		}
+		if run.FinishedAt.After(last) {
+			last = run.FinishedAt
+		}
+	}
+	if throughput.Runs == 0 {
+		return throughput
+	}
+
+	if span := last.Sub(first); span > 0 {
+		throughput.SentPerMinute = roundTenth(float64(sent) / span.Minutes())
+	}
+	throughput.AvgBatchSeconds = roundTenth(busy.Seconds() / float64(throughput.Runs))
+	if claimed > 0 {
+		throughput.AvgSendMillis = roundTenth(float64(busy.Milliseconds()) * float64(max(a.concurrency, 1)) / float64(claimed))
+	}
+	return throughput
+}
+
+// suggest returns the most useful change for a channel, if any: batches that overrun the
+// interval need more concurrency, full batches with a backlog need larger batches, then
+// shorter intervals, then more workers.
+func (a *TuningAdvisor) suggest(throughput model.ChannelThroughput, interval time.Duration, pending int64) (model.TuningSuggestion, bool) {
+	if throughput.Runs < minTuningRuns {
+		return model.TuningSuggestion{}, false
+	}
+
+	suggestion := model.TuningSuggestion{Channel: throughput.Channel}
+	avgBatch := time.Duration(throughput.AvgBatchSeconds * float64(time.Second))
+	if avgBatch > interval*8/10 {
+		suggestion.Kind = model.TuneIncreaseConcurrency
+		suggestion.Setting = "SCHEDULER_SEND_CONCURRENCY"
+		suggestion.Current = strconv.Itoa(a.concurrency)
+		suggestion.Suggested = strconv.Itoa(a.concurrency * 2)
+		suggestion.Reason = fmt.Sprintf("batches take %s of the %s interval, about %.0fms per send", avgBatch.Round(time.Millisecond), interval, throughput.AvgSendMillis)
+		return suggestion, true
+	}
+
+	if throughput.FullBatches*2 < throughput.Runs || pending == 0 {
+		return model.TuningSuggestion{}, false
+	}
+	full := fmt.Sprintf("%d of the last %d batches were full and %d messages are pending", throughput.FullBatches, throughput.Runs, pending)
+
+	// The batch size that drains the backlog within drainTarget at the current interval.
+	needed := int(math.Ceil(float64(pending) * interval.Seconds() / a.drainTarget.Seconds()))
+	if needed <= throughput.BatchSize {
+		return model.TuningSuggestion{}, false
+	}
+	if throughput.BatchSize < MaxScheduleBatchSize {
+		suggestion.Kind = model.TuneIncreaseBatch
+		suggestion.Setting = "batch_size"
+		suggestion.Current = strconv.Itoa(throughput.BatchSize)
+		suggestion.Suggested = strconv.Itoa(min(needed, MaxScheduleBatchSize))
+		suggestion.Reason = full
+		return suggestion, true
+	}
+
+	// Batches are as large as they get, run them more often while they leave room.
+	shorter := max(interval*time.Duration(throughput.BatchSize)/time.Duration(needed), avgBatch*2, MinScheduleInterval).Round(time.Second)
+	if shorter < interval {
+		suggestion.Kind = model.TuneShortenInterval
+		suggestion.Setting = "interval"
+		suggestion.Current = interval.String()
+		suggestion.Suggested = shorter.String()
+		suggestion.Reason = full
+		return suggestion, true
+	}
+
+	suggestion.Kind = model.TuneAddWorker
+	suggestion.Reason = full + ", and batches cannot grow or run more often"
+	return suggestion, true
+}
+
+// sample records the backlog and returns its growth per minute since the oldest sample.
+func (a *TuningAdvisor) sample(at time.Time, pending int64) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.samples = append(a.samples, backlogSample{at: at, pending: pending})
+	if len(a.samples) > maxBacklogSamples {
+		a.samples = a.samples[len(a.samples)-maxBacklogSamples:]
+	}
+
+	oldest := a.samples[0]
+	elapsed := at.Sub(oldest.at)
+	if elapsed < time.Minute {
+		return 0
+	}
+	return roundTenth(float64(pending-oldest.pending) / elapsed.Minutes())
+}
+
+// roundTenth rounds to one decimal for reports.
+func roundTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"message-service/internal/mmemory"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestTuningAdvisorSuggestions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		pending   int
+		batchSize int
+		claimed   int
+		took      time.Duration
+		kind      string
+		suggested string
+	}{
+		{"batches overrunning the interval need concurrency", 10, 10, 10, 55 * time.Second, model.TuneIncreaseConcurrency, "8"},
+		{"full batches grow to drain the backlog", 1000, 100, 100, 5 * time.Second, model.TuneIncreaseBatch, "200"},
+		{"maximum batches run more often", 10000, 1000, 1000, 5 * time.Second, model.TuneShortenInterval, "30s"},
+		{"maximum batches without room need a worker", 10000, 1000, 1000, 40 * time.Second, model.TuneAddWorker, ""},
+		{"batches that are not full need nothing", 1000, 100, 40, 5 * time.Second, "", ""},
+		{"full batches keeping up need nothing", 100, 100, 100, 5 * time.Second, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seed []model.Message
+			for i := 1; i <= tt.pending; i++ {
+				seed = append(seed, model.Message{ID: uint(i)})
+			}
+			messages := mmemory.NewMessageService(inslogger.NewNopLogger(), seed...)
+
+			runs := mmemory.NewSchedulerRunService(0)
+			for i := 0; i < 4; i++ {
+				startedAt := now.Add(time.Duration(i-4) * time.Minute)
+				run := model.SchedulerRun{Channel: model.ChannelSMS, StartedAt: startedAt, FinishedAt: startedAt.Add(tt.took),
+					BatchSize: tt.batchSize, Claimed: tt.claimed, Sent: tt.claimed}
+				if err := runs.RecordRun(context.Background(), run); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			schedules := map[model.Channel]ChannelSettings{model.ChannelSMS: {BatchSize: tt.batchSize, Interval: time.Minute}}
+			scheduler := NewSchedulerService(&stubSender{}, nil, nil, schedules, WarmupSettings{}, inslogger.NewNopLogger())
+			advisor := NewTuningAdvisor(runs, messages, scheduler, stubElector(true), 50, 5*time.Minute, 4, 0, inslogger.NewNopLogger())
+			advisor.now = func() time.Time { return now }
+
+			report, err := advisor.Report(context.Background())
+
+			assert.NoError(t, err)
+			if assert.Len(t, report.Channels, 1) {
+				assert.Equal(t, 4, report.Channels[0].Runs)
+			}
+			if tt.kind == "" {
+				assert.Empty(t, report.Suggestions)
+				return
+			}
+			if assert.Len(t, report.Suggestions, 1) {
+				assert.Equal(t, tt.kind, report.Suggestions[0].Kind)
+				assert.Equal(t, tt.suggested, report.Suggestions[0].Suggested)
+			}
+		})
+	}
+}
+
+func TestTuningAdvisorBacklogGrowth(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	advisor := NewTuningAdvisor(nil, nil, nil, nil, 50, 5*time.Minute, 4, 0, inslogger.NewNopLogger())
+
+	assert.Zero(t, advisor.sample(now, 10))
+	assert.Zero(t, advisor.sample(now.Add(30*time.Second), 20), "growth needs a minute of samples")
+	assert.Equal(t, 20.0, advisor.sample(now.Add(2*time.Minute), 50))
+}
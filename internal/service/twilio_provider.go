@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/phone"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+const twilioProviderName = "twilio"
+
+// twilioMessage is the part of Twilio's Message resource the driver reads.
+type twilioMessage struct {
+	SID    string `json:"sid"`
+	Status string `json:"status"`
+}
+
+// twilioError is Twilio's error body; its status is the HTTP status as a number.
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// twilioProvider sends SMS through the Messages resource of Twilio's REST API.
+type twilioProvider struct {
+	logger      inslogger.Interface
+	breaker     *CircuitBreaker
+	httpClient  *http.Client
+	messagesURL string
+	accountSID  string
+	authToken   string
+	from        string
+	countryCode string
+}
+
+func NewTwilioProvider(breaker *CircuitBreaker, httpClient *http.Client, cfg *config.App, logger inslogger.Interface) Provider {
+	twilio := cfg.SMS.Twilio
+	return &twilioProvider{
+		logger:      logger,
+		breaker:     breaker,
+		httpClient:  withTransport(httpClient, newProviderTransport(httpClient.Transport, cfg, logger)),
+		messagesURL: fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(twilio.BaseURL, "/"), url.PathEscape(twilio.AccountSID)),
+		accountSID:  twilio.AccountSID,
+		authToken:   twilio.AuthToken,
+		from:        twilio.From,
+		countryCode: cfg.Phone.DefaultCountryCode,
+	}
+}
+
+func (p *twilioProvider) Name() string {
+	return twilioProviderName
+}
+
+// Probe checks that the API accepts connections.
+func (p *twilioProvider) Probe(ctx context.Context) error {
+	return probeURL(ctx, p.messagesURL)
+}
+
+// Send returns Twilio's message SID and its initial status, usually "queued" or "accepted".
+func (p *twilioProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	recipientPhone, err := phone.Normalize(message.RecipientPhone, p.countryCode)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("recipient %q: %w", message.RecipientPhone, err)
+	}
+
+	form := url.Values{
+		"To":   {recipientPhone},
+		"From": {p.from},
+		"Body": {message.Content},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.messagesURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.accountSID, p.authToken)
+
+	resp, err := doProviderRequest(p.breaker, p.httpClient, req, p.logger)
+	if err != nil {
+		return ProviderResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		var twilioErr twilioError
+		if err := json.NewDecoder(resp.Body).Decode(&twilioErr); err == nil && twilioErr.Code != 0 {
+			return ProviderResult{}, fmt.Errorf("twilio error %d: %s: status code %d", twilioErr.Code, twilioErr.Message, resp.StatusCode)
+		}
+		return ProviderResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var response twilioMessage
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return ProviderResult{MessageID: response.SID, Status: response.Status}, nil
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/logctx"
+	"message-service/internal/pkg/phone"
+
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// webhookProviderName is the provider name used for metrics and limits of the webhook SMS gateway.
+const webhookProviderName = "webhook"
+
+const (
+	// WebhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">" per
+	// signing key, comma separated, the primary key first.
+	WebhookSignatureHeader = "X-Signature"
+	// WebhookSignatureTimestampHeader carries the unix time the request was signed.
+	WebhookSignatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// MessagePayload is the v1 request body of the webhook gateway.
+type MessagePayload struct {
+	To      string `json:"to"`
+	Content string `json:"content"`
+}
+
+// MessagePayloadV2 is the v2 request body: v1 plus its version, the sender ID and
+// metadata identifying the message.
+type MessagePayloadV2 struct {
+	Version  string          `json:"version"`
+	To       string          `json:"to"`
+	Content  string          `json:"content"`
+	SenderID string          `json:"sender_id,omitempty"`
+	Metadata MessageMetadata `json:"metadata"`
+}
+
+// MessageMetadata identifies the message of a v2 payload.
+type MessageMetadata struct {
+	MessageID   uint               `json:"message_id"`
+	Tenant      string             `json:"tenant,omitempty"`
+	Channel     model.Channel      `json:"channel"`
+	ExternalRef *model.ExternalRef `json:"external_ref,omitempty"`
+}
+
+type MessageResponse struct {
+	Message   string `json:"message"`
+	MessageID string `json:"messageId"`
+}
+
+// Default response fields of the webhook gateway, see MessageResponse.
+const (
+	defaultResponseIDField     = "messageId"
+	defaultResponseStatusField = "message"
+)
+
+// WebhookTemplateData is what the custom payload template renders. json encodes a value,
+// so {{json .Content}} yields a quoted and escaped JSON string.
+type WebhookTemplateData struct {
+	To             string
+	Content        string
+	SenderID       string
+	MessageID      uint
+	Tenant         string
+	Channel        model.Channel
+	ExternalSystem string
+	ExternalID     string
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// webhookProvider sends SMS through the HTTP webhook gateway.
+type webhookProvider struct {
+	logger      inslogger.Interface
+	breaker     *CircuitBreaker
+	httpClient  *http.Client
+	webhookURL  string
+	authKey     string
+	countryCode string
+	// payloadVersion is the request body schema, config.WebhookPayloadV1 or V2.
+	payloadVersion string
+	senderID       string
+	// payloadTemplate renders the body of the custom payload version.
+	payloadTemplate *template.Template
+	// responseIDField and responseStatusField are the dot separated paths of the provider
+	// message ID and status in the response.
+	responseIDField     string
+	responseStatusField string
+	// signingKeys sign the request body, none leaves requests unsigned.
+	signingKeys []string
+	now         func() time.Time
+}
+
+// NewWebhookProvider sends through httpClient, which is shared so its connection pool and
+// timeout apply to every send; retries and tape recording are layered on its transport.
+func NewWebhookProvider(breaker *CircuitBreaker, httpClient *http.Client, config *config.App, logger inslogger.Interface) Provider {
+	webhookURL := config.WebhookURL
+	next := httpClient.Transport
+	if config.Local.Enabled && webhookURL == "" {
+		logger.Warn("WEBHOOK_URL is empty, local mode accepts sends without calling a provider")
+		webhookURL = localProviderURL
+		next = localProvider{}
+	}
+
+	var payloadTemplate *template.Template
+	if config.PayloadTemplate != "" {
+		var err error
+		payloadTemplate, err = template.New("payload").Funcs(webhookTemplateFuncs).Parse(config.PayloadTemplate)
+		if err != nil {
+			logger.Fatal(fmt.Errorf("invalid WEBHOOK_PAYLOAD_TEMPLATE: %w", err))
+		}
+	}
+
+	return &webhookProvider{
+		logger:              logger,
+		breaker:             breaker,
+		httpClient:          withTransport(httpClient, newProviderTransport(next, config, logger)),
+		webhookURL:          webhookURL,
+		authKey:             config.AuthKey,
+		countryCode:         config.Phone.DefaultCountryCode,
+		payloadVersion:      config.PayloadVersion,
+		senderID:            config.SenderID,
+		signingKeys:         signingKeys(config.SigningKey, config.SigningKeySecondary),
+		payloadTemplate:     payloadTemplate,
+		responseIDField:     cmp.Or(config.ResponseIDField, defaultResponseIDField),
+		responseStatusField: cmp.Or(config.ResponseStatusField, defaultResponseStatusField),
+		now:                 time.Now,
+	}
+}
+
+func signingKeys(keys ...string) []string {
+	var set []string
+	for _, key := range keys {
+		if key != "" {
+			set = append(set, key)
+		}
+	}
+	return set
+}
+
+// withTransport returns a copy of client using transport, keeping its timeout and other settings.
+func withTransport(client *http.Client, transport http.RoundTripper) *http.Client {
+	wrapped := *client
+	wrapped.Transport = transport
+	return &wrapped
+}
+
+func (p *webhookProvider) Name() string {
+	return webhookProviderName
+}
+
+// Probe checks that the webhook accepts connections. Local mode without a webhook is always
+// reachable.
+func (p *webhookProvider) Probe(ctx context.Context) error {
+	if p.webhookURL == localProviderURL {
+		return nil
+	}
+	return probeURL(ctx, p.webhookURL)
+}
+
+func (p *webhookProvider) Send(ctx context.Context, message model.Message) (ProviderResult, error) {
+	recipientPhone, err := phone.Normalize(message.RecipientPhone, p.countryCode)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("recipient %q: %w", message.RecipientPhone, err)
+	}
+
+	payloadBytes, err := p.encode(message, recipientPhone)
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.webhookURL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-ins-auth-key", p.authKey)
+	if len(p.signingKeys) > 0 {
+		timestamp := strconv.FormatInt(p.now().Unix(), 10)
+		req.Header.Set(WebhookSignatureTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, signWebhookPayload(p.signingKeys, timestamp, payloadBytes))
+	}
+	if requestID := logctx.RequestID(ctx); requestID != "" {
+		req.Header.Set(logctx.RequestIDHeader, requestID)
+	}
+
+	resp, err := doProviderRequest(p.breaker, p.httpClient, req, p.logger)
+	if err != nil {
+		return ProviderResult{}, err
+	}
+	defer resp.Body.Close()
+
+	// Check for valid response status codes (202 Accepted or 200 OK)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return ProviderResult{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var response map[string]any
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&response); err != nil {
+		return ProviderResult{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return ProviderResult{
+		MessageID: responseField(response, p.responseIDField),
+		Status:    responseField(response, p.responseStatusField),
+	}, nil
+}
+
+// responseField returns the value at the dot separated path of response as a string, or
+// an empty string when the response has none.
+func responseField(response map[string]any, path string) string {
+	var value any = response
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = object[name]
+	}
+
+	switch value := value.(type) {
+	case nil, map[string]any, []any:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// encode returns the request body of message in the configured schema version.
+func (p *webhookProvider) encode(message model.Message, recipientPhone string) ([]byte, error) {
+	if p.payloadVersion != config.WebhookPayloadCustom {
+		return json.Marshal(p.payload(message, recipientPhone))
+	}
+
+	data := WebhookTemplateData{
+		To:        recipientPhone,
+		Content:   message.Content,
+		SenderID:  p.senderID,
+		MessageID: message.ID,
+		Tenant:    message.Tenant,
+		Channel:   message.DeliveryChannel(),
+	}
+	if message.ExternalRef != nil {
+		data.ExternalSystem, data.ExternalID = message.ExternalRef.System, message.ExternalRef.ID
+	}
+
+	var body bytes.Buffer
+	if err := p.payloadTemplate.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, errors.New("WEBHOOK_PAYLOAD_TEMPLATE did not render valid JSON")
+	}
+	return body.Bytes(), nil
+}
+
+// signWebhookPayload returns the X-Signature value of body signed at timestamp.
+func signWebhookPayload(keys []string, timestamp string, body []byte) string {
+	signatures := make([]string, 0, len(keys))
+	for _, key := range keys {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		signatures = append(signatures, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(signatures, ",")
+}
+
+// payload builds the request body of message in the configured schema version.
+func (p *webhookProvider) payload(message model.Message, recipientPhone string) any {
+	if p.payloadVersion != config.WebhookPayloadV2 {
+		return MessagePayload{
+			To:      recipientPhone,
+			Content: message.Content,
+		}
+	}
+
+	return MessagePayloadV2{
+		Version:  config.WebhookPayloadV2,
+		To:       recipientPhone,
+		Content:  message.Content,
+		SenderID: p.senderID,
+		Metadata: MessageMetadata{
+			MessageID:   message.ID,
+			Tenant:      message.Tenant,
+			Channel:     message.DeliveryChannel(),
+			ExternalRef: message.ExternalRef,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/httptape"
+	"message-service/internal/pkg/localredis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+// newTapeSender builds a dispatcher whose webhook traffic in payload version goes through
+// an httptape transport replaying testdata/provider/<version>. Re-record the golden files
+// with mode httptape.ModeRecord against a real provider.
+func newTapeSender(t *testing.T, mode httptape.Mode, version string) DispatchService {
+	transport, err := httptape.NewTransport(mode, "testdata/provider/"+version, http.DefaultTransport)
+	assert.NoError(t, err)
+
+	providers := NewProviderRegistry()
+	providers.Register(model.ChannelSMS, &webhookProvider{
+		logger:     inslogger.NewNopLogger(),
+		breaker:    NewCircuitBreaker(webhookProviderName, 0, 0, inslogger.NewNopLogger()),
+		httpClient: &http.Client{Transport: transport},
+		webhookURL: "https://webhook.site/c3f13233-1ed4-429e-9649-8133b3b9c9cd",
+		authKey:    "test-auth-key",
+		// Golden files are per payload version, a schema change needs new recordings.
+		payloadVersion:      version,
+		senderID:            "INSIDER",
+		responseIDField:     defaultResponseIDField,
+		responseStatusField: defaultResponseStatusField,
+	})
+
+	return &dispatchService{
+		logger:      inslogger.NewNopLogger(),
+		redisClient: localredis.New(),
+		kpis:        NewBusinessMetrics(false),
+		inFlight:    NewInFlightLimiter(0, nil),
+		providers:   providers,
+	}
+}
+
+func TestSendMessageProviderContract(t *testing.T) {
+	sender := newTapeSender(t, httptape.ModeReplay, config.WebhookPayloadV1)
+
+	providerMessageID, err := sender.SendMessage(context.Background(), model.Message{
+		ID:             1,
+		Content:        "Insider - Project",
+		RecipientPhone: "+905551111111",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "67f2f8a8-ea58-4ed0-a6f9-ff217df4d849", providerMessageID)
+}
+
+func TestSendMessageProviderContractV2(t *testing.T) {
+	sender := newTapeSender(t, httptape.ModeReplay, config.WebhookPayloadV2)
+
+	providerMessageID, err := sender.SendMessage(context.Background(), model.Message{
+		ID:             1,
+		Content:        "Insider - Project",
+		RecipientPhone: "+905551111111",
+		Tenant:         "acme",
+		ExternalRef:    &model.ExternalRef{System: "orders", ID: "SO-10045"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0d1c6a8e-5b7f-4a52-9d3e-7c41f2a9b806", providerMessageID)
+}
+
+func TestSendMessageProviderContractDetectsPayloadChange(t *testing.T) {
+	sender := newTapeSender(t, httptape.ModeReplay, config.WebhookPayloadV1)
+
+	_, err := sender.SendMessage(context.Background(), model.Message{
+		ID:             1,
+		Content:        "A payload that was never recorded",
+		RecipientPhone: "+905551111111",
+	})
+
+	assert.True(t, errors.Is(err, httptape.ErrNoRecording))
+
+	// The same message in another payload version is a different contract.
+	sender = newTapeSender(t, httptape.ModeReplay, config.WebhookPayloadV2)
+	_, err = sender.SendMessage(context.Background(), model.Message{
+		ID:             1,
+		Content:        "Insider - Project",
+		RecipientPhone: "+905551111111",
+	})
+
+	assert.True(t, errors.Is(err, httptape.ErrNoRecording))
+}
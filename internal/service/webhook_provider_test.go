@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"message-service/internal/config"
+	"message-service/internal/middleware"
+	"message-service/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/useinsider/go-pkg/inslogger"
+)
+
+func TestWebhookProviderSignsRequests(t *testing.T) {
+	var signed *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"message": "Accepted", "messageId": "SM1"}`))
+	}))
+	defer server.Close()
+
+	newProvider := func(primary, secondary string) Provider {
+		cfg := newDriverConfig(config.ProviderWebhook)
+		cfg.WebhookURL = server.URL
+		cfg.SigningKey = primary
+		cfg.SigningKeySecondary = secondary
+		return NewWebhookProvider(NewCircuitBreaker(webhookProviderName, 0, 0, inslogger.NewNopLogger()), server.Client(), cfg, inslogger.NewNopLogger())
+	}
+	message := model.Message{ID: 1, Content: "hi", RecipientPhone: "+905551111111"}
+
+	_, err := newProvider("", "").Send(context.Background(), message)
+	require.NoError(t, err)
+	assert.Empty(t, signed.Header.Get(WebhookSignatureHeader), "requests are unsigned without a key")
+
+	_, err = newProvider("primary", "").Send(context.Background(), message)
+	require.NoError(t, err)
+	verifier, err := middleware.NewSignatureVerifier("hmac", []string{"primary"})
+	require.NoError(t, err)
+	assert.NoError(t, verifier.Verify(signed, body), "the signature follows the callback scheme")
+
+	_, err = newProvider("primary", "secondary").Send(context.Background(), message)
+	require.NoError(t, err)
+	timestamp := signed.Header.Get(WebhookSignatureTimestampHeader)
+	signatures := strings.Split(signed.Header.Get(WebhookSignatureHeader), ",")
+	assert.Equal(t, []string{
+		signWebhookPayload([]string{"primary"}, timestamp, body),
+		signWebhookPayload([]string{"secondary"}, timestamp, body),
+	}, signatures, "both keys sign while they are rotated")
+}
+
+func TestWebhookProviderCustomContract(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"data": {"sid": 4021, "state": "queued"}}`))
+	}))
+	defer server.Close()
+
+	cfg := newDriverConfig(config.ProviderWebhook)
+	cfg.WebhookURL = server.URL
+	cfg.PayloadVersion = config.WebhookPayloadCustom
+	cfg.PayloadTemplate = `{"phone": {{json .To}}, "text": {{json .Content}}, "order": {{json .ExternalID}}}`
+	cfg.ResponseIDField = "data.sid"
+	cfg.ResponseStatusField = "data.state"
+	provider := NewWebhookProvider(NewCircuitBreaker(webhookProviderName, 0, 0, inslogger.NewNopLogger()), server.Client(), cfg, inslogger.NewNopLogger())
+
+	result, err := provider.Send(context.Background(), model.Message{
+		ID:             1,
+		Content:        `Say "hi"`,
+		RecipientPhone: "+905551111111",
+		ExternalRef:    &model.ExternalRef{System: "orders", ID: "SO-1"},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"phone": "+905551111111", "text": "Say \"hi\"", "order": "SO-1"}`, string(body))
+	assert.Equal(t, ProviderResult{MessageID: "4021", Status: "queued"}, result)
+
+	cfg.PayloadTemplate = `{"text": {{.Content}}}`
+	provider = NewWebhookProvider(NewCircuitBreaker(webhookProviderName, 0, 0, inslogger.NewNopLogger()), server.Client(), cfg, inslogger.NewNopLogger())
+	_, err = provider.Send(context.Background(), model.Message{ID: 1, Content: "unquoted", RecipientPhone: "+905551111111"})
+	assert.ErrorContains(t, err, "valid JSON", "bodies that are not JSON are never sent")
+}
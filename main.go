@@ -1,23 +1,16 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
-	"time"
+	"os"
+	"strings"
+	// DISPLAY_TIMEZONE must resolve in images without a zoneinfo database, e.g. alpine.
+	_ "time/tzdata"
 
-	"github.com/gin-gonic/gin"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/useinsider/go-pkg/inslogger"
-	"github.com/useinsider/go-pkg/insredis"
 
 	_ "message-service/docs"
-	"message-service/internal/config"
-	"message-service/internal/handler"
-	"message-service/internal/mpostgres"
-	"message-service/internal/pkg/gpostgresql"
-	"message-service/internal/service"
 )
 
 // @title message-service API
@@ -36,59 +29,57 @@ import (
 // @BasePath /
 
 // @schemes http
-func main() {
-	logger := inslogger.NewLogger(inslogger.Debug)
-	logger.Log("Starting the application...")
 
-	ctx := context.Background()
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 
-	logger.Log("Reading configuration...")
-	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
-
-	logger.Log("Connecting to the database...")
-	dbPool, err := gpostgresql.NewDBConnection(ctx, &appConfig.Database, logger)
-	if err != nil {
-		logger.Fatal(fmt.Errorf("database connection failed: %w", err))
-	}
-	defer gpostgresql.Close(ctx, dbPool, logger)
-	logger.Log("Connected to the database.")
-
-	logger.Log("Initializing services...")
-	messageService := mpostgres.NewMessageService(dbPool, logger)
-
-	redisCfg := insredis.Config{
-		RedisHost:     fmt.Sprintf("%s:%d", appConfig.Redis.Host, appConfig.Redis.Port),
-		RedisPoolSize: 10,
-		DialTimeout:   500 * time.Millisecond,
-		ReadTimeout:   500 * time.Millisecond,
-		MaxRetries:    3,
-	}
+// command is a subcommand of the binary. run parses its own flags from args.
+type command struct {
+	summary string
+	run     func(args []string, logger inslogger.Interface) error
+}
 
-	redisClient := insredis.Init(redisCfg)
-	if err := redisClient.Ping().Err(); err != nil {
-		logger.Fatal(fmt.Errorf("failed to connect to Redis: %w", err))
-	}
-	logger.Log("Connected to Redis.")
+var commands = map[string]command{
+	"serve":        {"Run the HTTP API and the scheduler (default)", runServe},
+	"worker":       {"Run the scheduler only, without the HTTP API", runWorker},
+	"send":         {"Send a single message through its provider and exit", runSend},
+	"migrate":      {"Apply the pending database migrations and exit", runMigrate},
+	"smoke":        {"Send a test message through a deployment and check it end to end", runSmoke},
+	"mockprovider": {"Serve a mock of the webhook gateway for local development", runMockProvider},
+}
 
-	messageSender := service.NewMessageSender(messageService, redisClient, appConfig, logger)
-	schedulerService := service.NewSchedulerService(messageSender, 2*time.Minute, 2, logger)
+func main() {
+	logger := inslogger.NewLogger(inslogger.Debug)
 
-	logger.Log("Creating message handler...")
-	messageHandler := handler.NewMessageHandler(messageService, schedulerService, messageSender, logger)
-	logger.Log("Setting up the router...")
-	router := gin.Default()
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	logger.Log("Registering routes...")
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "help" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		}
+		usage()
+		os.Exit(2)
+	}
 
-	router.POST("/api/messages/send", messageHandler.SendMessage)
-	router.POST("/api/scheduler/start", messageHandler.StartScheduler)
-	router.POST("/api/scheduler/stop", messageHandler.StopScheduler)
-	router.GET("/api/messages/sent", messageHandler.GetSentMessages)
+	if err := cmd.run(args, logger); err != nil {
+		logger.Fatal(err)
+	}
+}
 
-	logger.Log("Starting the server...")
-	err = router.Run(fmt.Sprintf(":%d", appConfig.Server.Port))
-	if err != nil {
-		logger.Fatal(fmt.Errorf("failed to start server: %w", err))
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range []string{"serve", "worker", "send", "migrate", "smoke", "mockprovider"} {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+// newFlagSet returns the flag set of the named command. Parse errors exit the process.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
 }
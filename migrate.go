@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/pkg/gpostgresql"
+	"message-service/migrations"
+)
+
+// runMigrate applies the embedded migrations that are not recorded in the database yet.
+func runMigrate(args []string, logger inslogger.Interface) error {
+	flags := newFlagSet("migrate")
+	baseline := flags.Int("baseline", 0, "record migrations up to this version as applied without running them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+	if appConfig.Local.Enabled {
+		return errors.New("LOCAL_MODE keeps messages in memory, there is no database to migrate")
+	}
+
+	logger.Log("Connecting to the database...")
+	dbPool, err := gpostgresql.NewDBConnection(ctx, &appConfig.Database, logger)
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer gpostgresql.Close(context.Background(), dbPool, logger)
+
+	applied, err := gpostgresql.Migrate(ctx, dbPool, migrations.Files, *baseline, logger)
+	if errors.Is(err, gpostgresql.ErrUntrackedSchema) {
+		return fmt.Errorf("%w, rerun with --baseline set to the last migration the database has, i.e. the newest migrations/ file it was created from", err)
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	logger.Logf("Applied %d migrations.", applied)
+	return nil
+}
@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_messages_lease_expires_at ON messages(lease_expires_at);
@@ -0,0 +1,11 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'pending';
+
+UPDATE messages SET status = 'sent' WHERE sent = TRUE;
+
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'cancelled'));
+
+DROP INDEX IF EXISTS idx_messages_sent;
+ALTER TABLE messages DROP COLUMN IF EXISTS sent;
+
+CREATE INDEX IF NOT EXISTS idx_messages_status ON messages(status);
@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_messages_scheduled_at ON messages(scheduled_at);
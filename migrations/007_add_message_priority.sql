@@ -0,0 +1,7 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0;
+
+ALTER TABLE messages ADD CONSTRAINT messages_priority_check
+    CHECK (priority BETWEEN 0 AND 9);
+
+CREATE INDEX IF NOT EXISTS idx_messages_pending_priority ON messages(priority DESC, created_at)
+    WHERE status = 'pending';
@@ -0,0 +1,8 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS channel VARCHAR(16) NOT NULL DEFAULT 'sms';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS recipient_email VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE messages ADD CONSTRAINT messages_channel_check
+    CHECK (channel IN ('sms', 'email', 'push'));
+
+-- Email messages have no phone number.
+ALTER TABLE messages ALTER COLUMN recipient_phone SET DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE scheduler_runs ADD COLUMN IF NOT EXISTS channel VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(16);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_messages_provider_message_id ON messages(provider_message_id);
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tenant VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Anonymized messages keep a sha256:<hex> hash of the recipient instead of the phone number.
+ALTER TABLE messages ALTER COLUMN recipient_phone TYPE VARCHAR(80);
+
+CREATE INDEX IF NOT EXISTS idx_messages_recipient_phone ON messages(recipient_phone);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
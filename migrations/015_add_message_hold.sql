@@ -0,0 +1,4 @@
+-- Held messages wait for a compliance review and are skipped by the scheduler until released.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_held ON messages(id) WHERE held;
@@ -0,0 +1,3 @@
+-- Decision of the pre-send moderation step and why it was taken, NULL until a message is moderated.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS moderation_decision VARCHAR(16);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS moderation_reason TEXT;
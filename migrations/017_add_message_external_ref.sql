@@ -0,0 +1,5 @@
+-- Reference of the message in the upstream system that requested it, NULL when it has none.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS external_system VARCHAR(64);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_external_ref ON messages(tenant, external_system, external_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+-- Backfilled messages were imported from a legacy system after it had sent them.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS backfilled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,15 @@
+-- The audit trail of every message: its state changes and send attempts.
+CREATE TABLE IF NOT EXISTS message_events (
+    id BIGSERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    provider VARCHAR(255) NOT NULL DEFAULT '',
+    response_code INTEGER,
+    error TEXT NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_events_message_id ON message_events(message_id, occurred_at);
@@ -0,0 +1,16 @@
+-- Announces the channel of every inserted message that is due on messages_due, so the
+-- scheduler sends it right away instead of on its next tick.
+CREATE OR REPLACE FUNCTION notify_message_due() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('messages_due', NEW.channel);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS messages_notify_due ON messages;
+CREATE TRIGGER messages_notify_due
+    AFTER INSERT ON messages
+    FOR EACH ROW
+    WHEN (NEW.status = 'pending' AND NOT NEW.held
+        AND (NEW.scheduled_at IS NULL OR NEW.scheduled_at <= NOW() AT TIME ZONE 'UTC'))
+    EXECUTE FUNCTION notify_message_due();
@@ -0,0 +1,2 @@
+-- Dry-run messages were marked sent without calling a provider, see SEND_MODE.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS provider_configs (
+    id SERIAL PRIMARY KEY,
+    tenant VARCHAR(255) NOT NULL UNIQUE,
+    webhook_url TEXT NOT NULL,
+    auth_key TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
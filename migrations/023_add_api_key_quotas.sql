@@ -0,0 +1,3 @@
+-- Messages an API key may send per UTC day and month, 0 leaves the period uncapped.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS daily_quota INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS monthly_quota INTEGER NOT NULL DEFAULT 0;
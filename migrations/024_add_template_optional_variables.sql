@@ -0,0 +1,3 @@
+-- Variables a template only tests with if or passes to default. Templates stored before
+-- keep requiring them until they are saved again.
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS optional_variables TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- Sends of a message that failed. Failed messages are only retried while they have fewer
+-- than SCHEDULER_MAX_ATTEMPTS, the others stay failed.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Templates belong to the tenant of the key that created them, names are unique per tenant.
+-- Templates stored before belong to no tenant and are only seen by admin keys.
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS tenant VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE templates DROP CONSTRAINT IF EXISTS templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_templates_tenant_name ON templates(tenant, name);
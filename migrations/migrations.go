@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so the binary can apply them with the migrate
+// command. The files are also mounted into the PostgreSQL container as init scripts.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
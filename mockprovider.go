@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/pkg/mockwebhook"
+)
+
+// runMockProvider serves a mock of the webhook gateway for local development. Point
+// WEBHOOK_URL at it, with query parameters to simulate latency and rate limiting.
+func runMockProvider(args []string, logger inslogger.Interface) error {
+	flags := newFlagSet("mockprovider")
+	addr := flags.String("addr", ":9091", "address to listen on, the admin listener takes 9090")
+	authKey := flags.String("auth-key", "", "AUTH_KEY sends must present, any key is accepted when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mockwebhook.NewServer(*authKey, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	logger.Logf("Mock provider listening on %s", *addr)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("mock provider: %w", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
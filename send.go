@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/phone"
+)
+
+// runSend hands one message to the provider of its channel and prints the provider message
+// ID. The message is not stored, --id is only passed on to the provider, so the command is
+// meant for checking provider credentials and templates from a shell.
+func runSend(args []string, logger inslogger.Interface) error {
+	flags := newFlagSet("send")
+	id := flags.Uint("id", 0, "message ID passed to the provider (required)")
+	content := flags.String("content", "", "message content (required)")
+	phoneNumber := flags.String("phone", "", "recipient phone number, required unless --channel=email")
+	email := flags.String("email", "", "recipient email address, required for --channel=email")
+	channel := flags.String("channel", string(model.ChannelSMS), "delivery channel: sms, email or push")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	message := model.Message{
+		ID:             *id,
+		Content:        *content,
+		RecipientEmail: *email,
+		Channel:        model.Channel(*channel),
+		Status:         model.StatusPending,
+	}
+	switch {
+	case message.ID == 0:
+		return errors.New("--id is required")
+	case message.Content == "":
+		return errors.New("--content is required")
+	case !message.Channel.Valid():
+		return fmt.Errorf("unknown channel %q", *channel)
+	case message.Channel == model.ChannelEmail && message.RecipientEmail == "":
+		return errors.New("--email is required for the email channel")
+	case message.Channel != model.ChannelEmail && *phoneNumber == "":
+		return errors.New("--phone is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+	if *phoneNumber != "" {
+		normalized, err := phone.Normalize(*phoneNumber, appConfig.Phone.DefaultCountryCode)
+		if err != nil {
+			return fmt.Errorf("invalid --phone: %w", err)
+		}
+		message.RecipientPhone = normalized
+	}
+
+	a := newApp(ctx, appConfig, logger)
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+	defer a.lifecycle.Stop(context.Background())
+
+	providerMessageID, err := a.dispatcher.SendMessage(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send message ID %d: %w", message.ID, err)
+	}
+
+	fmt.Println(providerMessageID)
+	return nil
+}
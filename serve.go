@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/handler"
+	"message-service/internal/middleware"
+	"message-service/internal/pkg/lifecycle"
+	"message-service/internal/pkg/metrics"
+)
+
+// runServe runs the HTTP API, the admin listener and the scheduler until SIGINT or SIGTERM.
+func runServe(args []string, logger inslogger.Interface) error {
+	if err := newFlagSet("serve").Parse(args); err != nil {
+		return err
+	}
+	logger.Log("Starting the application...")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Log("Reading configuration...")
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+	a := newApp(ctx, appConfig, logger)
+	a.addBackgroundJobs(false)
+	if a.eventTail != nil {
+		a.lifecycle.Append(lifecycle.Background("event stream tail", a.eventTail.Run))
+	}
+
+	router, err := newRouter(a, appConfig, logger)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", appConfig.Server.Port),
+		Handler: router,
+	}
+	// Shutdown waits for open requests, event streams would only end with the grace period.
+	server.RegisterOnShutdown(a.eventBus.Close)
+	adminServer := newAdminServer(appConfig, nil, logger)
+
+	// Appended last so they stop first: no new requests reach the scheduler or the pools
+	// while those shut down.
+	serverErr := make(chan error, 2)
+	a.lifecycle.Append(httpServerHook("the server", server, serverErr))
+	a.lifecycle.Append(httpServerHook("the admin server", adminServer, serverErr))
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Log("Shutdown signal received.")
+	case err := <-serverErr:
+		logger.Errorf("failed to start server: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appConfig.Server.ShutdownGracePeriod)
+	defer cancel()
+
+	if err := a.lifecycle.Stop(shutdownCtx); err != nil {
+		logger.Errorf("shutdown did not complete: %v", err)
+	}
+	logger.Log("Shutdown complete.")
+	return nil
+}
+
+// newRouter registers the public API, the provider callbacks and the health probes of a on
+// a new router.
+func newRouter(a *app, appConfig *config.App, logger inslogger.Interface) (*gin.Engine, error) {
+	logger.Log("Creating message handler...")
+	messageHandler := handler.NewMessageHandler(a.messageService, a.schedulerService, a.schedulerState, a.dispatcher, a.templateService, appConfig.Phone.DefaultCountryCode, appConfig.Scheduler.ClockSkew, appConfig.Display.Location(), a.events, a.moderation, logger)
+	schedulerRunHandler := handler.NewSchedulerRunHandler(a.schedulerRuns, appConfig.Display.Location(), logger)
+	templateHandler := handler.NewTemplateHandler(a.templateService, logger)
+	apiKeyHandler := handler.NewAPIKeyHandler(a.apiKeyService, logger)
+	providerConfigHandler := handler.NewProviderConfigHandler(a.providerConfigService, logger)
+	quotaHandler := handler.NewQuotaHandler(a.quotas, logger)
+	callbackHandler := handler.NewCallbackHandler(a.messageService, logger)
+	workerHandler := handler.NewWorkerHandler(a.messageService, appConfig.Worker.Lease, a.events, logger)
+	scalingHandler := handler.NewScalingHandler(a.scalingAdvisor, logger)
+	statsHandler := handler.NewStatsHandler(a.statsService, logger)
+	messageAuditHandler := handler.NewMessageAuditHandler(a.messageService, a.messageAudit, logger)
+	tuningHandler := handler.NewTuningHandler(a.tuningAdvisor, logger)
+	backfillHandler := handler.NewBackfillHandler(a.messageService, appConfig.Phone.DefaultCountryCode, logger)
+	outboundLimitHandler := handler.NewOutboundLimitHandler(a.outbound, logger)
+	diagnosticsHandler := handler.NewDiagnosticsHandler(a.diagnostics, logger)
+	eventStreamHandler := handler.NewEventStreamHandler(a.eventBus, appConfig.Events.SSEHeartbeat, logger)
+	healthHandler := handler.NewHealthHandler(a.readinessChecks, a.schedulerService, a.breakers, logger)
+	logger.Log("Setting up the router...")
+	gin.SetMode(appConfig.Server.GinMode)
+	router := gin.New()
+	// Behind the load balancer the peer address is the proxy, the client IP used for logs
+	// and rate limits comes from RemoteIPHeaders set by the trusted proxies only.
+	router.RemoteIPHeaders = appConfig.Server.RemoteIPHeaders
+	if err := router.SetTrustedProxies(appConfig.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(gin.Recovery(), middleware.RequestLogger(logger))
+	if appConfig.Swagger.Enabled {
+		router.GET("/swagger/*any", middleware.StaticCacheHeaders(appConfig.Swagger.CacheMaxAge), ginSwagger.WrapHandler(swaggerFiles.Handler))
+	} else {
+		logger.Log("SWAGGER_ENABLED is false, API docs are not served")
+	}
+	if appConfig.AdminUI.Enabled {
+		router.GET("/admin", handler.AdminUI)
+	}
+
+	logger.Log("Registering routes...")
+
+	api := router.Group("/api")
+	// Keys in API_KEYS bootstrap authentication, clients get their own keys from the admin
+	// endpoints so they can be revoked one by one. Without API_KEYS only issued keys are
+	// accepted.
+	if appConfig.Auth.Disabled {
+		logger.Warn("AUTH_DISABLED is true, /api routes are not authenticated")
+		api.Use(middleware.DisabledAuth())
+	} else {
+		api.Use(middleware.APIKeyAuth(appConfig.Auth.APIKeys, a.apiKeyService, logger))
+		if len(appConfig.Tenants.RateLimits) > 0 {
+			api.Use(a.rateLimiter.Tenants(appConfig.Tenants.RateLimits))
+		}
+	}
+
+	// Every API route and the rate limit class it draws from. Routes sharing a class share
+	// one budget per client, and the class is also the scope a key needs to call the route.
+	routes := []struct {
+		method  string
+		path    string
+		class   string
+		handler gin.HandlerFunc
+	}{
+		{http.MethodPost, "/messages/send", "write", middleware.WithQuota(a.quotas, messageHandler.SendMessage, logger)},
+		{http.MethodGet, "/messages", "read", messageHandler.ListMessages},
+		{http.MethodGet, "/messages/sent", "read", messageHandler.GetSentMessages},
+		{http.MethodGet, "/messages/stream", "read", eventStreamHandler.StreamMessageEvents},
+		{http.MethodGet, "/messages/:id", "read", messageHandler.GetMessage},
+		{http.MethodGet, "/messages/by-ref/:system/:id", "read", messageHandler.GetMessageByExternalRef},
+		{http.MethodGet, "/messages/:id/events", "read", messageAuditHandler.ListMessageEvents},
+		{http.MethodPatch, "/messages/:id", "write", messageHandler.PatchMessage},
+		{http.MethodPost, "/messages/:id/cancel", "write", messageHandler.CancelMessage},
+		{http.MethodPost, "/messages/:id/release", "admin", messageHandler.ReleaseMessage},
+		{http.MethodDelete, "/messages/purge", "admin", messageHandler.PurgeMessages},
+		{http.MethodPost, "/scheduler/start", "admin", messageHandler.StartScheduler},
+		{http.MethodPost, "/scheduler/stop", "admin", messageHandler.StopScheduler},
+		{http.MethodPost, "/scheduler/run-now", "admin", messageHandler.RunSchedulerNow},
+		{http.MethodGet, "/scheduler/status", "read", messageHandler.GetSchedulerStatus},
+		{http.MethodGet, "/scheduler/runs", "read", schedulerRunHandler.ListRuns},
+		{http.MethodGet, "/scheduler/channels", "read", messageHandler.GetSchedulerChannels},
+		{http.MethodPut, "/scheduler/channels/:channel", "admin", messageHandler.UpdateSchedulerChannel},
+		{http.MethodPost, "/templates", "write", templateHandler.CreateTemplate},
+		{http.MethodGet, "/templates", "read", templateHandler.ListTemplates},
+		{http.MethodGet, "/templates/:id", "read", templateHandler.GetTemplate},
+		{http.MethodPut, "/templates/:id", "write", templateHandler.UpdateTemplate},
+		{http.MethodDelete, "/templates/:id", "write", templateHandler.DeleteTemplate},
+		{http.MethodPost, "/worker/claim", "write", workerHandler.Claim},
+		{http.MethodPost, "/worker/complete", "write", workerHandler.Complete},
+		{http.MethodGet, "/internal/scaling", "read", scalingHandler.GetScaling},
+		{http.MethodGet, "/stats", "read", statsHandler.GetStats},
+		{http.MethodGet, "/quota", "read", quotaHandler.GetQuota},
+		{http.MethodPost, "/admin/apikeys", "admin", apiKeyHandler.CreateAPIKey},
+		{http.MethodGet, "/admin/apikeys", "admin", apiKeyHandler.ListAPIKeys},
+		{http.MethodPost, "/admin/apikeys/:id/revoke", "admin", apiKeyHandler.RevokeAPIKey},
+		{http.MethodPost, "/admin/apikeys/:id/rotate", "admin", apiKeyHandler.RotateAPIKey},
+		{http.MethodPut, "/admin/apikeys/:id/quota", "admin", apiKeyHandler.SetAPIKeyQuota},
+		{http.MethodPost, "/admin/provider-configs", "admin", providerConfigHandler.CreateProviderConfig},
+		{http.MethodGet, "/admin/provider-configs", "admin", providerConfigHandler.ListProviderConfigs},
+		{http.MethodGet, "/admin/provider-configs/:id", "admin", providerConfigHandler.GetProviderConfig},
+		{http.MethodPut, "/admin/provider-configs/:id", "admin", providerConfigHandler.UpdateProviderConfig},
+		{http.MethodDelete, "/admin/provider-configs/:id", "admin", providerConfigHandler.DeleteProviderConfig},
+		{http.MethodGet, "/admin/outbound-limits", "admin", outboundLimitHandler.GetLimits},
+		{http.MethodPut, "/admin/outbound-limits", "admin", outboundLimitHandler.UpdateLimits},
+		{http.MethodDelete, "/admin/outbound-limits", "admin", outboundLimitHandler.ResetLimits},
+		{http.MethodGet, "/admin/tuning", "admin", tuningHandler.GetTuning},
+		{http.MethodGet, "/admin/diagnostics", "admin", diagnosticsHandler.GetDiagnostics},
+		{http.MethodPost, "/admin/messages/backfill", "admin", backfillHandler.BackfillMessages},
+	}
+	for _, route := range routes {
+		limit, err := a.rateLimiter.Class(route.class)
+		if err != nil {
+			logger.Fatal(fmt.Errorf("route %s %s: %w", route.method, route.path, err))
+		}
+		api.Handle(route.method, route.path, middleware.RequireScope(route.class), limit, route.handler)
+	}
+
+	// Provider callbacks are signed instead of carrying an API key, so they are registered
+	// outside the authenticated group.
+	if len(appConfig.Callback.SigningSecrets) > 0 {
+		verifier, err := middleware.NewSignatureVerifier(appConfig.Callback.SigningScheme, appConfig.Callback.SigningSecrets)
+		if err != nil {
+			logger.Fatal(fmt.Errorf("invalid callback signing configuration: %w", err))
+		}
+		router.POST("/api/webhooks/delivery-status",
+			middleware.VerifyCallbackSignature(appConfig.SMS.Driver, verifier, logger),
+			callbackHandler.DeliveryStatus)
+	} else {
+		logger.Warn("CALLBACK_SIGNING_SECRETS is empty, delivery status callbacks are disabled")
+	}
+
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+
+	return router, nil
+}
+
+// newAdminServer serves metrics and pprof, plus the health probes of health when it is set.
+// It is bound to an internal interface so it is never reachable through the public load
+// balancer.
+func newAdminServer(appConfig *config.App, health *handler.HealthHandler, logger inslogger.Interface) *http.Server {
+	adminRouter := gin.New()
+	// Admin clients connect directly, never through a proxy.
+	_ = adminRouter.SetTrustedProxies(nil)
+	adminRouter.Use(gin.Recovery(), middleware.RequestLogger(logger))
+	adminRouter.GET("/metrics", gin.WrapH(metrics.DefaultRegistry.Handler()))
+	// net/http/pprof registers its handlers on the default mux, gin cannot route the
+	// named profiles next to a wildcard so the whole subtree is delegated to it.
+	adminRouter.Any("/debug/pprof/*profile", gin.WrapH(http.DefaultServeMux))
+	if health != nil {
+		adminRouter.GET("/healthz", health.Healthz)
+		adminRouter.GET("/readyz", health.Readyz)
+	}
+
+	return &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", appConfig.Admin.Host, appConfig.Admin.Port),
+		Handler: adminRouter,
+	}
+}
+
+// httpServerHook serves server in the background once started. Errors other than the
+// server being shut down are sent to serverErr.
+func httpServerHook(name string, server *http.Server, serverErr chan<- error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: fmt.Sprintf("%s on %s", name, server.Addr),
+		Start: func(context.Context) error {
+			go func() {
+				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					serverErr <- fmt.Errorf("%s: %w", name, err)
+				}
+			}()
+			return nil
+		},
+		Stop: server.Shutdown,
+	}
+}
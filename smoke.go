@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/model"
+	"message-service/internal/pkg/phone"
+	"message-service/internal/service"
+)
+
+// smokeTransitions are the status changes a smoke test message may go through. Polling can
+// miss sending, so pending may also turn into sent directly.
+var smokeTransitions = map[model.MessageStatus][]model.MessageStatus{
+	model.StatusPending: {model.StatusSending, model.StatusSent},
+	model.StatusSending: {model.StatusSent},
+}
+
+// runSmoke checks a deployment end to end, for use as a post-deploy gate: it stores a test
+// message, waits until the deployment sends it and checks the status transitions on the
+// way and what the send cached in Redis. It reads the environment of the deployment for
+// its database and Redis and polls its API at --url. The first failed check ends the
+// command with an error.
+func runSmoke(args []string, logger inslogger.Interface) error {
+	flags := newFlagSet("smoke")
+	baseURL := flags.String("url", "", "base URL of the deployed API, e.g. https://messages.internal (required)")
+	apiKey := flags.String("api-key", "", "API key with the read scope, required unless the deployment sets AUTH_DISABLED")
+	tenant := flags.String("tenant", "smoke", "tenant of the test message, configure it as sandbox in TENANT_ENVIRONMENTS")
+	phoneNumber := flags.String("phone", "+905550000000", "recipient phone number of the test message")
+	timeout := flags.Duration("timeout", 5*time.Minute, "how long to wait for the test message to be sent")
+	interval := flags.Duration("interval", 2*time.Second, "how often to poll the test message")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *baseURL == "" {
+		return errors.New("--url is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+	if appConfig.Local.Enabled {
+		return errors.New("smoke checks the database and Redis of a deployment and cannot run in LOCAL_MODE")
+	}
+	recipient, err := phone.Normalize(*phoneNumber, appConfig.Phone.DefaultCountryCode)
+	if err != nil {
+		return fmt.Errorf("invalid --phone: %w", err)
+	}
+
+	a := newApp(ctx, appConfig, logger)
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+	defer a.lifecycle.Stop(context.Background())
+
+	client := &smokeClient{
+		baseURL:    strings.TrimRight(*baseURL, "/"),
+		apiKey:     *apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := client.get(ctx, "/readyz", nil); err != nil {
+		return fmt.Errorf("deployment is not ready: %w", err)
+	}
+	fmt.Println("ok   deployment is ready")
+
+	message, err := a.messageService.CreateMessage(ctx, model.Message{
+		Content:        "message-service smoke test " + time.Now().UTC().Format(time.RFC3339),
+		RecipientPhone: recipient,
+		Tenant:         *tenant,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the test message: %w", err)
+	}
+	fmt.Printf("ok   created message %d for tenant %q\n", message.ID, message.Tenant)
+
+	start := time.Now()
+	sent, err := waitForSent(ctx, client, message.ID, *timeout, *interval)
+	if err != nil {
+		// A test message left pending would be sent by a later deployment.
+		if cancelErr := a.messageService.CancelMessage(context.Background(), message.ID); cancelErr == nil {
+			fmt.Printf("     cancelled message %d\n", message.ID)
+		}
+		return err
+	}
+	if sent.ProviderMessageID == "" {
+		return fmt.Errorf("message %d was sent without a provider message ID", message.ID)
+	}
+	fmt.Printf("ok   message %d sent after %s, provider message ID %s\n", message.ID, time.Since(start).Round(time.Millisecond), sent.ProviderMessageID)
+
+	// With MESSAGE_CACHE_TTL=0 nothing about messages is kept in Redis, and with
+	// REDIS_ENABLED=false it is kept in the memory of the service.
+	if appConfig.Cache.MessageTTL > 0 && appConfig.Redis.Enabled {
+		if _, err := service.SentAt(a.redisClient, message.ID); err != nil {
+			return fmt.Errorf("send time of message %d is not cached: %w", message.ID, err)
+		}
+		cached, err := service.CachedMessage(a.redisClient, message.ID)
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return err
+		case cached.Status != model.StatusSent:
+			return fmt.Errorf("cached copy of message %d is stale: %s instead of %s", message.ID, cached.Status, model.StatusSent)
+		}
+		fmt.Println("ok   Redis caches match the sent message")
+	}
+
+	return nil
+}
+
+// waitForSent polls message id through the API until it is sent, failing on transitions
+// outside smokeTransitions and once timeout passed.
+func waitForSent(ctx context.Context, client *smokeClient, id uint, timeout, interval time.Duration) (model.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	status := model.StatusPending
+	for {
+		var message model.Message
+		if err := client.get(ctx, fmt.Sprintf("/api/messages/%d", id), &message); err != nil {
+			if ctx.Err() != nil {
+				return message, fmt.Errorf("message %d is still %s after %s", id, status, timeout)
+			}
+			return message, err
+		}
+
+		if message.Status != status {
+			if !slices.Contains(smokeTransitions[status], message.Status) {
+				return message, fmt.Errorf("message %d went from %s to %s", id, status, message.Status)
+			}
+			fmt.Printf("ok   message %d went from %s to %s\n", id, status, message.Status)
+			status = message.Status
+		}
+		if status == model.StatusSent {
+			return message, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return message, fmt.Errorf("message %d is still %s after %s", id, status, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// smokeClient calls the API of the deployment under test.
+type smokeClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// get fetches path and decodes the response into out unless it is nil. Responses other
+// than 200 are errors.
+func (c *smokeClient) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
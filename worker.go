@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github.com/useinsider/go-pkg/inslogger"
+
+	"message-service/internal/config"
+	"message-service/internal/handler"
+)
+
+// runWorker runs the scheduler without the public API, so the sender can be deployed and
+// scaled apart from the HTTP servers. Only the admin listener is served, with the health
+// probes added to it.
+func runWorker(args []string, logger inslogger.Interface) error {
+	if err := newFlagSet("worker").Parse(args); err != nil {
+		return err
+	}
+	logger.Log("Starting the worker...")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Log("Reading configuration...")
+	appConfig := config.ReadEnvironment(ctx, &config.AppEnv, logger)
+	a := newApp(ctx, appConfig, logger)
+	// Nobody calls POST /api/scheduler/start on a worker, it starts sending right away
+	// unless the scheduler was stopped through the API of a serve deployment.
+	a.addBackgroundJobs(true)
+
+	healthHandler := handler.NewHealthHandler(a.readinessChecks, a.schedulerService, a.breakers, logger)
+	adminServer := newAdminServer(appConfig, healthHandler, logger)
+	serverErr := make(chan error, 1)
+	a.lifecycle.Append(httpServerHook("the admin server", adminServer, serverErr))
+	if err := a.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Log("Shutdown signal received.")
+	case err := <-serverErr:
+		logger.Errorf("failed to start server: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appConfig.Server.ShutdownGracePeriod)
+	defer cancel()
+
+	if err := a.lifecycle.Stop(shutdownCtx); err != nil {
+		logger.Errorf("shutdown did not complete: %v", err)
+	}
+	logger.Log("Shutdown complete.")
+	return nil
+}